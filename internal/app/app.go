@@ -12,6 +12,7 @@ import (
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/email/smtp"
 	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -31,12 +32,22 @@ func Run(configPath string) {
 		logger.Error(logger.ConvertErrorToStruct(err, 0, nil))
 		return
 	}
-	memcache := cache.NewMemoryCache()
+	memcache, err := newCache(cfg.Cache)
+	if err != nil {
+		logger.Error(logger.ConvertErrorToStruct(err, 0, nil))
+		return
+	}
 	emailSender := smtp.NewMailer(cfg.Smtp.Host, cfg.Smtp.Port, cfg.Smtp.Username, cfg.Smtp.Password, cfg.Smtp.Sender)
 
 	repos := repository.NewRepositories(db, *cfg, memcache)
 	services := service.NewServices(*repos, emailSender, &wg, *cfg, memcache)
 	handlers := http2.NewHandler(services, cfg)
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	services.Sync.Start(jobsCtx)
+	services.Notifications.StartImport(jobsCtx)
+	services.Webhooks.Start(jobsCtx)
+	services.Payments.Start(jobsCtx)
 	// HTTP Server
 	srv := server.NewServer(cfg, handlers.Init())
 
@@ -58,6 +69,7 @@ func Run(configPath string) {
 
 	ctx, shutdown := context.WithTimeout(context.Background(), timeout)
 	defer shutdown()
+	stopJobs()
 	wg.Wait()
 
 	if err := srv.Stop(ctx); err != nil {
@@ -68,6 +80,31 @@ func Run(configPath string) {
 		logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
 	}
 
+	if closer, ok := memcache.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("Failed to close cache: " + err.Error()))
+		}
+	}
+}
+
+// newCache creates cache storage depending on cache.driver option, memory cache is used by default.
+func newCache(cfg config.CacheConfig) (cache.Cache, error) {
+	switch cfg.Driver {
+	case "", "memory":
+		return cache.NewMemoryCache(), nil
+	case "file":
+		return cache.NewFileCache(cfg.Dir)
+	case "redis":
+		return cache.NewRedisCache(cache.RedisOptions{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			Db:       cfg.Redis.Db,
+			Prefix:   cfg.Redis.Prefix,
+			PoolSize: cfg.Redis.PoolSize,
+			Timeout:  cfg.Redis.Timeout,
+		})
+	}
+	return nil, errors.New("unknown cache driver: " + cfg.Driver)
 }
 
 func openDB(cfg *config.Config) (*sql.DB, error) {
@@ -20,10 +20,16 @@ type (
 		Cors        struct {
 			TrustedOrigins []string `yaml:"trustedOrigins"`
 		}
-		Email   EmailConfig   `yaml:"email"`
-		Vtiger  VtigerConfig  `yaml:"vtiger"`
-		Otp     OtpConfig     `yaml:"otp"`
-		Payment PaymentConfig `json:"payment"`
+		Email         EmailConfig         `yaml:"email"`
+		Vtiger        VtigerConfig        `yaml:"vtiger"`
+		Otp           OtpConfig           `yaml:"otp"`
+		Payment       PaymentConfig       `json:"payment"`
+		Sync          SyncConfig          `yaml:"sync"`
+		Notifications NotificationsConfig `yaml:"notifications"`
+		Webhooks      WebhooksConfig      `yaml:"webhooks"`
+		Tokens        TokensConfig        `yaml:"tokens"`
+		Webauthn      WebauthnConfig      `yaml:"webauthn"`
+		Sso           SsoConfig           `yaml:"sso"`
 	}
 	HTTPConfig struct {
 		Host               string        `yaml:"host"`
@@ -31,9 +37,22 @@ type (
 		ReadTimeout        time.Duration `yaml:"readTimeout"`
 		WriteTimeout       time.Duration `yaml:"writeTimeout"`
 		MaxHeaderMegabytes int           `yaml:"maxHeaderBytes"`
+		// TrustedProxies are addresses or networks of proxies, which are allowed to pass client ip in X-Forwarded-For.
+		TrustedProxies []string `yaml:"trustedProxies"`
 	}
 	CacheConfig struct {
-		TTL time.Duration `yaml:"ttl"`
+		TTL    time.Duration `yaml:"ttl"`
+		Driver string        `yaml:"driver"`
+		Dir    string        `yaml:"dir"`
+		Redis  RedisConfig   `yaml:"redis"`
+	}
+	RedisConfig struct {
+		Addr     string        `yaml:"addr"`
+		Password string        `yaml:"password"`
+		Db       int           `yaml:"db"`
+		Prefix   string        `yaml:"prefix"`
+		PoolSize int           `yaml:"poolSize"`
+		Timeout  time.Duration `yaml:"timeout"`
 	}
 	DatabaseConfig struct {
 		Dsn          string
@@ -57,6 +76,15 @@ type (
 		Burst   int
 		Enabled bool
 		TTL     time.Duration
+		Login   LoginLimiter `yaml:"login"`
+	}
+	LoginLimiter struct {
+		MaxAttempts     int           `yaml:"maxAttempts"`
+		MaxIpAttempts   int           `yaml:"maxIpAttempts"`
+		LockoutDuration time.Duration `yaml:"lockoutDuration"`
+		Delay           time.Duration `yaml:"delay"`
+		MaxDelay        time.Duration `yaml:"maxDelay"`
+		Window          time.Duration `yaml:"window"`
 	}
 	EmailConfig struct {
 		SendWelcomeEmail bool           `yaml:"sendWelcomeEmail"`
@@ -68,16 +96,31 @@ type (
 		RegistrationEmail    string `yaml:"registrationEmail"`
 		TicketSuccessful     string `yaml:"ticketSuccessful"`
 		RestorePasswordEmail string `yaml:"restorePasswordEmail"`
+		OtpLocked            string `yaml:"otpLocked"`
+		MagicLink            string `yaml:"magicLink"`
+		Invitation           string `yaml:"invitation"`
+		LoginLocked          string `yaml:"loginLocked"`
+		PaymentRefunded      string `yaml:"paymentRefunded"`
 	}
 
 	EmailSubjects struct {
 		RegistrationEmail string `yaml:"registrationEmail"`
 		TicketSuccessful  string `yaml:"ticketSuccessful"`
 		RestorePassword   string `yaml:"restorePassword"`
+		OtpLocked         string `yaml:"otpLocked"`
+		MagicLink         string `yaml:"magicLink"`
+		Invitation        string `yaml:"invitation"`
+		LoginLocked       string `yaml:"loginLocked"`
+		PaymentRefunded   string `yaml:"paymentRefunded"`
 	}
 	VtigerConfig struct {
 		Connection vtiger.VtigerConnectionConfig `yaml:"connection"`
 		Business   VtigerBusinessConfig          `yaml:"business"`
+		Webhook    VtigerWebhookConfig           `yaml:"webhook"`
+	}
+	VtigerWebhookConfig struct {
+		Secret    string        `yaml:"secret"`
+		Tolerance time.Duration `yaml:"tolerance"`
 	}
 	VtigerBusinessConfig struct {
 		EmailField         string              `yaml:"emailField"`
@@ -91,15 +134,95 @@ type (
 		CustomModules      map[string][]string `yaml:"customModules"`
 	}
 	OtpConfig struct {
-		Issuer      string `yaml:"issuer"`
-		AccountName string `yaml:"accountName"`
-		SecretSize  uint   `yaml:"secretSize"`
+		Issuer          string        `yaml:"issuer"`
+		AccountName     string        `yaml:"accountName"`
+		SecretSize      uint          `yaml:"secretSize"`
+		MaxAttempts     int           `yaml:"maxAttempts"`
+		LockoutDuration time.Duration `yaml:"lockoutDuration"`
+		RecoveryCodes   int           `yaml:"recoveryCodes"`
+	}
+	SyncConfig struct {
+		Enabled        bool          `yaml:"enabled"`
+		ReadFromMirror bool          `yaml:"readFromMirror"`
+		Interval       time.Duration `yaml:"interval"`
+		Modules        []string      `yaml:"modules"`
+	}
+	NotificationsConfig struct {
+		Enabled       bool          `yaml:"enabled"`
+		Module        string        `yaml:"module"`
+		AccountField  string        `yaml:"accountField"`
+		Interval      time.Duration `yaml:"interval"`
+		Heartbeat     time.Duration `yaml:"heartbeat"`
+		StreamHistory int           `yaml:"streamHistory"`
+	}
+	WebhooksConfig struct {
+		Enabled              bool          `yaml:"enabled"`
+		Timeout              time.Duration `yaml:"timeout"`
+		MaxAttempts          int           `yaml:"maxAttempts"`
+		RetryBackoff         time.Duration `yaml:"retryBackoff"`
+		MaxRetryBackoff      time.Duration `yaml:"maxRetryBackoff"`
+		PollInterval         time.Duration `yaml:"pollInterval"`
+		BatchSize            int           `yaml:"batchSize"`
+		AllowPrivateNetworks bool          `yaml:"allowPrivateNetworks"`
+	}
+	TokensConfig struct {
+		AccessTTL     time.Duration `yaml:"accessTTL"`
+		RefreshTTL    time.Duration `yaml:"refreshTTL"`
+		TouchInterval time.Duration `yaml:"touchInterval"`
+		MagicLinkTTL  time.Duration `yaml:"magicLinkTTL"`
+		InvitationTTL time.Duration `yaml:"invitationTTL"`
+	}
+	WebauthnConfig struct {
+		RPID          string        `yaml:"rpId"`
+		RPDisplayName string        `yaml:"rpDisplayName"`
+		RPOrigins     []string      `yaml:"rpOrigins"`
+		Timeout       time.Duration `yaml:"timeout"`
+	}
+	SsoConfig struct {
+		BaseUrl string `yaml:"baseUrl"`
+		// RedirectUrl is a page of frontend, which receives one-time code after sign in. By default, it is /sso of domain.
+		RedirectUrl string                 `yaml:"redirectUrl"`
+		Timeout     time.Duration          `yaml:"timeout"`
+		Providers   map[string]SsoProvider `yaml:"providers"`
+	}
+	SsoProvider struct {
+		Protocol       string   `yaml:"protocol"`
+		Issuer         string   `yaml:"issuer"`
+		ClientId       string   `yaml:"clientId"`
+		ClientSecret   string   `yaml:"clientSecret"`
+		Scopes         []string `yaml:"scopes"`
+		EntityId       string   `yaml:"entityId"`
+		MetadataUrl    string   `yaml:"metadataUrl"`
+		Metadata       string   `yaml:"metadata"`
+		EmailAttribute string   `yaml:"emailAttribute"`
 	}
 	PaymentConfig struct {
-		StripeKey         string `yaml:"stripe_key"`
-		StripePublic      string `yaml:"stripe_public"`
-		PaidSoStatus      string `yaml:"payed_so_status"`
-		PaidInvoiceStatus string `yaml:"payed_invoice_status"`
+		StripeKey    string `yaml:"stripe_key"`
+		StripePublic string `yaml:"stripe_public"`
+		// StripeWebhookSecret is a signing secret of stripe webhook endpoint.
+		StripeWebhookSecret string `yaml:"stripe_webhook_secret"`
+		PaidSoStatus        string `yaml:"payed_so_status"`
+		PaidInvoiceStatus   string `yaml:"payed_invoice_status"`
+		// Refunded statuses are set, when paid invoice or sales order is refunded. Status is not changed, when it is empty.
+		RefundedSoStatus      string `yaml:"refunded_so_status"`
+		RefundedInvoiceStatus string `yaml:"refunded_invoice_status"`
+		// DefaultProvider accepts payments in currencies, which are not listed in Providers.
+		DefaultProvider string `yaml:"default_provider"`
+		// Providers maps lowercase currency code to name of provider, e.g. rub: yookassa.
+		Providers map[string]string `yaml:"providers"`
+		// ReturnUrl is a page, where customer comes back after payment on page of provider.
+		ReturnUrl string         `yaml:"return_url"`
+		YooKassa  YooKassaConfig `yaml:"yookassa"`
+		// Failed revisions of invoices and sales orders in crm are retried with growing backoff.
+		RevisionAttempts     int           `yaml:"revision_attempts"`
+		RevisionBackoff      time.Duration `yaml:"revision_backoff"`
+		RevisionPollInterval time.Duration `yaml:"revision_poll_interval"`
+	}
+	YooKassaConfig struct {
+		ShopId    string        `yaml:"shop_id"`
+		SecretKey string        `yaml:"secret_key"`
+		Url       string        `yaml:"url"`
+		Timeout   time.Duration `yaml:"timeout"`
 	}
 )
 
@@ -22,6 +22,11 @@ http:
   maxHeaderBytes: 1024
 cache:
   ttl: 1h
+  driver: redis
+  redis:
+    addr: 127.0.0.1:6380
+    prefix: "portal:"
+    timeout: 2s
 db:
   host: localhost
   login: user
@@ -75,6 +80,10 @@ vtiger:
 	assert.Equal(t, 10*time.Second, cfg.HTTP.WriteTimeout)
 	assert.Equal(t, 1024, cfg.HTTP.MaxHeaderMegabytes)
 	assert.Equal(t, 1*time.Hour, cfg.Cache.TTL)
+	assert.Equal(t, "redis", cfg.Cache.Driver)
+	assert.Equal(t, "127.0.0.1:6380", cfg.Cache.Redis.Addr)
+	assert.Equal(t, "portal:", cfg.Cache.Redis.Prefix)
+	assert.Equal(t, 2*time.Second, cfg.Cache.Redis.Timeout)
 	assert.Equal(t, "user:password@localhost/testdb?parseTime=true", cfg.Db.Dsn)
 	assert.Equal(t, 10, cfg.Db.MaxOpenConns)
 	assert.Equal(t, 5, cfg.Db.MaxIdleConns)
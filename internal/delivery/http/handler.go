@@ -8,6 +8,8 @@ import (
 	v1 "github.com/semelyanov86/vtiger-portal/internal/delivery/http/v1"
 	"github.com/semelyanov86/vtiger-portal/internal/service"
 	"github.com/semelyanov86/vtiger-portal/pkg/limiter"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
 	"net/http"
 )
 
@@ -26,6 +28,11 @@ func NewHandler(services *service.Services, config *config.Config) *Handler {
 func (h *Handler) Init() *gin.Engine {
 	// Init gin handler
 	router := gin.Default()
+	// client ip is used by limiters and audit log, so forwarded ip is accepted only from own proxies
+	if err := router.SetTrustedProxies(h.config.HTTP.TrustedProxies); err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not set trusted proxies, forwarded ip is ignored: " + err.Error()))
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	router.Use(
 		gin.Recovery(),
@@ -42,11 +49,23 @@ func (h *Handler) Init() *gin.Engine {
 		c.JSON(http.StatusOK, gin.H{"success": true, "message": "active"})
 	})
 
+	router.GET("/api/v1/health", h.health)
+
 	h.initAPI(router)
 
 	return router
 }
 
+// health reports state of vtiger circuit breaker, so load balancers can see that crm is unavailable.
+func (h *Handler) health(c *gin.Context) {
+	state := vtiger.SharedTransport(h.config.Vtiger.Connection).Breaker().State()
+	status := http.StatusOK
+	if state.State == vtiger.BreakerOpen {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"success": status == http.StatusOK, "vtiger": state})
+}
+
 func (h *Handler) initAPI(router *gin.Engine) {
 	handlerV1 := v1.NewHandler(h.services, h.config)
 	api := router.Group("/api")
@@ -1,12 +1,21 @@
 package http_test
 
 import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	http2 "github.com/semelyanov86/vtiger-portal/internal/delivery/http"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
 	"github.com/semelyanov86/vtiger-portal/internal/service"
 	"github.com/stretchr/testify/require"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 )
@@ -40,3 +49,105 @@ func TestNewHandler_Init(t *testing.T) {
 
 	require.Equal(t, http.StatusOK, res.StatusCode)
 }
+
+func TestNewHandler_ClientIp(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		ip      string
+	}{
+		{name: "Forwarded ip is ignored without trusted proxies", ip: "10.0.0.5"},
+		{name: "Forwarded ip of trusted proxy is used", proxies: []string{"10.0.0.0/8"}, ip: "203.0.113.7"},
+		{name: "Forwarded ip of other proxy is ignored", proxies: []string{"192.168.0.1"}, ip: "10.0.0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http2.NewHandler(&service.Services{
+				Context: service.MockedContextService{},
+			}, &config.Config{
+				HTTP:    config.HTTPConfig{TrustedProxies: tt.proxies},
+				Limiter: config.Limiter{Rps: 2, Burst: 4, TTL: 10 * time.Minute},
+			})
+			router := h.Init()
+			router.GET("/ip", func(c *gin.Context) {
+				c.String(http.StatusOK, c.ClientIP())
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/ip", nil)
+			req.RemoteAddr = "10.0.0.5:51000"
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.ip, w.Body.String())
+		})
+	}
+}
+
+func TestNewHandler_Health(t *testing.T) {
+	h := http2.NewHandler(&service.Services{
+		Context: service.MockedContextService{},
+	}, &config.Config{
+		Limiter: config.Limiter{
+			Rps:   2,
+			Burst: 4,
+			TTL:   10 * time.Minute,
+		},
+	})
+
+	ts := httptest.NewServer(h.Init())
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/v1/health")
+	if err != nil {
+		t.Error(err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Success bool
+		Vtiger  struct {
+			State string
+		}
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.True(t, body.Success)
+	require.Equal(t, "closed", body.Vtiger.State)
+}
+
+func TestNewHandler_ExpiredToken(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	const token = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	ru := mock_repository.NewMockUsers(c)
+	ru.EXPECT().GetForToken(gomock.Any(), domain.ScopeAuthentication, token).Return(nil, repository.ErrRecordNotFound)
+
+	var wg sync.WaitGroup
+	h := http2.NewHandler(&service.Services{
+		Users:   service.NewUsersService(ru, nil, &wg, service.NewMockEmailService(), service.Company{}, nil, nil, nil, service.AccountService{}, config.Config{}),
+		Context: service.MockedContextService{},
+	}, &config.Config{
+		Limiter: config.Limiter{
+			Rps:   2,
+			Burst: 4,
+			TTL:   10 * time.Minute,
+		},
+	})
+
+	ts := httptest.NewServer(h.Init())
+	defer ts.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, ts.URL+"/api/v1/users/my", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Contains(t, string(body), "Passed token is not attached to user")
+}
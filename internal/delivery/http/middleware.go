@@ -47,7 +47,7 @@ func (h Handler) corsMiddleware(c *gin.Context) {
 }
 
 func (h Handler) authenticate(c *gin.Context) {
-	if c.FullPath() == "/api/v1/users/" || c.FullPath() == "/api/v1/users/restore" || c.FullPath() == "/api/v1/users/password" || c.FullPath() == "/api/v1/users/login" || c.FullPath() == "/api/v1/payments/webhook" {
+	if c.FullPath() == "/api/v1/users/" || c.FullPath() == "/api/v1/users/restore" || c.FullPath() == "/api/v1/users/password" || c.FullPath() == "/api/v1/users/login" || c.FullPath() == "/api/v1/users/refresh" || c.FullPath() == "/api/v1/users/magic-link" || c.FullPath() == "/api/v1/users/magic-link/verify" || c.FullPath() == "/api/v1/users/accept-invite" || c.FullPath() == "/api/v1/users/unlock" || c.FullPath() == "/api/v1/payments/webhook" || c.FullPath() == "/api/v1/payments/webhook/:provider" || c.FullPath() == "/api/v1/crm/webhook" || strings.HasPrefix(c.FullPath(), "/api/v1/sso/") {
 		c.Next()
 		return
 	}
@@ -74,15 +74,6 @@ func (h Handler) authenticate(c *gin.Context) {
 	}
 
 	user, err := h.services.Users.GetUserByToken(c.Request.Context(), token)
-	if user != nil && !user.IsActive {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not Active", "field": "is_active", "message": "Authorized user is deactivated!"})
-		return
-	}
-
-	if !user.Otp_verified && user.Otp_enabled && c.FullPath() != "/api/v1/otp/validate" && c.FullPath() != "/api/v1/otp/verify" {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "OTP Required", "field": "otp_verified", "message": "You need to pass otp verification to use this service"})
-		return
-	}
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrRecordNotFound):
@@ -92,6 +83,25 @@ func (h Handler) authenticate(c *gin.Context) {
 		}
 		return
 	}
+	if !user.IsActive {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not Active", "field": "is_active", "message": "Authorized user is deactivated!"})
+		return
+	}
+
+	if !user.Otp_verified && user.SecondFactorEnabled() && !isSecondFactorPath(c.FullPath()) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "OTP Required", "field": "otp_verified", "message": "You need to pass otp verification to use this service"})
+		return
+	}
+	h.services.Tokens.Touch(c.Request.Context(), token, c.Request.UserAgent(), c.ClientIP())
 	h.services.Context.ContextSetUser(c, user)
 	c.Next()
 }
+
+// isSecondFactorPath tells, that route checks OTP or passkey, so it is open for users, who did not pass second factor yet.
+func isSecondFactorPath(path string) bool {
+	switch path {
+	case "/api/v1/otp/validate", "/api/v1/otp/verify", "/api/v1/otp/webauthn/login/begin", "/api/v1/otp/webauthn/login/finish":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"net/http"
+	"time"
+)
+
+func (h *Handler) initAuditRoutes(api *gin.RouterGroup) {
+	audit := api.Group("/audit")
+	{
+		audit.GET("", h.getAudit)
+	}
+}
+
+// getAudit returns changes, made by users of account. It can be filtered by user, module, entity, action and period.
+func (h *Handler) getAudit(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	page, size := h.getPageAndSizeParams(c)
+	if page < 0 {
+		return
+	}
+	filter := domain.AuditFilter{
+		UserCrmid: c.Query("user"),
+		Module:    c.Query("module"),
+		EntityId:  c.Query("entity_id"),
+		Action:    c.Query("action"),
+		Page:      page,
+		PageSize:  size,
+	}
+	var ok bool
+	if filter.From, ok = parseAuditDate(c, "from"); !ok {
+		return
+	}
+	if filter.To, ok = parseAuditDate(c, "to"); !ok {
+		return
+	}
+
+	entries, count, err := h.services.Audit.GetAll(c.Request.Context(), filter, *userModel)
+	if errors.Is(err, service.ErrOperationNotPermitted) {
+		notPermittedResponse(c)
+		return
+	}
+	if err != nil {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, DataResponse[domain.AuditEntry]{
+		Data:  entries,
+		Count: count,
+		Page:  page,
+		Size:  size,
+	})
+}
+
+// parseAuditDate reads date of period from query. Both RFC3339 time and plain date are accepted.
+func parseAuditDate(c *gin.Context, field string) (*time.Time, bool) {
+	value := c.Query(field)
+	if value == "" {
+		return nil, true
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		date, err = time.Parse("2006-01-02", value)
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": field, "message": "Date should be in format 2006-01-02 or RFC3339"})
+		return nil, false
+	}
+	return &date, true
+}
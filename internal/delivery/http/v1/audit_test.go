@@ -0,0 +1,155 @@
+package v1
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHandler_getAudit(t *testing.T) {
+	type mockAudit func(r *mock_repository.MockAudit)
+
+	tests := []struct {
+		name         string
+		query        string
+		mockAudit    mockAudit
+		userModel    *domain.User
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:  "Owner gets filtered audit log",
+			query: "?module=HelpDesk&entity_id=17x16&user=12x12&action=update&from=2026-10-01&to=2026-10-18T10:00:00Z&page=2&size=10",
+			mockAudit: func(r *mock_repository.MockAudit) {
+				r.EXPECT().GetAll(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int, error) {
+					assert.Equal(t, "11x1", filter.AccountId)
+					assert.Equal(t, "HelpDesk", filter.Module)
+					assert.Equal(t, "17x16", filter.EntityId)
+					assert.Equal(t, "12x12", filter.UserCrmid)
+					assert.Equal(t, service.AuditUpdate, filter.Action)
+					assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), *filter.From)
+					assert.Equal(t, time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC), *filter.To)
+					assert.Equal(t, 2, filter.Page)
+					assert.Equal(t, 10, filter.PageSize)
+					return []domain.AuditEntry{{
+						Id:        5,
+						UserCrmid: "12x12",
+						Action:    service.AuditUpdate,
+						Module:    "HelpDesk",
+						EntityId:  "17x16",
+						Changes:   map[string]domain.AuditChange{"ticketstatus": {Old: "Open", New: "Closed"}},
+					}}, 11, nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"changes":{"ticketstatus":{"old":"Open","new":"Closed"}}`,
+		},
+		{
+			name:         "Member can not read audit log",
+			query:        "",
+			mockAudit:    func(r *mock_repository.MockAudit) {},
+			userModel:    &domain.User{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleMember},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name:         "Wrong date",
+			query:        "?from=01.10.2026",
+			mockAudit:    func(r *mock_repository.MockAudit) {},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"from"`,
+		},
+		{
+			name:         "Anonymous Access",
+			query:        "",
+			mockAudit:    func(r *mock_repository.MockAudit) {},
+			userModel:    domain.AnonymousUser,
+			statusCode:   http.StatusUnauthorized,
+			responseBody: `"error":"Anonymous Access",`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ra := mock_repository.NewMockAudit(c)
+			tt.mockAudit(ra)
+
+			services := &service.Services{
+				Audit:   service.NewAuditService(ra, &sync.WaitGroup{}),
+				Context: service.MockedContextService{MockedUser: tt.userModel},
+			}
+			handler := Handler{services: services, config: &config.Config{}}
+
+			r := gin.New()
+			r.GET("/api/v1/audit", handler.getAudit)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/audit"+tt.query, nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_createWebhookIsAudited(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	rw := mock_repository.NewMockWebhooks(c)
+	rw.EXPECT().Insert(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, hook *domain.Webhook) error {
+		hook.Id = 3
+		return nil
+	})
+	ra := mock_repository.NewMockAudit(c)
+	ra.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, entry *domain.AuditEntry) error {
+		assert.Equal(t, int64(1), entry.UserId)
+		assert.Equal(t, "11x1", entry.AccountId)
+		assert.Equal(t, "Sergey Emelyanov", entry.UserName)
+		assert.Equal(t, "192.0.2.1", entry.Ip)
+		assert.Equal(t, service.AuditCreate, entry.Action)
+		assert.Equal(t, domain.ModuleWebhooks, entry.Module)
+		assert.Equal(t, "3", entry.EntityId)
+		assert.Equal(t, domain.AuditChange{New: "https://example.com/hook"}, entry.Changes["url"])
+		assert.Equal(t, domain.AuditChange{New: "***"}, entry.Changes["secret"])
+		return nil
+	})
+	wg := &sync.WaitGroup{}
+
+	services := &service.Services{
+		Webhooks: service.NewWebhooksService(rw, config.Config{}, nil, nil),
+		Audit:    service.NewAuditService(ra, wg),
+		Context:  service.MockedContextService{MockedUser: &repository.MockedUser},
+	}
+	handler := Handler{services: services}
+
+	r := gin.New()
+	r.POST("/api/v1/webhooks", handler.createWebhook)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(`{"url":"https://example.com/hook","events":["ticket.created"]}`))
+
+	r.ServeHTTP(w, req)
+	wg.Wait()
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
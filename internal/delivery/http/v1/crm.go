@@ -0,0 +1,53 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"io"
+	"net/http"
+)
+
+const maxWebhookBodySize = 1 << 20
+
+func (h *Handler) initCrmRoutes(api *gin.RouterGroup) {
+	crm := api.Group("/crm")
+	{
+		crm.POST("/webhook", h.handleCrmWebhook)
+	}
+}
+
+// handleCrmWebhook receives record changes from vtiger workflow. Request is signed with shared secret
+// in X-Vtiger-Signature header, so it does not need user token.
+func (h *Handler) handleCrmWebhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		newResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	event, err := vtiger.ConstructWebhookEvent(body, c.GetHeader(vtiger.WebhookSignatureHeader), h.config.Vtiger.Webhook.Secret, h.config.Vtiger.Webhook.Tolerance)
+	if err != nil {
+		switch {
+		case errors.Is(err, vtiger.ErrWebhookNoSecret):
+			newResponse(c, http.StatusServiceUnavailable, err.Error())
+		default:
+			newResponse(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+	err = h.services.CrmWebhook.Handle(c.Request.Context(), event)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "id", "message": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrDuplicateWebhook) {
+			newResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_handleCrmWebhook(t *testing.T) {
+	type mockSync func(r *mock_repository.MockSync)
+
+	tests := []struct {
+		name         string
+		secret       string
+		body         string
+		signWith     string
+		mockSync     mockSync
+		statusCode   int
+		responseBody string
+		deletedKeys  []string
+	}{
+		{
+			name:         "Record cache is invalidated",
+			secret:       "secret",
+			signWith:     "secret",
+			body:         `{"module":"HelpDesk","id":"17x1","account_id":"11x1"}`,
+			mockSync:     func(r *mock_repository.MockSync) {},
+			statusCode:   http.StatusOK,
+			responseBody: `"success":true`,
+			deletedKeys:  []string{"17x1", "documents-17x1", "stat-11x1-12x1"},
+		},
+		{
+			name:     "Account of contact is taken from crm",
+			secret:   "secret",
+			signWith: "secret",
+			body:     `{"module":"Contacts","id":"12x1"}`,
+			mockSync: func(r *mock_repository.MockSync) {
+				r.EXPECT().Retrieve(gomock.Any(), "12x1").Return(map[string]any{"id": "12x1", "account_id": "11x1"}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: `"success":true`,
+			deletedKeys:  []string{"12x1", "account-11x1", "stat-11x1-12x1"},
+		},
+		{
+			name:         "Wrong signature",
+			secret:       "secret",
+			signWith:     "other",
+			body:         `{"module":"HelpDesk","id":"17x1","account_id":"11x1"}`,
+			mockSync:     func(r *mock_repository.MockSync) {},
+			statusCode:   http.StatusBadRequest,
+			responseBody: vtiger.ErrWebhookInvalidSignature.Error(),
+		},
+		{
+			name:         "Secret is not configured",
+			secret:       "",
+			signWith:     "",
+			body:         `{"module":"HelpDesk","id":"17x1"}`,
+			mockSync:     func(r *mock_repository.MockSync) {},
+			statusCode:   http.StatusServiceUnavailable,
+			responseBody: vtiger.ErrWebhookNoSecret.Error(),
+		},
+		{
+			name:         "Wrong id",
+			secret:       "secret",
+			signWith:     "secret",
+			body:         `{"module":"HelpDesk","id":"17"}`,
+			mockSync:     func(r *mock_repository.MockSync) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"error":"Validation Error"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rs := mock_repository.NewMockSync(c)
+			tt.mockSync(rs)
+			memory := cache.NewMemoryCache()
+			for _, key := range []string{"17x1", "documents-17x1", "stat-11x1-12x1", "12x1", "account-11x1", "stat-11x2-12x2"} {
+				assert.NoError(t, memory.Set(key, []byte("{}"), 100))
+			}
+
+			cfg := config.Config{}
+			cfg.Vtiger.Webhook.Secret = tt.secret
+			webhook := service.NewCrmWebhook(memory, cfg, service.Notifications{}, rs, mock_repository.NewMockMirror(c), nil)
+			handler := Handler{services: &service.Services{CrmWebhook: webhook}, config: &cfg}
+
+			r := gin.New()
+			r.POST("/api/v1/crm/webhook", handler.handleCrmWebhook)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/crm/webhook", strings.NewReader(tt.body))
+			req.Header.Set(vtiger.WebhookSignatureHeader, vtiger.SignWebhookPayload([]byte(tt.body), tt.signWith, time.Now()))
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+			for _, key := range tt.deletedKeys {
+				_, err := memory.Get(key)
+				assert.ErrorIs(t, err, cache.ErrItemNotFound, key)
+			}
+			_, err := memory.Get("stat-11x2-12x2")
+			assert.NoError(t, err, "cache of other account should be kept")
+		})
+	}
+}
+
+func TestHandler_handleCrmWebhookReplay(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	rs := mock_repository.NewMockSync(c)
+	gomock.InOrder(
+		rs.EXPECT().Retrieve(gomock.Any(), "12x1").Return(nil, errors.New("vtiger is not available")),
+		rs.EXPECT().Retrieve(gomock.Any(), "12x1").Return(map[string]any{"id": "12x1", "account_id": "11x1"}, nil),
+	)
+	cfg := config.Config{}
+	cfg.Vtiger.Webhook.Secret = "secret"
+	webhook := service.NewCrmWebhook(cache.NewMemoryCache(), cfg, service.Notifications{}, rs, mock_repository.NewMockMirror(c), nil)
+	handler := Handler{services: &service.Services{CrmWebhook: webhook}, config: &cfg}
+
+	r := gin.New()
+	r.POST("/api/v1/crm/webhook", handler.handleCrmWebhook)
+
+	body := `{"module":"Contacts","id":"12x1"}`
+	signature := vtiger.SignWebhookPayload([]byte(body), "secret", time.Now())
+	send := func(signature string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/crm/webhook", strings.NewReader(body))
+		req.Header.Set(vtiger.WebhookSignatureHeader, signature)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, send(signature))
+	assert.Equal(t, http.StatusOK, send(signature), "failed request can be sent again")
+	assert.Equal(t, http.StatusConflict, send(signature))
+	assert.Equal(t, http.StatusConflict, send(signature+",v1=00"))
+}
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
@@ -83,6 +84,10 @@ func (h *Handler) getEntityById(c *gin.Context) {
 		return
 	}
 	result, err := h.services.CustomModules.GetById(c.Request.Context(), moduleName, id, *userModel)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if errors.Is(repository.ErrRecordNotFound, err) {
 		notPermittedResponse(c)
 		return
@@ -125,6 +130,10 @@ func (h *Handler) createCustomModule(c *gin.Context) {
 	}
 
 	entity, err := h.services.CustomModules.CreateEntity(c.Request.Context(), inp, *userModel, moduleName)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if errors.Is(service.ErrModuleNotSupported, err) {
 		moduleNotSupportedResponse(c)
 		return
@@ -136,6 +145,7 @@ func (h *Handler) createCustomModule(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, moduleName, fmt.Sprint(entity["id"]), nil, entity)
 	c.JSON(http.StatusCreated, entity)
 }
 
@@ -158,6 +168,8 @@ func (h *Handler) updateEntity(c *gin.Context) {
 		return
 	}
 
+	// errors of missing entity or permissions are reported by update itself
+	before, _ := h.services.CustomModules.GetById(c.Request.Context(), moduleName, id, *userModel)
 	ticket, err := h.services.CustomModules.UpdateEntity(c.Request.Context(), inp, id, *userModel, moduleName)
 
 	if errors.Is(service.ErrOperationNotPermitted, err) {
@@ -175,6 +187,7 @@ func (h *Handler) updateEntity(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, moduleName, id, before, ticket)
 	c.JSON(http.StatusAccepted, ticket)
 }
 
@@ -195,6 +208,8 @@ func (h *Handler) updatePartlyEntity(c *gin.Context) {
 		return
 	}
 
+	// errors of missing entity or permissions are reported by update itself
+	before, _ := h.services.CustomModules.GetById(c.Request.Context(), moduleName, id, *userModel)
 	ticket, err := h.services.CustomModules.Revise(c.Request.Context(), inp, id, *userModel, moduleName)
 
 	if errors.Is(service.ErrOperationNotPermitted, err) {
@@ -212,6 +227,7 @@ func (h *Handler) updatePartlyEntity(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, moduleName, id, before, ticket)
 	c.JSON(http.StatusAccepted, ticket)
 }
 
@@ -290,6 +306,7 @@ func (h *Handler) addCustomComment(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, service.AuditModuleComments, comment.Id, nil, comment)
 	c.JSON(http.StatusCreated, comment)
 }
 
@@ -348,6 +365,10 @@ func (h *Handler) uploadCustomDocuments(c *gin.Context) {
 	}
 
 	_, err := h.services.CustomModules.GetById(c.Request.Context(), moduleName, id, *userModel)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -360,11 +381,16 @@ func (h *Handler) uploadCustomDocuments(c *gin.Context) {
 	}
 
 	document, err := h.services.Documents.AttachFile(c.Request.Context(), file, id, *userModel, header)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleDocuments, document.Id, nil, document)
 	c.JSON(http.StatusOK, AloneDataResponse[domain.Document]{Data: document})
 }
 
@@ -174,7 +174,7 @@ func TestHandler_getRelatedDocumentsFromCustomModule(t *testing.T) {
 
 			moduleService := service.NewModulesService(rt, cache.NewMemoryCache())
 
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
 			customModuleService := service.NewCustomModuleService(rm, cache.NewMemoryCache(), service.Comments{}, documentService, moduleService, config.Config{
 				Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{CustomModules: map[string][]string{tt.module: {"Documents"}}}},
@@ -670,7 +670,7 @@ func TestHandler_getRelatedCommentsFromCustomModule(t *testing.T) {
 			moduleService := service.NewModulesService(rt, cache.NewMemoryCache())
 			managerService := service.NewManagerService(rman, cache.NewMemoryCache())
 
-			commentService := service.NewComments(rd, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, managerService)
+			commentService := service.NewComments(rd, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, managerService, nil)
 
 			customModuleService := service.NewCustomModuleService(rm, cache.NewMemoryCache(), commentService, service.Documents{}, moduleService, config.Config{
 				Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{CustomModules: map[string][]string{tt.module: {"Comments"}}}},
@@ -770,7 +770,7 @@ func TestHandler_addCommentToCustomModule(t *testing.T) {
 			rc := repository.NewCommentConcrete(config.Config{}, vtiger.NewMockedVtigerConnector())
 
 			moduleService := service.NewModulesService(rt, cache.NewMemoryCache())
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
 			customModuleService := service.NewCustomModuleService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), moduleService, config.Config{
 				Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{CustomModules: map[string][]string{tt.module: {"ModComments"}}}},
 			})
@@ -44,6 +44,10 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 		h.initPaymentRoutes(v1)
 		h.initNotificationsRoutes(v1)
 		h.initCustomModulesRoutes(v1)
+		h.initCrmRoutes(v1)
+		h.initWebhooksRoutes(v1)
+		h.initSsoRoutes(v1)
+		h.initAuditRoutes(v1)
 	}
 }
 
@@ -79,6 +83,17 @@ func (h *Handler) getValidatedUser(c *gin.Context) *domain.User {
 	return userModel
 }
 
+// audit records change of entity, made by user in current request. Before is nil for created entities and after is
+// nil for deleted ones.
+func (h *Handler) audit(c *gin.Context, user domain.User, action string, module string, entityId string, before any, after any) {
+	h.services.Audit.Record(service.NewAuditEntry(user, c.ClientIP(), action, module, entityId, before, after))
+}
+
+// bearerToken returns access token of current request. It is already validated by authenticate middleware.
+func bearerToken(c *gin.Context) string {
+	return strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+}
+
 func (h *Handler) getPageAndSizeParams(c *gin.Context) (int, int) {
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if err != nil {
@@ -1,8 +1,11 @@
 package v1
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
 	"net/http"
 )
@@ -11,6 +14,7 @@ func (h *Handler) initInvoicesRoutes(api *gin.RouterGroup) {
 	invoices := api.Group("/invoices")
 	{
 		invoices.GET("/", h.getAllInvoices)
+		invoices.GET("/export", h.exportInvoices)
 		invoices.GET("/:id", h.getInvoice)
 	}
 }
@@ -70,3 +74,28 @@ func (h *Handler) getAllInvoices(c *gin.Context) {
 		Size:  size,
 	})
 }
+
+func (h *Handler) exportInvoices(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="invoices.csv"`)
+
+	err := h.services.Invoices.Export(c.Request.Context(), *userModel, c.Writer)
+	if err == nil {
+		return
+	}
+	// csv is already streamed, so status can not be changed
+	if c.Writer.Written() {
+		logger.Error(logger.GenerateErrorMessageFromString("invoices export is interrupted: " + err.Error()))
+		return
+	}
+	c.Header("Content-Disposition", "")
+	if errors.Is(err, service.ErrOperationNotPermitted) {
+		notPermittedResponse(c)
+		return
+	}
+	newResponse(c, http.StatusInternalServerError, err.Error())
+}
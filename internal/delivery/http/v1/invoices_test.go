@@ -2,6 +2,7 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
@@ -16,6 +17,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandler_receiveInvoiceById(t *testing.T) {
@@ -220,3 +222,77 @@ func TestHandler_getAllInvoices(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_exportInvoices(t *testing.T) {
+	type mockRepositoryInvoice func(r *mock_repository.MockInvoice)
+
+	tests := []struct {
+		name         string
+		mockInvoice  mockRepositoryInvoice
+		userModel    *domain.User
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Invoices are exported",
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().Export(context.Background(), "11x1", gomock.Any()).DoAndReturn(func(ctx context.Context, client string, fn func(domain.Invoice) error) error {
+					_ = fn(domain.Invoice{InvoiceNo: "INV1", Subject: "Support, March", InvoiceStatus: "Paid", InvoiceDate: domain.InvoiceDate(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)), HdnGrandTotal: 100, Received: 100})
+					return fn(domain.Invoice{InvoiceNo: "INV2", Subject: "Support", InvoiceStatus: "Created", HdnGrandTotal: 50.5, Balance: 50.5})
+				})
+			},
+			userModel:  &repository.MockedUser,
+			statusCode: http.StatusOK,
+			responseBody: "invoice_no,subject,invoicestatus,invoicedate,duedate,hdnGrandTotal,received,balance\n" +
+				"INV1,\"Support, March\",Paid,2023-03-01,,100.00,100.00,0.00\n" +
+				"INV2,Support,Created,,,50.50,0.00,50.50\n",
+		},
+		{
+			name: "Error of vtiger before export",
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().Export(context.Background(), "11x1", gomock.Any()).Return(errors.New("vtiger is not available"))
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusInternalServerError,
+			responseBody: `vtiger is not available`,
+		},
+		{
+			name:         "Anonymous Access",
+			mockInvoice:  func(r *mock_repository.MockInvoice) {},
+			userModel:    domain.AnonymousUser,
+			statusCode:   http.StatusUnauthorized,
+			responseBody: `"error":"Anonymous Access",`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rm := mock_repository.NewMockInvoice(c)
+			tt.mockInvoice(rm)
+
+			invoiceService := service.NewInvoiceService(rm, cache.NewMemoryCache(), service.ModulesService{}, config.Config{}, service.CurrencyService{})
+			services := &service.Services{Invoices: invoiceService, Context: service.MockedContextService{MockedUser: tt.userModel}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.GET("/api/v1/invoices/export", handler.exportInvoices)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/invoices/export", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+			if tt.statusCode == http.StatusOK {
+				assert.Equal(t, tt.responseBody, w.Body.String())
+				assert.Equal(t, `attachment; filename="invoices.csv"`, w.Header().Get("Content-Disposition"))
+			} else {
+				assert.Empty(t, w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
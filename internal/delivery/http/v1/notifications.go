@@ -1,16 +1,22 @@
 package v1
 
 import (
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+const defaultStreamHeartbeat = 15 * time.Second
+
 func (h *Handler) initNotificationsRoutes(api *gin.RouterGroup) {
 	invoices := api.Group("/notifications")
 	{
 		invoices.GET("", h.getAllNotifications)
+		invoices.GET("/stream", h.streamNotifications)
 		invoices.DELETE("/:id", h.markNotificationRead)
 	}
 }
@@ -53,3 +59,77 @@ func (h *Handler) markNotificationRead(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// streamNotifications sends events of user as server-sent events. Client, which reconnects with Last-Event-ID header,
+// receives events published while it was offline.
+func (h *Handler) streamNotifications(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("lastEventId")
+	}
+	lastId, err := strconv.ParseInt(lastEventId, 10, 64)
+	if lastEventId != "" && (err != nil || lastId < 0) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid last event id"})
+		return
+	}
+
+	subscription := h.services.Notifications.Subscribe(*userModel, lastId)
+	if subscription == nil {
+		newResponse(c, http.StatusServiceUnavailable, "notification stream is not available")
+		return
+	}
+	defer subscription.Close()
+
+	heartbeat := h.config.Notifications.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultStreamHeartbeat
+	}
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	controller := http.NewResponseController(c.Writer)
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// server write timeout would close stream, so deadline is moved with every heartbeat
+	controller.SetWriteDeadline(time.Now().Add(2 * heartbeat))
+
+	for _, event := range subscription.Missed() {
+		writeStreamEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+			controller.SetWriteDeadline(time.Now().Add(2 * heartbeat))
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case event, ok := <-subscription.C:
+			if !ok {
+				// subscriber was too slow, client reconnects with last received id
+				return
+			}
+			writeStreamEvent(c, event)
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(c *gin.Context, event pubsub.Event) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.Id, 10),
+		Event: event.Type,
+		Data:  event.Data,
+	})
+}
@@ -0,0 +1,293 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHandler_streamNotifications(t *testing.T) {
+	hub := pubsub.NewHub(10, 10)
+	missed := hub.Publish(pubsub.Event{Type: service.EventComment, AccountId: "11x1", Data: domain.Comment{Id: "17x1"}})
+	hub.Publish(pubsub.Event{Type: service.EventComment, AccountId: "11x2", Data: domain.Comment{Id: "17x2"}})
+
+	cfg := config.Config{}
+	cfg.Notifications.Heartbeat = 50 * time.Millisecond
+	var wg sync.WaitGroup
+	notifications := service.NewNotificationsService(cache.NewMemoryCache(), cfg, service.ManagerService{}, repository.NotificationsRepo{}, repository.NotificationsCrm{}, nil, &wg, hub)
+	services := &service.Services{Notifications: notifications, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+	handler := Handler{services: services, config: &cfg}
+
+	r := gin.New()
+	r.GET("/api/v1/notifications/stream", handler.streamNotifications)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/v1/notifications/stream", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	readUntil := func(prefix string) string {
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatal("stream is closed")
+				}
+				if strings.HasPrefix(line, prefix) {
+					return line
+				}
+			case <-timeout:
+				t.Fatal("no line with prefix " + prefix)
+			}
+		}
+	}
+
+	assert.Equal(t, "id:"+strconv.FormatInt(missed.Id, 10), readUntil("id:"))
+	assert.Contains(t, readUntil("data:"), `"id":"17x1"`)
+
+	for hub.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	notification := hub.Publish(pubsub.Event{Type: service.EventNotification, AccountId: "11x1", UserId: "12x11", Data: domain.Notification{Label: "Invoice is paid"}})
+	assert.Equal(t, "id:"+strconv.FormatInt(notification.Id, 10), readUntil("id:"))
+	assert.Equal(t, "event:"+service.EventNotification, readUntil("event:"))
+	assert.Contains(t, readUntil("data:"), `"label":"Invoice is paid"`)
+	assert.Equal(t, ": heartbeat", readUntil(":"))
+
+	cancel()
+	for hub.Subscribers() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandler_streamNotificationsValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		userModel  *domain.User
+		query      string
+		statusCode int
+	}{
+		{name: "Anonymous Access", userModel: domain.AnonymousUser, statusCode: http.StatusUnauthorized},
+		{name: "Wrong last event id", userModel: &repository.MockedUser, query: "?lastEventId=abc", statusCode: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services := &service.Services{Context: service.MockedContextService{MockedUser: tt.userModel}}
+			handler := Handler{services: services, config: &config.Config{}}
+
+			r := gin.New()
+			r.GET("/api/v1/notifications/stream", handler.streamNotifications)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/notifications/stream"+tt.query, nil))
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
+// notificationsConnector returns notifications from crm and keeps ids of deleted ones.
+// When release is set, selecting waits until it is closed.
+type notificationsConnector struct {
+	*vtiger.MockedConnector
+	items   []map[string]any
+	err     error
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	selects int
+	deleted []string
+}
+
+func (n *notificationsConnector) SelectAll(ctx context.Context, query *vtiger.QueryBuilder) ([]map[string]any, error) {
+	n.mu.Lock()
+	n.selects++
+	n.mu.Unlock()
+	if n.release != nil {
+		n.started <- struct{}{}
+		<-n.release
+	}
+	return n.items, n.err
+}
+
+func (n *notificationsConnector) Delete(ctx context.Context, element string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.deleted = append(n.deleted, element)
+	return nil
+}
+
+func (n *notificationsConnector) selected() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.selects
+}
+
+// accountUsers fails to return contacts of accounts from failed.
+type accountUsers struct {
+	*repository.UsersMock
+	failed map[string]error
+}
+
+func (u accountUsers) GetAllByAccountId(ctx context.Context, account string) ([]domain.User, error) {
+	if err, ok := u.failed[account]; ok {
+		return nil, err
+	}
+	return u.UsersMock.GetAllByAccountId(ctx, account)
+}
+
+func newImportService(connector *notificationsConnector, cfg config.Config, wg *sync.WaitGroup, users repository.Users) service.Notifications {
+	crm := repository.NewNotificationsConcrete(cfg, connector)
+	return service.NewNotificationsService(cache.NewMemoryCache(), cfg, service.ManagerService{}, repository.NotificationsRepo{}, crm, users, wg, pubsub.NewHub(10, 10))
+}
+
+func TestNotifications_ImportNotifications(t *testing.T) {
+	errDatabase := errors.New("database is not available")
+	tests := []struct {
+		name    string
+		items   []map[string]any
+		err     error
+		failed  map[string]error
+		deleted []string
+		wantErr bool
+	}{
+		{
+			name: "Delivered notifications are deleted from crm",
+			items: []map[string]any{
+				{"id": "50x1", "label": "Invoice is paid", "account_id": "11x1"},
+				{"id": "50x2", "label": "Without account"},
+			},
+			deleted: []string{"50x1", "50x2"},
+		},
+		{
+			name: "Failed account does not stop import",
+			items: []map[string]any{
+				{"id": "50x1", "label": "Invoice is paid", "account_id": "11x2"},
+				{"id": "50x2", "label": "Ticket is closed", "account_id": "11x1"},
+			},
+			err:     errDatabase,
+			failed:  map[string]error{"11x2": errDatabase},
+			deleted: []string{"50x2"},
+			wantErr: true,
+		},
+		{
+			name:  "Empty result",
+			items: []map[string]any{},
+		},
+		{
+			name:    "Error of crm",
+			err:     errors.New("vtiger is not available"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector := &notificationsConnector{MockedConnector: vtiger.NewMockedVtigerConnector(), items: tt.items}
+			if tt.failed == nil {
+				connector.err = tt.err
+			}
+			users := accountUsers{UsersMock: repository.NewUsersMock(), failed: tt.failed}
+			notifications := newImportService(connector, config.Config{}, &sync.WaitGroup{}, users)
+
+			err := notifications.ImportNotifications(context.Background())
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.deleted, connector.deleted)
+			assert.Equal(t, 1, connector.selected())
+		})
+	}
+}
+
+func TestNotifications_ImportNotificationsConcurrently(t *testing.T) {
+	connector := &notificationsConnector{
+		MockedConnector: vtiger.NewMockedVtigerConnector(),
+		items:           []map[string]any{{"id": "50x1", "account_id": "11x1"}},
+		started:         make(chan struct{}),
+		release:         make(chan struct{}),
+	}
+	notifications := newImportService(connector, config.Config{}, &sync.WaitGroup{}, repository.NewUsersMock())
+
+	done := make(chan error)
+	go func() {
+		done <- notifications.ImportNotifications(context.Background())
+	}()
+	<-connector.started
+
+	assert.NoError(t, notifications.ImportNotifications(context.Background()))
+	assert.Equal(t, 1, connector.selected())
+
+	close(connector.release)
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"50x1"}, connector.deleted)
+
+	connector.release = nil
+	assert.NoError(t, notifications.ImportNotifications(context.Background()))
+	assert.Equal(t, 2, connector.selected())
+}
+
+func TestNotifications_StartImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		selects int
+	}{
+		{name: "Import is disabled", enabled: false, selects: 0},
+		{name: "Import runs until context is cancelled", enabled: true, selects: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{}
+			cfg.Notifications.Enabled = tt.enabled
+			cfg.Notifications.Interval = time.Hour
+			connector := &notificationsConnector{MockedConnector: vtiger.NewMockedVtigerConnector()}
+			var wg sync.WaitGroup
+			notifications := newImportService(connector, cfg, &wg, repository.NewUsersMock())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			notifications.StartImport(ctx)
+			for connector.selected() < tt.selects {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			wg.Wait()
+
+			assert.Equal(t, tt.selects, connector.selected())
+		})
+	}
+}
@@ -3,8 +3,11 @@ package v1
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/internal/service"
 	"net/http"
+	"strconv"
 )
 
 func (h *Handler) initOtpRoutes(api *gin.RouterGroup) {
@@ -14,6 +17,13 @@ func (h *Handler) initOtpRoutes(api *gin.RouterGroup) {
 		users.PATCH("/verify", h.verifyOtp)
 		users.PATCH("/validate", h.verifyOtp)
 		users.PATCH("/disable", h.disableOtp)
+		users.POST("/recovery-codes", h.regenerateRecoveryCodes)
+		users.POST("/webauthn/register/begin", h.beginWebauthnRegistration)
+		users.POST("/webauthn/register/finish", h.finishWebauthnRegistration)
+		users.POST("/webauthn/login/begin", h.beginWebauthnLogin)
+		users.POST("/webauthn/login/finish", h.finishWebauthnLogin)
+		users.GET("/webauthn/credentials", h.getWebauthnCredentials)
+		users.DELETE("/webauthn/credentials/:id", h.deleteWebauthnCredential)
 	}
 }
 
@@ -58,10 +68,17 @@ func (h *Handler) verifyOtp(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Token Error", "message": err.Error()})
 		return
 	}
+	if errors.Is(err, service.ErrOtpLocked) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Token Error", "message": err.Error()})
+		return
+	}
 	if err != nil {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if !userModel.Otp_enabled && result.Otp_enabled {
+		h.audit(c, *userModel, service.AuditUpdate, service.AuditModuleOtp, userModel.Crmid, gin.H{"otp_enabled": false}, gin.H{"otp_enabled": true, "recovery_codes": result.RecoveryCodes})
+	}
 	c.JSON(http.StatusAccepted, result)
 }
 
@@ -85,9 +102,148 @@ func (h *Handler) disableOtp(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Token Error", "message": err.Error()})
 		return
 	}
+	if errors.Is(err, service.ErrOtpLocked) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Token Error", "message": err.Error()})
+		return
+	}
 	if err != nil {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, service.AuditModuleOtp, userModel.Crmid, gin.H{"otp_enabled": userModel.Otp_enabled}, gin.H{"otp_enabled": result.Otp_enabled})
 	c.JSON(http.StatusAccepted, result)
 }
+
+func (h *Handler) regenerateRecoveryCodes(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+
+	if userModel == nil {
+		newResponse(c, http.StatusUnauthorized, "User with this ID not found")
+		return
+	}
+
+	var payload service.OTPInput
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "message": err.Error()})
+		return
+	}
+	payload.UserId = userModel.Id
+	result, err := h.services.Auth.RegenerateRecoveryCodes(c.Request.Context(), payload)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTokenNotExist):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token Error", "message": err.Error()})
+		case errors.Is(err, service.ErrOtpLocked):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Token Error", "message": err.Error()})
+		case errors.Is(err, service.ErrOtpNotEnabled):
+			c.JSON(http.StatusConflict, gin.H{"error": "Otp Error", "message": err.Error()})
+		default:
+			newResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.audit(c, *userModel, service.AuditUpdate, service.AuditModuleOtp, userModel.Crmid, nil, result)
+	c.JSON(http.StatusCreated, result)
+}
+
+func (h *Handler) beginWebauthnRegistration(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	creation, err := h.services.Webauthn.BeginRegistration(c.Request.Context(), *userModel)
+	if err != nil {
+		webauthnErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, creation)
+}
+
+// finishWebauthnRegistration accepts credential, returned by navigator.credentials.create(). Name of key is passed in query.
+func (h *Handler) finishWebauthnRegistration(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	credential, err := h.services.Webauthn.FinishRegistration(c.Request.Context(), *userModel, c.Query("name"), c.Request.Body)
+	if err != nil {
+		webauthnErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditCreate, service.AuditModulePasskeys, strconv.FormatInt(credential.Id, 10), nil, credential)
+	c.JSON(http.StatusCreated, AloneDataResponse[domain.WebauthnCredential]{Data: credential})
+}
+
+func (h *Handler) beginWebauthnLogin(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	assertion, err := h.services.Webauthn.BeginLogin(c.Request.Context(), *userModel)
+	if err != nil {
+		webauthnErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, assertion)
+}
+
+// finishWebauthnLogin accepts assertion, returned by navigator.credentials.get(), and passes second factor.
+func (h *Handler) finishWebauthnLogin(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	user, err := h.services.Webauthn.FinishLogin(c.Request.Context(), *userModel, c.Request.Body)
+	if err != nil {
+		webauthnErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, user)
+}
+
+func (h *Handler) getWebauthnCredentials(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	credentials, err := h.services.Webauthn.GetCredentials(c.Request.Context(), *userModel)
+	if err != nil {
+		webauthnErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, DataResponse[domain.WebauthnCredential]{Data: credentials, Count: len(credentials)})
+}
+
+func (h *Handler) deleteWebauthnCredential(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid passkey number"})
+		return
+	}
+	if err = h.services.Webauthn.DeleteCredential(c.Request.Context(), *userModel, id); err != nil {
+		webauthnErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditDelete, service.AuditModulePasskeys, c.Param("id"), gin.H{"id": id}, nil)
+	c.Status(http.StatusNoContent)
+}
+
+func webauthnErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrWebauthnFailed), errors.Is(err, service.ErrWebauthnSessionNotFound):
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Token Error", "message": err.Error()})
+	case errors.Is(err, service.ErrWebauthnNoCredentials):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Webauthn Error", "message": err.Error()})
+	case errors.Is(err, service.ErrWebauthnNotConfigured):
+		newResponse(c, http.StatusNotImplemented, err.Error())
+	case errors.Is(err, repository.ErrRecordNotFound):
+		newResponse(c, http.StatusNotFound, err.Error())
+	default:
+		newResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/pquerna/otp/totp"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type otpLockedEmails struct {
+	service.MockEmailService
+	sent []service.OtpLockedData
+}
+
+func (s *otpLockedEmails) SendOtpLocked(input service.OtpLockedData) error {
+	s.sent = append(s.sent, input)
+	return nil
+}
+
+func TestHandler_verifyOtp(t *testing.T) {
+	type mockRepository func(u *mock_repository.MockUsers, o *mock_repository.MockOtp)
+	const secret = "JBSWY3DPEHPK3PXP"
+	validCode, _ := totp.GenerateCode(secret, time.Now())
+	lockedUntil := time.Now().Add(10 * time.Minute)
+
+	disabledUser := repository.MockedUser
+	disabledUser.Otp_secret = secret
+	enabledUser := disabledUser
+	enabledUser.Otp_enabled = true
+
+	tests := []struct {
+		name         string
+		target       string
+		body         string
+		mock         mockRepository
+		statusCode   int
+		responseBody string
+		emailsSent   int
+	}{
+		{
+			name:   "Recovery codes are returned when otp is enabled",
+			target: "/api/v1/otp/verify",
+			body:   `{"token":"` + validCode + `"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(disabledUser, nil)
+				o.EXPECT().GetState(context.Background(), int64(1)).Return(domain.OtpState{}, nil)
+				u.EXPECT().EnableAndVerifyOtp(context.Background(), int64(1)).Return(nil)
+				o.EXPECT().ReplaceRecoveryCodes(context.Background(), int64(1), gomock.Len(10)).Return(nil)
+			},
+			statusCode:   http.StatusAccepted,
+			responseBody: `"otp_verified":true,"webauthn_enabled":false,"recovery_codes":["`,
+		},
+		{
+			name:   "Recovery code is accepted on validate",
+			target: "/api/v1/otp/validate",
+			body:   `{"recovery_code":"ABCDE-FGHIJ"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(enabledUser, nil)
+				o.EXPECT().GetState(context.Background(), int64(1)).Return(domain.OtpState{FailedAttempts: 2}, nil)
+				o.EXPECT().UseRecoveryCode(context.Background(), int64(1), gomock.Any()).Return(true, nil)
+				o.EXPECT().ResetFailures(context.Background(), int64(1)).Return(nil)
+				u.EXPECT().EnableAndVerifyOtp(context.Background(), int64(1)).Return(nil)
+			},
+			statusCode:   http.StatusAccepted,
+			responseBody: `"otp_verified":true`,
+		},
+		{
+			name:   "Used recovery code is counted as failure",
+			target: "/api/v1/otp/validate",
+			body:   `{"recovery_code":"abcde-fghij"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(enabledUser, nil)
+				o.EXPECT().GetState(context.Background(), int64(1)).Return(domain.OtpState{}, nil)
+				o.EXPECT().UseRecoveryCode(context.Background(), int64(1), gomock.Any()).Return(false, nil)
+				o.EXPECT().IncrementFailures(context.Background(), int64(1)).Return(1, nil)
+			},
+			statusCode:   http.StatusBadRequest,
+			responseBody: service.ErrTokenNotExist.Error(),
+		},
+		{
+			name:   "Last wrong code locks otp and sends alert",
+			target: "/api/v1/otp/validate",
+			body:   `{"token":"000000"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(enabledUser, nil)
+				o.EXPECT().GetState(context.Background(), int64(1)).Return(domain.OtpState{FailedAttempts: 4}, nil)
+				o.EXPECT().IncrementFailures(context.Background(), int64(1)).Return(5, nil)
+				o.EXPECT().Lock(context.Background(), int64(1), gomock.Any()).Return(nil)
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: service.ErrOtpLocked.Error(),
+			emailsSent:   1,
+		},
+		{
+			name:   "Locked otp is not checked",
+			target: "/api/v1/otp/validate",
+			body:   `{"token":"` + validCode + `"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(enabledUser, nil)
+				o.EXPECT().GetState(context.Background(), int64(1)).Return(domain.OtpState{LockedUntil: &lockedUntil}, nil)
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: service.ErrOtpLocked.Error(),
+		},
+		{
+			name:   "Recovery codes are regenerated",
+			target: "/api/v1/otp/recovery-codes",
+			body:   `{"token":"` + validCode + `"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(enabledUser, nil)
+				o.EXPECT().GetState(context.Background(), int64(1)).Return(domain.OtpState{}, nil)
+				o.EXPECT().ReplaceRecoveryCodes(context.Background(), int64(1), gomock.Len(10)).Return(nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"recovery_codes":["`,
+		},
+		{
+			name:   "Recovery codes need enabled otp",
+			target: "/api/v1/otp/recovery-codes",
+			body:   `{"token":"` + validCode + `"}`,
+			mock: func(u *mock_repository.MockUsers, o *mock_repository.MockOtp) {
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(disabledUser, nil)
+			},
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrOtpNotEnabled.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			ro := mock_repository.NewMockOtp(c)
+			tt.mock(ru, ro)
+
+			wg := &sync.WaitGroup{}
+			emails := &otpLockedEmails{}
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			authService := service.NewAuthService(ru, ro, wg, cache.NewMemoryCache(), config.Config{}, emails, companyService)
+
+			services := &service.Services{Auth: authService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.PATCH("/api/v1/otp/verify", handler.verifyOtp)
+			r.PATCH("/api/v1/otp/validate", handler.verifyOtp)
+			r.POST("/api/v1/otp/recovery-codes", handler.regenerateRecoveryCodes)
+
+			method := "PATCH"
+			if strings.HasSuffix(tt.target, "recovery-codes") {
+				method = "POST"
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(method, tt.target, bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+			wg.Wait()
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+			assert.Len(t, emails.sent, tt.emailsSent)
+		})
+	}
+}
@@ -1,21 +1,31 @@
 package v1
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/internal/service"
 	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	stripeprovider "github.com/semelyanov86/vtiger-portal/pkg/payment/stripe"
 	"github.com/stripe/stripe-go/v72"
-	"github.com/stripe/stripe-go/v72/webhook"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 )
 
 type PaymentIntentResponse struct {
-	ClientSecret string `json:"clientSecret"`
+	ClientSecret    string  `json:"clientSecret,omitempty"`
+	ConfirmationUrl string  `json:"confirmationUrl,omitempty"`
+	Provider        string  `json:"provider"`
+	Amount          float64 `json:"amount"`
+	Currency        string  `json:"currency"`
+}
+
+type paymentConfirmInput struct {
+	Id string `json:"id" binding:"required"`
 }
 
 func (h *Handler) initPaymentRoutes(api *gin.RouterGroup) {
@@ -25,7 +35,9 @@ func (h *Handler) initPaymentRoutes(api *gin.RouterGroup) {
 		payments.GET("/config", h.getPaymentConfig)
 		payments.POST("/create-payment-intent", h.createPaymentIntent)
 		payments.POST("/webhook", h.handleWebhook)
+		payments.POST("/webhook/:provider", h.handleWebhook)
 		payments.POST("/confirm", h.confirmPayment)
+		payments.POST("/:id/cancel", h.cancelPayment)
 	}
 }
 
@@ -34,9 +46,8 @@ func (h *Handler) getPaymentConfig(c *gin.Context) {
 	if userModel == nil {
 		return
 	}
-	paymentConfig := domain.PaymentConfig{PublishableKey: h.config.Payment.StripePublic}
 	res := AloneDataResponse[domain.PaymentConfig]{
-		Data: paymentConfig,
+		Data: h.services.Payments.GetConfig(),
 	}
 	c.JSON(http.StatusOK, res)
 }
@@ -68,47 +79,69 @@ func (h *Handler) createPaymentIntent(c *gin.Context) {
 	}
 
 	req := service.PaymentIntent{}
-	if err := c.BindJSON(&req); err != nil {
-		for _, fieldErr := range err.(validator.ValidationErrors) {
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var validationErrs validator.ValidationErrors
+		errors.As(err, &validationErrs)
+		for _, fieldErr := range validationErrs {
 			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": fieldErr.Field(), "message": fieldErr.Error()})
 			return // exit on first error
 		}
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "/", "message": "Please pass correct data"})
 		return
 	}
-	if req.PaymentMethodType == "" || req.Currency == "" {
-		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "paymentMethodType", "message": "Payment method type and currency should be not empty"})
+	if req.PaymentMethodType == "" {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "paymentMethodType", "message": "Payment method type should be not empty"})
 		return
 	}
-	if req.SoId == "" && req.InvoiceId == "" {
+	if req.SoId == "" && req.InvoiceId == "" && len(req.Invoices) == 0 {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "invoiceId", "message": "You should pass sales order ID or invoice id"})
 		return
 	}
-	if req.Amount < 0.1 {
-		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "amount", "message": "Please pass correct amount"})
-		return
-	}
 	req.UserId = userModel.Crmid
 	req.AccountId = userModel.AccountId
 
-	pi, err := h.services.Payments.CreatePaymentIntent(c.Request.Context(), req)
+	pi, err := h.services.Payments.CreatePaymentIntent(c.Request.Context(), req, *userModel)
+	if errors.Is(err, service.ErrOperationNotPermitted) {
+		notPermittedResponse(c)
+		return
+	}
+	if errors.Is(err, service.ErrNothingToPay) {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Payment Error", "field": "invoiceId", "message": err.Error()})
+		return
+	}
+	if errors.Is(err, service.ErrUnknownPaymentProvider) {
+		newResponse(c, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrInvalidAllocation) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "invoices", "message": err.Error()})
+		return
+	}
 
 	if err != nil {
 		// Try to safely cast a generic error to a stripe.Error so that we can get at
 		// some additional Stripe-specific information about what went wrong.
-		if stripeErr, ok := err.(*stripe.Error); ok {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) {
 			logger.Error(logger.GenerateErrorMessageFromString("Other Stripe error occurred: " + stripeErr.Error()))
 			newResponse(c, http.StatusBadRequest, stripeErr.Error())
 		} else {
-			logger.Error(logger.GenerateErrorMessageFromString("Other Stripe error occurred: " + err.Error()))
+			logger.Error(logger.GenerateErrorMessageFromString("Payment provider error occurred: " + err.Error()))
 			newResponse(c, http.StatusInternalServerError, "Unknown server error")
 		}
 
 		return
 	}
 
+	h.audit(c, *userModel, service.AuditCreate, domain.ModulePayments, pi.Payment.StripePaymentId, nil, pi.Payment)
 	res := AloneDataResponse[PaymentIntentResponse]{
-		Data: PaymentIntentResponse{ClientSecret: pi.ClientSecret},
+		Data: PaymentIntentResponse{
+			ClientSecret:    pi.ClientSecret,
+			ConfirmationUrl: pi.ConfirmationUrl,
+			Provider:        pi.Payment.Provider,
+			Amount:          pi.Payment.Amount,
+			Currency:        pi.Payment.Currency,
+		},
 	}
 	c.JSON(http.StatusOK, res)
 }
@@ -120,23 +153,46 @@ func (h *Handler) handleWebhook(c *gin.Context) {
 		log.Printf("ioutil.ReadAll: %v", err)
 		return
 	}
-	event, err := webhook.ConstructEvent(b, c.Request.Header.Get("Stripe-Signature"), os.Getenv("STRIPE_WEBHOOK_SECRET"))
+	provider := c.Param("provider")
+	if provider == "" {
+		provider = stripeprovider.Name
+	}
+	event, err := h.services.Payments.ParseWebhook(c.Request.Context(), provider, b, c.Request.Header)
+	if errors.Is(err, service.ErrUnknownPaymentProvider) {
+		newResponse(c, http.StatusNotFound, err.Error())
+		return
+	}
 	if err != nil {
 		newResponse(c, http.StatusBadRequest, err.Error())
-		log.Printf("webhook.ConstructEvent: %v", err)
+		log.Printf("payments.ParseWebhook: %v", err)
 		return
 	}
-	if event.Type == "payment_intent.succeeded" || event.Type == "payment_intent.processing" || event.Type == "payment_intent.canceled" || event.Type == "payment_intent.created" || event.Type == "payment_intent.requires_action" {
-		h.services.Payments.AcceptPayment(c.Request.Context(), event)
-		logger.Debug(logger.LogMessage{
-			Msg:  "Got webhook from stripe",
-			Code: "102",
-			Properties: map[string]string{
-				"id":     event.ID,
-				"object": event.Object,
-			},
-		})
+	logger.Debug(logger.LogMessage{
+		Msg:  "Got webhook from " + provider,
+		Code: "102",
+		Properties: map[string]string{
+			"id":     event.Id,
+			"intent": event.Intent.Id,
+		},
+	})
+	err = h.services.Payments.HandleEvent(c.Request.Context(), provider, event)
+	if errors.Is(err, service.ErrInvalidPaymentEvent) {
+		newResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	// events of unknown payments are not retried by provider
+	if errors.Is(err, service.ErrDuplicatePaymentEvent) || errors.Is(err, repository.ErrRecordNotFound) || errors.Is(err, service.ErrPaymentMismatch) {
+		logger.Debug(logger.LogMessage{Msg: err.Error(), Code: "102", Properties: map[string]string{"id": event.Id}})
+		c.Status(http.StatusOK)
+		return
+	}
+	if err != nil {
+		// provider sends event again, when it is not accepted
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		logger.Error(logger.GenerateErrorMessageFromString("can not handle payment event " + event.Id + ": " + err.Error()))
+		return
 	}
+	c.Status(http.StatusOK)
 }
 
 func (h *Handler) confirmPayment(c *gin.Context) {
@@ -146,20 +202,59 @@ func (h *Handler) confirmPayment(c *gin.Context) {
 		return
 	}
 
-	req := stripe.PaymentIntent{}
-	if err := c.BindJSON(&req); err != nil {
-		for _, fieldErr := range err.(validator.ValidationErrors) {
-			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": fieldErr.Field(), "message": fieldErr.Error()})
-			return // exit on first error
+	var inp paymentConfirmInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "id", "message": err.Error()})
+		return
+	}
+	payment, err := h.services.Payments.ConfirmPayment(c.Request.Context(), inp.Id, *userModel)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOperationNotPermitted):
+			notPermittedResponse(c)
+		case errors.Is(err, repository.ErrRecordNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not Found", "field": "id", "message": "Payment not found"})
+		case errors.Is(err, service.ErrPaymentMismatch):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Payment Error", "field": "id", "message": err.Error()})
+		default:
+			newResponse(c, http.StatusInternalServerError, err.Error())
 		}
-		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "/", "message": "Please pass correct data"})
 		return
 	}
-	payment, err := h.services.Payments.ConfirmPayment(c.Request.Context(), req)
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModulePayments, strconv.FormatInt(payment.ID, 10), nil, payment)
+	res := AloneDataResponse[domain.Payment]{
+		Data: payment,
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *Handler) cancelPayment(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+
+	if userModel == nil {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "id", "message": "Invalid payment number"})
+		return
+	}
+	payment, err := h.services.Payments.CancelPayment(c.Request.Context(), id, *userModel)
 	if err != nil {
-		c.AbortWithError(500, err)
+		switch {
+		case errors.Is(err, service.ErrOperationNotPermitted):
+			notPermittedResponse(c)
+		case errors.Is(err, repository.ErrRecordNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not Found", "field": "id", "message": "Payment not found"})
+		case errors.Is(err, service.ErrPaymentNotCancelable):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Payment Error", "field": "id", "message": err.Error()})
+		default:
+			newResponse(c, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModulePayments, strconv.FormatInt(payment.ID, 10), nil, payment)
 	res := AloneDataResponse[domain.Payment]{
 		Data: payment,
 	}
@@ -0,0 +1,1195 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment/fake"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var paymentsTestConfig = config.Config{Payment: config.PaymentConfig{
+	PaidInvoiceStatus:     "Paid",
+	PaidSoStatus:          "Delivered",
+	RefundedInvoiceStatus: "Approved",
+	DefaultProvider:       "fake",
+	Providers:             map[string]string{"rub": "redirect"},
+	ReturnUrl:             "https://portal.example.com/payments",
+}}
+
+var rubCurrency = domain.Currency{Id: "21x2", CurrencyName: "Russian Ruble", CurrencyCode: "RUB", ConversionRate: 1}
+
+func TestHandler_createPaymentIntent(t *testing.T) {
+	type mockInvoice func(r *mock_repository.MockInvoice)
+	type mockSalesOrder func(r *mock_repository.MockSalesOrder)
+	type mockPayments func(r *mock_repository.MockPayments)
+
+	tests := []struct {
+		name           string
+		body           string
+		mockInvoice    mockInvoice
+		mockSalesOrder mockSalesOrder
+		mockPayments   mockPayments
+		userModel      *domain.User
+		statusCode     int
+		responseBody   string
+		intent         payment.Intent
+	}{
+		{
+			name: "Balance of invoice is paid, amount of client is ignored",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23","amount":1,"currency":"usd"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60.5, InvoiceStatus: "Created"}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
+					assert.Equal(t, 60.5, payment.Amount)
+					assert.Equal(t, "eur", payment.Currency)
+					assert.Equal(t, "5x23", payment.ParentId)
+					assert.Equal(t, "fake_1", payment.StripePaymentId)
+					assert.Equal(t, "fake", payment.Provider)
+					assert.Equal(t, service.PENDING, payment.Status)
+					return nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `{"data":{"clientSecret":"fake_1_secret","confirmationUrl":"https://fake.local/confirm/fake_1","provider":"fake","amount":60.5,"currency":"eur"}}`,
+			intent:       payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusRequiresPaymentMethod, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name: "Invoice without balance is paid by grand total",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"amount":100`,
+			intent:       payment.Intent{Id: "fake_1", Amount: 10000, Currency: "eur", Status: payment.StatusRequiresPaymentMethod, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name: "Member can pay invoice",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
+					assert.Equal(t, "12x12", payment.UserId)
+					return nil
+				})
+			},
+			userModel:    &domain.User{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleMember},
+			statusCode:   http.StatusOK,
+			responseBody: `"amount":100`,
+			intent:       payment.Intent{Id: "fake_1", Amount: 10000, Currency: "eur", Status: payment.StatusRequiresPaymentMethod, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name:        "Sales order is paid by grand total",
+			body:        `{"paymentMethodType":"card","so_id":"6x7"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {
+				r.EXPECT().RetrieveById(context.Background(), "6x7").Return(domain.SalesOrder{ID: "6x7", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 250, SoStatus: "Approved"}, nil)
+			},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
+					assert.Equal(t, "6x7", payment.ParentId)
+					return nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"amount":250`,
+			intent:       payment.Intent{Id: "fake_1", Amount: 25000, Currency: "eur", Status: payment.StatusRequiresPaymentMethod, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name: "Provider is chosen by currency of invoice",
+			body: `{"paymentMethodType":"bank_card","invoice_id":"5x24"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x24").Return(domain.Invoice{ID: "5x24", AccountID: "11x1", CurrencyID: "21x2", HdnGrandTotal: 1500}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
+					assert.Equal(t, "redirect", payment.Provider)
+					assert.Equal(t, "rub", payment.Currency)
+					return nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"confirmationUrl":"https://redirect.local/confirm/redirect_1","provider":"redirect","amount":1500,"currency":"rub"`,
+		},
+		{
+			name: "Several invoices are paid with one intent",
+			body: `{"paymentMethodType":"card","invoices":[{"invoice_id":"5x23","amount":20.25},{"invoice_id":"5x24"}]}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60}, nil)
+				r.EXPECT().RetrieveById(context.Background(), "5x24").Return(domain.Invoice{ID: "5x24", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 50}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
+					assert.Equal(t, 70.25, payment.Amount)
+					assert.Equal(t, "5x23", payment.ParentId)
+					assert.Equal(t, []domain.PaymentAllocation{
+						{ParentId: "5x23", Module: domain.ModuleInvoice, Amount: 20.25, Balance: 60},
+						{ParentId: "5x24", Module: domain.ModuleInvoice, Amount: 50, Balance: 50},
+					}, payment.Allocations)
+					assert.Contains(t, reserved.Paid, service.SUCCEEDED)
+					return nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"amount":70.25`,
+			intent:       payment.Intent{Id: "fake_1", Amount: 7025, Currency: "eur", Status: payment.StatusRequiresPaymentMethod, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name: "Amount is greater than balance of invoice",
+			body: `{"paymentMethodType":"card","invoices":[{"invoice_id":"5x23","amount":60.01}]}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusUnprocessableEntity,
+			responseBody:   `"field":"invoices"`,
+		},
+		{
+			name: "Invoices with different currencies",
+			body: `{"paymentMethodType":"card","invoices":[{"invoice_id":"5x23"},{"invoice_id":"5x24"}]}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100}, nil)
+				r.EXPECT().RetrieveById(context.Background(), "5x24").Return(domain.Invoice{ID: "5x24", AccountID: "11x1", CurrencyID: "21x2", HdnGrandTotal: 1500}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusUnprocessableEntity,
+			responseBody:   `"field":"invoices"`,
+		},
+		{
+			name: "Invoice is passed twice",
+			body: `{"paymentMethodType":"card","invoices":[{"invoice_id":"5x23","amount":10},{"invoice_id":"5x23","amount":10}]}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusUnprocessableEntity,
+			responseBody:   `"field":"invoices"`,
+		},
+		{
+			name: "Amounts of pending intents are not paid again",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetAllocatedAmount(context.Background(), "5x23", gomock.Any()).Return(40.0, nil)
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"amount":20`,
+			intent:       payment.Intent{Id: "fake_1", Amount: 2000, Currency: "eur", Status: payment.StatusRequiresPaymentMethod, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name: "Amount is greater than balance without pending intents",
+			body: `{"paymentMethodType":"card","invoices":[{"invoice_id":"5x23","amount":20.01}]}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetAllocatedAmount(context.Background(), "5x23", gomock.Any()).Return(40.0, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"invoices"`,
+		},
+		{
+			name: "Whole balance is in pending intents",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetAllocatedAmount(context.Background(), "5x23", gomock.Any()).Return(60.0, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrNothingToPay.Error(),
+		},
+		{
+			name: "Confirmed payment, which is not settled yet, reserves balance",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetAllocatedAmount(context.Background(), "5x23", gomock.Any()).DoAndReturn(func(ctx context.Context, parentId string, reserved domain.PaymentReservation) (float64, error) {
+					assert.Contains(t, reserved.Paid, service.SUCCEEDED)
+					assert.NotContains(t, reserved.Pending, service.SUCCEEDED)
+					return 60, nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrNothingToPay.Error(),
+		},
+		{
+			name:        "Sales order, which is paid by other payment, is not paid again",
+			body:        `{"paymentMethodType":"card","so_id":"6x7"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {
+				r.EXPECT().RetrieveById(context.Background(), "6x7").Return(domain.SalesOrder{ID: "6x7", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 250, SoStatus: "Approved"}, nil)
+			},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetAllocatedAmount(context.Background(), "6x7", gomock.Any()).Return(250.0, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrNothingToPay.Error(),
+		},
+		{
+			name: "Intent is canceled, when parallel payment has reserved balance",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 40, Balance: 60.5}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().Insert(context.Background(), gomock.Any(), gomock.Any()).Return(repository.ErrBalanceExceeded)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrNothingToPay.Error(),
+			intent:       payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusCanceled, ClientSecret: "fake_1_secret", ConfirmationUrl: "https://fake.local/confirm/fake_1"},
+		},
+		{
+			name:           "Id of invoice in list is required",
+			body:           `{"paymentMethodType":"card","invoices":[{"amount":10}]}`,
+			mockInvoice:    func(r *mock_repository.MockInvoice) {},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusUnprocessableEntity,
+			responseBody:   `"field":"InvoiceId"`,
+		},
+		{
+			name: "Invoice of other account",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x2", CurrencyID: "21x1", HdnGrandTotal: 100}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusForbidden,
+			responseBody:   `"error":"Access Not Permitted"`,
+		},
+		{
+			name: "Paid invoice",
+			body: `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", AccountID: "11x1", CurrencyID: "21x1", HdnGrandTotal: 100, Received: 100, InvoiceStatus: "Created"}, nil)
+			},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusConflict,
+			responseBody:   service.ErrNothingToPay.Error(),
+		},
+		{
+			name:           "Invoice is not passed",
+			body:           `{"paymentMethodType":"card"}`,
+			mockInvoice:    func(r *mock_repository.MockInvoice) {},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &repository.MockedUser,
+			statusCode:     http.StatusUnprocessableEntity,
+			responseBody:   `"field":"invoiceId"`,
+		},
+		{
+			name:           "Read only user can not pay",
+			body:           `{"paymentMethodType":"card","invoice_id":"5x23"}`,
+			mockInvoice:    func(r *mock_repository.MockInvoice) {},
+			mockSalesOrder: func(r *mock_repository.MockSalesOrder) {},
+			mockPayments:   func(r *mock_repository.MockPayments) {},
+			userModel:      &domain.User{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleReadOnly},
+			statusCode:     http.StatusForbidden,
+			responseBody:   `"error":"Access Not Permitted"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ri := mock_repository.NewMockInvoice(c)
+			rs := mock_repository.NewMockSalesOrder(c)
+			rp := mock_repository.NewMockPayments(c)
+			rc := mock_repository.NewMockCurrency(c)
+			tt.mockInvoice(ri)
+			tt.mockSalesOrder(rs)
+			tt.mockPayments(rp)
+			rp.EXPECT().GetAllocatedAmount(gomock.Any(), gomock.Any(), gomock.Any()).Return(0.0, nil).AnyTimes()
+			rc.EXPECT().RetrieveById(gomock.Any(), "21x1").Return(domain.MockedCurrency, nil).AnyTimes()
+			rc.EXPECT().RetrieveById(gomock.Any(), "21x2").Return(rubCurrency, nil).AnyTimes()
+			provider := fake.NewProvider("fake")
+
+			currencyService := service.NewCurrencyService(rc, cache.NewMemoryCache())
+			services := &service.Services{
+				Payments: service.NewPaymentsService(cache.NewMemoryCache(), paymentsTestConfig, currencyService, rp, ri, rs, []payment.Provider{provider, fake.NewProvider("redirect")}, vtiger.NewMockedVtigerConnector(), pubsub.NewHub(10, 10), nil, service.NewMockEmailService(), service.Company{}, service.Audit{}, &sync.WaitGroup{}),
+				Context:  service.MockedContextService{MockedUser: tt.userModel},
+			}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/payments/create-payment-intent", handler.createPaymentIntent)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/payments/create-payment-intent", strings.NewReader(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+			if tt.intent.Id != "" {
+				intent, err := provider.GetIntent(context.Background(), tt.intent.Id)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.intent, intent)
+			}
+		})
+	}
+}
+
+// revisingConnector keeps data of revised crm entities.
+type revisingConnector struct {
+	*vtiger.MockedConnector
+	revised []map[string]any
+	// beforeRevise is called before entity is revised, when it is set
+	beforeRevise func(data map[string]any)
+}
+
+func (r *revisingConnector) Revise(ctx context.Context, data map[string]any) (*vtiger.VtigerResponse[map[string]any], error) {
+	if r.beforeRevise != nil {
+		r.beforeRevise(data)
+	}
+	r.revised = append(r.revised, data)
+	return nil, nil
+}
+
+func TestHandler_confirmPayment(t *testing.T) {
+	type mockPayments func(r *mock_repository.MockPayments)
+
+	stored := domain.Payment{ID: 3, StripePaymentId: "fake_1", Provider: "fake", UserId: "12x11", AccountId: "11x1", Amount: 60.5, Currency: "eur", Status: service.PENDING, ParentId: "5x23"}
+	succeeded := &payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusSucceeded}
+
+	tests := []struct {
+		name         string
+		body         string
+		intent       *payment.Intent
+		mockPayments mockPayments
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:   "Status is taken from provider, not from request",
+			body:   `{"id":"fake_1","status":"canceled"}`,
+			intent: succeeded,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+				r.EXPECT().UpdatePayment(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, p domain.Payment) (domain.Payment, error) {
+					assert.Equal(t, service.SUCCEEDED, p.Status)
+					assert.Nil(t, p.SettledAt)
+					return p, nil
+				})
+			},
+			statusCode:   http.StatusOK,
+			responseBody: `"status":1`,
+		},
+		{
+			name:   "Succeeded payment is not updated again",
+			body:   `{"id":"fake_1"}`,
+			intent: succeeded,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				paid := stored
+				paid.Status = service.SUCCEEDED
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(paid, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: `"status":1`,
+		},
+		{
+			name:   "Amount of intent does not match payment",
+			body:   `{"id":"fake_1"}`,
+			intent: &payment.Intent{Id: "fake_1", Amount: 100, Currency: "eur", Status: payment.StatusSucceeded},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+			},
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrPaymentMismatch.Error(),
+		},
+		{
+			name: "Payment of other account",
+			body: `{"id":"fake_1"}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				other := stored
+				other.AccountId = "11x2"
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(other, nil)
+			},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name: "Payment not found",
+			body: `{"id":"fake_2"}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetByStripeId(context.Background(), "fake_2").Return(domain.Payment{}, repository.ErrRecordNotFound)
+			},
+			statusCode:   http.StatusNotFound,
+			responseBody: `Payment not found`,
+		},
+		{
+			name:         "Id is not passed",
+			body:         `{}`,
+			mockPayments: func(r *mock_repository.MockPayments) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"id"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rp := mock_repository.NewMockPayments(c)
+			ri := mock_repository.NewMockInvoice(c)
+			tt.mockPayments(rp)
+			provider := fake.NewProvider("fake")
+			if tt.intent != nil {
+				provider.Put(*tt.intent)
+			}
+			connector := &revisingConnector{MockedConnector: vtiger.NewMockedVtigerConnector()}
+
+			services := &service.Services{
+				Payments: service.NewPaymentsService(cache.NewMemoryCache(), paymentsTestConfig, service.CurrencyService{}, rp, ri, nil, []payment.Provider{provider}, connector, pubsub.NewHub(10, 10), nil, service.NewMockEmailService(), service.Company{}, service.Audit{}, &sync.WaitGroup{}),
+				Context:  service.MockedContextService{MockedUser: &repository.MockedUser},
+			}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/payments/confirm", handler.confirmPayment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/payments/confirm", strings.NewReader(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+			assert.Empty(t, connector.revised)
+		})
+	}
+}
+
+// refundEmails keeps sent emails about refunds.
+type refundEmails struct {
+	service.MockEmailService
+	sent []service.PaymentRefundedData
+}
+
+func (r *refundEmails) SendPaymentRefunded(input service.PaymentRefundedData) error {
+	r.sent = append(r.sent, input)
+	return nil
+}
+
+func TestHandler_handleWebhook(t *testing.T) {
+	type mockPayments func(r *mock_repository.MockPayments)
+	type mockInvoice func(r *mock_repository.MockInvoice)
+
+	settledAt := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	stored := domain.Payment{ID: 3, StripePaymentId: "fake_1", Provider: "fake", UserId: "12x11", AccountId: "11x1", Amount: 60.5, Currency: "eur", Status: service.PENDING, ParentId: "5x23"}
+	paid := stored
+	paid.Status = service.SUCCEEDED
+	paid.SettledAt = &settledAt
+	confirmed := stored
+	confirmed.Status = service.SUCCEEDED
+	allocations := []domain.PaymentAllocation{
+		{ID: 1, PaymentId: 3, ParentId: "5x23", Module: domain.ModuleInvoice, Amount: 20.5},
+		{ID: 2, PaymentId: 3, ParentId: "5x24", Module: domain.ModuleInvoice, Amount: 40},
+	}
+	newEvent := func(r *mock_repository.MockPayments) {
+		r.EXPECT().InsertEvent(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+			event.Id = 7
+			return true, nil
+		})
+		r.EXPECT().ClaimReceivedEvent(context.Background(), int64(7), gomock.Any(), gomock.Any()).Return(true, nil)
+	}
+	succeededBody := `{"Id":"evt_1","Type":"intent.updated","Intent":{"Id":"fake_1","Amount":6050,"Currency":"eur","Status":"succeeded"}}`
+
+	tests := []struct {
+		name         string
+		provider     string
+		body         string
+		mockPayments mockPayments
+		mockInvoice  mockInvoice
+		statusCode   int
+		revised      []map[string]any
+		emails       int
+		eventStatus  string
+		audit        []string
+		eventRevised []string
+	}{
+		{
+			name:     "Status of payment is updated",
+			provider: "fake",
+			body:     `{"Id":"evt_1","Type":"intent.updated","Created":"2026-01-10T12:00:00Z","Intent":{"Id":"fake_1","Amount":6050,"Currency":"eur","Status":"processing"}}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+				r.EXPECT().GetLastEventTime(context.Background(), int64(3)).Return(time.Time{}, nil)
+				r.EXPECT().UpdatePayment(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, p domain.Payment) (domain.Payment, error) {
+					assert.Equal(t, service.PROCESSING, p.Status)
+					assert.Nil(t, p.SettledAt)
+					return p, nil
+				})
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"status"},
+		},
+		{
+			name:     "Succeeded payment is settled and revises balances of invoices",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+				r.EXPECT().UpdatePayment(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, p domain.Payment) (domain.Payment, error) {
+					assert.Equal(t, service.SUCCEEDED, p.Status)
+					return p, nil
+				})
+				r.EXPECT().SettlePayment(context.Background(), int64(3), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, id int64, settledAt time.Time, event *domain.PaymentEvent) (bool, error) {
+					assert.Equal(t, domain.PaymentEventPending, event.Status)
+					assert.NotNil(t, event.NextAttemptAt)
+					return true, nil
+				})
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(paid, nil)
+				r.EXPECT().GetAllocations(context.Background(), int64(3)).Return(allocations, nil)
+				r.EXPECT().MarkAllocationRevised(context.Background(), int64(1), gomock.Any()).Return(nil)
+				r.EXPECT().MarkAllocationRevised(context.Background(), int64(2), gomock.Any()).Return(nil)
+			},
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", HdnGrandTotal: 100, Received: 40, Balance: 60, InvoiceStatus: "Created"}, nil)
+				r.EXPECT().RetrieveById(context.Background(), "5x24").Return(domain.Invoice{ID: "5x24", HdnGrandTotal: 40, InvoiceStatus: "Created"}, nil)
+			},
+			statusCode: http.StatusOK,
+			revised: []map[string]any{
+				{"id": "5x23", "received": 60.5, "balance": 39.5},
+				{"id": "5x24", "received": float64(40), "balance": float64(0), "invoicestatus": "Paid"},
+			},
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"settled_at,status"},
+		},
+		{
+			name:     "Payment, confirmed by client, is settled by event",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(confirmed, nil)
+				r.EXPECT().SettlePayment(context.Background(), int64(3), gomock.Any(), gomock.Any()).Return(true, nil)
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(paid, nil)
+				r.EXPECT().GetAllocations(context.Background(), int64(3)).Return([]domain.PaymentAllocation{}, nil)
+			},
+			statusCode:  http.StatusOK,
+			revised:     []map[string]any{{"id": "5x23", "invoicestatus": "Paid", "sostatus": "Delivered"}},
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"settled_at"},
+		},
+		{
+			name:     "Settled payment is not revised twice",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(paid, nil)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventProcessed,
+		},
+		{
+			name:     "Payment, settled by parallel event, is not revised",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(confirmed, nil)
+				r.EXPECT().SettlePayment(context.Background(), int64(3), gomock.Any(), gomock.Any()).Return(false, nil)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventProcessed,
+		},
+		{
+			name:     "Failed revision is left for retry",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+				r.EXPECT().UpdatePayment(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, p domain.Payment) (domain.Payment, error) {
+					return p, nil
+				})
+				r.EXPECT().SettlePayment(context.Background(), int64(3), gomock.Any(), gomock.Any()).Return(true, nil)
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(paid, nil)
+				r.EXPECT().GetAllocations(context.Background(), int64(3)).Return(allocations, nil)
+				r.EXPECT().MarkAllocationRevised(context.Background(), int64(1), gomock.Any()).Return(nil)
+			},
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", HdnGrandTotal: 100, Received: 40, Balance: 60, InvoiceStatus: "Created"}, nil)
+				r.EXPECT().RetrieveById(context.Background(), "5x24").Return(domain.Invoice{}, errors.New("vtiger is not available"))
+			},
+			statusCode:   http.StatusOK,
+			revised:      []map[string]any{{"id": "5x23", "received": 60.5, "balance": 39.5}},
+			eventStatus:  domain.PaymentEventPending,
+			eventRevised: []string{"5x23"},
+			audit:        []string{"settled_at,status"},
+		},
+		{
+			name:     "Event older than the last applied one is skipped",
+			provider: "fake",
+			body:     `{"Id":"evt_0","Type":"intent.updated","Created":"2026-01-10T11:00:00Z","Intent":{"Id":"fake_1","Amount":6050,"Currency":"eur","Status":"requires_payment_method"}}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+				r.EXPECT().GetLastEventTime(context.Background(), int64(3)).Return(settledAt, nil)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventSkipped,
+		},
+		{
+			name:     "Processed event is not applied twice",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().InsertEvent(context.Background(), gomock.Any()).Return(false, nil)
+				r.EXPECT().GetEvent(context.Background(), "fake", "evt_1").Return(domain.PaymentEvent{Id: 7, Status: domain.PaymentEventProcessed}, nil)
+			},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:     "Event, which is applied by parallel delivery, is not applied twice",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().InsertEvent(context.Background(), gomock.Any()).Return(false, nil)
+				r.EXPECT().GetEvent(context.Background(), "fake", "evt_1").Return(domain.PaymentEvent{Id: 7, Status: domain.PaymentEventProcessing}, nil)
+				r.EXPECT().ClaimReceivedEvent(context.Background(), int64(7), gomock.Any(), gomock.Any()).Return(false, nil)
+			},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:     "Failed event is released for redelivery",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(domain.Payment{}, errors.New("connection refused"))
+			},
+			statusCode:  http.StatusInternalServerError,
+			eventStatus: domain.PaymentEventReceived,
+		},
+		{
+			name:     "Event of other provider does not change payment",
+			provider: "redirect",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventSkipped,
+		},
+		{
+			name:     "Event of unknown payment is skipped",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(domain.Payment{}, repository.ErrRecordNotFound)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventSkipped,
+		},
+		{
+			name:         "Event without id",
+			provider:     "fake",
+			body:         `{"Type":"intent.updated","Intent":{"Id":"fake_1","Status":"succeeded"}}`,
+			mockPayments: func(r *mock_repository.MockPayments) {},
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:     "Event is sent again, when it is not stored",
+			provider: "fake",
+			body:     succeededBody,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().InsertEvent(context.Background(), gomock.Any()).Return(false, errors.New("connection refused"))
+			},
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:     "Partial refund returns balance to invoice",
+			provider: "fake",
+			body:     `{"Id":"evt_2","Type":"intent.refunded","Intent":{"Id":"fake_1"},"Refunds":[{"Id":"re_1","IntentId":"fake_1","Amount":2050,"Currency":"eur","Status":"succeeded"}]}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(paid, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{}, nil)
+				r.EXPECT().InsertRefund(context.Background(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, refund *domain.PaymentRefund, p domain.Payment, event *domain.PaymentEvent) (bool, error) {
+					assert.Equal(t, domain.PaymentRefund{PaymentId: 3, RefundId: "re_1", Amount: 20.5, Currency: "eur", Status: payment.StatusSucceeded}, *refund)
+					assert.Equal(t, service.SUCCEEDED, p.Status)
+					assert.Equal(t, domain.PaymentEventPending, event.Status)
+					return true, nil
+				})
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(paid, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{{ID: 1, PaymentId: 3, RefundId: "re_1", Amount: 20.5}}, nil)
+				r.EXPECT().GetAllocations(gomock.Any(), int64(3)).Return([]domain.PaymentAllocation{{ID: 1, PaymentId: 3, ParentId: "5x23", Module: domain.ModuleInvoice, Amount: 60.5}}, nil).Times(2)
+			},
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", HdnGrandTotal: 100, Received: 100, InvoiceStatus: "Paid"}, nil)
+			},
+			statusCode:  http.StatusOK,
+			revised:     []map[string]any{{"id": "5x23", "received": 79.5, "balance": 20.5, "invoicestatus": "Approved"}},
+			emails:      1,
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"refunded"},
+		},
+		{
+			name:     "Rest of payment is refunded from the last invoice",
+			provider: "fake",
+			body:     `{"Id":"evt_3","Type":"intent.refunded","Intent":{"Id":"fake_1"},"Refunds":[{"Id":"re_2","IntentId":"fake_1","Amount":4000,"Currency":"eur","Status":"succeeded"}]}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(paid, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{{ID: 1, PaymentId: 3, RefundId: "re_1", Amount: 20.5}}, nil)
+				r.EXPECT().InsertRefund(context.Background(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, refund *domain.PaymentRefund, p domain.Payment, event *domain.PaymentEvent) (bool, error) {
+					assert.Equal(t, service.REFUNDED, p.Status)
+					return true, nil
+				})
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(paid, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{
+					{ID: 1, PaymentId: 3, RefundId: "re_1", Amount: 20.5},
+					{ID: 2, PaymentId: 3, RefundId: "re_2", Amount: 40},
+				}, nil)
+				r.EXPECT().GetAllocations(gomock.Any(), int64(3)).Return(allocations, nil).Times(2)
+			},
+			mockInvoice: func(r *mock_repository.MockInvoice) {
+				r.EXPECT().RetrieveById(context.Background(), "5x24").Return(domain.Invoice{ID: "5x24", HdnGrandTotal: 40, Received: 20.5, InvoiceStatus: "Approved"}, nil)
+				r.EXPECT().RetrieveById(context.Background(), "5x23").Return(domain.Invoice{ID: "5x23", HdnGrandTotal: 100, Received: 60.5, InvoiceStatus: "Created"}, nil)
+			},
+			statusCode: http.StatusOK,
+			revised: []map[string]any{
+				{"id": "5x24", "received": float64(1), "balance": float64(39)},
+				{"id": "5x23", "received": float64(40), "balance": float64(60)},
+			},
+			emails:      1,
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"refunded,status"},
+		},
+		{
+			name:     "Refund of not settled payment does not revise crm",
+			provider: "fake",
+			body:     `{"Id":"evt_2","Type":"intent.refunded","Intent":{"Id":"fake_1"},"Refunds":[{"Id":"re_1","IntentId":"fake_1","Amount":2050,"Currency":"eur","Status":"succeeded"}]}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(confirmed, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{}, nil)
+				r.EXPECT().InsertRefund(context.Background(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, refund *domain.PaymentRefund, p domain.Payment, event *domain.PaymentEvent) (bool, error) {
+					assert.Equal(t, domain.PaymentEventProcessed, event.Status)
+					return true, nil
+				})
+				r.EXPECT().GetAllocations(gomock.Any(), int64(3)).Return([]domain.PaymentAllocation{}, nil)
+			},
+			statusCode:  http.StatusOK,
+			emails:      1,
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"refunded"},
+		},
+		{
+			name:     "Refunds of event, which are not stored yet, are applied",
+			provider: "fake",
+			body:     `{"Id":"evt_5","Type":"intent.refunded","Intent":{"Id":"fake_1"},"Refunds":[{"Id":"re_1","IntentId":"fake_1","Amount":2050,"Currency":"eur","Status":"succeeded"},{"Id":"re_2","IntentId":"fake_1","Amount":1000,"Currency":"eur","Status":"succeeded"}]}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				var eventIds []string
+				r.EXPECT().InsertEvent(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+					eventIds = append(eventIds, event.EventId)
+					assert.Equal(t, "re_"+strconv.Itoa(len(eventIds)), event.RefundId)
+					event.Id = 7
+					return true, nil
+				}).Times(2)
+				r.EXPECT().ClaimReceivedEvent(context.Background(), int64(7), gomock.Any(), gomock.Any()).Return(true, nil).Times(2)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(confirmed, nil).Times(2)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{{ID: 1, PaymentId: 3, RefundId: "re_1", Amount: 20.5}}, nil).Times(2)
+				r.EXPECT().InsertRefund(context.Background(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, refund *domain.PaymentRefund, p domain.Payment, event *domain.PaymentEvent) (bool, error) {
+					assert.Equal(t, []string{"evt_5:re_1", "evt_5:re_2"}, eventIds)
+					assert.Equal(t, "re_2", refund.RefundId)
+					assert.Equal(t, float64(10), refund.Amount)
+					return true, nil
+				})
+				r.EXPECT().GetAllocations(gomock.Any(), int64(3)).Return([]domain.PaymentAllocation{}, nil)
+			},
+			statusCode:  http.StatusOK,
+			emails:      1,
+			eventStatus: domain.PaymentEventProcessed,
+			audit:       []string{"refunded"},
+		},
+		{
+			name:     "Refund, stored by parallel event, is skipped",
+			provider: "fake",
+			body:     `{"Id":"evt_2","Type":"intent.refunded","Intent":{"Id":"fake_1"},"Refunds":[{"Id":"re_1","IntentId":"fake_1","Amount":2050,"Currency":"eur","Status":"succeeded"}]}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(paid, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{}, nil)
+				r.EXPECT().InsertRefund(context.Background(), gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventSkipped,
+		},
+		{
+			name:     "Stored refund is ignored",
+			provider: "fake",
+			body:     `{"Id":"evt_4","Type":"intent.refunded","Intent":{"Id":"fake_1"},"Refunds":[{"Id":"re_1","IntentId":"fake_1","Amount":2050,"Currency":"eur","Status":"succeeded"}]}`,
+			mockPayments: func(r *mock_repository.MockPayments) {
+				newEvent(r)
+				r.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(paid, nil)
+				r.EXPECT().GetRefunds(context.Background(), int64(3)).Return([]domain.PaymentRefund{{ID: 1, PaymentId: 3, RefundId: "re_1", Amount: 20.5}}, nil)
+			},
+			statusCode:  http.StatusOK,
+			eventStatus: domain.PaymentEventSkipped,
+		},
+		{
+			name:         "Not parsed event",
+			provider:     "fake",
+			body:         `not a json`,
+			mockPayments: func(r *mock_repository.MockPayments) {},
+			statusCode:   http.StatusBadRequest,
+		},
+		{
+			name:         "Unknown provider",
+			provider:     "paypal",
+			body:         `{}`,
+			mockPayments: func(r *mock_repository.MockPayments) {},
+			statusCode:   http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rp := mock_repository.NewMockPayments(c)
+			ri := mock_repository.NewMockInvoice(c)
+			ru := mock_repository.NewMockUsers(c)
+			tt.mockPayments(rp)
+			if tt.mockInvoice != nil {
+				tt.mockInvoice(ri)
+			}
+			var eventStatus string
+			var eventRevised []string
+			rp.EXPECT().UpdateEvent(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.PaymentEvent) error {
+				assert.Equal(t, int64(7), event.Id)
+				eventStatus = event.Status
+				eventRevised = append([]string(nil), event.Revised...)
+				return nil
+			}).AnyTimes()
+			ru.EXPECT().GetAllByAccountId(gomock.Any(), "11x1").Return([]domain.User{repository.MockedUser}, nil).Times(tt.emails)
+			var audited []string
+			ra := mock_repository.NewMockAudit(c)
+			ra.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, entry *domain.AuditEntry) error {
+				assert.Equal(t, service.AuditUpdate, entry.Action)
+				assert.Equal(t, domain.ModulePayments, entry.Module)
+				assert.Equal(t, "3", entry.EntityId)
+				assert.Equal(t, "11x1", entry.AccountId)
+				assert.Equal(t, tt.provider, entry.UserName)
+				fields := make([]string, 0, len(entry.Changes))
+				for field := range entry.Changes {
+					fields = append(fields, field)
+				}
+				sort.Strings(fields)
+				audited = append(audited, strings.Join(fields, ","))
+				return nil
+			}).AnyTimes()
+			connector := &revisingConnector{MockedConnector: vtiger.NewMockedVtigerConnector(), beforeRevise: func(data map[string]any) {
+				// revision is saved with event before crm is changed, so retry does not change crm twice
+				assert.Contains(t, eventRevised, data["id"])
+			}}
+			emails := &refundEmails{}
+			wg := &sync.WaitGroup{}
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+
+			services := &service.Services{
+				Payments: service.NewPaymentsService(cache.NewMemoryCache(), paymentsTestConfig, service.CurrencyService{}, rp, ri, nil, []payment.Provider{fake.NewProvider("fake"), fake.NewProvider("redirect")}, connector, pubsub.NewHub(10, 10), ru, emails, companyService, service.NewAuditService(ra, wg), wg),
+			}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/payments/webhook/:provider", handler.handleWebhook)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/payments/webhook/"+tt.provider, strings.NewReader(tt.body))
+
+			r.ServeHTTP(w, req)
+			wg.Wait()
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.revised, connector.revised)
+			assert.Equal(t, tt.eventStatus, eventStatus)
+			assert.Equal(t, tt.audit, audited)
+			if tt.eventRevised != nil {
+				assert.Equal(t, tt.eventRevised, eventRevised)
+			}
+			assert.Len(t, emails.sent, tt.emails)
+			for _, sent := range emails.sent {
+				assert.Equal(t, repository.MockedUser.Email, sent.Email)
+				assert.Equal(t, "EUR", sent.Currency)
+			}
+		})
+	}
+}
+
+func TestHandler_handleWebhookConcurrently(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	stored := domain.Payment{ID: 3, StripePaymentId: "fake_1", Provider: "fake", UserId: "12x11", AccountId: "11x1", Amount: 60.5, Currency: "eur", Status: service.PENDING, ParentId: "5x23"}
+	paid := stored
+	paid.Status = service.SUCCEEDED
+	body := `{"Id":"evt_1","Type":"intent.updated","Intent":{"Id":"fake_1","Amount":6050,"Currency":"eur","Status":"succeeded"}}`
+
+	// repository keeps the only row of event, so both deliveries see it as received until one of them claims it
+	var mu sync.Mutex
+	eventStored, claimed := false, false
+	rp := mock_repository.NewMockPayments(c)
+	rp.EXPECT().InsertEvent(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		event.Id = 7
+		inserted := !eventStored
+		eventStored = true
+		return inserted, nil
+	}).Times(2)
+	rp.EXPECT().GetEvent(context.Background(), "fake", "evt_1").Return(domain.PaymentEvent{Id: 7, Provider: "fake", EventId: "evt_1", Type: payment.EventIntentUpdated, IntentId: "fake_1", Status: domain.PaymentEventReceived}, nil)
+	rp.EXPECT().ClaimReceivedEvent(context.Background(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		wasClaimed := claimed
+		claimed = true
+		return !wasClaimed, nil
+	}).Times(2)
+	rp.EXPECT().GetByStripeId(context.Background(), "fake_1").Return(stored, nil)
+	rp.EXPECT().UpdatePayment(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, p domain.Payment) (domain.Payment, error) {
+		return p, nil
+	})
+	rp.EXPECT().SettlePayment(context.Background(), int64(3), gomock.Any(), gomock.Any()).Return(true, nil)
+	rp.EXPECT().UpdateEvent(context.Background(), gomock.Any()).Return(nil).Times(3)
+	rp.EXPECT().GetById(context.Background(), int64(3)).Return(paid, nil)
+	rp.EXPECT().GetAllocations(context.Background(), int64(3)).Return([]domain.PaymentAllocation{}, nil)
+	connector := &revisingConnector{MockedConnector: vtiger.NewMockedVtigerConnector()}
+	wg := &sync.WaitGroup{}
+
+	services := &service.Services{
+		Payments: service.NewPaymentsService(cache.NewMemoryCache(), paymentsTestConfig, service.CurrencyService{}, rp, mock_repository.NewMockInvoice(c), nil, []payment.Provider{fake.NewProvider("fake")}, connector, pubsub.NewHub(10, 10), nil, service.NewMockEmailService(), service.Company{}, service.Audit{}, wg),
+	}
+	handler := Handler{services: services}
+
+	r := gin.New()
+	r.POST("/api/v1/payments/webhook/:provider", handler.handleWebhook)
+
+	start := make(chan struct{})
+	codes := make(chan int, 2)
+	var deliveries sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		deliveries.Add(1)
+		go func() {
+			defer deliveries.Done()
+			<-start
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/payments/webhook/fake", strings.NewReader(body)))
+			codes <- w.Code
+		}()
+	}
+	close(start)
+	deliveries.Wait()
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		assert.Equal(t, http.StatusOK, code)
+	}
+	assert.Equal(t, []map[string]any{{"id": "5x23", "invoicestatus": "Paid", "sostatus": "Delivered"}}, connector.revised)
+}
+
+func TestHandler_cancelPayment(t *testing.T) {
+	type mockPayments func(r *mock_repository.MockPayments)
+
+	stored := domain.Payment{ID: 3, StripePaymentId: "fake_1", Provider: "fake", UserId: "12x11", AccountId: "11x1", Amount: 60.5, Currency: "eur", Status: service.REQUIRES_PAYMENT_METHOD, ParentId: "5x23"}
+
+	tests := []struct {
+		name         string
+		id           string
+		intent       payment.Intent
+		mockPayments mockPayments
+		userModel    *domain.User
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:   "Pending payment is canceled",
+			id:     "3",
+			intent: payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusRequiresPaymentMethod},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(stored, nil)
+				r.EXPECT().UpdatePayment(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, p domain.Payment) (domain.Payment, error) {
+					assert.Equal(t, service.CANCELLED, p.Status)
+					return p, nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusOK,
+			responseBody: `"status":2`,
+		},
+		{
+			name:   "Succeeded payment can not be canceled",
+			id:     "3",
+			intent: payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusSucceeded},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				succeeded := stored
+				succeeded.Status = service.SUCCEEDED
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(succeeded, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusConflict,
+			responseBody: service.ErrPaymentNotCancelable.Error(),
+		},
+		{
+			name:   "Intent is already processed by provider",
+			id:     "3",
+			intent: payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusSucceeded},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(stored, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusConflict,
+			responseBody: `"field":"id"`,
+		},
+		{
+			name:   "Payment of other account",
+			id:     "3",
+			intent: payment.Intent{Id: "fake_1", Amount: 6050, Currency: "eur", Status: payment.StatusRequiresPaymentMethod},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				other := stored
+				other.AccountId = "11x2"
+				r.EXPECT().GetById(context.Background(), int64(3)).Return(other, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name:   "Payment not found",
+			id:     "4",
+			intent: payment.Intent{Id: "fake_1"},
+			mockPayments: func(r *mock_repository.MockPayments) {
+				r.EXPECT().GetById(context.Background(), int64(4)).Return(domain.Payment{}, repository.ErrRecordNotFound)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusNotFound,
+			responseBody: `Payment not found`,
+		},
+		{
+			name:         "Invalid id",
+			id:           "abc",
+			intent:       payment.Intent{Id: "fake_1"},
+			mockPayments: func(r *mock_repository.MockPayments) {},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"id"`,
+		},
+		{
+			name:         "Read only user can not cancel",
+			id:           "3",
+			intent:       payment.Intent{Id: "fake_1"},
+			mockPayments: func(r *mock_repository.MockPayments) {},
+			userModel:    &domain.User{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleReadOnly},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rp := mock_repository.NewMockPayments(c)
+			tt.mockPayments(rp)
+			provider := fake.NewProvider("fake")
+			provider.Put(tt.intent)
+
+			services := &service.Services{
+				Payments: service.NewPaymentsService(cache.NewMemoryCache(), paymentsTestConfig, service.CurrencyService{}, rp, nil, nil, []payment.Provider{provider}, vtiger.NewMockedVtigerConnector(), pubsub.NewHub(10, 10), nil, service.NewMockEmailService(), service.Company{}, service.Audit{}, &sync.WaitGroup{}),
+				Context:  service.MockedContextService{MockedUser: tt.userModel},
+			}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/payments/:id/cancel", handler.cancelPayment)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/payments/"+tt.id+"/cancel", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
@@ -231,7 +231,7 @@ func TestHandler_getRelatedCommentsFromProjectTask(t *testing.T) {
 			rt := mock_repository.NewMockProjectTask(c)
 			tt.mockProjectTask(rt)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.NewManagerService(rman, cache.NewMemoryCache()))
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.NewManagerService(rman, cache.NewMemoryCache()), nil)
 
 			projectsService := service.NewProjectsService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, rt)
 			projectTaskService := service.NewProjectTasksService(rt, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, projectsService)
@@ -349,8 +349,8 @@ func TestHandler_getRelatedDocumentsFromProjectTask(t *testing.T) {
 			tt.mockDocument(rd)
 			tt.mockProjectTask(rt)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
 			projectsService := service.NewProjectsService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{}, rt)
 			projectTasksService := service.NewProjectTasksService(rt, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{}, projectsService)
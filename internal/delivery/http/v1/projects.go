@@ -197,11 +197,16 @@ func (h *Handler) uploadProjectDocument(c *gin.Context) {
 	}
 
 	document, err := h.services.Documents.AttachFile(c.Request.Context(), file, id, *userModel, header)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleDocuments, document.Id, nil, document)
 	c.JSON(http.StatusOK, AloneDataResponse[domain.Document]{Data: document})
 }
 
@@ -215,6 +220,10 @@ func (h *Handler) uploadProjectTaskDocument(c *gin.Context) {
 		return
 	}
 	_, err := h.services.Projects.GetProjectById(c.Request.Context(), id, false, userModel)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -227,11 +236,16 @@ func (h *Handler) uploadProjectTaskDocument(c *gin.Context) {
 	}
 
 	document, err := h.services.Documents.AttachFile(c.Request.Context(), file, taskId, *userModel, header)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleDocuments, document.Id, nil, document)
 	c.JSON(http.StatusOK, AloneDataResponse[domain.Document]{Data: document})
 }
 
@@ -334,6 +348,7 @@ func (h *Handler) addProjectComment(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, service.AuditModuleComments, comment.Id, nil, comment)
 	res := AloneDataResponse[domain.Comment]{
 		Data: comment,
 	}
@@ -398,15 +413,20 @@ func (h *Handler) createProjectTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.services.ProjectTasks.CreateProjectTask(c.Request.Context(), inp, id)
+	task, err := h.services.ProjectTasks.CreateProjectTask(c.Request.Context(), inp, id, *userModel)
 	if errors.Is(service.ErrValidation, err) {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "projecttaskname", "message": err.Error()})
 		return
 	}
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleProjectTask, task.Id, nil, task)
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -423,6 +443,8 @@ func (h *Handler) updateProjectTask(c *gin.Context) {
 		return
 	}
 
+	// errors of missing task are reported by update itself
+	before, _ := h.services.ProjectTasks.GetProjectTaskById(c.Request.Context(), taskId)
 	task, err := h.services.ProjectTasks.Revise(c.Request.Context(), inp, taskId, id, *userModel)
 	if errors.Is(service.ErrValidation, err) {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "projecttaskstatus", "message": err.Error()})
@@ -436,6 +458,7 @@ func (h *Handler) updateProjectTask(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModuleProjectTask, taskId, before, task)
 	c.JSON(http.StatusAccepted, task)
 }
 
@@ -491,6 +514,7 @@ func (h *Handler) addProjectTaskComment(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, service.AuditModuleComments, comment.Id, nil, comment)
 	res := AloneDataResponse[domain.Comment]{
 		Data: comment,
 	}
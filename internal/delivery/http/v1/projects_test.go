@@ -221,7 +221,7 @@ func TestHandler_getRelatedCommentsFromProject(t *testing.T) {
 			tt.mockComment(rc)
 			tt.mockManager(rman)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.NewManagerService(rman, cache.NewMemoryCache()))
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.NewManagerService(rman, cache.NewMemoryCache()), nil)
 
 			projectsService := service.NewProjectsService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, repository.ProjectTaskCrm{})
 
@@ -317,7 +317,7 @@ func TestHandler_getAllProjects(t *testing.T) {
 			rc := mock_repository.NewMockComment(c)
 			tt.mockProject(rm)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
 
 			projectsService := service.NewProjectsService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, repository.ProjectTaskCrm{})
 
@@ -420,8 +420,8 @@ func TestHandler_getRelatedDocumentsFromProject(t *testing.T) {
 			tt.mockProject(rm)
 			tt.mockDocument(rd)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
 			projectsService := service.NewProjectsService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{}, repository.ProjectTaskCrm{})
 
@@ -533,8 +533,8 @@ func TestHandler_getFileFromProject(t *testing.T) {
 			tt.mockProject(rm)
 			tt.mockDocument(rd)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
 			projectsService := service.NewProjectsService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{}, repository.ProjectTaskCrm{})
 
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"net/http"
+)
+
+func (h *Handler) initSsoRoutes(api *gin.RouterGroup) {
+	sso := api.Group("/sso")
+	{
+		sso.GET("/:account/login", h.ssoLogin)
+		sso.GET("/:account/callback", h.ssoOidcCallback)
+		sso.POST("/:account/acs", h.ssoSamlCallback)
+		sso.GET("/:account/metadata", h.ssoMetadata)
+		sso.POST("/exchange", h.ssoExchange)
+	}
+}
+
+func (h *Handler) ssoLogin(c *gin.Context) {
+	location, err := h.services.Sso.Begin(c.Request.Context(), c.Param("account"))
+	if err != nil {
+		ssoErrorResponse(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+func (h *Handler) ssoOidcCallback(c *gin.Context) {
+	if errorCode := c.Query("error"); errorCode != "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "SSO Error", "field": errorCode, "message": c.Query("error_description")})
+		return
+	}
+	location, err := h.services.Sso.FinishOidc(c.Request.Context(), c.Param("account"), c.Query("code"), c.Query("state"))
+	if err != nil {
+		ssoErrorResponse(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+func (h *Handler) ssoSamlCallback(c *gin.Context) {
+	location, err := h.services.Sso.FinishSaml(c.Request.Context(), c.Param("account"), c.PostForm("SAMLResponse"), c.PostForm("RelayState"))
+	if err != nil {
+		ssoErrorResponse(c, err)
+		return
+	}
+	// browser comes with form post from identity provider, so it is sent to frontend with GET
+	c.Redirect(http.StatusSeeOther, location)
+}
+
+type ssoExchangeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func (h *Handler) ssoExchange(c *gin.Context) {
+	var inp ssoExchangeInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "code", "message": "Code is required"})
+		return
+	}
+	token, err := h.services.Sso.Exchange(inp.Code)
+	if err != nil {
+		ssoErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *Handler) ssoMetadata(c *gin.Context) {
+	metadata, err := h.services.Sso.Metadata(c.Request.Context(), c.Param("account"))
+	if err != nil {
+		ssoErrorResponse(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+func ssoErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSsoNotConfigured):
+		newResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrSsoStateNotFound), errors.Is(err, service.ErrSsoFailed), errors.Is(err, service.ErrSsoCodeNotFound):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "SSO Error", "message": err.Error()})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "SSO Error", "field": "email", "message": "Contact with this email is not found in account"})
+	case errors.Is(err, service.ErrUserIsNotActive):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not Active", "field": "is_active", "message": err.Error()})
+	default:
+		newResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
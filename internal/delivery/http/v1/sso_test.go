@@ -0,0 +1,453 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/golang/mock/gomock"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"html"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const ssoBaseUrl = "http://portal.example.com/api/v1"
+const ssoRedirectUrl = "http://app.example.com/sso"
+
+// mockOidcIdp is a minimal openid provider: discovery, authorization endpoint, which signs in configured email
+// without asking anything, token endpoint with PKCE check and keys.
+type mockOidcIdp struct {
+	server        *httptest.Server
+	key           *rsa.PrivateKey
+	email         string
+	emailVerified bool
+	nonce         string
+	codes         map[string]url.Values
+	discoveries   int
+}
+
+func newMockOidcIdp(t *testing.T) *mockOidcIdp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &mockOidcIdp{key: key, emailVerified: true, codes: map[string]url.Values{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.discoveries++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &idp.key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		code := "code-" + r.URL.Query().Get("state")
+		idp.codes[code] = r.URL.Query()
+		redirect, _ := url.Parse(r.URL.Query().Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {code}, "state": {r.URL.Query().Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		request, ok := idp.codes[r.PostForm.Get("code")]
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || request.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		delete(idp.codes, r.PostForm.Get("code"))
+		nonce := request.Get("nonce")
+		if idp.nonce != "" {
+			nonce = idp.nonce
+		}
+		signer, _ := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: idp.key}, (&jose.SignerOptions{}).WithHeader("kid", "test"))
+		idToken, _ := jwt.Signed(signer).Claims(map[string]any{
+			"iss":            idp.server.URL,
+			"aud":            request.Get("client_id"),
+			"sub":            "42",
+			"email":          idp.email,
+			"email_verified": idp.emailVerified,
+			"nonce":          nonce,
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		}).CompactSerialize()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// mockSamlIdp is identity provider from saml library with session of configured email.
+type mockSamlIdp struct {
+	server *httptest.Server
+	idp    *saml.IdentityProvider
+	email  string
+	sp     func() *saml.EntityDescriptor
+}
+
+func (m *mockSamlIdp) GetSession(w http.ResponseWriter, r *http.Request, req *saml.IdpAuthnRequest) *saml.Session {
+	return &saml.Session{
+		ID:           "session",
+		CreateTime:   time.Now(),
+		ExpireTime:   time.Now().Add(time.Hour),
+		NameID:       m.email,
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		UserEmail:    m.email,
+	}
+}
+
+func (m *mockSamlIdp) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	return m.sp(), nil
+}
+
+func newMockSamlIdp(t *testing.T) *mockSamlIdp {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	mock := &mockSamlIdp{}
+	mux := http.NewServeMux()
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+	metadataUrl, _ := url.Parse(mock.server.URL + "/metadata")
+	ssoUrl, _ := url.Parse(mock.server.URL + "/sso")
+	mock.idp = &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             certificate,
+		MetadataURL:             *metadataUrl,
+		SSOURL:                  *ssoUrl,
+		SessionProvider:         mock,
+		ServiceProviderProvider: mock,
+	}
+	mux.HandleFunc("/metadata", mock.idp.ServeMetadata)
+	mux.HandleFunc("/sso", mock.idp.ServeSSO)
+	return mock
+}
+
+var samlFormValue = regexp.MustCompile(`name="(SAMLResponse|RelayState)" value="([^"]*)"`)
+
+func newSsoRouter(t *testing.T, c *gomock.Controller, cfg config.Config, ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens) *gin.Engine {
+	var wg sync.WaitGroup
+	t.Cleanup(wg.Wait)
+	companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+	usersService := service.NewUsersService(ru, rc, &wg, service.NewMockEmailService(), companyService, rt, mock_repository.NewMockDocument(c), cache.NewMemoryCache(), service.NewAccountService(ra, cache.NewMemoryCache()), cfg)
+	tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), cfg, companyService, cache.NewMemoryCache())
+	services := &service.Services{Users: usersService, Tokens: tokensService, Sso: service.NewSsoService(usersService, tokensService, cache.NewMemoryCache(), cfg)}
+	handler := Handler{services: services, config: &cfg}
+
+	r := gin.New()
+	handler.initSsoRoutes(r.Group("/api/v1"))
+	return r
+}
+
+// exchangeSsoCode follows redirect of callback to frontend and exchanges one-time code from it.
+func exchangeSsoCode(t *testing.T, r *gin.Engine, w *httptest.ResponseRecorder) *httptest.ResponseRecorder {
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(location.String(), ssoRedirectUrl+"?"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+	assert.NotContains(t, location.String(), "SSO_ACCESS_TOKEN")
+
+	body, _ := json.Marshal(map[string]string{"code": code})
+	exchanged := httptest.NewRecorder()
+	r.ServeHTTP(exchanged, httptest.NewRequest("POST", "/api/v1/sso/exchange", bytes.NewReader(body)))
+
+	replayed := httptest.NewRecorder()
+	r.ServeHTTP(replayed, httptest.NewRequest("POST", "/api/v1/sso/exchange", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusUnauthorized, replayed.Code, "code must be accepted only once")
+	return exchanged
+}
+
+func expectSsoToken(rt *mock_repository.MockTokens, userId int64) {
+	rt.EXPECT().DeleteExpiredForUser(context.Background(), userId).Return(nil)
+	rt.EXPECT().NewInFamily(context.Background(), userId, 15*time.Minute, domain.ScopeAuthentication, gomock.Any()).Return(&domain.Token{ID: 1, Plaintext: "SSO_ACCESS_TOKEN", UserId: userId}, nil)
+	rt.EXPECT().NewInFamily(context.Background(), userId, 30*24*time.Hour, domain.ScopeRefresh, gomock.Any()).Return(&domain.Token{ID: 2, Plaintext: "SSO_REFRESH_TOKEN", UserId: userId}, nil)
+}
+
+func TestHandler_ssoOidc(t *testing.T) {
+	type mockBehaviour func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens)
+	const email = "staff@example.com"
+	contact := repository.MockedUser
+	contact.Id = 0
+	contact.Email = email
+
+	tests := []struct {
+		name          string
+		emailVerified bool
+		nonce         string
+		mock          mockBehaviour
+		statusCode    int
+		responseBody  string
+	}{
+		{
+			name:          "Registered user signs in",
+			emailVerified: true,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(repository.MockedUser, nil)
+				expectSsoToken(rt, 1)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"token":"SSO_ACCESS_TOKEN"`,
+		},
+		{
+			name:          "Contact is provisioned on first sign in",
+			emailVerified: true,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens) {
+				other := contact
+				other.Crmid = "12x99"
+				other.AccountId = "11x5"
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(domain.User{}, repository.ErrRecordNotFound)
+				rc.EXPECT().FindByEmail(context.Background(), email).Return([]domain.User{other, contact}, nil)
+				ra.EXPECT().RetrieveById(context.Background(), "11x1").Return(domain.Account{AccountName: "Example Ltd"}, nil)
+				ru.EXPECT().GetAllByAccountId(context.Background(), "11x1").Return([]domain.User{repository.MockedUser}, nil)
+				ru.EXPECT().Insert(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *domain.User) error {
+					assert.Equal(t, "12x11", user.Crmid)
+					assert.Equal(t, "Example Ltd", user.AccountName)
+					assert.Equal(t, domain.RoleMember, user.Role)
+					assert.True(t, user.IsActive)
+					assert.NotEmpty(t, user.Password.Hash)
+					user.Id = 5
+					return nil
+				})
+				expectSsoToken(rt, 5)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"token":"SSO_ACCESS_TOKEN"`,
+		},
+		{
+			name:          "Contact from other account is rejected",
+			emailVerified: true,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens) {
+				other := contact
+				other.AccountId = "11x5"
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(domain.User{}, repository.ErrRecordNotFound)
+				rc.EXPECT().FindByEmail(context.Background(), email).Return([]domain.User{other}, nil)
+			},
+			statusCode:   http.StatusForbidden,
+			responseBody: "Contact with this email is not found in account",
+		},
+		{
+			name:          "Unverified email is rejected",
+			emailVerified: false,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens) {
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: "email is not verified",
+		},
+		{
+			name:          "Id token of other request is rejected",
+			emailVerified: true,
+			nonce:         "replayed",
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, ra *mock_repository.MockAccount, rt *mock_repository.MockTokens) {
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: "nonce does not match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			idp := newMockOidcIdp(t)
+			idp.email = email
+			idp.emailVerified = tt.emailVerified
+			idp.nonce = tt.nonce
+
+			ru := mock_repository.NewMockUsers(c)
+			rc := mock_repository.NewMockUsersCrm(c)
+			ra := mock_repository.NewMockAccount(c)
+			rt := mock_repository.NewMockTokens(c)
+			tt.mock(ru, rc, ra, rt)
+
+			cfg := config.Config{Sso: config.SsoConfig{BaseUrl: ssoBaseUrl, RedirectUrl: ssoRedirectUrl, Providers: map[string]config.SsoProvider{
+				"11x1": {Protocol: service.SsoOidc, Issuer: idp.server.URL, ClientId: "portal", ClientSecret: "secret"},
+			}}}
+			r := newSsoRouter(t, c, cfg, ru, rc, ra, rt)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/sso/11x1/login", nil))
+			require.Equal(t, http.StatusFound, w.Code)
+			require.True(t, strings.HasPrefix(w.Header().Get("Location"), idp.server.URL+"/authorize?"))
+
+			noRedirects := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			}}
+			authorized, err := noRedirects.Get(w.Header().Get("Location"))
+			require.NoError(t, err)
+			authorized.Body.Close()
+			callback := authorized.Header.Get("Location")
+			require.True(t, strings.HasPrefix(callback, ssoBaseUrl+"/sso/11x1/callback?"))
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", strings.TrimPrefix(callback, "http://portal.example.com"), nil))
+			if w.Code == http.StatusFound {
+				w = exchangeSsoCode(t, r, w)
+			}
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+			assert.Equal(t, 1, idp.discoveries, "provider must be discovered once")
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", strings.TrimPrefix(callback, "http://portal.example.com"), nil))
+			assert.Equal(t, http.StatusUnauthorized, w.Code, "state must be accepted only once")
+		})
+	}
+}
+
+func TestHandler_ssoSaml(t *testing.T) {
+	const email = "staff@example.com"
+
+	tests := []struct {
+		name         string
+		tamper       func(form url.Values)
+		mock         func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens)
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:   "Signed assertion signs user in",
+			tamper: func(form url.Values) {},
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(repository.MockedUser, nil)
+				expectSsoToken(rt, 1)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"token":"SSO_ACCESS_TOKEN"`,
+		},
+		{
+			name: "Changed assertion is rejected",
+			tamper: func(form url.Values) {
+				decoded, _ := base64.StdEncoding.DecodeString(form.Get("SAMLResponse"))
+				changed := strings.ReplaceAll(string(decoded), email, "boss@example.com")
+				form.Set("SAMLResponse", base64.StdEncoding.EncodeToString([]byte(changed)))
+			},
+			mock:         func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrSsoFailed.Error(),
+		},
+		{
+			name: "Unknown relay state is rejected",
+			tamper: func(form url.Values) {
+				form.Set("RelayState", "unknown")
+			},
+			mock:         func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrSsoStateNotFound.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			idp := newMockSamlIdp(t)
+			idp.email = email
+
+			ru := mock_repository.NewMockUsers(c)
+			rt := mock_repository.NewMockTokens(c)
+			tt.mock(ru, rt)
+
+			cfg := config.Config{Sso: config.SsoConfig{BaseUrl: ssoBaseUrl, RedirectUrl: ssoRedirectUrl, Providers: map[string]config.SsoProvider{
+				"11x1": {Protocol: service.SsoSaml, MetadataUrl: idp.server.URL + "/metadata", EmailAttribute: "eduPersonPrincipalName"},
+			}}}
+			r := newSsoRouter(t, c, cfg, ru, mock_repository.NewMockUsersCrm(c), mock_repository.NewMockAccount(c), rt)
+			idp.sp = func() *saml.EntityDescriptor {
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/sso/11x1/metadata", nil))
+				metadata := &saml.EntityDescriptor{}
+				require.NoError(t, xml.Unmarshal(w.Body.Bytes(), metadata))
+				return metadata
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/sso/11x1/login", nil))
+			require.Equal(t, http.StatusFound, w.Code)
+			require.True(t, strings.HasPrefix(w.Header().Get("Location"), idp.server.URL+"/sso?"))
+
+			signedIn, err := http.Get(w.Header().Get("Location"))
+			require.NoError(t, err)
+			page := new(bytes.Buffer)
+			_, _ = page.ReadFrom(signedIn.Body)
+			signedIn.Body.Close()
+			form := url.Values{}
+			for _, match := range samlFormValue.FindAllStringSubmatch(page.String(), -1) {
+				form.Set(match[1], html.UnescapeString(match[2]))
+			}
+			require.NotEmpty(t, form.Get("SAMLResponse"), page.String())
+			tt.tamper(form)
+
+			w = httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/sso/11x1/acs", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			r.ServeHTTP(w, req)
+			if w.Code == http.StatusSeeOther {
+				w = exchangeSsoCode(t, r, w)
+			}
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_ssoLoginNotConfigured(t *testing.T) {
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	r := newSsoRouter(t, c, config.Config{}, mock_repository.NewMockUsers(c), mock_repository.NewMockUsersCrm(c), mock_repository.NewMockAccount(c), mock_repository.NewMockTokens(c))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/sso/11x7/login", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), service.ErrSsoNotConfigured.Error())
+}
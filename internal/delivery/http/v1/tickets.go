@@ -104,6 +104,7 @@ func (h *Handler) addComment(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, service.AuditModuleComments, comment.Id, nil, comment)
 	c.JSON(http.StatusCreated, comment)
 }
 
@@ -158,11 +159,16 @@ func (h *Handler) uploadTicketDocuments(c *gin.Context) {
 	}
 
 	document, err := h.services.Documents.AttachFile(c.Request.Context(), file, id, *userModel, header)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleDocuments, document.Id, nil, document)
 	c.JSON(http.StatusOK, AloneDataResponse[domain.Document]{Data: document})
 }
 
@@ -252,6 +258,10 @@ func (h *Handler) createTicket(c *gin.Context) {
 	}
 
 	ticket, err := h.services.HelpDesk.CreateTicket(c.Request.Context(), inp, *userModel)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
 	if errors.Is(service.ErrValidation, err) {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "ticketcategories", "message": err.Error()})
 		return
@@ -260,6 +270,7 @@ func (h *Handler) createTicket(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleHelpDesk, ticket.ID, nil, ticket)
 	c.JSON(http.StatusCreated, ticket)
 }
 
@@ -277,6 +288,16 @@ func (h *Handler) updateTicket(c *gin.Context) {
 		return
 	}
 
+	before, err := h.services.HelpDesk.GetHelpDeskById(c.Request.Context(), id, *userModel)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
+	if err != nil {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	ticket, err := h.services.HelpDesk.UpdateTicket(c.Request.Context(), inp, id, *userModel)
 	if errors.Is(service.ErrValidation, err) {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "ticketcategories", "message": err.Error()})
@@ -290,6 +311,7 @@ func (h *Handler) updateTicket(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModuleHelpDesk, id, before, ticket)
 	c.JSON(http.StatusAccepted, ticket)
 }
 
@@ -305,6 +327,16 @@ func (h *Handler) updatePartlyTicket(c *gin.Context) {
 		return
 	}
 
+	before, err := h.services.HelpDesk.GetHelpDeskById(c.Request.Context(), id, *userModel)
+	if errors.Is(service.ErrOperationNotPermitted, err) {
+		notPermittedResponse(c)
+		return
+	}
+	if err != nil {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	ticket, err := h.services.HelpDesk.Revise(c.Request.Context(), inp, id, *userModel)
 	if errors.Is(service.ErrValidation, err) {
 		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "ticketstatus", "message": err.Error()})
@@ -318,5 +350,6 @@ func (h *Handler) updatePartlyTicket(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModuleHelpDesk, id, before, ticket)
 	c.JSON(http.StatusAccepted, ticket)
 }
@@ -81,7 +81,7 @@ func TestHandler_getTicketById(t *testing.T) {
 			rm := mock_repository.NewMockHelpDesk(c)
 			tt.mockTicket(rm)
 
-			managerService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), &mock_service.MockCommentServiceInterface{}, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{})
+			managerService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), &mock_service.MockCommentServiceInterface{}, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, nil)
 
 			services := &service.Services{HelpDesk: managerService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -197,9 +197,9 @@ func TestHandler_getRelatedComments(t *testing.T) {
 			tt.mockComment(rc)
 			tt.mockManager(rman)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.NewManagerService(rman, cache.NewMemoryCache()))
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.NewManagerService(rman, cache.NewMemoryCache()), nil)
 
-			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{})
+			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -292,9 +292,9 @@ func TestHandler_getAllTickets(t *testing.T) {
 			rc := mock_repository.NewMockComment(c)
 			tt.mockTicket(rm)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
 
-			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{})
+			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, mock_service.NewMockDocumentServiceInterface(c), service.ModulesService{}, config.Config{}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services, config: &config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultPagination: 20}}}}
@@ -395,10 +395,10 @@ func TestHandler_getRelatedDocuments(t *testing.T) {
 			tt.mockTicket(rm)
 			tt.mockDocument(rd)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
-			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{})
+			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Documents: documentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -506,10 +506,10 @@ func TestHandler_getFile(t *testing.T) {
 			tt.mockTicket(rm)
 			tt.mockDocument(rd)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
-			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{})
+			helpDeskService := service.NewHelpDeskService(rm, cache.NewMemoryCache(), commentService, documentService, service.ModulesService{}, config.Config{}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Documents: documentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -554,6 +554,13 @@ func TestHandler_createTicket(t *testing.T) {
 			responseBody: `"ticket_no":"TICKET_28"`,
 			userModel:    &repository.MockedUser,
 		},
+		{
+			name:         "Read-only contact can not create ticket",
+			mockModule:   func(r *mock_repository.MockModules) {},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+			userModel:    &domain.User{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleReadOnly},
+		},
 	}
 
 	for _, tt := range tests {
@@ -567,10 +574,10 @@ func TestHandler_createTicket(t *testing.T) {
 			rmm := mock_repository.NewMockModules(c)
 			tt.mockModule(rmm)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
-			helpDeskService := service.NewHelpDeskService(repository.HelpDeskMockRepository{}, cache.NewMemoryCache(), commentService, documentService, service.NewModulesService(rmm, cache.NewMemoryCache()), config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}})
+			helpDeskService := service.NewHelpDeskService(repository.HelpDeskMockRepository{}, cache.NewMemoryCache(), commentService, documentService, service.NewModulesService(rmm, cache.NewMemoryCache()), config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Documents: documentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -645,10 +652,10 @@ func TestHandler_updateTicket(t *testing.T) {
 			rmm := mock_repository.NewMockModules(c)
 			tt.mockModule(rmm)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
-			helpDeskService := service.NewHelpDeskService(repository.HelpDeskMockRepository{}, cache.NewMemoryCache(), commentService, documentService, service.NewModulesService(rmm, cache.NewMemoryCache()), config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}})
+			helpDeskService := service.NewHelpDeskService(repository.HelpDeskMockRepository{}, cache.NewMemoryCache(), commentService, documentService, service.NewModulesService(rmm, cache.NewMemoryCache()), config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Documents: documentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -706,10 +713,10 @@ func TestHandler_addCommentToTicket(t *testing.T) {
 			rd := mock_repository.NewMockDocument(c)
 			rmm := mock_repository.NewMockModules(c)
 
-			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{})
-			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{})
+			commentService := service.NewComments(rc, cache.NewMemoryCache(), config.Config{}, service.UsersService{}, service.ManagerService{}, nil)
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
 
-			helpDeskService := service.NewHelpDeskService(repository.HelpDeskMockRepository{}, cache.NewMemoryCache(), commentService, documentService, service.NewModulesService(rmm, cache.NewMemoryCache()), config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}})
+			helpDeskService := service.NewHelpDeskService(repository.HelpDeskMockRepository{}, cache.NewMemoryCache(), commentService, documentService, service.NewModulesService(rmm, cache.NewMemoryCache()), config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}}, nil)
 
 			services := &service.Services{HelpDesk: helpDeskService, Comments: commentService, Documents: documentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
 			handler := Handler{services: services}
@@ -16,6 +16,16 @@ func (h *Handler) initUsersRoutes(api *gin.RouterGroup) {
 	{
 		users.POST("/", h.userSignUp)
 		users.POST("/login", h.signIn)
+		users.POST("/refresh", h.refreshToken)
+		users.POST("/logout", h.signOut)
+		users.POST("/magic-link", h.sendMagicLink)
+		users.POST("/magic-link/verify", h.verifyMagicLink)
+		users.POST("/invite", h.inviteUser)
+		users.POST("/accept-invite", h.acceptInvite)
+		users.POST("/unlock", h.unlockLogin)
+		users.GET("/sessions", h.getSessions)
+		users.DELETE("/sessions", h.deleteOtherSessions)
+		users.DELETE("/sessions/:id", h.deleteSession)
 		users.GET("/my", h.getUserInfo)
 		users.GET("/settings", h.getUserSettings)
 		users.PATCH("/settings", h.updateUserSettings)
@@ -27,6 +37,8 @@ func (h *Handler) initUsersRoutes(api *gin.RouterGroup) {
 		users.GET("/all", h.usersFromAccount)
 		users.GET("/:id/file/:file", h.getUserFile)
 		users.DELETE("/:id/documents/:document", h.deleteUserFile)
+		users.GET("/:id/role", h.getUserRole)
+		users.PUT("/:id/role", h.changeUserRole)
 	}
 }
 
@@ -71,6 +83,12 @@ func (h *Handler) updateUserSettings(c *gin.Context) {
 		}
 	}
 
+	// missing settings are recorded as new values, it is not a reason to refuse change
+	before, _ := h.services.Users.GetUserSettings(c.Request.Context(), userModel.Crmid)
+	after := make(map[string]bool, len(before))
+	for field, value := range before {
+		after[field] = value
+	}
 	fields := h.config.Vtiger.Business.UserSettingsFields
 	for _, field := range fields {
 		value, ok := inp[field]
@@ -80,8 +98,10 @@ func (h *Handler) updateUserSettings(c *gin.Context) {
 				newResponse(c, http.StatusInternalServerError, err.Error())
 				return
 			}
+			after[field] = value
 		}
 	}
+	h.audit(c, *userModel, service.AuditUpdate, service.AuditModuleSettings, userModel.Crmid, before, after)
 
 	c.JSON(http.StatusAccepted, userModel)
 }
@@ -117,6 +137,7 @@ func (h *Handler) updateUserInfo(c *gin.Context) {
 
 		return
 	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModuleContacts, userModel.Crmid, userModel, user)
 	go h.getUserInfo(c)
 	c.JSON(http.StatusAccepted, user)
 }
@@ -130,9 +151,14 @@ func (h *Handler) signIn(c *gin.Context) {
 		}
 	}
 
-	token, err := h.services.Tokens.CreateAuthToken(c.Request.Context(), inp.Email, inp.Password)
+	token, err := h.services.LoginGuard.SignIn(c.Request.Context(), inp.Email, inp.Password, c.ClientIP())
 
 	if err != nil {
+		if errors.Is(err, service.ErrLoginLocked) || errors.Is(err, service.ErrLoginThrottled) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too Many Attempts", "field": "email", "message": err.Error()})
+
+			return
+		}
 		if errors.Is(err, repository.ErrRecordNotFound) {
 			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "email", "message": "User with this email not found"})
 
@@ -149,6 +175,148 @@ func (h *Handler) signIn(c *gin.Context) {
 	c.JSON(http.StatusCreated, token)
 }
 
+func (h *Handler) refreshToken(c *gin.Context) {
+	var inp service.RefreshInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "refresh_token", "message": err.Error()})
+		return
+	}
+
+	token, err := h.services.Tokens.Refresh(c.Request.Context(), inp.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRefreshToken), errors.Is(err, service.ErrRefreshTokenReused):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token Error", "field": "refresh_token", "message": err.Error()})
+		case errors.Is(err, service.ErrUserIsNotActive):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not Active", "field": "is_active", "message": err.Error()})
+		default:
+			newResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+// sendMagicLink answers the same way for unknown and disabled emails, so it can not be used to find registered users.
+func (h *Handler) sendMagicLink(c *gin.Context) {
+	var inp struct {
+		Email string `json:"email" binding:"required,email,max=64"`
+	}
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "email", "message": err.Error()})
+		return
+	}
+
+	err := h.services.Tokens.SendMagicLink(c.Request.Context(), inp.Email)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) && !errors.Is(err, service.ErrUserIsNotActive) {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "message": "If this email is registered, we sent a link to sign in"})
+}
+
+func (h *Handler) verifyMagicLink(c *gin.Context) {
+	var inp service.MagicLinkInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "token", "message": err.Error()})
+		return
+	}
+
+	token, err := h.services.Tokens.VerifyMagicLink(c.Request.Context(), inp.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidMagicLink):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token Error", "field": "token", "message": err.Error()})
+		case errors.Is(err, service.ErrUserIsNotActive):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not Active", "field": "is_active", "message": err.Error()})
+		default:
+			newResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *Handler) unlockLogin(c *gin.Context) {
+	var inp service.UnlockInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "token", "message": err.Error()})
+		return
+	}
+
+	err := h.services.LoginGuard.Unlock(c.Request.Context(), inp)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidUnlockToken) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token Error", "field": "token", "message": err.Error()})
+			return
+		}
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Sign in is unlocked"})
+}
+
+func (h *Handler) signOut(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	if err := h.services.Tokens.Logout(c.Request.Context(), bearerToken(c)); err != nil {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) getSessions(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	sessions, err := h.services.Tokens.GetSessions(c.Request.Context(), *userModel, bearerToken(c))
+	if err != nil {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, DataResponse[domain.Session]{Data: sessions, Count: len(sessions)})
+}
+
+func (h *Handler) deleteSession(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	err := h.services.Tokens.DeleteSession(c.Request.Context(), *userModel, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.audit(c, *userModel, service.AuditDelete, service.AuditModuleSessions, c.Param("id"), gin.H{"id": c.Param("id")}, nil)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) deleteOtherSessions(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	err := h.services.Tokens.DeleteOtherSessions(c.Request.Context(), *userModel, bearerToken(c))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			newResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.audit(c, *userModel, service.AuditDelete, service.AuditModuleSessions, userModel.Crmid, gin.H{"sessions": "other"}, nil)
+	c.Status(http.StatusNoContent)
+}
+
 func (h Handler) getUserInfo(c *gin.Context) {
 	userModel := h.getValidatedUser(c)
 	if userModel == nil {
@@ -228,6 +396,7 @@ func (h Handler) resetPassword(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, user, service.AuditUpdate, domain.ModuleContacts, user.Crmid, nil, gin.H{"password": true})
 	c.JSON(http.StatusAccepted, user)
 }
 
@@ -351,7 +520,7 @@ func (h *Handler) deleteUserFile(c *gin.Context) {
 		return
 	}
 
-	err := h.services.Documents.DeleteFile(c.Request.Context(), fileId, id)
+	err := h.services.Documents.DeleteFile(c.Request.Context(), fileId, id, *userModel)
 
 	if errors.Is(service.ErrOperationNotPermitted, err) {
 		notPermittedResponse(c)
@@ -362,6 +531,96 @@ func (h *Handler) deleteUserFile(c *gin.Context) {
 		newResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	h.audit(c, *userModel, service.AuditDelete, domain.ModuleDocuments, fileId, gin.H{"id": fileId, "contact_id": id}, nil)
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+func (h *Handler) getUserRole(c *gin.Context) {
+	id := h.getAndValidateId(c, "id")
+	userModel := h.getValidatedUser(c)
+	if id == "" || userModel == nil {
+		return
+	}
+	role, err := h.services.Users.GetRole(c.Request.Context(), id, *userModel)
+	if err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, AloneDataResponse[domain.UserRole]{Data: role})
+}
+
+func (h *Handler) changeUserRole(c *gin.Context) {
+	var inp service.RoleInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "role", "message": err.Error()})
+		return
+	}
+	id := h.getAndValidateId(c, "id")
+	userModel := h.getValidatedUser(c)
+	if id == "" || userModel == nil {
+		return
+	}
+	role, err := h.services.Users.ChangeRole(c.Request.Context(), id, inp.Role, *userModel)
+	if err != nil {
+		roleErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModuleContacts, id, nil, gin.H{"role": role.Role})
+	c.JSON(http.StatusAccepted, AloneDataResponse[domain.UserRole]{Data: role})
+}
+
+func (h *Handler) inviteUser(c *gin.Context) {
+	var inp service.InviteInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "email", "message": err.Error()})
+		return
+	}
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	user, err := h.services.Users.Invite(c.Request.Context(), inp, *userModel)
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateEmail) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "email", "message": "User with this email is already registered"})
+			return
+		}
+		roleErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleContacts, user.Crmid, nil, user)
+	c.JSON(http.StatusCreated, AloneDataResponse[domain.User]{Data: user})
+}
+
+func (h *Handler) acceptInvite(c *gin.Context) {
+	var inp service.AcceptInviteInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "password", "message": err.Error()})
+		return
+	}
+	user, err := h.services.Users.AcceptInvitation(c.Request.Context(), inp)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidInvitation) {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "token", "message": err.Error()})
+			return
+		}
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.audit(c, user, service.AuditUpdate, domain.ModuleContacts, user.Crmid, gin.H{"is_active": false}, gin.H{"is_active": true, "password": true})
+	c.JSON(http.StatusOK, user)
+}
+
+func roleErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "role", "message": err.Error()})
+	case errors.Is(err, service.ErrOperationNotPermitted):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access Not Permitted", "field": "role", "message": err.Error()})
+	case errors.Is(err, repository.ErrRecordNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not Found", "field": "id", "message": "Contact is not registered in portal or belongs to other account"})
+	default:
+		newResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -168,9 +168,23 @@ func TestHandler_createUser(t *testing.T) {
 	}
 }
 
+// expectReserve reserves attempt of key like repository does, when allow accepts attempts.
+func expectReserve(r *mock_repository.MockLoginAttempts, key string, attempts domain.LoginAttempts) {
+	r.EXPECT().Reserve(context.Background(), key, time.Hour, gomock.Any()).DoAndReturn(func(ctx context.Context, key string, window time.Duration, allow func(domain.LoginAttempts) error) (domain.LoginAttempts, error) {
+		if err := allow(attempts); err != nil {
+			return attempts, err
+		}
+		now := time.Now()
+		attempts.FailedAttempts++
+		attempts.LastFailedAt = &now
+		return attempts, nil
+	})
+}
+
 func TestHandler_Login(t *testing.T) {
 	type mockRepositoryUser func(r *mock_repository.MockUsers)
 	type mockRepositoryToken func(r *mock_repository.MockTokens)
+	type mockRepositoryAttempts func(r *mock_repository.MockLoginAttempts)
 	mockedUserModel := repository.MockedUser
 
 	mockedToken := &domain.Token{
@@ -188,8 +202,10 @@ func TestHandler_Login(t *testing.T) {
 		password     string
 		mockUser     mockRepositoryUser
 		mockToken    mockRepositoryToken
+		mockAttempts mockRepositoryAttempts
 		statusCode   int
 		responseBody string
+		audit        []string
 	}{
 		{
 			name:     "Login Successfull",
@@ -202,10 +218,18 @@ func TestHandler_Login(t *testing.T) {
 				r.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(mockedUserModel, nil)
 			},
 			mockToken: func(r *mock_repository.MockTokens) {
-				r.EXPECT().New(context.Background(), int64(1), 24*time.Hour*90, domain.ScopeAuthentication).Return(mockedToken, nil)
+				r.EXPECT().DeleteExpiredForUser(context.Background(), int64(1)).Return(nil)
+				r.EXPECT().NewInFamily(context.Background(), int64(1), 15*time.Minute, domain.ScopeAuthentication, gomock.Any()).Return(mockedToken, nil)
+				r.EXPECT().NewInFamily(context.Background(), int64(1), 30*24*time.Hour, domain.ScopeRefresh, gomock.Any()).Return(&domain.Token{ID: 2, Plaintext: "REFRESH_TEXT", UserId: 1, Scope: domain.ScopeRefresh}, nil)
+			},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{})
+				expectReserve(r, "ip:192.0.2.1", domain.LoginAttempts{})
+				r.EXPECT().Release(context.Background(), "ip:192.0.2.1", nil).Return(nil)
+				r.EXPECT().Reset(context.Background(), "email:emelyanov86@km.ru").Return(nil)
 			},
 			statusCode:   201,
-			responseBody: `"id":1,"token":"SOME_TEXT"`,
+			responseBody: `"refresh_token":"REFRESH_TEXT"`,
 		}, {
 			name:     "Wrong Email",
 			email:    "emelyanov8611@km.ru",
@@ -219,6 +243,10 @@ func TestHandler_Login(t *testing.T) {
 			mockToken: func(r *mock_repository.MockTokens) {
 				//r.EXPECT().New(context.Background(), int64(1), 24*time.Hour*90, domain.ScopeAuthentication).Return(mockedToken, nil)
 			},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				expectReserve(r, "email:emelyanov8611@km.ru", domain.LoginAttempts{})
+				expectReserve(r, "ip:192.0.2.1", domain.LoginAttempts{})
+			},
 			statusCode:   http.StatusUnprocessableEntity,
 			responseBody: `User with this email not found`,
 		}, {
@@ -226,13 +254,120 @@ func TestHandler_Login(t *testing.T) {
 			email:    "emelyanov86@km.ru",
 			password: "PasswordWrong",
 			mockUser: func(r *mock_repository.MockUsers) {
-				r.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(mockedUserModel, service.ErrPasswordDoesNotMatch)
+				var pass domain.Password
+				pass.Set("GoodPasswordHele")
+				mockedUserModel.Password = pass
+				r.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(mockedUserModel, nil)
 			},
 			mockToken: func(r *mock_repository.MockTokens) {
 				//r.EXPECT().New(context.Background(), int64(1), 24*time.Hour*90, domain.ScopeAuthentication).Return(mockedToken, nil)
 			},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lastFailedAt := time.Now().Add(-10 * time.Second)
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{FailedAttempts: 1, LastFailedAt: &lastFailedAt})
+				expectReserve(r, "ip:192.0.2.1", domain.LoginAttempts{FailedAttempts: 1, LastFailedAt: &lastFailedAt})
+			},
 			statusCode:   http.StatusUnprocessableEntity,
 			responseBody: `Password you passed to us is incorrect`,
+			audit:        []string{service.AuditFail},
+		}, {
+			name:     "Successful login resets failures",
+			email:    "emelyanov86@km.ru",
+			password: "GoodPasswordHele",
+			mockUser: func(r *mock_repository.MockUsers) {
+				var pass domain.Password
+				pass.Set("GoodPasswordHele")
+				mockedUserModel.Password = pass
+				r.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(mockedUserModel, nil)
+			},
+			mockToken: func(r *mock_repository.MockTokens) {
+				r.EXPECT().DeleteExpiredForUser(context.Background(), int64(1)).Return(nil)
+				r.EXPECT().NewInFamily(context.Background(), int64(1), 15*time.Minute, domain.ScopeAuthentication, gomock.Any()).Return(mockedToken, nil)
+				r.EXPECT().NewInFamily(context.Background(), int64(1), 30*24*time.Hour, domain.ScopeRefresh, gomock.Any()).Return(&domain.Token{ID: 2, Plaintext: "REFRESH_TEXT", UserId: 1, Scope: domain.ScopeRefresh}, nil)
+			},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lastFailedAt := time.Now().Add(-10 * time.Second)
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{FailedAttempts: 2, LastFailedAt: &lastFailedAt})
+				expectReserve(r, "ip:192.0.2.1", domain.LoginAttempts{FailedAttempts: 2, LastFailedAt: &lastFailedAt})
+				r.EXPECT().Release(context.Background(), "ip:192.0.2.1", &lastFailedAt).Return(nil)
+				r.EXPECT().Reset(context.Background(), "email:emelyanov86@km.ru").Return(nil)
+			},
+			statusCode:   201,
+			responseBody: `"refresh_token":"REFRESH_TEXT"`,
+		}, {
+			name:      "Email is locked",
+			email:     "emelyanov86@km.ru",
+			password:  "GoodPasswordHele",
+			mockUser:  func(r *mock_repository.MockUsers) {},
+			mockToken: func(r *mock_repository.MockTokens) {},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lockedUntil := time.Now().Add(10 * time.Minute)
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{LockedUntil: &lockedUntil})
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: service.ErrLoginLocked.Error(),
+		}, {
+			name:      "Next attempt is too early",
+			email:     "emelyanov86@km.ru",
+			password:  "GoodPasswordHele",
+			mockUser:  func(r *mock_repository.MockUsers) {},
+			mockToken: func(r *mock_repository.MockTokens) {},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lastFailedAt := time.Now().Add(-time.Second)
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{FailedAttempts: 3, LastFailedAt: &lastFailedAt})
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: "next attempt is allowed in 3s",
+		}, {
+			name:      "Attempts in progress take all attempts of email",
+			email:     "emelyanov86@km.ru",
+			password:  "GoodPasswordHele",
+			mockUser:  func(r *mock_repository.MockUsers) {},
+			mockToken: func(r *mock_repository.MockTokens) {},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lastFailedAt := time.Now().Add(-2 * time.Minute)
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{FailedAttempts: 5, LastFailedAt: &lastFailedAt})
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: "previous attempts are not finished",
+		}, {
+			name:      "Ip address is throttled after free attempts",
+			email:     "emelyanov86@km.ru",
+			password:  "GoodPasswordHele",
+			mockUser:  func(r *mock_repository.MockUsers) {},
+			mockToken: func(r *mock_repository.MockTokens) {},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lastFailedAt := time.Now()
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{})
+				expectReserve(r, "ip:192.0.2.1", domain.LoginAttempts{FailedAttempts: 6, LastFailedAt: &lastFailedAt})
+				r.EXPECT().Release(context.Background(), "email:emelyanov86@km.ru", nil).Return(nil)
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: service.ErrLoginThrottled.Error(),
+		}, {
+			name:     "Last failed attempt locks email",
+			email:    "emelyanov86@km.ru",
+			password: "PasswordWrong",
+			mockUser: func(r *mock_repository.MockUsers) {
+				var pass domain.Password
+				pass.Set("GoodPasswordHele")
+				mockedUserModel.Password = pass
+				r.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(mockedUserModel, nil)
+				r.EXPECT().GetByEmail(gomock.Any(), "emelyanov86@km.ru").Return(mockedUserModel, nil)
+			},
+			mockToken: func(r *mock_repository.MockTokens) {
+				r.EXPECT().DeleteAllForUser(gomock.Any(), domain.ScopeLoginUnlock, int64(1)).Return(nil)
+				r.EXPECT().New(gomock.Any(), int64(1), 24*time.Hour, domain.ScopeLoginUnlock).Return(&domain.Token{Plaintext: "UNLOCK_TEXT"}, nil)
+			},
+			mockAttempts: func(r *mock_repository.MockLoginAttempts) {
+				lastFailedAt := time.Now().Add(-10 * time.Minute)
+				expectReserve(r, "email:emelyanov86@km.ru", domain.LoginAttempts{FailedAttempts: 4, LastFailedAt: &lastFailedAt})
+				expectReserve(r, "ip:192.0.2.1", domain.LoginAttempts{FailedAttempts: 4, LastFailedAt: &lastFailedAt})
+				r.EXPECT().Lock(context.Background(), "email:emelyanov86@km.ru", gomock.Any()).Return(nil)
+			},
+			statusCode:   http.StatusTooManyRequests,
+			responseBody: service.ErrLoginLocked.Error(),
+			audit:        []string{service.AuditFail, service.AuditLock},
 		},
 	}
 
@@ -245,13 +380,28 @@ func TestHandler_Login(t *testing.T) {
 			rt := mock_repository.NewMockTokens(c)
 
 			ru := mock_repository.NewMockUsers(c)
+			ra := mock_repository.NewMockLoginAttempts(c)
 			tt.mockUser(ru)
 			tt.mockToken(rt)
+			tt.mockAttempts(ra)
+			raudit := mock_repository.NewMockAudit(c)
+			var auditMu sync.Mutex
+			var audited []string
+			raudit.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, entry *domain.AuditEntry) error {
+				assert.Equal(t, service.AuditModuleLogin, entry.Module)
+				assert.Equal(t, "11x1", entry.AccountId)
+				assert.Equal(t, "192.0.2.1", entry.Ip)
+				auditMu.Lock()
+				audited = append(audited, entry.Action)
+				auditMu.Unlock()
+				return nil
+			}).AnyTimes()
 
+			var wg sync.WaitGroup
 			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
-			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService)
+			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
 
-			services := &service.Services{Tokens: tokensService}
+			services := &service.Services{Tokens: tokensService, LoginGuard: service.NewLoginGuard(ra, tokensService, service.NewAuditService(raudit, &wg), &wg, config.Config{})}
 			handler := Handler{services: services}
 
 			// Init Endpoint
@@ -270,6 +420,7 @@ func TestHandler_Login(t *testing.T) {
 
 			// Make Request
 			r.ServeHTTP(w, req)
+			wg.Wait()
 
 			// Assert
 			assert.Equal(t, tt.statusCode, w.Code)
@@ -278,6 +429,224 @@ func TestHandler_Login(t *testing.T) {
 	}
 }
 
+func TestHandler_refreshToken(t *testing.T) {
+	type mockRepositoryToken func(r *mock_repository.MockTokens, u *mock_repository.MockUsers)
+	const refresh = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	usedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name         string
+		body         string
+		mockToken    mockRepositoryToken
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Token rotated",
+			body: `{"refresh_token":"` + refresh + `"}`,
+			mockToken: func(r *mock_repository.MockTokens, u *mock_repository.MockUsers) {
+				r.EXPECT().GetByPlaintext(context.Background(), domain.ScopeRefresh, refresh).Return(domain.Token{ID: 5, UserId: 1, Family: "family", Expiry: time.Now().Add(time.Hour)}, nil)
+				r.EXPECT().MarkUsed(context.Background(), int64(5)).Return(true, nil)
+				u.EXPECT().GetById(context.Background(), int64(1)).Return(repository.MockedUser, nil)
+				r.EXPECT().DeleteExpiredForUser(context.Background(), int64(1)).Return(nil)
+				r.EXPECT().NewInFamily(context.Background(), int64(1), 15*time.Minute, domain.ScopeAuthentication, "family").Return(&domain.Token{ID: 6, Plaintext: "NEW_ACCESS", UserId: 1}, nil)
+				r.EXPECT().NewInFamily(context.Background(), int64(1), 30*24*time.Hour, domain.ScopeRefresh, "family").Return(&domain.Token{ID: 7, Plaintext: "NEW_REFRESH", UserId: 1}, nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"token":"NEW_ACCESS","expiry":"0001-01-01T00:00:00Z","otp_enabled":false,"webauthn_enabled":false,"refresh_token":"NEW_REFRESH"`,
+		}, {
+			name: "Reused token revokes family",
+			body: `{"refresh_token":"` + refresh + `"}`,
+			mockToken: func(r *mock_repository.MockTokens, u *mock_repository.MockUsers) {
+				r.EXPECT().GetByPlaintext(context.Background(), domain.ScopeRefresh, refresh).Return(domain.Token{ID: 5, UserId: 1, Family: "family", Expiry: time.Now().Add(time.Hour), UsedAt: &usedAt}, nil)
+				r.EXPECT().DeleteFamily(context.Background(), "family").Return(nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrRefreshTokenReused.Error(),
+		}, {
+			name: "Concurrent use revokes family",
+			body: `{"refresh_token":"` + refresh + `"}`,
+			mockToken: func(r *mock_repository.MockTokens, u *mock_repository.MockUsers) {
+				r.EXPECT().GetByPlaintext(context.Background(), domain.ScopeRefresh, refresh).Return(domain.Token{ID: 5, UserId: 1, Family: "family", Expiry: time.Now().Add(time.Hour)}, nil)
+				r.EXPECT().MarkUsed(context.Background(), int64(5)).Return(false, nil)
+				r.EXPECT().DeleteFamily(context.Background(), "family").Return(nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrRefreshTokenReused.Error(),
+		}, {
+			name: "Expired token",
+			body: `{"refresh_token":"` + refresh + `"}`,
+			mockToken: func(r *mock_repository.MockTokens, u *mock_repository.MockUsers) {
+				r.EXPECT().GetByPlaintext(context.Background(), domain.ScopeRefresh, refresh).Return(domain.Token{ID: 5, UserId: 1, Family: "family", Expiry: time.Now().Add(-time.Hour)}, nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidRefreshToken.Error(),
+		}, {
+			name: "Unknown token",
+			body: `{"refresh_token":"` + refresh + `"}`,
+			mockToken: func(r *mock_repository.MockTokens, u *mock_repository.MockUsers) {
+				r.EXPECT().GetByPlaintext(context.Background(), domain.ScopeRefresh, refresh).Return(domain.Token{}, repository.ErrRecordNotFound)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidRefreshToken.Error(),
+		}, {
+			name:         "Missing token",
+			body:         `{}`,
+			mockToken:    func(r *mock_repository.MockTokens, u *mock_repository.MockUsers) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"refresh_token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rt := mock_repository.NewMockTokens(c)
+			ru := mock_repository.NewMockUsers(c)
+			tt.mockToken(rt, ru)
+
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
+
+			services := &service.Services{Tokens: tokensService}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/users/refresh", handler.refreshToken)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/refresh", bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_signOut(t *testing.T) {
+	const access = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	rt := mock_repository.NewMockTokens(c)
+	rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeAuthentication, access).Return(domain.Token{ID: 3, UserId: 1, Family: "family"}, nil)
+	rt.EXPECT().DeleteFamily(context.Background(), "family").Return(nil)
+
+	companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+	tokensService := service.NewTokensService(rt, mock_repository.NewMockUsers(c), service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
+	services := &service.Services{Tokens: tokensService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+	handler := Handler{services: services}
+
+	r := gin.New()
+	r.POST("/api/v1/users/logout", handler.signOut)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/users/logout", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestHandler_getSessions(t *testing.T) {
+	const access = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	rt := mock_repository.NewMockTokens(c)
+	rt.EXPECT().GetSessions(context.Background(), int64(1)).Return([]domain.Session{
+		{Id: "current", UserAgent: "Firefox", Ip: "10.0.0.1"},
+		{Id: "other", UserAgent: "Safari", Ip: "10.0.0.2"},
+	}, nil)
+	rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeAuthentication, access).Return(domain.Token{ID: 3, UserId: 1, Family: "current"}, nil)
+
+	companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+	tokensService := service.NewTokensService(rt, mock_repository.NewMockUsers(c), service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
+	services := &service.Services{Tokens: tokensService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+	handler := Handler{services: services}
+
+	r := gin.New()
+	r.GET("/api/v1/users/sessions", handler.getSessions)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/v1/users/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+access)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"current","created_at":"0001-01-01T00:00:00Z","last_used_at":null,"user_agent":"Firefox","ip":"10.0.0.1","expiry":"0001-01-01T00:00:00Z","current":true`)
+	assert.Contains(t, w.Body.String(), `"ip":"10.0.0.2","expiry":"0001-01-01T00:00:00Z","current":false`)
+}
+
+func TestHandler_deleteSession(t *testing.T) {
+	type mockRepositoryToken func(r *mock_repository.MockTokens)
+	const access = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	tests := []struct {
+		name       string
+		target     string
+		mockToken  mockRepositoryToken
+		statusCode int
+	}{
+		{
+			name:   "Session deleted",
+			target: "/api/v1/users/sessions/other",
+			mockToken: func(r *mock_repository.MockTokens) {
+				r.EXPECT().DeleteSession(context.Background(), int64(1), "other").Return(nil)
+			},
+			statusCode: http.StatusNoContent,
+		}, {
+			name:   "Session of other user",
+			target: "/api/v1/users/sessions/foreign",
+			mockToken: func(r *mock_repository.MockTokens) {
+				r.EXPECT().DeleteSession(context.Background(), int64(1), "foreign").Return(repository.ErrRecordNotFound)
+			},
+			statusCode: http.StatusNotFound,
+		}, {
+			name:   "Sign out everywhere else",
+			target: "/api/v1/users/sessions",
+			mockToken: func(r *mock_repository.MockTokens) {
+				r.EXPECT().GetByPlaintext(context.Background(), domain.ScopeAuthentication, access).Return(domain.Token{ID: 3, UserId: 1, Family: "current"}, nil)
+				r.EXPECT().DeleteOtherSessions(context.Background(), int64(1), "current").Return(nil)
+			},
+			statusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rt := mock_repository.NewMockTokens(c)
+			tt.mockToken(rt)
+
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			tokensService := service.NewTokensService(rt, mock_repository.NewMockUsers(c), service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
+			services := &service.Services{Tokens: tokensService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.DELETE("/api/v1/users/sessions", handler.deleteOtherSessions)
+			r.DELETE("/api/v1/users/sessions/:id", handler.deleteSession)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", tt.target, nil)
+			req.Header.Set("Authorization", "Bearer "+access)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+		})
+	}
+}
+
 func TestHandler_userInfo(t *testing.T) {
 	type mockRepositoryAccount func(r *mock_repository.MockAccount)
 
@@ -410,7 +779,7 @@ func TestHandler_restorePassword(t *testing.T) {
 			tt.mockToken(rt)
 
 			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
-			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService)
+			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
 
 			services := &service.Services{Tokens: tokensService}
 			handler := Handler{services: services}
@@ -438,6 +807,24 @@ func TestHandler_restorePassword(t *testing.T) {
 	}
 }
 
+// passwordAudit keeps password changes, written to audit log.
+type passwordAudit struct {
+	repo      *mock_repository.MockAudit
+	passwords []domain.AuditChange
+}
+
+func expectPasswordAudit(t *testing.T, c *gomock.Controller) *passwordAudit {
+	audited := &passwordAudit{repo: mock_repository.NewMockAudit(c)}
+	audited.repo.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, entry *domain.AuditEntry) error {
+		assert.Equal(t, service.AuditUpdate, entry.Action)
+		assert.Equal(t, domain.ModuleContacts, entry.Module)
+		assert.Equal(t, entry.UserCrmid, entry.EntityId)
+		audited.passwords = append(audited.passwords, entry.Changes["password"])
+		return nil
+	}).AnyTimes()
+	return audited
+}
+
 func TestHandler_resetPassword(t *testing.T) {
 	type mockRepositoryToken func(r *mock_repository.MockTokens)
 
@@ -448,6 +835,7 @@ func TestHandler_resetPassword(t *testing.T) {
 		mockToken    mockRepositoryToken
 		statusCode   int
 		responseBody string
+		audit        []domain.AuditChange
 	}{
 		{
 			name:     "Password updated",
@@ -458,6 +846,7 @@ func TestHandler_resetPassword(t *testing.T) {
 			},
 			statusCode:   http.StatusAccepted,
 			responseBody: `"email":"emelyanov86@km.ru"`,
+			audit:        []domain.AuditChange{{New: "***"}},
 		}, {
 			name:     "Wrong Token",
 			token:    "",
@@ -480,12 +869,13 @@ func TestHandler_resetPassword(t *testing.T) {
 			rt := mock_repository.NewMockTokens(c)
 
 			tt.mockToken(rt)
+			audited := expectPasswordAudit(t, c)
 
 			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
 			rc := repository.NewUsersCrmMock(repository.MockedUser)
 			usersService := service.NewUsersService(repository.NewUsersMock(), rc, &wg, service.NewMockEmailService(), companyService, rt, mock_repository.NewMockDocument(c), cache.NewMemoryCache(), service.AccountService{}, config.Config{})
 
-			services := &service.Services{Users: usersService}
+			services := &service.Services{Users: usersService, Audit: service.NewAuditService(audited.repo, &wg)}
 			handler := Handler{services: services}
 
 			// Init Endpoint
@@ -503,10 +893,12 @@ func TestHandler_resetPassword(t *testing.T) {
 
 			// Make Request
 			r.ServeHTTP(w, req)
+			wg.Wait()
 
 			// Assert
 			assert.Equal(t, tt.statusCode, w.Code)
 			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+			assert.Equal(t, tt.audit, audited.passwords)
 		})
 	}
 }
@@ -569,3 +961,773 @@ func TestHandler_allUsers(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_changeUserRole(t *testing.T) {
+	type mockUsers func(r *mock_repository.MockUsers)
+
+	admin := repository.MockedUser
+	admin.Role = domain.RoleAdmin
+	member := repository.MockedUser
+	member.Role = domain.RoleMember
+	accountUsers := []domain.User{
+		repository.MockedUser,
+		{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleMember},
+		{Id: 3, Crmid: "12x13", AccountId: "11x1", Role: domain.RoleOwner},
+	}
+
+	tests := []struct {
+		name         string
+		id           string
+		body         string
+		userModel    *domain.User
+		mockUsers    mockUsers
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:      "Owner makes member an admin",
+			id:        "12x12",
+			body:      `{"role":"admin"}`,
+			userModel: &repository.MockedUser,
+			mockUsers: func(r *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByAccountId(gomock.Any(), "11x1").Return(accountUsers, nil)
+				r.EXPECT().UpdateRole(gomock.Any(), int64(2), domain.RoleAdmin).Return(nil)
+			},
+			statusCode:   http.StatusAccepted,
+			responseBody: `"role":"admin","permissions":{`,
+		},
+		{
+			name:      "Admin can not grant owner role",
+			id:        "12x12",
+			body:      `{"role":"owner"}`,
+			userModel: &admin,
+			mockUsers: func(r *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByAccountId(gomock.Any(), "11x1").Return(accountUsers, nil)
+			},
+			statusCode:   http.StatusForbidden,
+			responseBody: `only owner can change owner role`,
+		},
+		{
+			name:      "Admin can not demote owner",
+			id:        "12x13",
+			body:      `{"role":"member"}`,
+			userModel: &admin,
+			mockUsers: func(r *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByAccountId(gomock.Any(), "11x1").Return(accountUsers, nil)
+			},
+			statusCode:   http.StatusForbidden,
+			responseBody: `only owner can change owner role`,
+		},
+		{
+			name:         "Member can not manage roles",
+			id:           "12x12",
+			body:         `{"role":"admin"}`,
+			userModel:    &member,
+			mockUsers:    func(r *mock_repository.MockUsers) {},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name:         "Own role can not be changed",
+			id:           "12x11",
+			body:         `{"role":"member"}`,
+			userModel:    &repository.MockedUser,
+			mockUsers:    func(r *mock_repository.MockUsers) {},
+			statusCode:   http.StatusForbidden,
+			responseBody: `you can not change own role`,
+		},
+		{
+			name:         "Unknown role",
+			id:           "12x12",
+			body:         `{"role":"superuser"}`,
+			userModel:    &repository.MockedUser,
+			mockUsers:    func(r *mock_repository.MockUsers) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: service.ErrWrongRole.Error(),
+		},
+		{
+			name:      "Contact from other account",
+			id:        "12x99",
+			body:      `{"role":"admin"}`,
+			userModel: &repository.MockedUser,
+			mockUsers: func(r *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByAccountId(gomock.Any(), "11x1").Return(accountUsers, nil)
+			},
+			statusCode:   http.StatusNotFound,
+			responseBody: `"error":"Not Found"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			tt.mockUsers(ru)
+
+			usersService := service.NewUsersService(ru, repository.NewUsersCrmMock(repository.MockedUser), &wg, service.NewMockEmailService(), service.Company{}, mock_repository.NewMockTokens(c), mock_repository.NewMockDocument(c), cache.NewMemoryCache(), service.AccountService{}, config.Config{})
+
+			services := &service.Services{Users: usersService, Context: service.MockedContextService{MockedUser: tt.userModel}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.PUT("/api/v1/users/:id/role", handler.changeUserRole)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("PUT", "/api/v1/users/"+tt.id+"/role", strings.NewReader(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
+
+type magicLinkEmails struct {
+	service.MockEmailService
+	sent []service.MagicLinkData
+}
+
+func (s *magicLinkEmails) SendMagicLink(input service.MagicLinkData) error {
+	s.sent = append(s.sent, input)
+	return nil
+}
+
+func TestHandler_sendMagicLink(t *testing.T) {
+	type mockBehaviour func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens)
+	inactiveUser := repository.MockedUser
+	inactiveUser.IsActive = false
+
+	tests := []struct {
+		name         string
+		body         string
+		mock         mockBehaviour
+		statusCode   int
+		responseBody string
+		emailsSent   int
+	}{
+		{
+			name: "Link is sent",
+			body: `{"email":"emelyanov86@km.ru"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(repository.MockedUser, nil)
+				rt.EXPECT().DeleteAllForUser(context.Background(), domain.ScopeMagicLink, int64(1)).Return(nil)
+				rt.EXPECT().New(context.Background(), int64(1), 15*time.Minute, domain.ScopeMagicLink).Return(&domain.Token{ID: 4, Plaintext: "MAGICLINKTOKENPLAINTEXT123", UserId: 1}, nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"success":true`,
+			emailsSent:   1,
+		},
+		{
+			name: "Unknown email gets the same answer",
+			body: `{"email":"unknown@km.ru"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), "unknown@km.ru").Return(domain.User{}, repository.ErrRecordNotFound)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"success":true`,
+		},
+		{
+			name: "Disabled user does not get link",
+			body: `{"email":"emelyanov86@km.ru"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), "emelyanov86@km.ru").Return(inactiveUser, nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"success":true`,
+		},
+		{
+			name:         "Wrong email",
+			body:         `{"email":"wrong"}`,
+			mock:         func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"email"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			rt := mock_repository.NewMockTokens(c)
+			tt.mock(ru, rt)
+
+			emails := &magicLinkEmails{}
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			tokensService := service.NewTokensService(rt, ru, emails, config.Config{}, companyService, cache.NewMemoryCache())
+			handler := Handler{services: &service.Services{Tokens: tokensService}}
+
+			r := gin.New()
+			r.POST("/api/v1/users/magic-link", handler.sendMagicLink)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/magic-link", bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+			assert.Len(t, emails.sent, tt.emailsSent)
+			if tt.emailsSent > 0 {
+				assert.Equal(t, "MAGICLINKTOKENPLAINTEXT123", emails.sent[0].Token)
+				assert.Equal(t, "emelyanov86@km.ru", emails.sent[0].Email)
+			}
+		})
+	}
+}
+
+func TestHandler_verifyMagicLink(t *testing.T) {
+	type mockBehaviour func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens)
+	const plaintext = "MAGICLINKTOKENPLAINTEXT123"
+	usedAt := time.Now().Add(-time.Minute)
+	link := domain.Token{ID: 4, UserId: 1, Scope: domain.ScopeMagicLink, Expiry: time.Now().Add(10 * time.Minute)}
+	otpUser := repository.MockedUser
+	otpUser.Otp_enabled = true
+
+	expectPair := func(rt *mock_repository.MockTokens) {
+		rt.EXPECT().DeleteExpiredForUser(context.Background(), int64(1)).Return(nil)
+		rt.EXPECT().NewInFamily(context.Background(), int64(1), 15*time.Minute, domain.ScopeAuthentication, gomock.Any()).Return(&domain.Token{ID: 5, Plaintext: "ACCESS_TOKEN", UserId: 1}, nil)
+		rt.EXPECT().NewInFamily(context.Background(), int64(1), 30*24*time.Hour, domain.ScopeRefresh, gomock.Any()).Return(&domain.Token{ID: 6, Plaintext: "REFRESH_TOKEN", UserId: 1}, nil)
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		mock         mockBehaviour
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Link signs user in",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeMagicLink, plaintext).Return(link, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(4)).Return(true, nil)
+				ru.EXPECT().GetById(context.Background(), int64(1)).Return(repository.MockedUser, nil)
+				expectPair(rt)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"token":"ACCESS_TOKEN","expiry":"0001-01-01T00:00:00Z","otp_enabled":false`,
+		},
+		{
+			name: "Otp is still required",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeMagicLink, plaintext).Return(link, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(4)).Return(true, nil)
+				ru.EXPECT().GetById(context.Background(), int64(1)).Return(otpUser, nil)
+				expectPair(rt)
+				ru.EXPECT().VerifyOrInvalidateOtp(context.Background(), int64(1), false).Return(nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"otp_enabled":true`,
+		},
+		{
+			name: "Used link",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				used := link
+				used.UsedAt = &usedAt
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeMagicLink, plaintext).Return(used, nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidMagicLink.Error(),
+		},
+		{
+			name: "Link used by parallel request",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeMagicLink, plaintext).Return(link, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(4)).Return(false, nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidMagicLink.Error(),
+		},
+		{
+			name: "Expired link",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				expired := link
+				expired.Expiry = time.Now().Add(-time.Second)
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeMagicLink, plaintext).Return(expired, nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidMagicLink.Error(),
+		},
+		{
+			name: "Unknown link",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeMagicLink, plaintext).Return(domain.Token{}, repository.ErrRecordNotFound)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidMagicLink.Error(),
+		},
+		{
+			name:         "Wrong token",
+			body:         `{"token":"short"}`,
+			mock:         func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			rt := mock_repository.NewMockTokens(c)
+			tt.mock(ru, rt)
+
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
+			handler := Handler{services: &service.Services{Tokens: tokensService}}
+
+			r := gin.New()
+			r.POST("/api/v1/users/magic-link/verify", handler.verifyMagicLink)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/magic-link/verify", bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+		})
+	}
+}
+
+type invitationEmails struct {
+	service.MockEmailService
+	sent []service.InvitationData
+}
+
+func (s *invitationEmails) SendInvitation(input service.InvitationData) error {
+	s.sent = append(s.sent, input)
+	return nil
+}
+
+func TestHandler_inviteUser(t *testing.T) {
+	type mockBehaviour func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens)
+	const email = "colleague@km.ru"
+	invitation := &domain.Token{ID: 9, Plaintext: "INVITATIONTOKENPLAINTEXT12", UserId: 7, Expiry: time.Now().Add(time.Hour)}
+	contact := domain.User{Crmid: "12x50", FirstName: "Ivan", LastName: "Petrov", Email: email, AccountId: "11x1"}
+	admin := repository.MockedUser
+	admin.Role = domain.RoleAdmin
+	member := repository.MockedUser
+	member.Role = domain.RoleMember
+	registered := contact
+	registered.Id = 7
+	registered.IsActive = true
+
+	expectInvitation := func(rt *mock_repository.MockTokens) {
+		rt.EXPECT().DeleteAllForUser(context.Background(), domain.ScopeInvitation, int64(7)).Return(nil)
+		rt.EXPECT().New(context.Background(), int64(7), 7*24*time.Hour, domain.ScopeInvitation).Return(invitation, nil)
+	}
+
+	tests := []struct {
+		name         string
+		inviter      domain.User
+		body         string
+		mock         mockBehaviour
+		statusCode   int
+		responseBody string
+		emailsSent   int
+	}{
+		{
+			name:    "Contact is created in crm",
+			inviter: repository.MockedUser,
+			body:    `{"email":"` + email + `","firstname":"Ivan","lastname":"Petrov"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(domain.User{}, repository.ErrRecordNotFound)
+				rc.EXPECT().FindByEmail(context.Background(), email).Return([]domain.User{}, nil)
+				rc.EXPECT().Create(context.Background(), domain.User{FirstName: "Ivan", LastName: "Petrov", Email: email, AccountId: "11x1", AssignedUserId: "19x1"}).Return(contact, nil)
+				ru.EXPECT().Insert(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *domain.User) error {
+					assert.Equal(t, "12x50", user.Crmid)
+					assert.False(t, user.IsActive)
+					assert.Equal(t, domain.RoleMember, user.Role)
+					assert.NotEmpty(t, user.Password.Hash)
+					user.Id = 7
+					return nil
+				})
+				expectInvitation(rt)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"crmid":"12x50"`,
+			emailsSent:   1,
+		},
+		{
+			name:    "Existing contact of account is invited with role",
+			inviter: admin,
+			body:    `{"email":"` + email + `","lastname":"Petrov","role":"billing"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(domain.User{}, repository.ErrRecordNotFound)
+				rc.EXPECT().FindByEmail(context.Background(), email).Return([]domain.User{contact}, nil)
+				ru.EXPECT().Insert(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *domain.User) error {
+					assert.Equal(t, domain.RoleBilling, user.Role)
+					user.Id = 7
+					return nil
+				})
+				expectInvitation(rt)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"role":"billing"`,
+			emailsSent:   1,
+		},
+		{
+			name:    "Pending invitation is sent again",
+			inviter: repository.MockedUser,
+			body:    `{"email":"` + email + `","lastname":"Petrov"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+				pending := registered
+				pending.IsActive = false
+				pending.Role = domain.RoleMember
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(pending, nil)
+				expectInvitation(rt)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"is_active":false`,
+			emailsSent:   1,
+		},
+		{
+			name:    "Registered user",
+			inviter: repository.MockedUser,
+			body:    `{"email":"` + email + `","lastname":"Petrov"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(registered, nil)
+			},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: "already registered",
+		},
+		{
+			name:    "Contact of other account",
+			inviter: repository.MockedUser,
+			body:    `{"email":"` + email + `","lastname":"Petrov"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+				other := contact
+				other.AccountId = "11x5"
+				ru.EXPECT().GetByEmail(context.Background(), email).Return(domain.User{}, repository.ErrRecordNotFound)
+				rc.EXPECT().FindByEmail(context.Background(), email).Return([]domain.User{other}, nil)
+			},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: "already registered",
+		},
+		{
+			name:    "Member can not invite",
+			inviter: member,
+			body:    `{"email":"` + email + `","lastname":"Petrov"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+			},
+			statusCode:   http.StatusForbidden,
+			responseBody: service.ErrOperationNotPermitted.Error(),
+		},
+		{
+			name:    "Admin can not invite owner",
+			inviter: admin,
+			body:    `{"email":"` + email + `","lastname":"Petrov","role":"owner"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+			},
+			statusCode:   http.StatusForbidden,
+			responseBody: "only owner can invite owners",
+		},
+		{
+			name:    "Wrong role",
+			inviter: repository.MockedUser,
+			body:    `{"email":"` + email + `","lastname":"Petrov","role":"boss"}`,
+			mock: func(ru *mock_repository.MockUsers, rc *mock_repository.MockUsersCrm, rt *mock_repository.MockTokens) {
+			},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: service.ErrWrongRole.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			rc := mock_repository.NewMockUsersCrm(c)
+			rt := mock_repository.NewMockTokens(c)
+			tt.mock(ru, rc, rt)
+
+			emails := &invitationEmails{}
+			cfg := config.Config{Vtiger: config.VtigerConfig{Business: config.VtigerBusinessConfig{DefaultUser: "19x1"}}}
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			usersService := service.NewUsersService(ru, rc, &wg, emails, companyService, rt, mock_repository.NewMockDocument(c), cache.NewMemoryCache(), service.AccountService{}, cfg)
+			inviter := tt.inviter
+			handler := Handler{services: &service.Services{Users: usersService, Context: service.MockedContextService{MockedUser: &inviter}}}
+
+			r := gin.New()
+			r.POST("/api/v1/users/invite", handler.inviteUser)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/invite", bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+			assert.Len(t, emails.sent, tt.emailsSent)
+			if tt.emailsSent > 0 {
+				assert.Equal(t, invitation.Plaintext, emails.sent[0].Token)
+				assert.Equal(t, email, emails.sent[0].Email)
+				assert.Equal(t, "Sergey Emelyanov", emails.sent[0].InvitedBy)
+			}
+		})
+	}
+}
+
+func TestHandler_acceptInvite(t *testing.T) {
+	type mockBehaviour func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens)
+	const plaintext = "INVITATIONTOKENPLAINTEXT12"
+	invitation := domain.Token{ID: 9, UserId: 7, Scope: domain.ScopeInvitation, Expiry: time.Now().Add(time.Hour)}
+	invited := domain.User{Id: 7, Crmid: "12x50", Email: "colleague@km.ru", AccountId: "11x1", Role: domain.RoleMember}
+
+	tests := []struct {
+		name         string
+		body         string
+		mock         mockBehaviour
+		statusCode   int
+		responseBody string
+		audit        []domain.AuditChange
+	}{
+		{
+			name: "Invitation is accepted",
+			body: `{"token":"` + plaintext + `","password":"NewPassword"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeInvitation, plaintext).Return(invitation, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(9)).Return(true, nil)
+				ru.EXPECT().GetById(context.Background(), int64(7)).Return(invited, nil)
+				ru.EXPECT().Update(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, user *domain.User) error {
+					assert.True(t, user.IsActive)
+					assert.True(t, user.Password.Matches("NewPassword"))
+					return nil
+				})
+				rt.EXPECT().DeleteAllForUser(context.Background(), domain.ScopeInvitation, int64(7)).Return(nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: `"is_active":true`,
+			audit:        []domain.AuditChange{{New: "***"}},
+		},
+		{
+			name: "Expired invitation",
+			body: `{"token":"` + plaintext + `","password":"NewPassword"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				expired := invitation
+				expired.Expiry = time.Now().Add(-time.Second)
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeInvitation, plaintext).Return(expired, nil)
+			},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: service.ErrInvalidInvitation.Error(),
+		},
+		{
+			name: "Accepted invitation",
+			body: `{"token":"` + plaintext + `","password":"NewPassword"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeInvitation, plaintext).Return(invitation, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(9)).Return(false, nil)
+			},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: service.ErrInvalidInvitation.Error(),
+		},
+		{
+			name:         "Short password",
+			body:         `{"token":"` + plaintext + `","password":"123"}`,
+			mock:         func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"password"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			rt := mock_repository.NewMockTokens(c)
+			tt.mock(ru, rt)
+			audited := expectPasswordAudit(t, c)
+
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			usersService := service.NewUsersService(ru, mock_repository.NewMockUsersCrm(c), &wg, service.NewMockEmailService(), companyService, rt, mock_repository.NewMockDocument(c), cache.NewMemoryCache(), service.AccountService{}, config.Config{})
+			handler := Handler{services: &service.Services{Users: usersService, Audit: service.NewAuditService(audited.repo, &wg)}}
+
+			r := gin.New()
+			r.POST("/api/v1/users/accept-invite", handler.acceptInvite)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/accept-invite", bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+			wg.Wait()
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+			assert.Equal(t, tt.audit, audited.passwords)
+		})
+	}
+}
+
+func TestHandler_unlockLogin(t *testing.T) {
+	type mockBehaviour func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens, ra *mock_repository.MockLoginAttempts)
+	const plaintext = "UNLOCKTOKENPLAINTEXT123456"
+	unlock := domain.Token{ID: 4, UserId: 1, Scope: domain.ScopeLoginUnlock, Expiry: time.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name         string
+		body         string
+		mock         mockBehaviour
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Email is unlocked",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens, ra *mock_repository.MockLoginAttempts) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeLoginUnlock, plaintext).Return(unlock, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(4)).Return(true, nil)
+				ru.EXPECT().GetById(context.Background(), int64(1)).Return(repository.MockedUser, nil)
+				ra.EXPECT().Reset(context.Background(), "email:emelyanov86@km.ru").Return(nil)
+				rt.EXPECT().DeleteAllForUser(context.Background(), domain.ScopeLoginUnlock, int64(1)).Return(nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: `"success":true`,
+		},
+		{
+			name: "Unknown token",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens, ra *mock_repository.MockLoginAttempts) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeLoginUnlock, plaintext).Return(domain.Token{}, repository.ErrRecordNotFound)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidUnlockToken.Error(),
+		},
+		{
+			name: "Used token",
+			body: `{"token":"` + plaintext + `"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens, ra *mock_repository.MockLoginAttempts) {
+				rt.EXPECT().GetByPlaintext(context.Background(), domain.ScopeLoginUnlock, plaintext).Return(unlock, nil)
+				rt.EXPECT().MarkUsed(context.Background(), int64(4)).Return(false, nil)
+			},
+			statusCode:   http.StatusUnauthorized,
+			responseBody: service.ErrInvalidUnlockToken.Error(),
+		},
+		{
+			name: "Wrong token length",
+			body: `{"token":"SHORT"}`,
+			mock: func(ru *mock_repository.MockUsers, rt *mock_repository.MockTokens, ra *mock_repository.MockLoginAttempts) {
+			},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `"field":"token"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			ru := mock_repository.NewMockUsers(c)
+			rt := mock_repository.NewMockTokens(c)
+			ra := mock_repository.NewMockLoginAttempts(c)
+			tt.mock(ru, rt, ra)
+
+			companyService := service.NewCompanyService(repository.NewCompanyMock(), cache.NewMemoryCache())
+			tokensService := service.NewTokensService(rt, ru, service.NewMockEmailService(), config.Config{}, companyService, cache.NewMemoryCache())
+			handler := Handler{services: &service.Services{LoginGuard: service.NewLoginGuard(ra, tokensService, service.Audit{}, &wg, config.Config{})}}
+
+			r := gin.New()
+			r.POST("/api/v1/users/unlock", handler.unlockLogin)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/users/unlock", bytes.NewBufferString(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_deleteUserFile(t *testing.T) {
+	type mockDocument func(r *mock_repository.MockDocument)
+
+	tests := []struct {
+		name         string
+		mockDocument mockDocument
+		userModel    *domain.User
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Document is deleted",
+			mockDocument: func(r *mock_repository.MockDocument) {
+				r.EXPECT().RetrieveFromModule(gomock.Any(), "12x11").Return([]domain.Document{{Id: "15x1"}}, nil)
+				r.EXPECT().DeleteFile(gomock.Any(), "15x1").Return(nil)
+			},
+			userModel:  &repository.MockedUser,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name: "Document is not related to user",
+			mockDocument: func(r *mock_repository.MockDocument) {
+				r.EXPECT().RetrieveFromModule(gomock.Any(), "12x11").Return([]domain.Document{{Id: "15x2"}}, nil)
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name:         "Read only user can not delete document",
+			mockDocument: func(r *mock_repository.MockDocument) {},
+			userModel:    &domain.User{Id: 1, Crmid: "12x11", AccountId: "11x1", Role: domain.RoleReadOnly},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name:         "Anonymous Access",
+			mockDocument: func(r *mock_repository.MockDocument) {},
+			userModel:    domain.AnonymousUser,
+			statusCode:   http.StatusUnauthorized,
+			responseBody: `"error":"Anonymous Access",`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rd := mock_repository.NewMockDocument(c)
+			tt.mockDocument(rd)
+
+			documentService := service.NewDocuments(rd, cache.NewMemoryCache(), config.Config{}, nil)
+			services := &service.Services{Documents: documentService, Context: service.MockedContextService{MockedUser: tt.userModel}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.DELETE("/api/v1/users/:id/documents/:document", handler.deleteUserFile)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("DELETE", "/api/v1/users/12x11/documents/15x1", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang/mock/gomock"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// webauthnFixture is recorded from software authenticator with "none" attestation for user with id 1.
+type webauthnFixture struct {
+	RpId         string `json:"rp_id"`
+	Origin       string `json:"origin"`
+	Registration struct {
+		Challenge string          `json:"challenge"`
+		Response  json.RawMessage `json:"response"`
+	} `json:"registration"`
+	Login struct {
+		Challenge string          `json:"challenge"`
+		Response  json.RawMessage `json:"response"`
+	} `json:"login"`
+	Credential struct {
+		Id        []byte `json:"id"`
+		PublicKey []byte `json:"public_key"`
+	} `json:"credential"`
+}
+
+func loadWebauthnFixture(t *testing.T) webauthnFixture {
+	data, err := os.ReadFile("testdata/webauthn.json")
+	require.NoError(t, err)
+	var fixture webauthnFixture
+	require.NoError(t, json.Unmarshal(data, &fixture))
+	return fixture
+}
+
+func storeWebauthnSession(t *testing.T, c cache.Cache, ceremony string, challenge string) {
+	session := webauthn.SessionData{Challenge: challenge, UserID: []byte("1"), Expires: time.Now().Add(time.Minute)}
+	data, err := json.Marshal(session)
+	require.NoError(t, err)
+	require.NoError(t, c.Set(service.WebauthnSessionKey(ceremony, 1), data, 60))
+}
+
+func TestHandler_finishWebauthnRegistration(t *testing.T) {
+	type mockRepository func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers)
+	fixture := loadWebauthnFixture(t)
+
+	tests := []struct {
+		name         string
+		challenge    string
+		origin       string
+		mock         mockRepository
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:      "Passkey registered",
+			challenge: fixture.Registration.Challenge,
+			origin:    fixture.Origin,
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{}, nil)
+				r.EXPECT().Insert(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, credential *domain.WebauthnCredential) error {
+					assert.Equal(t, fixture.Credential.Id, credential.CredentialId)
+					assert.Equal(t, fixture.Credential.PublicKey, credential.PublicKey)
+					assert.Equal(t, "none", credential.AttestationType)
+					credential.Id = 7
+					return nil
+				})
+				u.EXPECT().SetWebauthnEnabled(context.Background(), int64(1), true).Return(nil)
+				u.EXPECT().VerifyOrInvalidateOtp(context.Background(), int64(1), true).Return(nil)
+			},
+			statusCode:   http.StatusCreated,
+			responseBody: `"id":7,"name":"YubiKey","transports":["usb"]`,
+		},
+		{
+			name:      "Challenge does not match",
+			challenge: fixture.Login.Challenge,
+			origin:    fixture.Origin,
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{}, nil)
+			},
+			statusCode:   http.StatusBadRequest,
+			responseBody: service.ErrWebauthnFailed.Error(),
+		},
+		{
+			name:      "Origin is not allowed",
+			challenge: fixture.Registration.Challenge,
+			origin:    "https://evil.example.com",
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{}, nil)
+			},
+			statusCode:   http.StatusBadRequest,
+			responseBody: service.ErrWebauthnFailed.Error(),
+		},
+		{
+			name:         "Ceremony is not started",
+			mock:         func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {},
+			origin:       fixture.Origin,
+			statusCode:   http.StatusBadRequest,
+			responseBody: service.ErrWebauthnSessionNotFound.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rw := mock_repository.NewMockWebauthnCredentials(c)
+			ru := mock_repository.NewMockUsers(c)
+			tt.mock(rw, ru)
+
+			memoryCache := cache.NewMemoryCache()
+			if tt.challenge != "" {
+				storeWebauthnSession(t, memoryCache, service.WebauthnRegistration, tt.challenge)
+			}
+			cfg := config.Config{Webauthn: config.WebauthnConfig{RPID: fixture.RpId, RPDisplayName: "Portal", RPOrigins: []string{tt.origin}}}
+			webauthnService := service.NewWebauthnService(rw, ru, memoryCache, cfg)
+
+			services := &service.Services{Webauthn: webauthnService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/otp/webauthn/register/finish", handler.finishWebauthnRegistration)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/otp/webauthn/register/finish?name=YubiKey", bytes.NewReader(fixture.Registration.Response))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_finishWebauthnLogin(t *testing.T) {
+	type mockRepository func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers)
+	fixture := loadWebauthnFixture(t)
+	stored := domain.WebauthnCredential{Id: 7, UserId: 1, CredentialId: fixture.Credential.Id, PublicKey: fixture.Credential.PublicKey, AttestationType: "none"}
+	used := stored
+	used.SignCount = 5
+
+	tests := []struct {
+		name         string
+		challenge    string
+		mock         mockRepository
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name:      "Second factor passed",
+			challenge: fixture.Login.Challenge,
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{stored}, nil)
+				r.EXPECT().UpdateUsage(context.Background(), int64(7), uint32(1), false).Return(nil)
+				u.EXPECT().VerifyOrInvalidateOtp(context.Background(), int64(1), true).Return(nil)
+			},
+			statusCode:   http.StatusAccepted,
+			responseBody: `"otp_verified":true`,
+		},
+		{
+			name:      "Cloned authenticator",
+			challenge: fixture.Login.Challenge,
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{used}, nil)
+				r.EXPECT().UpdateUsage(context.Background(), int64(7), uint32(5), true).Return(nil)
+			},
+			statusCode:   http.StatusBadRequest,
+			responseBody: "may be cloned",
+		},
+		{
+			name:      "Unknown credential",
+			challenge: fixture.Login.Challenge,
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{}, nil)
+			},
+			statusCode:   http.StatusBadRequest,
+			responseBody: service.ErrWebauthnFailed.Error(),
+		},
+		{
+			name:      "Replayed registration challenge",
+			challenge: fixture.Registration.Challenge,
+			mock: func(r *mock_repository.MockWebauthnCredentials, u *mock_repository.MockUsers) {
+				r.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{stored}, nil)
+			},
+			statusCode:   http.StatusBadRequest,
+			responseBody: service.ErrWebauthnFailed.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rw := mock_repository.NewMockWebauthnCredentials(c)
+			ru := mock_repository.NewMockUsers(c)
+			tt.mock(rw, ru)
+
+			memoryCache := cache.NewMemoryCache()
+			storeWebauthnSession(t, memoryCache, service.WebauthnLogin, tt.challenge)
+			cfg := config.Config{Webauthn: config.WebauthnConfig{RPID: fixture.RpId, RPDisplayName: "Portal", RPOrigins: []string{fixture.Origin}}}
+			webauthnService := service.NewWebauthnService(rw, ru, memoryCache, cfg)
+
+			services := &service.Services{Webauthn: webauthnService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/otp/webauthn/login/finish", handler.finishWebauthnLogin)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/otp/webauthn/login/finish", bytes.NewReader(fixture.Login.Response))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_beginWebauthnLogin(t *testing.T) {
+	fixture := loadWebauthnFixture(t)
+	c := gomock.NewController(t)
+	defer c.Finish()
+
+	rw := mock_repository.NewMockWebauthnCredentials(c)
+	rw.EXPECT().GetAllByUser(context.Background(), int64(1)).Return([]domain.WebauthnCredential{
+		{Id: 7, UserId: 1, CredentialId: fixture.Credential.Id, PublicKey: fixture.Credential.PublicKey},
+	}, nil)
+
+	memoryCache := cache.NewMemoryCache()
+	cfg := config.Config{Webauthn: config.WebauthnConfig{RPID: fixture.RpId, RPDisplayName: "Portal", RPOrigins: []string{fixture.Origin}}}
+	webauthnService := service.NewWebauthnService(rw, mock_repository.NewMockUsers(c), memoryCache, cfg)
+	services := &service.Services{Webauthn: webauthnService, Context: service.MockedContextService{MockedUser: &repository.MockedUser}}
+	handler := Handler{services: services}
+
+	r := gin.New()
+	r.POST("/api/v1/otp/webauthn/login/begin", handler.beginWebauthnLogin)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/v1/otp/webauthn/login/begin", nil)
+
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"rpId":"portal.example.com"`)
+	assert.Contains(t, w.Body.String(), `"allowCredentials":[{"type":"public-key","id":"_8p7`)
+	session, err := memoryCache.Get(service.WebauthnSessionKey(service.WebauthnLogin, 1))
+	assert.NoError(t, err)
+	assert.Contains(t, string(session), `"challenge":"`)
+}
@@ -0,0 +1,149 @@
+package v1
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) initWebhooksRoutes(api *gin.RouterGroup) {
+	webhooks := api.Group("/webhooks")
+	{
+		webhooks.GET("", h.getAllWebhooks)
+		webhooks.POST("", h.createWebhook)
+		webhooks.GET("/:id", h.getWebhook)
+		webhooks.PUT("/:id", h.updateWebhook)
+		webhooks.DELETE("/:id", h.deleteWebhook)
+		webhooks.GET("/:id/deliveries", h.getWebhookDeliveries)
+	}
+}
+
+func (h *Handler) getAllWebhooks(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	webhooks, err := h.services.Webhooks.GetAll(c.Request.Context(), *userModel)
+	if err != nil {
+		newResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, DataResponse[domain.Webhook]{
+		Data:  webhooks,
+		Count: len(webhooks),
+		Page:  1,
+		Size:  len(webhooks),
+	})
+}
+
+func (h *Handler) getWebhook(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	id := getWebhookId(c)
+	if userModel == nil || id == 0 {
+		return
+	}
+	webhook, err := h.services.Webhooks.Get(c.Request.Context(), id, *userModel)
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, AloneDataResponse[domain.Webhook]{Data: webhook})
+}
+
+func (h *Handler) createWebhook(c *gin.Context) {
+	var inp service.WebhookInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "url", "message": err.Error()})
+		return
+	}
+	userModel := h.getValidatedUser(c)
+	if userModel == nil {
+		return
+	}
+	webhook, err := h.services.Webhooks.Create(c.Request.Context(), inp, *userModel)
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditCreate, domain.ModuleWebhooks, strconv.FormatInt(webhook.Id, 10), nil, webhook)
+	c.JSON(http.StatusCreated, AloneDataResponse[domain.Webhook]{Data: webhook})
+}
+
+func (h *Handler) updateWebhook(c *gin.Context) {
+	var inp service.WebhookInput
+	if err := c.ShouldBindJSON(&inp); err != nil {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "url", "message": err.Error()})
+		return
+	}
+	userModel := h.getValidatedUser(c)
+	id := getWebhookId(c)
+	if userModel == nil || id == 0 {
+		return
+	}
+	webhook, err := h.services.Webhooks.Update(c.Request.Context(), id, inp, *userModel)
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditUpdate, domain.ModuleWebhooks, strconv.FormatInt(id, 10), nil, webhook)
+	c.JSON(http.StatusAccepted, AloneDataResponse[domain.Webhook]{Data: webhook})
+}
+
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	id := getWebhookId(c)
+	if userModel == nil || id == 0 {
+		return
+	}
+	if err := h.services.Webhooks.Delete(c.Request.Context(), id, *userModel); err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	h.audit(c, *userModel, service.AuditDelete, domain.ModuleWebhooks, strconv.FormatInt(id, 10), gin.H{"id": id}, nil)
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) getWebhookDeliveries(c *gin.Context) {
+	userModel := h.getValidatedUser(c)
+	id := getWebhookId(c)
+	if userModel == nil || id == 0 {
+		return
+	}
+	deliveries, err := h.services.Webhooks.GetDeliveries(c.Request.Context(), id, *userModel)
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, DataResponse[domain.WebhookDelivery]{
+		Data:  deliveries,
+		Count: len(deliveries),
+		Page:  1,
+		Size:  len(deliveries),
+	})
+}
+
+func getWebhookId(c *gin.Context) int64 {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Invalid webhook number"})
+		return 0
+	}
+	return id
+}
+
+func webhookErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Validation Error", "field": "webhook", "message": err.Error()})
+	case errors.Is(err, service.ErrOperationNotPermitted):
+		notPermittedResponse(c)
+	case errors.Is(err, repository.ErrRecordNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not Found", "field": "id", "message": "Webhook not found"})
+	default:
+		newResponse(c, http.StatusInternalServerError, err.Error())
+	}
+}
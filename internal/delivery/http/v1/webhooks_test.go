@@ -0,0 +1,175 @@
+package v1
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	mock_repository "github.com/semelyanov86/vtiger-portal/internal/repository/mocks"
+	"github.com/semelyanov86/vtiger-portal/internal/service"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_createWebhook(t *testing.T) {
+	type mockWebhooks func(r *mock_repository.MockWebhooks)
+
+	tests := []struct {
+		name         string
+		body         string
+		mockWebhooks mockWebhooks
+		userModel    *domain.User
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Webhook created with secret",
+			body: `{"url":"https://example.com/hook","events":["ticket.created","payment.succeeded"]}`,
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {
+				r.EXPECT().Insert(context.Background(), gomock.Any()).DoAndReturn(func(ctx context.Context, hook *domain.Webhook) error {
+					assert.Equal(t, "11x1", hook.AccountId)
+					assert.Equal(t, "12x11", hook.UserId)
+					assert.True(t, hook.IsActive)
+					assert.True(t, strings.HasPrefix(hook.Secret, "whsec_"))
+					hook.Id = 1
+					return nil
+				})
+			},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusCreated,
+			responseBody: `"secret":"whsec_`,
+		},
+		{
+			name:         "Unknown event",
+			body:         `{"url":"https://example.com/hook","events":["ticket.deleted"]}`,
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `ticket.deleted`,
+		},
+		{
+			name:         "Wrong url",
+			body:         `{"url":"ftp://example.com/hook","events":["ticket.created"]}`,
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {},
+			userModel:    &repository.MockedUser,
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: service.ErrWrongWebhookUrl.Error(),
+		},
+		{
+			name:         "Member can not manage webhooks",
+			body:         `{"url":"https://example.com/hook","events":["ticket.created"]}`,
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {},
+			userModel:    &domain.User{Id: 2, Crmid: "12x12", AccountId: "11x1", Role: domain.RoleMember},
+			statusCode:   http.StatusForbidden,
+			responseBody: `"error":"Access Not Permitted"`,
+		},
+		{
+			name:         "Anonymous Access",
+			body:         `{"url":"https://example.com/hook","events":["ticket.created"]}`,
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {},
+			userModel:    domain.AnonymousUser,
+			statusCode:   http.StatusUnauthorized,
+			responseBody: `"error":"Anonymous Access",`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rw := mock_repository.NewMockWebhooks(c)
+			tt.mockWebhooks(rw)
+
+			services := &service.Services{
+				Webhooks: service.NewWebhooksService(rw, config.Config{}, nil, nil),
+				Context:  service.MockedContextService{MockedUser: tt.userModel},
+			}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.POST("/api/v1/webhooks", handler.createWebhook)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(tt.body))
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
+
+func TestHandler_getWebhookDeliveries(t *testing.T) {
+	type mockWebhooks func(r *mock_repository.MockWebhooks)
+
+	tests := []struct {
+		name         string
+		id           string
+		mockWebhooks mockWebhooks
+		statusCode   int
+		responseBody string
+	}{
+		{
+			name: "Deliveries of own webhook",
+			id:   "1",
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {
+				r.EXPECT().GetById(context.Background(), int64(1), "11x1").Return(domain.Webhook{Id: 1, AccountId: "11x1"}, nil)
+				r.EXPECT().GetDeliveries(context.Background(), int64(1), gomock.Any()).Return([]domain.WebhookDelivery{
+					{Id: 5, WebhookId: 1, Event: "ticket.created", Status: domain.WebhookDeliveryFailed, Attempts: 8, ResponseCode: 500},
+				}, nil)
+			},
+			statusCode:   http.StatusOK,
+			responseBody: `"response_code":500`,
+		},
+		{
+			name: "Webhook of other account",
+			id:   "2",
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {
+				r.EXPECT().GetById(context.Background(), int64(2), "11x1").Return(domain.Webhook{}, repository.ErrRecordNotFound)
+			},
+			statusCode:   http.StatusNotFound,
+			responseBody: `Webhook not found`,
+		},
+		{
+			name:         "Wrong id",
+			id:           "abc",
+			mockWebhooks: func(r *mock_repository.MockWebhooks) {},
+			statusCode:   http.StatusUnprocessableEntity,
+			responseBody: `Invalid webhook number`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := gomock.NewController(t)
+			defer c.Finish()
+
+			rw := mock_repository.NewMockWebhooks(c)
+			tt.mockWebhooks(rw)
+
+			services := &service.Services{
+				Webhooks: service.NewWebhooksService(rw, config.Config{}, nil, nil),
+				Context:  service.MockedContextService{MockedUser: &repository.MockedUser},
+			}
+			handler := Handler{services: services}
+
+			r := gin.New()
+			r.GET("/api/v1/webhooks/:id/deliveries", handler.getWebhookDeliveries)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/webhooks/"+tt.id+"/deliveries", nil)
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.responseBody), "response body does not match, expected "+w.Body.String()+" has a string "+tt.responseBody)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// AuditEntry is a change, made by portal user.
+type AuditEntry struct {
+	Id        int64                  `json:"id"`
+	UserId    int64                  `json:"user_id"`
+	UserCrmid string                 `json:"user_crmid"`
+	UserName  string                 `json:"user_name"`
+	AccountId string                 `json:"-"`
+	Ip        string                 `json:"ip"`
+	Action    string                 `json:"action"`
+	Module    string                 `json:"module"`
+	EntityId  string                 `json:"entity_id"`
+	Changes   map[string]AuditChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// AuditChange keeps value of field before and after change. Old is empty for created entities and New for deleted ones.
+type AuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type AuditFilter struct {
+	AccountId string
+	UserCrmid string
+	Module    string
+	EntityId  string
+	Action    string
+	From      *time.Time
+	To        *time.Time
+	Page      int
+	PageSize  int
+}
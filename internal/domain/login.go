@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// LoginAttempts keeps failed sign in attempts for email or ip address.
+type LoginAttempts struct {
+	FailedAttempts int
+	LastFailedAt   *time.Time
+	LockedUntil    *time.Time
+}
+
+func (a LoginAttempts) IsLocked() bool {
+	return a.LockedUntil != nil && a.LockedUntil.After(time.Now())
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// MirrorRecord is a copy of vtiger record stored in local database by sync process.
+type MirrorRecord struct {
+	Id         string         `json:"id"`
+	Module     string         `json:"module"`
+	AccountId  string         `json:"account_id"`
+	ContactId  string         `json:"contact_id"`
+	ParentId   string         `json:"parent_id"`
+	Data       map[string]any `json:"data"`
+	ModifiedAt time.Time      `json:"modified_at"`
+	SyncedAt   time.Time      `json:"synced_at"`
+}
+
+type SyncState struct {
+	Module           string    `json:"module"`
+	LastModifiedTime int64     `json:"last_modified_time"`
+	SyncedAt         time.Time `json:"synced_at"`
+}
@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// OtpState keeps failed OTP attempts of user, so guessing of codes can be stopped.
+type OtpState struct {
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+func (s OtpState) IsLocked() bool {
+	return s.LockedUntil != nil && s.LockedUntil.After(time.Now())
+}
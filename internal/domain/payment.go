@@ -2,13 +2,25 @@ package domain
 
 import "time"
 
+const (
+	PaymentEventReceived   = "received"
+	PaymentEventProcessing = "processing"
+	PaymentEventProcessed  = "processed"
+	PaymentEventSkipped    = "skipped"
+	PaymentEventPending    = "pending"
+	PaymentEventFailed     = "failed"
+)
+
 type PaymentConfig struct {
-	PublishableKey string `json:"publishableKey"`
+	PublishableKey  string            `json:"publishableKey"`
+	DefaultProvider string            `json:"defaultProvider"`
+	Providers       map[string]string `json:"providers"`
 }
 
 type Payment struct {
 	ID              int64     `json:"id"`
 	StripePaymentId string    `json:"stripe_payment_id"`
+	Provider        string    `json:"provider"`
 	UserId          string    `json:"user_id"`
 	AccountId       string    `json:"account_id"`
 	Amount          float64   `json:"amount"`
@@ -18,4 +30,58 @@ type Payment struct {
 	ParentId        string    `json:"parent_id"`
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
+	// SettledAt is a time, when provider has confirmed payment by webhook and its invoices were revised in crm.
+	SettledAt   *time.Time          `json:"settled_at"`
+	Allocations []PaymentAllocation `json:"allocations,omitempty"`
+}
+
+// PaymentAllocation is a part of payment, which goes to one invoice or sales order.
+type PaymentAllocation struct {
+	ID        int64     `json:"id"`
+	PaymentId int64     `json:"payment_id"`
+	ParentId  string    `json:"parent_id"`
+	Module    string    `json:"module"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	// RevisedAt is a time, when allocation was added to invoice or sales order in crm.
+	RevisedAt *time.Time `json:"revised_at"`
+	// Balance of invoice or sales order in crm, new allocation can not reserve more than it. It is not stored.
+	Balance float64 `json:"-"`
+}
+
+// PaymentReservation contains statuses of payments, which allocations are not available for new payments. Allocations
+// of paid payments are reserved, until they are revised in crm.
+type PaymentReservation struct {
+	Pending []int
+	Paid    []int
+}
+
+// PaymentRefund is a refund of payment, issued at payment provider.
+type PaymentRefund struct {
+	ID        int64     `json:"id"`
+	PaymentId int64     `json:"payment_id"`
+	RefundId  string    `json:"refund_id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PaymentEvent is a webhook event of payment provider. Event is pending, while revision of crm waits for next attempt.
+type PaymentEvent struct {
+	Id            int64      `json:"id"`
+	Provider      string     `json:"provider"`
+	EventId       string     `json:"event_id"`
+	Type          string     `json:"type"`
+	IntentId      string     `json:"intent_id"`
+	PaymentId     int64      `json:"payment_id"`
+	RefundId      string     `json:"refund_id"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	Error         string     `json:"error"`
+	Revised       []string   `json:"revised"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	OccurredAt    time.Time  `json:"occurred_at"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
 }
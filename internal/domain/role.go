@@ -0,0 +1,130 @@
+package domain
+
+const (
+	RoleOwner    = "owner"
+	RoleAdmin    = "admin"
+	RoleBilling  = "billing"
+	RoleMember   = "member"
+	RoleReadOnly = "read-only"
+)
+
+// Roles are ordered from most to least privileged.
+var Roles = []string{RoleOwner, RoleAdmin, RoleBilling, RoleMember, RoleReadOnly}
+
+type Permission int
+
+const (
+	PermissionNone Permission = iota
+	PermissionRead
+	PermissionWrite
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionRead:
+		return "read"
+	case PermissionWrite:
+		return "write"
+	default:
+		return "none"
+	}
+}
+
+// Modules, which have own permissions. Payments, Webhooks and Audit are not vtiger modules, they are parts of portal.
+const (
+	ModuleHelpDesk         = "HelpDesk"
+	ModuleInvoice          = "Invoice"
+	ModuleSalesOrder       = "SalesOrder"
+	ModuleProject          = "Project"
+	ModuleProjectTask      = "ProjectTask"
+	ModuleServiceContracts = "ServiceContracts"
+	ModuleDocuments        = "Documents"
+	ModuleContacts         = "Contacts"
+	ModulePayments         = "Payments"
+	ModuleWebhooks         = "Webhooks"
+	ModuleAudit            = "Audit"
+)
+
+// PermissionModules are modules, which are listed in role description.
+var PermissionModules = []string{ModuleHelpDesk, ModuleInvoice, ModuleSalesOrder, ModuleProject, ModuleProjectTask, ModuleServiceContracts, ModuleDocuments, ModuleContacts, ModulePayments, ModuleWebhooks, ModuleAudit}
+
+type rolePermissions struct {
+	modules  map[string]Permission
+	fallback Permission
+}
+
+var permissions = map[string]rolePermissions{
+	RoleOwner: {fallback: PermissionWrite},
+	RoleAdmin: {fallback: PermissionWrite},
+	RoleBilling: {
+		modules: map[string]Permission{
+			ModuleInvoice:    PermissionWrite,
+			ModuleSalesOrder: PermissionWrite,
+			ModulePayments:   PermissionWrite,
+			ModuleDocuments:  PermissionWrite,
+			ModuleWebhooks:   PermissionNone,
+			ModuleAudit:      PermissionNone,
+		},
+		fallback: PermissionRead,
+	},
+	RoleMember: {
+		modules: map[string]Permission{
+			ModuleInvoice:          PermissionRead,
+			ModuleSalesOrder:       PermissionRead,
+			ModuleServiceContracts: PermissionRead,
+			ModuleContacts:         PermissionRead,
+			ModuleWebhooks:         PermissionNone,
+			ModuleAudit:            PermissionNone,
+		},
+		fallback: PermissionWrite,
+	},
+	RoleReadOnly: {
+		modules: map[string]Permission{
+			ModuleWebhooks: PermissionNone,
+			ModuleAudit:    PermissionNone,
+		},
+		fallback: PermissionRead,
+	},
+}
+
+// UserRole describes role of contact and what this role allows in every module.
+type UserRole struct {
+	Crmid       string            `json:"crmid"`
+	Role        string            `json:"role"`
+	Permissions map[string]string `json:"permissions"`
+}
+
+func IsValidRole(role string) bool {
+	_, ok := permissions[role]
+	return ok
+}
+
+// RoleOrDefault returns role of user. Users, registered before roles were introduced, are members.
+func (u User) RoleOrDefault() string {
+	if IsValidRole(u.Role) {
+		return u.Role
+	}
+	return RoleMember
+}
+
+// PermissionFor returns access level of user to module. Modules, which are not listed for role, get fallback level.
+func (u User) PermissionFor(module string) Permission {
+	role := permissions[u.RoleOrDefault()]
+	if permission, ok := role.modules[module]; ok {
+		return permission
+	}
+	return role.fallback
+}
+
+// Can reports whether user has at least given access level to module.
+func (u User) Can(module string, permission Permission) bool {
+	return u.PermissionFor(module) >= permission
+}
+
+func NewUserRole(user User) UserRole {
+	result := UserRole{Crmid: user.Crmid, Role: user.RoleOrDefault(), Permissions: make(map[string]string, len(PermissionModules))}
+	for _, module := range PermissionModules {
+		result.Permissions[module] = user.PermissionFor(module).String()
+	}
+	return result
+}
@@ -8,15 +8,35 @@ import (
 const ScopeActivation = "activation"
 const ScopeAuthentication = "authentication"
 const ScopePasswordReset = "password-reset"
+const ScopeRefresh = "refresh"
+const ScopeMagicLink = "magic-link"
+const ScopeInvitation = "invitation"
+const ScopeLoginUnlock = "login-unlock"
 
 type Token struct {
-	ID         int64     `json:"id"`
-	Plaintext  string    `json:"token"`
-	Hash       string    `json:"-"`
-	UserId     int64     `json:"-"`
-	Expiry     time.Time `json:"expiry"`
-	Scope      string    `json:"-"`
-	OtpEnabled bool      `json:"otp_enabled"`
+	ID            int64      `json:"id"`
+	Plaintext     string     `json:"token"`
+	Hash          string     `json:"-"`
+	UserId        int64      `json:"-"`
+	Expiry        time.Time  `json:"expiry"`
+	Scope         string     `json:"-"`
+	OtpEnabled    bool       `json:"otp_enabled"`
+	Webauthn      bool       `json:"webauthn_enabled"`
+	Family        string     `json:"-"`
+	UsedAt        *time.Time `json:"-"`
+	RefreshToken  string     `json:"refresh_token,omitempty"`
+	RefreshExpiry *time.Time `json:"refresh_expiry,omitempty"`
+}
+
+// Session is a login of user. All tokens of one login, including rotated ones, share its id.
+type Session struct {
+	Id         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	UserAgent  string     `json:"user_agent"`
+	Ip         string     `json:"ip"`
+	Expiry     time.Time  `json:"expiry"`
+	Current    bool       `json:"current"`
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
@@ -15,6 +15,7 @@ type User struct {
 	Description        string    `json:"description"`
 	AccountId          string    `json:"account_id"`
 	AccountName        string    `json:"account_name"`
+	Role               string    `json:"role"`
 	Title              string    `json:"title"`
 	Department         string    `json:"department"`
 	Email              string    `json:"email"`
@@ -47,10 +48,16 @@ type User struct {
 	Otp_verified       bool      `json:"otp_verified"`
 	Otp_secret         string    `json:"-"`
 	Otp_auth_url       string    `json:"-"`
+	Webauthn_enabled   bool      `json:"webauthn_enabled"`
 }
 
 var AnonymousUser = &User{}
 
+// SecondFactorEnabled tells, that user has to pass OTP or passkey check after login.
+func (u User) SecondFactorEnabled() bool {
+	return u.Otp_enabled || u.Webauthn_enabled
+}
+
 type Password struct {
 	Plaintext *string
 	Hash      []byte
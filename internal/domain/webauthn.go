@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// WebauthnCredential is a passkey or hardware key, which user registered as second factor.
+type WebauthnCredential struct {
+	Id              int64      `json:"id"`
+	UserId          int64      `json:"-"`
+	Name            string     `json:"name"`
+	CredentialId    []byte     `json:"-"`
+	PublicKey       []byte     `json:"-"`
+	AttestationType string     `json:"-"`
+	Transports      []string   `json:"transports"`
+	Aaguid          []byte     `json:"-"`
+	SignCount       uint32     `json:"-"`
+	CloneWarning    bool       `json:"clone_warning"`
+	CreatedAt       time.Time  `json:"created_at"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+}
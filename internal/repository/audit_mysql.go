@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"strings"
+	"time"
+)
+
+type AuditRepo struct {
+	db *sql.DB
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{
+		db: db,
+	}
+}
+
+func (r *AuditRepo) Insert(ctx context.Context, entry *domain.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	var query = `
+				INSERT INTO audit_log (user_id, user_crmid, user_name, account_id, ip, action, module, entity_id, changes, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{entry.UserId, entry.UserCrmid, entry.UserName, entry.AccountId, entry.Ip, entry.Action, entry.Module, entry.EntityId, changes, entry.CreatedAt}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.Id = id
+	return nil
+}
+
+// GetAll returns page of audit entries, newest first, and total count of entries, which match filter.
+func (r *AuditRepo) GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int, error) {
+	conditions := []string{"account_id = ?"}
+	args := []any{filter.AccountId}
+	if filter.UserCrmid != "" {
+		conditions = append(conditions, "user_crmid = ?")
+		args = append(args, filter.UserCrmid)
+	}
+	if filter.Module != "" {
+		conditions = append(conditions, "module = ?")
+		args = append(args, filter.Module)
+	}
+	if filter.EntityId != "" {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityId)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, *filter.To)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE `+where, args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+
+	var query = `SELECT id, user_id, user_crmid, user_name, account_id, ip, action, module, entity_id, changes, created_at FROM audit_log WHERE ` + where + ` ORDER BY id DESC`
+	if filter.PageSize > 0 {
+		page := filter.Page
+		if page < 1 {
+			page = 1
+		}
+		query += ` LIMIT ?, ?`
+		args = append(args, (page-1)*filter.PageSize, filter.PageSize)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var entries = make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var changes []byte
+		err = rows.Scan(&entry.Id, &entry.UserId, &entry.UserCrmid, &entry.UserName, &entry.AccountId, &entry.Ip, &entry.Action, &entry.Module, &entry.EntityId, &changes, &entry.CreatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(changes) > 0 {
+			if err = json.Unmarshal(changes, &entry.Changes); err != nil {
+				return nil, 0, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return entries, count, nil
+}
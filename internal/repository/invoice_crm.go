@@ -62,13 +62,13 @@ func (m InvoiceCrm) Count(ctx context.Context, client string) (int, error) {
 }
 
 func (m InvoiceCrm) GetFromSalesOrder(ctx context.Context, soId string) ([]domain.Invoice, error) {
-	query := "SELECT * FROM Invoice WHERE salesorder_id = " + soId + ";"
+	query := vtiger.NewQuery("Invoice").Where(vtiger.Eq("salesorder_id", soId))
 	invoices := make([]domain.Invoice, 0)
-	result, err := m.vtiger.Query(ctx, query)
+	result, err := m.vtiger.Select(ctx, query)
 	if err != nil {
-		return invoices, e.Wrap("can not execute query "+query+", got error", err)
+		return invoices, e.Wrap("can not get invoices from sales order "+soId, err)
 	}
-	for _, data := range result.Result {
+	for _, data := range result {
 		invoice, err := domain.ConvertMapToInvoice(data)
 		if err != nil {
 			return invoices, e.Wrap("can not convert map to invoice", err)
@@ -77,3 +77,19 @@ func (m InvoiceCrm) GetFromSalesOrder(ctx context.Context, soId string) ([]domai
 	}
 	return invoices, nil
 }
+
+// Export passes all invoices of account to fn, pages of vtiger are requested while fn consumes invoices.
+func (m InvoiceCrm) Export(ctx context.Context, client string, fn func(invoice domain.Invoice) error) error {
+	it := m.vtiger.QueryAll(ctx, vtiger.NewQuery("Invoice").Where(vtiger.Eq("account_id", client)).OrderBy("invoice_no"))
+	defer it.Close()
+	for it.Next() {
+		invoice, err := domain.ConvertMapToInvoice(it.Record())
+		if err != nil {
+			return e.Wrap("can not convert map to invoice", err)
+		}
+		if err = fn(invoice); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"time"
+)
+
+type LoginAttemptsRepo struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptsRepo(db *sql.DB) *LoginAttemptsRepo {
+	return &LoginAttemptsRepo{
+		db: db,
+	}
+}
+
+// Get returns failed attempts for key. Empty state is returned, when there were no failures.
+func (r *LoginAttemptsRepo) Get(ctx context.Context, key string) (domain.LoginAttempts, error) {
+	var query = "SELECT failed_attempts, last_failed_at, locked_until FROM login_attempts WHERE `key` = ?"
+	var attempts domain.LoginAttempts
+	var lastFailedAt, lockedUntil sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&attempts.FailedAttempts, &lastFailedAt, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attempts, nil
+		}
+		return attempts, err
+	}
+	if lastFailedAt.Valid {
+		attempts.LastFailedAt = &lastFailedAt.Time
+	}
+	if lockedUntil.Valid {
+		attempts.LockedUntil = &lockedUntil.Time
+	}
+	return attempts, nil
+}
+
+// Reserve counts attempt as failed before password is checked, so parallel requests can not check more passwords, than
+// limits allow. Allow is called with current attempts under row lock, attempt is not counted, when it returns error.
+// Failures, which are older than window, are forgotten. Attempts with reserved one are returned.
+func (r *LoginAttemptsRepo) Reserve(ctx context.Context, key string, window time.Duration, allow func(domain.LoginAttempts) error) (domain.LoginAttempts, error) {
+	var attempts domain.LoginAttempts
+	// row is created first, so parallel requests wait for lock of the same row
+	if _, err := r.db.ExecContext(ctx, "INSERT IGNORE INTO login_attempts (`key`) VALUES (?)", key); err != nil {
+		return attempts, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return attempts, err
+	}
+	defer tx.Rollback()
+
+	var lastFailedAt, lockedUntil sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT failed_attempts, last_failed_at, locked_until FROM login_attempts WHERE `key` = ? FOR UPDATE", key).
+		Scan(&attempts.FailedAttempts, &lastFailedAt, &lockedUntil)
+	if err != nil {
+		return attempts, err
+	}
+	if lastFailedAt.Valid {
+		attempts.LastFailedAt = &lastFailedAt.Time
+	}
+	if lockedUntil.Valid {
+		attempts.LockedUntil = &lockedUntil.Time
+	}
+	now := time.Now()
+	if attempts.LastFailedAt != nil && attempts.LastFailedAt.Before(now.Add(-window)) {
+		attempts.FailedAttempts = 0
+	}
+	if err = allow(attempts); err != nil {
+		return attempts, err
+	}
+
+	attempts.FailedAttempts++
+	attempts.LastFailedAt = &now
+	_, err = tx.ExecContext(ctx, "UPDATE login_attempts SET failed_attempts = ?, last_failed_at = ? WHERE `key` = ?", attempts.FailedAttempts, now, key)
+	if err != nil {
+		return attempts, err
+	}
+	return attempts, tx.Commit()
+}
+
+// Release takes back attempt, reserved by successful sign in. Time of last failure is set back to passed one.
+func (r *LoginAttemptsRepo) Release(ctx context.Context, key string, lastFailedAt *time.Time) error {
+	var query = "UPDATE login_attempts SET failed_attempts = GREATEST(failed_attempts - 1, 0), last_failed_at = ? WHERE `key` = ?"
+
+	_, err := r.db.ExecContext(ctx, query, lastFailedAt, key)
+	return err
+}
+
+// Lock forbids sign in until passed time and starts counting of failed attempts again.
+func (r *LoginAttemptsRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	var query = "UPDATE login_attempts SET failed_attempts = 0, locked_until = ? WHERE `key` = ?"
+
+	_, err := r.db.ExecContext(ctx, query, until, key)
+	return err
+}
+
+func (r *LoginAttemptsRepo) Reset(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_attempts WHERE `key` = ?", key)
+	return err
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"time"
+)
+
+// MirrorScope describes fields, which link record of module to account, contact and parent record.
+// When ParentModule is set, account and contact are taken from mirrored parent record.
+type MirrorScope struct {
+	AccountField string
+	ContactField string
+	ParentField  string
+	ParentModule string
+}
+
+var MirrorScopes = map[string]MirrorScope{
+	"HelpDesk":    {AccountField: "parent_id", ContactField: "contact_id"},
+	"Invoice":     {AccountField: "account_id", ContactField: "contact_id"},
+	"SalesOrder":  {AccountField: "account_id", ContactField: "contact_id"},
+	"Project":     {AccountField: "linktoaccountscontacts", ContactField: "linktoaccountscontacts"},
+	"ProjectTask": {ParentField: "projectid", ParentModule: "Project"},
+	// Documents are linked to records through relations, which are not returned by sync,
+	// so mirrored documents have no account and are always read from vtiger.
+	"Documents": {},
+}
+
+var ErrUnscopedMirror = errors.New("module is not scoped by account in mirror")
+
+// Scoped reports whether mirrored records of module can be filtered by account or contact.
+func (s MirrorScope) Scoped() bool {
+	return s.AccountField != "" || s.ContactField != "" || s.ParentModule != ""
+}
+
+// MirrorRecordFromData builds record for mirror table from data received from vtiger.
+func MirrorRecordFromData(ctx context.Context, module string, scope MirrorScope, data map[string]any, mirror Mirror) (domain.MirrorRecord, error) {
+	record := domain.MirrorRecord{Module: module, Data: data}
+	record.Id, _ = data["id"].(string)
+	if record.Id == "" {
+		return record, ErrWrongCrmId
+	}
+	if modified, ok := data["modifiedtime"].(string); ok {
+		record.ModifiedAt, _ = time.Parse("2006-01-02 15:04:05", modified)
+	}
+	if scope.AccountField != "" {
+		record.AccountId, _ = data[scope.AccountField].(string)
+	}
+	if scope.ContactField != "" {
+		record.ContactId, _ = data[scope.ContactField].(string)
+	}
+	if scope.ParentField != "" {
+		record.ParentId, _ = data[scope.ParentField].(string)
+	}
+	if scope.ParentModule != "" && record.ParentId != "" {
+		parent, err := mirror.GetById(ctx, scope.ParentModule, record.ParentId)
+		if err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return record, e.Wrap("can not get parent record "+record.ParentId, err)
+		}
+		record.AccountId = parent.AccountId
+		record.ContactId = parent.ContactId
+	}
+	return record, nil
+}
+
+// mirrorWriter stores records changed by portal in mirror, so they are visible before next sync.
+type mirrorWriter struct {
+	mirror Mirror
+	crm    Sync
+}
+
+func (w mirrorWriter) refresh(ctx context.Context, module string, id string) {
+	data, err := w.crm.Retrieve(ctx, id)
+	if err == nil {
+		var record domain.MirrorRecord
+		record, err = MirrorRecordFromData(ctx, module, MirrorScopes[module], data, w.mirror)
+		if err == nil {
+			err = w.mirror.Upsert(ctx, record)
+		}
+	}
+	if err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not refresh mirror record " + id + ": " + err.Error()))
+	}
+}
+
+func findInMirror[T any](ctx context.Context, mirror Mirror, module string, filter vtiger.PaginationQueryFilter, fields vtiger.QueryFieldsProps, fn func(map[string]any) (T, error)) ([]T, error) {
+	if scope, ok := MirrorScopes[module]; !ok || !scope.Scoped() {
+		return nil, e.Wrap("can not find "+module+" in mirror", ErrUnscopedMirror)
+	}
+	records, err := mirror.Find(ctx, module, filter, fields)
+	if err != nil {
+		return nil, e.Wrap("can not find "+module+" in mirror", err)
+	}
+	result := make([]T, 0, len(records))
+	for _, record := range records {
+		item, err := fn(record.Data)
+		if err != nil {
+			return result, e.Wrap("can not convert map to "+module, err)
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// HelpDeskMirror reads tickets from local mirror, writes are sent to vtiger.
+type HelpDeskMirror struct {
+	HelpDesk
+	mirror Mirror
+	writer mirrorWriter
+}
+
+func NewHelpDeskMirror(crm HelpDesk, mirror Mirror, syncCrm Sync) HelpDeskMirror {
+	return HelpDeskMirror{HelpDesk: crm, mirror: mirror, writer: mirrorWriter{mirror: mirror, crm: syncCrm}}
+}
+
+func (m HelpDeskMirror) RetrieveById(ctx context.Context, id string) (domain.HelpDesk, error) {
+	record, err := m.mirror.GetById(ctx, "HelpDesk", id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return m.HelpDesk.RetrieveById(ctx, id)
+	}
+	if err != nil {
+		return domain.HelpDesk{}, e.Wrap("can not get ticket "+id+" from mirror", err)
+	}
+	return domain.ConvertMapToHelpDesk(record.Data)
+}
+
+func (m HelpDeskMirror) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.HelpDesk, error) {
+	filter.Contact = ""
+	return findInMirror[domain.HelpDesk](ctx, m.mirror, "HelpDesk", filter, vtiger.QueryFieldsProps{
+		DefaultSort:  "-ticket_no",
+		SearchFields: []string{"ticket_title", "ticket_no"},
+	}, domain.ConvertMapToHelpDesk)
+}
+
+func (m HelpDeskMirror) Count(ctx context.Context, client string) (int, error) {
+	return m.mirror.Count(ctx, "HelpDesk", client, "", "")
+}
+
+func (m HelpDeskMirror) Create(ctx context.Context, ticket domain.HelpDesk) (domain.HelpDesk, error) {
+	ticket, err := m.HelpDesk.Create(ctx, ticket)
+	if err == nil {
+		m.writer.refresh(ctx, "HelpDesk", ticket.ID)
+	}
+	return ticket, err
+}
+
+func (m HelpDeskMirror) Update(ctx context.Context, ticket domain.HelpDesk) (domain.HelpDesk, error) {
+	ticket, err := m.HelpDesk.Update(ctx, ticket)
+	if err == nil {
+		m.writer.refresh(ctx, "HelpDesk", ticket.ID)
+	}
+	return ticket, err
+}
+
+func (m HelpDeskMirror) Revise(ctx context.Context, data map[string]any) (domain.HelpDesk, error) {
+	ticket, err := m.HelpDesk.Revise(ctx, data)
+	if err == nil {
+		m.writer.refresh(ctx, "HelpDesk", ticket.ID)
+	}
+	return ticket, err
+}
+
+type InvoiceMirror struct {
+	Invoice
+	mirror Mirror
+}
+
+func NewInvoiceMirror(crm Invoice, mirror Mirror) InvoiceMirror {
+	return InvoiceMirror{Invoice: crm, mirror: mirror}
+}
+
+func (m InvoiceMirror) RetrieveById(ctx context.Context, id string) (domain.Invoice, error) {
+	record, err := m.mirror.GetById(ctx, "Invoice", id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return m.Invoice.RetrieveById(ctx, id)
+	}
+	if err != nil {
+		return domain.Invoice{}, e.Wrap("can not get invoice "+id+" from mirror", err)
+	}
+	return domain.ConvertMapToInvoice(record.Data)
+}
+
+func (m InvoiceMirror) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Invoice, error) {
+	filter.Contact = ""
+	return findInMirror[domain.Invoice](ctx, m.mirror, "Invoice", filter, vtiger.QueryFieldsProps{
+		DefaultSort:  "-invoice_no",
+		SearchFields: []string{"subject", "invoice_no", "invoicestatus"},
+	}, domain.ConvertMapToInvoice)
+}
+
+func (m InvoiceMirror) Count(ctx context.Context, client string) (int, error) {
+	return m.mirror.Count(ctx, "Invoice", client, "", "")
+}
+
+type SalesOrderMirror struct {
+	SalesOrder
+	mirror Mirror
+}
+
+func NewSalesOrderMirror(crm SalesOrder, mirror Mirror) SalesOrderMirror {
+	return SalesOrderMirror{SalesOrder: crm, mirror: mirror}
+}
+
+func (m SalesOrderMirror) RetrieveById(ctx context.Context, id string) (domain.SalesOrder, error) {
+	record, err := m.mirror.GetById(ctx, "SalesOrder", id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return m.SalesOrder.RetrieveById(ctx, id)
+	}
+	if err != nil {
+		return domain.SalesOrder{}, e.Wrap("can not get sales order "+id+" from mirror", err)
+	}
+	return domain.ConvertMapToSalesOrder(record.Data)
+}
+
+func (m SalesOrderMirror) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.SalesOrder, error) {
+	filter.Contact = ""
+	return findInMirror[domain.SalesOrder](ctx, m.mirror, "SalesOrder", filter, vtiger.QueryFieldsProps{
+		DefaultSort:  "-salesorder_no",
+		SearchFields: []string{"subject", "salesorder_no", "sostatus"},
+	}, domain.ConvertMapToSalesOrder)
+}
+
+func (m SalesOrderMirror) Count(ctx context.Context, client string) (int, error) {
+	return m.mirror.Count(ctx, "SalesOrder", client, "", "")
+}
+
+type ProjectMirror struct {
+	Project
+	mirror Mirror
+}
+
+func NewProjectMirror(crm Project, mirror Mirror) ProjectMirror {
+	return ProjectMirror{Project: crm, mirror: mirror}
+}
+
+func (m ProjectMirror) RetrieveById(ctx context.Context, id string) (domain.Project, error) {
+	record, err := m.mirror.GetById(ctx, "Project", id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return m.Project.RetrieveById(ctx, id)
+	}
+	if err != nil {
+		return domain.Project{}, e.Wrap("can not get project "+id+" from mirror", err)
+	}
+	return domain.ConvertMapToProject(record.Data)
+}
+
+func (m ProjectMirror) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Project, error) {
+	return findInMirror[domain.Project](ctx, m.mirror, "Project", filter, vtiger.QueryFieldsProps{
+		DefaultSort:  "-project_no",
+		SearchFields: []string{"projectname", "project_no", "projecttype"},
+	}, domain.ConvertMapToProject)
+}
+
+func (m ProjectMirror) Count(ctx context.Context, client string, contact string) (int, error) {
+	return m.mirror.Count(ctx, "Project", client, contact, "")
+}
+
+type ProjectTaskMirror struct {
+	ProjectTask
+	mirror Mirror
+	writer mirrorWriter
+}
+
+func NewProjectTaskMirror(crm ProjectTask, mirror Mirror, syncCrm Sync) ProjectTaskMirror {
+	return ProjectTaskMirror{ProjectTask: crm, mirror: mirror, writer: mirrorWriter{mirror: mirror, crm: syncCrm}}
+}
+
+func (m ProjectTaskMirror) RetrieveById(ctx context.Context, id string) (domain.ProjectTask, error) {
+	record, err := m.mirror.GetById(ctx, "ProjectTask", id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return m.ProjectTask.RetrieveById(ctx, id)
+	}
+	if err != nil {
+		return domain.ProjectTask{}, e.Wrap("can not get project task "+id+" from mirror", err)
+	}
+	return domain.ConvertMapToProjectTask(record.Data)
+}
+
+func (m ProjectTaskMirror) GetFromProject(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.ProjectTask, error) {
+	filter.Client = ""
+	filter.Contact = ""
+	return findInMirror[domain.ProjectTask](ctx, m.mirror, "ProjectTask", filter, vtiger.QueryFieldsProps{}, domain.ConvertMapToProjectTask)
+}
+
+func (m ProjectTaskMirror) Count(ctx context.Context, parent string) (int, error) {
+	return m.mirror.Count(ctx, "ProjectTask", "", "", parent)
+}
+
+func (m ProjectTaskMirror) Create(ctx context.Context, task domain.ProjectTask) (domain.ProjectTask, error) {
+	task, err := m.ProjectTask.Create(ctx, task)
+	if err == nil {
+		m.writer.refresh(ctx, "ProjectTask", task.Id)
+	}
+	return task, err
+}
+
+func (m ProjectTaskMirror) Revise(ctx context.Context, data map[string]any) (domain.ProjectTask, error) {
+	task, err := m.ProjectTask.Revise(ctx, data)
+	if err == nil {
+		m.writer.refresh(ctx, "ProjectTask", task.Id)
+	}
+	return task, err
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"strings"
+	"time"
+)
+
+type MirrorRepo struct {
+	db *sql.DB
+}
+
+func NewMirrorRepo(db *sql.DB) *MirrorRepo {
+	return &MirrorRepo{
+		db: db,
+	}
+}
+
+func (r *MirrorRepo) Upsert(ctx context.Context, record domain.MirrorRecord) error {
+	data, err := json.Marshal(record.Data)
+	if err != nil {
+		return err
+	}
+	var modifiedAt any
+	if !record.ModifiedAt.IsZero() {
+		modifiedAt = record.ModifiedAt
+	}
+	var query = `
+				INSERT INTO crm_records (id, module, account_id, contact_id, parent_id, data, modified_at, synced_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON DUPLICATE KEY UPDATE module = VALUES(module), account_id = VALUES(account_id), contact_id = VALUES(contact_id), parent_id = VALUES(parent_id), data = VALUES(data), modified_at = VALUES(modified_at), synced_at = VALUES(synced_at)`
+	var args = []any{record.Id, record.Module, record.AccountId, record.ContactId, record.ParentId, data, modifiedAt, time.Now()}
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *MirrorRepo) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	var query = `DELETE FROM crm_records WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *MirrorRepo) GetById(ctx context.Context, module string, id string) (domain.MirrorRecord, error) {
+	var query = `SELECT id, module, account_id, contact_id, parent_id, data, modified_at, synced_at FROM crm_records WHERE module = ? AND id = ?`
+	record, err := r.scan(r.db.QueryRowContext(ctx, query, module, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return record, ErrRecordNotFound
+		default:
+			return record, err
+		}
+	}
+	return record, nil
+}
+
+// Find returns records of module, which belong to client or contact of filter. Sort and search fields are taken from
+// json data, so they are the same as in vtiger queries.
+func (r *MirrorRepo) Find(ctx context.Context, module string, filter vtiger.PaginationQueryFilter, fields vtiger.QueryFieldsProps) ([]domain.MirrorRecord, error) {
+	where, args := r.scopeCondition(module, filter.Client, filter.Contact, filter.Parent)
+	if filter.Search != "" && len(fields.SearchFields) > 0 {
+		searches := make([]string, 0, len(fields.SearchFields))
+		for _, field := range fields.SearchFields {
+			field = strings.TrimPrefix(field, "-")
+			if !vtiger.IsValidIdentifier(field) {
+				return nil, vtiger.ErrInvalidIdentifier
+			}
+			searches = append(searches, `JSON_UNQUOTE(JSON_EXTRACT(data, '$.`+field+`')) LIKE ?`)
+			args = append(args, "%"+vtiger.EscapeLike(filter.Search)+"%")
+		}
+		where += ` AND (` + strings.Join(searches, " OR ") + `)`
+	}
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = fields.DefaultSort
+	}
+	orderBy, err := r.orderBy(sort)
+	if err != nil {
+		return nil, err
+	}
+
+	var query = `SELECT id, module, account_id, contact_id, parent_id, data, modified_at, synced_at FROM crm_records WHERE ` + where + orderBy
+	if filter.PageSize > 0 {
+		page := filter.Page
+		if page < 1 {
+			page = 1
+		}
+		query += ` LIMIT ?, ?`
+		args = append(args, (page-1)*filter.PageSize, filter.PageSize)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records = make([]domain.MirrorRecord, 0)
+	for rows.Next() {
+		record, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *MirrorRepo) Count(ctx context.Context, module string, client string, contact string, parent string) (int, error) {
+	where, args := r.scopeCondition(module, client, contact, parent)
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM crm_records WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+func (r *MirrorRepo) GetSyncState(ctx context.Context, module string) (domain.SyncState, error) {
+	var query = `SELECT module, last_modified_time, synced_at FROM crm_sync_state WHERE module = ?`
+	state := domain.SyncState{Module: module}
+	err := r.db.QueryRowContext(ctx, query, module).Scan(&state.Module, &state.LastModifiedTime, &state.SyncedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return state, ErrRecordNotFound
+		default:
+			return state, err
+		}
+	}
+	return state, nil
+}
+
+func (r *MirrorRepo) SaveSyncState(ctx context.Context, state domain.SyncState) error {
+	var query = `
+				INSERT INTO crm_sync_state (module, last_modified_time, synced_at) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE last_modified_time = VALUES(last_modified_time), synced_at = VALUES(synced_at)`
+	_, err := r.db.ExecContext(ctx, query, state.Module, state.LastModifiedTime, time.Now())
+	return err
+}
+
+func (r *MirrorRepo) scopeCondition(module string, client string, contact string, parent string) (string, []any) {
+	where := `module = ?`
+	args := []any{module}
+	switch {
+	case client != "" && contact != "":
+		where += ` AND (account_id = ? OR contact_id = ?)`
+		args = append(args, client, contact)
+	case client != "":
+		where += ` AND account_id = ?`
+		args = append(args, client)
+	case contact != "":
+		where += ` AND contact_id = ?`
+		args = append(args, contact)
+	}
+	if parent != "" {
+		where += ` AND parent_id = ?`
+		args = append(args, parent)
+	}
+	return where, args
+}
+
+func (r *MirrorRepo) orderBy(sort string) (string, error) {
+	if sort == "" {
+		return "", nil
+	}
+	parts := make([]string, 0)
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		direction := " ASC"
+		if strings.HasPrefix(field, "-") {
+			field = strings.TrimPrefix(field, "-")
+			direction = " DESC"
+		}
+		if field == "" {
+			continue
+		}
+		if !vtiger.IsValidIdentifier(field) {
+			return "", vtiger.ErrInvalidIdentifier
+		}
+		parts = append(parts, `JSON_UNQUOTE(JSON_EXTRACT(data, '$.`+field+`'))`+direction)
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return ` ORDER BY ` + strings.Join(parts, ", "), nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *MirrorRepo) scan(row rowScanner) (domain.MirrorRecord, error) {
+	var record domain.MirrorRecord
+	var data []byte
+	var modifiedAt sql.NullTime
+	err := row.Scan(&record.Id, &record.Module, &record.AccountId, &record.ContactId, &record.ParentId, &data, &modifiedAt, &record.SyncedAt)
+	if err != nil {
+		return record, err
+	}
+	if modifiedAt.Valid {
+		record.ModifiedAt = modifiedAt.Time
+	}
+	if err = json.Unmarshal(data, &record.Data); err != nil {
+		return record, err
+	}
+	return record, nil
+}
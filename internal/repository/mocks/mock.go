@@ -153,6 +153,20 @@ func (mr *MockUsersMockRecorder) SaveOtp(ctx, otpSecret, otpUrl, userId interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOtp", reflect.TypeOf((*MockUsers)(nil).SaveOtp), ctx, otpSecret, otpUrl, userId)
 }
 
+// SetWebauthnEnabled mocks base method.
+func (m *MockUsers) SetWebauthnEnabled(ctx context.Context, userId int64, enabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetWebauthnEnabled", ctx, userId, enabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetWebauthnEnabled indicates an expected call of SetWebauthnEnabled.
+func (mr *MockUsersMockRecorder) SetWebauthnEnabled(ctx, userId, enabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetWebauthnEnabled", reflect.TypeOf((*MockUsers)(nil).SetWebauthnEnabled), ctx, userId, enabled)
+}
+
 // Update mocks base method.
 func (m *MockUsers) Update(ctx context.Context, user *domain.User) error {
 	m.ctrl.T.Helper()
@@ -167,6 +181,20 @@ func (mr *MockUsersMockRecorder) Update(ctx, user interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUsers)(nil).Update), ctx, user)
 }
 
+// UpdateRole mocks base method.
+func (m *MockUsers) UpdateRole(ctx context.Context, userId int64, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, userId, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockUsersMockRecorder) UpdateRole(ctx, userId, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockUsers)(nil).UpdateRole), ctx, userId, role)
+}
+
 // VerifyOrInvalidateOtp mocks base method.
 func (m *MockUsers) VerifyOrInvalidateOtp(ctx context.Context, userId int64, valid bool) error {
 	m.ctrl.T.Helper()
@@ -233,6 +261,21 @@ func (mr *MockUsersCrmMockRecorder) ClearUserCodeField(ctx, id interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearUserCodeField", reflect.TypeOf((*MockUsersCrm)(nil).ClearUserCodeField), ctx, id)
 }
 
+// Create mocks base method.
+func (m *MockUsersCrm) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, user)
+	ret0, _ := ret[0].(domain.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockUsersCrmMockRecorder) Create(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockUsersCrm)(nil).Create), ctx, user)
+}
+
 // FindByEmail mocks base method.
 func (m *MockUsersCrm) FindByEmail(ctx context.Context, email string) ([]domain.User, error) {
 	m.ctrl.T.Helper()
@@ -331,6 +374,20 @@ func (m *MockTokens) EXPECT() *MockTokensMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method.
+func (m *MockTokens) Delete(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockTokensMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTokens)(nil).Delete), ctx, id)
+}
+
 // DeleteAllForUser mocks base method.
 func (m *MockTokens) DeleteAllForUser(ctx context.Context, scope string, userId int64) error {
 	m.ctrl.T.Helper()
@@ -345,1013 +402,2228 @@ func (mr *MockTokensMockRecorder) DeleteAllForUser(ctx, scope, userId interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllForUser", reflect.TypeOf((*MockTokens)(nil).DeleteAllForUser), ctx, scope, userId)
 }
 
-// Insert mocks base method.
-func (m *MockTokens) Insert(ctx context.Context, token *domain.Token) error {
+// DeleteExpiredForUser mocks base method.
+func (m *MockTokens) DeleteExpiredForUser(ctx context.Context, userId int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Insert", ctx, token)
+	ret := m.ctrl.Call(m, "DeleteExpiredForUser", ctx, userId)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// Insert indicates an expected call of Insert.
-func (mr *MockTokensMockRecorder) Insert(ctx, token interface{}) *gomock.Call {
+// DeleteExpiredForUser indicates an expected call of DeleteExpiredForUser.
+func (mr *MockTokensMockRecorder) DeleteExpiredForUser(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockTokens)(nil).Insert), ctx, token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredForUser", reflect.TypeOf((*MockTokens)(nil).DeleteExpiredForUser), ctx, userId)
 }
 
-// New mocks base method.
-func (m *MockTokens) New(ctx context.Context, userId int64, ttl time.Duration, scope string) (*domain.Token, error) {
+// DeleteFamily mocks base method.
+func (m *MockTokens) DeleteFamily(ctx context.Context, family string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "New", ctx, userId, ttl, scope)
-	ret0, _ := ret[0].(*domain.Token)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "DeleteFamily", ctx, family)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// New indicates an expected call of New.
-func (mr *MockTokensMockRecorder) New(ctx, userId, ttl, scope interface{}) *gomock.Call {
+// DeleteFamily indicates an expected call of DeleteFamily.
+func (mr *MockTokensMockRecorder) DeleteFamily(ctx, family interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "New", reflect.TypeOf((*MockTokens)(nil).New), ctx, userId, ttl, scope)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFamily", reflect.TypeOf((*MockTokens)(nil).DeleteFamily), ctx, family)
 }
 
-// MockManagers is a mock of Managers interface.
-type MockManagers struct {
-	ctrl     *gomock.Controller
-	recorder *MockManagersMockRecorder
+// DeleteOtherSessions mocks base method.
+func (m *MockTokens) DeleteOtherSessions(ctx context.Context, userId int64, family string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOtherSessions", ctx, userId, family)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// MockManagersMockRecorder is the mock recorder for MockManagers.
-type MockManagersMockRecorder struct {
-	mock *MockManagers
+// DeleteOtherSessions indicates an expected call of DeleteOtherSessions.
+func (mr *MockTokensMockRecorder) DeleteOtherSessions(ctx, userId, family interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOtherSessions", reflect.TypeOf((*MockTokens)(nil).DeleteOtherSessions), ctx, userId, family)
 }
 
-// NewMockManagers creates a new mock instance.
-func NewMockManagers(ctrl *gomock.Controller) *MockManagers {
-	mock := &MockManagers{ctrl: ctrl}
-	mock.recorder = &MockManagersMockRecorder{mock}
-	return mock
+// DeleteSession mocks base method.
+func (m *MockTokens) DeleteSession(ctx context.Context, userId int64, family string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, userId, family)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockManagers) EXPECT() *MockManagersMockRecorder {
-	return m.recorder
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockTokensMockRecorder) DeleteSession(ctx, userId, family interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockTokens)(nil).DeleteSession), ctx, userId, family)
 }
 
-// RetrieveById mocks base method.
-func (m *MockManagers) RetrieveById(ctx context.Context, id string) (domain.Manager, error) {
+// GetByPlaintext mocks base method.
+func (m *MockTokens) GetByPlaintext(ctx context.Context, scope, plaintext string) (domain.Token, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Manager)
+	ret := m.ctrl.Call(m, "GetByPlaintext", ctx, scope, plaintext)
+	ret0, _ := ret[0].(domain.Token)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockManagersMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// GetByPlaintext indicates an expected call of GetByPlaintext.
+func (mr *MockTokensMockRecorder) GetByPlaintext(ctx, scope, plaintext interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockManagers)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByPlaintext", reflect.TypeOf((*MockTokens)(nil).GetByPlaintext), ctx, scope, plaintext)
 }
 
-// MockModules is a mock of Modules interface.
-type MockModules struct {
-	ctrl     *gomock.Controller
-	recorder *MockModulesMockRecorder
+// GetSessions mocks base method.
+func (m *MockTokens) GetSessions(ctx context.Context, userId int64) ([]domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", ctx, userId)
+	ret0, _ := ret[0].([]domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockModulesMockRecorder is the mock recorder for MockModules.
-type MockModulesMockRecorder struct {
-	mock *MockModules
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockTokensMockRecorder) GetSessions(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockTokens)(nil).GetSessions), ctx, userId)
 }
 
-// NewMockModules creates a new mock instance.
-func NewMockModules(ctrl *gomock.Controller) *MockModules {
-	mock := &MockModules{ctrl: ctrl}
-	mock.recorder = &MockModulesMockRecorder{mock}
-	return mock
+// Insert mocks base method.
+func (m *MockTokens) Insert(ctx context.Context, token *domain.Token) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockModules) EXPECT() *MockModulesMockRecorder {
-	return m.recorder
+// Insert indicates an expected call of Insert.
+func (mr *MockTokensMockRecorder) Insert(ctx, token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockTokens)(nil).Insert), ctx, token)
 }
 
-// GetModuleInfo mocks base method.
-func (m *MockModules) GetModuleInfo(ctx context.Context, module string) (vtiger.Module, error) {
+// MarkUsed mocks base method.
+func (m *MockTokens) MarkUsed(ctx context.Context, id int64) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetModuleInfo", ctx, module)
-	ret0, _ := ret[0].(vtiger.Module)
+	ret := m.ctrl.Call(m, "MarkUsed", ctx, id)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetModuleInfo indicates an expected call of GetModuleInfo.
-func (mr *MockModulesMockRecorder) GetModuleInfo(ctx, module interface{}) *gomock.Call {
+// MarkUsed indicates an expected call of MarkUsed.
+func (mr *MockTokensMockRecorder) MarkUsed(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleInfo", reflect.TypeOf((*MockModules)(nil).GetModuleInfo), ctx, module)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUsed", reflect.TypeOf((*MockTokens)(nil).MarkUsed), ctx, id)
 }
 
-// MockCompany is a mock of Company interface.
-type MockCompany struct {
-	ctrl     *gomock.Controller
-	recorder *MockCompanyMockRecorder
+// New mocks base method.
+func (m *MockTokens) New(ctx context.Context, userId int64, ttl time.Duration, scope string) (*domain.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "New", ctx, userId, ttl, scope)
+	ret0, _ := ret[0].(*domain.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockCompanyMockRecorder is the mock recorder for MockCompany.
-type MockCompanyMockRecorder struct {
-	mock *MockCompany
+// New indicates an expected call of New.
+func (mr *MockTokensMockRecorder) New(ctx, userId, ttl, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "New", reflect.TypeOf((*MockTokens)(nil).New), ctx, userId, ttl, scope)
 }
 
-// NewMockCompany creates a new mock instance.
-func NewMockCompany(ctrl *gomock.Controller) *MockCompany {
-	mock := &MockCompany{ctrl: ctrl}
-	mock.recorder = &MockCompanyMockRecorder{mock}
-	return mock
+// NewInFamily mocks base method.
+func (m *MockTokens) NewInFamily(ctx context.Context, userId int64, ttl time.Duration, scope, family string) (*domain.Token, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewInFamily", ctx, userId, ttl, scope, family)
+	ret0, _ := ret[0].(*domain.Token)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockCompany) EXPECT() *MockCompanyMockRecorder {
-	return m.recorder
+// NewInFamily indicates an expected call of NewInFamily.
+func (mr *MockTokensMockRecorder) NewInFamily(ctx, userId, ttl, scope, family interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewInFamily", reflect.TypeOf((*MockTokens)(nil).NewInFamily), ctx, userId, ttl, scope, family)
 }
 
-// GetCompanyInfo mocks base method.
-func (m *MockCompany) GetCompanyInfo(ctx context.Context) (domain.Company, error) {
+// Touch mocks base method.
+func (m *MockTokens) Touch(ctx context.Context, plaintext, userAgent, ip string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCompanyInfo", ctx)
-	ret0, _ := ret[0].(domain.Company)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Touch", ctx, plaintext, userAgent, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetCompanyInfo indicates an expected call of GetCompanyInfo.
-func (mr *MockCompanyMockRecorder) GetCompanyInfo(ctx interface{}) *gomock.Call {
+// Touch indicates an expected call of Touch.
+func (mr *MockTokensMockRecorder) Touch(ctx, plaintext, userAgent, ip interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyInfo", reflect.TypeOf((*MockCompany)(nil).GetCompanyInfo), ctx)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockTokens)(nil).Touch), ctx, plaintext, userAgent, ip)
 }
 
-// MockHelpDesk is a mock of HelpDesk interface.
-type MockHelpDesk struct {
+// MockOtp is a mock of Otp interface.
+type MockOtp struct {
 	ctrl     *gomock.Controller
-	recorder *MockHelpDeskMockRecorder
+	recorder *MockOtpMockRecorder
 }
 
-// MockHelpDeskMockRecorder is the mock recorder for MockHelpDesk.
-type MockHelpDeskMockRecorder struct {
-	mock *MockHelpDesk
+// MockOtpMockRecorder is the mock recorder for MockOtp.
+type MockOtpMockRecorder struct {
+	mock *MockOtp
 }
 
-// NewMockHelpDesk creates a new mock instance.
-func NewMockHelpDesk(ctrl *gomock.Controller) *MockHelpDesk {
-	mock := &MockHelpDesk{ctrl: ctrl}
-	mock.recorder = &MockHelpDeskMockRecorder{mock}
+// NewMockOtp creates a new mock instance.
+func NewMockOtp(ctrl *gomock.Controller) *MockOtp {
+	mock := &MockOtp{ctrl: ctrl}
+	mock.recorder = &MockOtpMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockHelpDesk) EXPECT() *MockHelpDeskMockRecorder {
+func (m *MockOtp) EXPECT() *MockOtpMockRecorder {
 	return m.recorder
 }
 
-// Count mocks base method.
-func (m *MockHelpDesk) Count(ctx context.Context, client string) (int, error) {
+// DeleteRecoveryCodes mocks base method.
+func (m *MockOtp) DeleteRecoveryCodes(ctx context.Context, userId int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, client)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "DeleteRecoveryCodes", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockHelpDeskMockRecorder) Count(ctx, client interface{}) *gomock.Call {
+// DeleteRecoveryCodes indicates an expected call of DeleteRecoveryCodes.
+func (mr *MockOtpMockRecorder) DeleteRecoveryCodes(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockHelpDesk)(nil).Count), ctx, client)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCodes", reflect.TypeOf((*MockOtp)(nil).DeleteRecoveryCodes), ctx, userId)
 }
 
-// Create mocks base method.
-func (m *MockHelpDesk) Create(ctx context.Context, ticket domain.HelpDesk) (domain.HelpDesk, error) {
+// GetState mocks base method.
+func (m *MockOtp) GetState(ctx context.Context, userId int64) (domain.OtpState, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, ticket)
-	ret0, _ := ret[0].(domain.HelpDesk)
+	ret := m.ctrl.Call(m, "GetState", ctx, userId)
+	ret0, _ := ret[0].(domain.OtpState)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockHelpDeskMockRecorder) Create(ctx, ticket interface{}) *gomock.Call {
+// GetState indicates an expected call of GetState.
+func (mr *MockOtpMockRecorder) GetState(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockHelpDesk)(nil).Create), ctx, ticket)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetState", reflect.TypeOf((*MockOtp)(nil).GetState), ctx, userId)
 }
 
-// GetAll mocks base method.
-func (m *MockHelpDesk) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.HelpDesk, error) {
+// IncrementFailures mocks base method.
+func (m *MockOtp) IncrementFailures(ctx context.Context, userId int64) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
-	ret0, _ := ret[0].([]domain.HelpDesk)
+	ret := m.ctrl.Call(m, "IncrementFailures", ctx, userId)
+	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockHelpDeskMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+// IncrementFailures indicates an expected call of IncrementFailures.
+func (mr *MockOtpMockRecorder) IncrementFailures(ctx, userId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockHelpDesk)(nil).GetAll), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementFailures", reflect.TypeOf((*MockOtp)(nil).IncrementFailures), ctx, userId)
 }
 
-// RetrieveById mocks base method.
-func (m *MockHelpDesk) RetrieveById(ctx context.Context, id string) (domain.HelpDesk, error) {
+// Lock mocks base method.
+func (m *MockOtp) Lock(ctx context.Context, userId int64, until time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.HelpDesk)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Lock", ctx, userId, until)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockHelpDeskMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// Lock indicates an expected call of Lock.
+func (mr *MockOtpMockRecorder) Lock(ctx, userId, until interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockHelpDesk)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockOtp)(nil).Lock), ctx, userId, until)
 }
 
-// Revise mocks base method.
-func (m *MockHelpDesk) Revise(ctx context.Context, ticket map[string]any) (domain.HelpDesk, error) {
+// ReplaceRecoveryCodes mocks base method.
+func (m *MockOtp) ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Revise", ctx, ticket)
-	ret0, _ := ret[0].(domain.HelpDesk)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "ReplaceRecoveryCodes", ctx, userId, hashes)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Revise indicates an expected call of Revise.
-func (mr *MockHelpDeskMockRecorder) Revise(ctx, ticket interface{}) *gomock.Call {
+// ReplaceRecoveryCodes indicates an expected call of ReplaceRecoveryCodes.
+func (mr *MockOtpMockRecorder) ReplaceRecoveryCodes(ctx, userId, hashes interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revise", reflect.TypeOf((*MockHelpDesk)(nil).Revise), ctx, ticket)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceRecoveryCodes", reflect.TypeOf((*MockOtp)(nil).ReplaceRecoveryCodes), ctx, userId, hashes)
 }
 
-// Update mocks base method.
-func (m *MockHelpDesk) Update(ctx context.Context, ticket domain.HelpDesk) (domain.HelpDesk, error) {
+// ResetFailures mocks base method.
+func (m *MockOtp) ResetFailures(ctx context.Context, userId int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", ctx, ticket)
-	ret0, _ := ret[0].(domain.HelpDesk)
+	ret := m.ctrl.Call(m, "ResetFailures", ctx, userId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetFailures indicates an expected call of ResetFailures.
+func (mr *MockOtpMockRecorder) ResetFailures(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetFailures", reflect.TypeOf((*MockOtp)(nil).ResetFailures), ctx, userId)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockOtp) UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userId, hash)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Update indicates an expected call of Update.
-func (mr *MockHelpDeskMockRecorder) Update(ctx, ticket interface{}) *gomock.Call {
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockOtpMockRecorder) UseRecoveryCode(ctx, userId, hash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockHelpDesk)(nil).Update), ctx, ticket)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockOtp)(nil).UseRecoveryCode), ctx, userId, hash)
 }
 
-// MockComment is a mock of Comment interface.
-type MockComment struct {
+// MockLoginAttempts is a mock of LoginAttempts interface.
+type MockLoginAttempts struct {
 	ctrl     *gomock.Controller
-	recorder *MockCommentMockRecorder
+	recorder *MockLoginAttemptsMockRecorder
 }
 
-// MockCommentMockRecorder is the mock recorder for MockComment.
-type MockCommentMockRecorder struct {
-	mock *MockComment
+// MockLoginAttemptsMockRecorder is the mock recorder for MockLoginAttempts.
+type MockLoginAttemptsMockRecorder struct {
+	mock *MockLoginAttempts
 }
 
-// NewMockComment creates a new mock instance.
-func NewMockComment(ctrl *gomock.Controller) *MockComment {
-	mock := &MockComment{ctrl: ctrl}
-	mock.recorder = &MockCommentMockRecorder{mock}
+// NewMockLoginAttempts creates a new mock instance.
+func NewMockLoginAttempts(ctrl *gomock.Controller) *MockLoginAttempts {
+	mock := &MockLoginAttempts{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptsMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockComment) EXPECT() *MockCommentMockRecorder {
+func (m *MockLoginAttempts) EXPECT() *MockLoginAttemptsMockRecorder {
 	return m.recorder
 }
 
-// Create mocks base method.
-func (m *MockComment) Create(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+// Get mocks base method.
+func (m *MockLoginAttempts) Get(ctx context.Context, key string) (domain.LoginAttempts, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, comment)
-	ret0, _ := ret[0].(domain.Comment)
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(domain.LoginAttempts)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockCommentMockRecorder) Create(ctx, comment interface{}) *gomock.Call {
+// Get indicates an expected call of Get.
+func (mr *MockLoginAttemptsMockRecorder) Get(ctx, key interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockComment)(nil).Create), ctx, comment)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockLoginAttempts)(nil).Get), ctx, key)
 }
 
-// RetrieveFromModule mocks base method.
-func (m *MockComment) RetrieveFromModule(ctx context.Context, id string) ([]domain.Comment, error) {
+// Lock mocks base method.
+func (m *MockLoginAttempts) Lock(ctx context.Context, key string, until time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveFromModule", ctx, id)
-	ret0, _ := ret[0].([]domain.Comment)
+	ret := m.ctrl.Call(m, "Lock", ctx, key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockLoginAttemptsMockRecorder) Lock(ctx, key, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockLoginAttempts)(nil).Lock), ctx, key, until)
+}
+
+// Release mocks base method.
+func (m *MockLoginAttempts) Release(ctx context.Context, key string, lastFailedAt *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, key, lastFailedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockLoginAttemptsMockRecorder) Release(ctx, key, lastFailedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLoginAttempts)(nil).Release), ctx, key, lastFailedAt)
+}
+
+// Reserve mocks base method.
+func (m *MockLoginAttempts) Reserve(ctx context.Context, key string, window time.Duration, allow func(domain.LoginAttempts) error) (domain.LoginAttempts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, key, window, allow)
+	ret0, _ := ret[0].(domain.LoginAttempts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockLoginAttemptsMockRecorder) Reserve(ctx, key, window, allow interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockLoginAttempts)(nil).Reserve), ctx, key, window, allow)
+}
+
+// Reset mocks base method.
+func (m *MockLoginAttempts) Reset(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLoginAttemptsMockRecorder) Reset(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLoginAttempts)(nil).Reset), ctx, key)
+}
+
+// MockAudit is a mock of Audit interface.
+type MockAudit struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditMockRecorder
+}
+
+// MockAuditMockRecorder is the mock recorder for MockAudit.
+type MockAuditMockRecorder struct {
+	mock *MockAudit
+}
+
+// NewMockAudit creates a new mock instance.
+func NewMockAudit(ctrl *gomock.Controller) *MockAudit {
+	mock := &MockAudit{ctrl: ctrl}
+	mock.recorder = &MockAuditMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAudit) EXPECT() *MockAuditMockRecorder {
+	return m.recorder
+}
+
+// GetAll mocks base method.
+func (m *MockAudit) GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.AuditEntry)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockAuditMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockAudit)(nil).GetAll), ctx, filter)
+}
+
+// Insert mocks base method.
+func (m *MockAudit) Insert(ctx context.Context, entry *domain.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockAuditMockRecorder) Insert(ctx, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockAudit)(nil).Insert), ctx, entry)
+}
+
+// MockWebauthnCredentials is a mock of WebauthnCredentials interface.
+type MockWebauthnCredentials struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebauthnCredentialsMockRecorder
+}
+
+// MockWebauthnCredentialsMockRecorder is the mock recorder for MockWebauthnCredentials.
+type MockWebauthnCredentialsMockRecorder struct {
+	mock *MockWebauthnCredentials
+}
+
+// NewMockWebauthnCredentials creates a new mock instance.
+func NewMockWebauthnCredentials(ctrl *gomock.Controller) *MockWebauthnCredentials {
+	mock := &MockWebauthnCredentials{ctrl: ctrl}
+	mock.recorder = &MockWebauthnCredentialsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebauthnCredentials) EXPECT() *MockWebauthnCredentialsMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockWebauthnCredentials) Delete(ctx context.Context, userId, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, userId, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockWebauthnCredentialsMockRecorder) Delete(ctx, userId, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebauthnCredentials)(nil).Delete), ctx, userId, id)
+}
+
+// GetAllByUser mocks base method.
+func (m *MockWebauthnCredentials) GetAllByUser(ctx context.Context, userId int64) ([]domain.WebauthnCredential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllByUser", ctx, userId)
+	ret0, _ := ret[0].([]domain.WebauthnCredential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllByUser indicates an expected call of GetAllByUser.
+func (mr *MockWebauthnCredentialsMockRecorder) GetAllByUser(ctx, userId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByUser", reflect.TypeOf((*MockWebauthnCredentials)(nil).GetAllByUser), ctx, userId)
+}
+
+// Insert mocks base method.
+func (m *MockWebauthnCredentials) Insert(ctx context.Context, credential *domain.WebauthnCredential) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, credential)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Insert indicates an expected call of Insert.
+func (mr *MockWebauthnCredentialsMockRecorder) Insert(ctx, credential interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockWebauthnCredentials)(nil).Insert), ctx, credential)
+}
+
+// UpdateUsage mocks base method.
+func (m *MockWebauthnCredentials) UpdateUsage(ctx context.Context, id int64, signCount uint32, cloneWarning bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUsage", ctx, id, signCount, cloneWarning)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUsage indicates an expected call of UpdateUsage.
+func (mr *MockWebauthnCredentialsMockRecorder) UpdateUsage(ctx, id, signCount, cloneWarning interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUsage", reflect.TypeOf((*MockWebauthnCredentials)(nil).UpdateUsage), ctx, id, signCount, cloneWarning)
+}
+
+// MockManagers is a mock of Managers interface.
+type MockManagers struct {
+	ctrl     *gomock.Controller
+	recorder *MockManagersMockRecorder
+}
+
+// MockManagersMockRecorder is the mock recorder for MockManagers.
+type MockManagersMockRecorder struct {
+	mock *MockManagers
+}
+
+// NewMockManagers creates a new mock instance.
+func NewMockManagers(ctrl *gomock.Controller) *MockManagers {
+	mock := &MockManagers{ctrl: ctrl}
+	mock.recorder = &MockManagersMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockManagers) EXPECT() *MockManagersMockRecorder {
+	return m.recorder
+}
+
+// RetrieveById mocks base method.
+func (m *MockManagers) RetrieveById(ctx context.Context, id string) (domain.Manager, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Manager)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockManagersMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockManagers)(nil).RetrieveById), ctx, id)
+}
+
+// MockModules is a mock of Modules interface.
+type MockModules struct {
+	ctrl     *gomock.Controller
+	recorder *MockModulesMockRecorder
+}
+
+// MockModulesMockRecorder is the mock recorder for MockModules.
+type MockModulesMockRecorder struct {
+	mock *MockModules
+}
+
+// NewMockModules creates a new mock instance.
+func NewMockModules(ctrl *gomock.Controller) *MockModules {
+	mock := &MockModules{ctrl: ctrl}
+	mock.recorder = &MockModulesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModules) EXPECT() *MockModulesMockRecorder {
+	return m.recorder
+}
+
+// GetModuleInfo mocks base method.
+func (m *MockModules) GetModuleInfo(ctx context.Context, module string) (vtiger.Module, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetModuleInfo", ctx, module)
+	ret0, _ := ret[0].(vtiger.Module)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetModuleInfo indicates an expected call of GetModuleInfo.
+func (mr *MockModulesMockRecorder) GetModuleInfo(ctx, module interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleInfo", reflect.TypeOf((*MockModules)(nil).GetModuleInfo), ctx, module)
+}
+
+// MockCompany is a mock of Company interface.
+type MockCompany struct {
+	ctrl     *gomock.Controller
+	recorder *MockCompanyMockRecorder
+}
+
+// MockCompanyMockRecorder is the mock recorder for MockCompany.
+type MockCompanyMockRecorder struct {
+	mock *MockCompany
+}
+
+// NewMockCompany creates a new mock instance.
+func NewMockCompany(ctrl *gomock.Controller) *MockCompany {
+	mock := &MockCompany{ctrl: ctrl}
+	mock.recorder = &MockCompanyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCompany) EXPECT() *MockCompanyMockRecorder {
+	return m.recorder
+}
+
+// GetCompanyInfo mocks base method.
+func (m *MockCompany) GetCompanyInfo(ctx context.Context) (domain.Company, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCompanyInfo", ctx)
+	ret0, _ := ret[0].(domain.Company)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCompanyInfo indicates an expected call of GetCompanyInfo.
+func (mr *MockCompanyMockRecorder) GetCompanyInfo(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCompanyInfo", reflect.TypeOf((*MockCompany)(nil).GetCompanyInfo), ctx)
+}
+
+// MockHelpDesk is a mock of HelpDesk interface.
+type MockHelpDesk struct {
+	ctrl     *gomock.Controller
+	recorder *MockHelpDeskMockRecorder
+}
+
+// MockHelpDeskMockRecorder is the mock recorder for MockHelpDesk.
+type MockHelpDeskMockRecorder struct {
+	mock *MockHelpDesk
+}
+
+// NewMockHelpDesk creates a new mock instance.
+func NewMockHelpDesk(ctrl *gomock.Controller) *MockHelpDesk {
+	mock := &MockHelpDesk{ctrl: ctrl}
+	mock.recorder = &MockHelpDeskMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHelpDesk) EXPECT() *MockHelpDeskMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockHelpDesk) Count(ctx context.Context, client string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, client)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockHelpDeskMockRecorder) Count(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockHelpDesk)(nil).Count), ctx, client)
+}
+
+// Create mocks base method.
+func (m *MockHelpDesk) Create(ctx context.Context, ticket domain.HelpDesk) (domain.HelpDesk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, ticket)
+	ret0, _ := ret[0].(domain.HelpDesk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockHelpDeskMockRecorder) Create(ctx, ticket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockHelpDesk)(nil).Create), ctx, ticket)
+}
+
+// GetAll mocks base method.
+func (m *MockHelpDesk) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.HelpDesk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.HelpDesk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockHelpDeskMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockHelpDesk)(nil).GetAll), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockHelpDesk) RetrieveById(ctx context.Context, id string) (domain.HelpDesk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.HelpDesk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockHelpDeskMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockHelpDesk)(nil).RetrieveById), ctx, id)
+}
+
+// Revise mocks base method.
+func (m *MockHelpDesk) Revise(ctx context.Context, ticket map[string]any) (domain.HelpDesk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revise", ctx, ticket)
+	ret0, _ := ret[0].(domain.HelpDesk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revise indicates an expected call of Revise.
+func (mr *MockHelpDeskMockRecorder) Revise(ctx, ticket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revise", reflect.TypeOf((*MockHelpDesk)(nil).Revise), ctx, ticket)
+}
+
+// Update mocks base method.
+func (m *MockHelpDesk) Update(ctx context.Context, ticket domain.HelpDesk) (domain.HelpDesk, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, ticket)
+	ret0, _ := ret[0].(domain.HelpDesk)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockHelpDeskMockRecorder) Update(ctx, ticket interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockHelpDesk)(nil).Update), ctx, ticket)
+}
+
+// MockComment is a mock of Comment interface.
+type MockComment struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommentMockRecorder
+}
+
+// MockCommentMockRecorder is the mock recorder for MockComment.
+type MockCommentMockRecorder struct {
+	mock *MockComment
+}
+
+// NewMockComment creates a new mock instance.
+func NewMockComment(ctrl *gomock.Controller) *MockComment {
+	mock := &MockComment{ctrl: ctrl}
+	mock.recorder = &MockCommentMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockComment) EXPECT() *MockCommentMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockComment) Create(ctx context.Context, comment domain.Comment) (domain.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, comment)
+	ret0, _ := ret[0].(domain.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockCommentMockRecorder) Create(ctx, comment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockComment)(nil).Create), ctx, comment)
+}
+
+// RetrieveFromModule mocks base method.
+func (m *MockComment) RetrieveFromModule(ctx context.Context, id string) ([]domain.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveFromModule", ctx, id)
+	ret0, _ := ret[0].([]domain.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveFromModule indicates an expected call of RetrieveFromModule.
+func (mr *MockCommentMockRecorder) RetrieveFromModule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveFromModule", reflect.TypeOf((*MockComment)(nil).RetrieveFromModule), ctx, id)
+}
+
+// MockLead is a mock of Lead interface.
+type MockLead struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeadMockRecorder
+}
+
+// MockLeadMockRecorder is the mock recorder for MockLead.
+type MockLeadMockRecorder struct {
+	mock *MockLead
+}
+
+// NewMockLead creates a new mock instance.
+func NewMockLead(ctrl *gomock.Controller) *MockLead {
+	mock := &MockLead{ctrl: ctrl}
+	mock.recorder = &MockLeadMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLead) EXPECT() *MockLeadMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockLead) Create(ctx context.Context, comment domain.Lead) (domain.Lead, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, comment)
+	ret0, _ := ret[0].(domain.Lead)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockLeadMockRecorder) Create(ctx, comment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLead)(nil).Create), ctx, comment)
+}
+
+// MockDocument is a mock of Document interface.
+type MockDocument struct {
+	ctrl     *gomock.Controller
+	recorder *MockDocumentMockRecorder
+}
+
+// MockDocumentMockRecorder is the mock recorder for MockDocument.
+type MockDocumentMockRecorder struct {
+	mock *MockDocument
+}
+
+// NewMockDocument creates a new mock instance.
+func NewMockDocument(ctrl *gomock.Controller) *MockDocument {
+	mock := &MockDocument{ctrl: ctrl}
+	mock.recorder = &MockDocumentMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDocument) EXPECT() *MockDocumentMockRecorder {
+	return m.recorder
+}
+
+// AttachFile mocks base method.
+func (m *MockDocument) AttachFile(ctx context.Context, doc domain.Document, parent string) (domain.Document, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttachFile", ctx, doc, parent)
+	ret0, _ := ret[0].(domain.Document)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AttachFile indicates an expected call of AttachFile.
+func (mr *MockDocumentMockRecorder) AttachFile(ctx, doc, parent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachFile", reflect.TypeOf((*MockDocument)(nil).AttachFile), ctx, doc, parent)
+}
+
+// DeleteFile mocks base method.
+func (m *MockDocument) DeleteFile(ctx context.Context, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFile", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFile indicates an expected call of DeleteFile.
+func (mr *MockDocumentMockRecorder) DeleteFile(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDocument)(nil).DeleteFile), ctx, id)
+}
+
+// RetrieveFile mocks base method.
+func (m *MockDocument) RetrieveFile(ctx context.Context, id string) (vtiger.File, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveFile", ctx, id)
+	ret0, _ := ret[0].(vtiger.File)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveFile indicates an expected call of RetrieveFile.
+func (mr *MockDocumentMockRecorder) RetrieveFile(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveFile", reflect.TypeOf((*MockDocument)(nil).RetrieveFile), ctx, id)
+}
+
+// RetrieveFromModule mocks base method.
+func (m *MockDocument) RetrieveFromModule(ctx context.Context, id string) ([]domain.Document, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveFromModule", ctx, id)
+	ret0, _ := ret[0].([]domain.Document)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveFromModule indicates an expected call of RetrieveFromModule.
+func (mr *MockDocumentMockRecorder) RetrieveFromModule(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveFromModule", reflect.TypeOf((*MockDocument)(nil).RetrieveFromModule), ctx, id)
+}
+
+// MockFaq is a mock of Faq interface.
+type MockFaq struct {
+	ctrl     *gomock.Controller
+	recorder *MockFaqMockRecorder
+}
+
+// MockFaqMockRecorder is the mock recorder for MockFaq.
+type MockFaqMockRecorder struct {
+	mock *MockFaq
+}
+
+// NewMockFaq creates a new mock instance.
+func NewMockFaq(ctrl *gomock.Controller) *MockFaq {
+	mock := &MockFaq{ctrl: ctrl}
+	mock.recorder = &MockFaqMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFaq) EXPECT() *MockFaqMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockFaq) Count(ctx context.Context, client string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, client)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockFaqMockRecorder) Count(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockFaq)(nil).Count), ctx, client)
+}
+
+// GetAllFaqs mocks base method.
+func (m *MockFaq) GetAllFaqs(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Faq, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllFaqs", ctx, filter)
+	ret0, _ := ret[0].([]domain.Faq)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllFaqs indicates an expected call of GetAllFaqs.
+func (mr *MockFaqMockRecorder) GetAllFaqs(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllFaqs", reflect.TypeOf((*MockFaq)(nil).GetAllFaqs), ctx, filter)
+}
+
+// MockInvoice is a mock of Invoice interface.
+type MockInvoice struct {
+	ctrl     *gomock.Controller
+	recorder *MockInvoiceMockRecorder
+}
+
+// MockInvoiceMockRecorder is the mock recorder for MockInvoice.
+type MockInvoiceMockRecorder struct {
+	mock *MockInvoice
+}
+
+// NewMockInvoice creates a new mock instance.
+func NewMockInvoice(ctrl *gomock.Controller) *MockInvoice {
+	mock := &MockInvoice{ctrl: ctrl}
+	mock.recorder = &MockInvoiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockInvoice) EXPECT() *MockInvoiceMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockInvoice) Count(ctx context.Context, client string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, client)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockInvoiceMockRecorder) Count(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockInvoice)(nil).Count), ctx, client)
+}
+
+// Export mocks base method.
+func (m *MockInvoice) Export(ctx context.Context, client string, fn func(domain.Invoice) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", ctx, client, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockInvoiceMockRecorder) Export(ctx, client, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockInvoice)(nil).Export), ctx, client, fn)
+}
+
+// GetAll mocks base method.
+func (m *MockInvoice) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockInvoiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockInvoice)(nil).GetAll), ctx, filter)
+}
+
+// GetFromSalesOrder mocks base method.
+func (m *MockInvoice) GetFromSalesOrder(ctx context.Context, soId string) ([]domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFromSalesOrder", ctx, soId)
+	ret0, _ := ret[0].([]domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFromSalesOrder indicates an expected call of GetFromSalesOrder.
+func (mr *MockInvoiceMockRecorder) GetFromSalesOrder(ctx, soId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromSalesOrder", reflect.TypeOf((*MockInvoice)(nil).GetFromSalesOrder), ctx, soId)
+}
+
+// RetrieveById mocks base method.
+func (m *MockInvoice) RetrieveById(ctx context.Context, id string) (domain.Invoice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Invoice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockInvoiceMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockInvoice)(nil).RetrieveById), ctx, id)
+}
+
+// MockSalesOrder is a mock of SalesOrder interface.
+type MockSalesOrder struct {
+	ctrl     *gomock.Controller
+	recorder *MockSalesOrderMockRecorder
+}
+
+// MockSalesOrderMockRecorder is the mock recorder for MockSalesOrder.
+type MockSalesOrderMockRecorder struct {
+	mock *MockSalesOrder
+}
+
+// NewMockSalesOrder creates a new mock instance.
+func NewMockSalesOrder(ctrl *gomock.Controller) *MockSalesOrder {
+	mock := &MockSalesOrder{ctrl: ctrl}
+	mock.recorder = &MockSalesOrderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSalesOrder) EXPECT() *MockSalesOrderMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockSalesOrder) Count(ctx context.Context, client string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, client)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockSalesOrderMockRecorder) Count(ctx, client interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockSalesOrder)(nil).Count), ctx, client)
+}
+
+// GetAll mocks base method.
+func (m *MockSalesOrder) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.SalesOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.SalesOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockSalesOrderMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockSalesOrder)(nil).GetAll), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockSalesOrder) RetrieveById(ctx context.Context, id string) (domain.SalesOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.SalesOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockSalesOrderMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockSalesOrder)(nil).RetrieveById), ctx, id)
+}
+
+// MockServiceContract is a mock of ServiceContract interface.
+type MockServiceContract struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceContractMockRecorder
+}
+
+// MockServiceContractMockRecorder is the mock recorder for MockServiceContract.
+type MockServiceContractMockRecorder struct {
+	mock *MockServiceContract
+}
+
+// NewMockServiceContract creates a new mock instance.
+func NewMockServiceContract(ctrl *gomock.Controller) *MockServiceContract {
+	mock := &MockServiceContract{ctrl: ctrl}
+	mock.recorder = &MockServiceContractMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServiceContract) EXPECT() *MockServiceContractMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockServiceContract) Count(ctx context.Context, client, contact string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, client, contact)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockServiceContractMockRecorder) Count(ctx, client, contact interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockServiceContract)(nil).Count), ctx, client, contact)
+}
+
+// GetAll mocks base method.
+func (m *MockServiceContract) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.ServiceContract, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.ServiceContract)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockServiceContractMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockServiceContract)(nil).GetAll), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockServiceContract) RetrieveById(ctx context.Context, id string) (domain.ServiceContract, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.ServiceContract)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockServiceContractMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockServiceContract)(nil).RetrieveById), ctx, id)
+}
+
+// MockCurrency is a mock of Currency interface.
+type MockCurrency struct {
+	ctrl     *gomock.Controller
+	recorder *MockCurrencyMockRecorder
+}
+
+// MockCurrencyMockRecorder is the mock recorder for MockCurrency.
+type MockCurrencyMockRecorder struct {
+	mock *MockCurrency
+}
+
+// NewMockCurrency creates a new mock instance.
+func NewMockCurrency(ctrl *gomock.Controller) *MockCurrency {
+	mock := &MockCurrency{ctrl: ctrl}
+	mock.recorder = &MockCurrencyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCurrency) EXPECT() *MockCurrencyMockRecorder {
+	return m.recorder
+}
+
+// RetrieveById mocks base method.
+func (m *MockCurrency) RetrieveById(ctx context.Context, id string) (domain.Currency, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Currency)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockCurrencyMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockCurrency)(nil).RetrieveById), ctx, id)
+}
+
+// MockProduct is a mock of Product interface.
+type MockProduct struct {
+	ctrl     *gomock.Controller
+	recorder *MockProductMockRecorder
+}
+
+// MockProductMockRecorder is the mock recorder for MockProduct.
+type MockProductMockRecorder struct {
+	mock *MockProduct
+}
+
+// NewMockProduct creates a new mock instance.
+func NewMockProduct(ctrl *gomock.Controller) *MockProduct {
+	mock := &MockProduct{ctrl: ctrl}
+	mock.recorder = &MockProductMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProduct) EXPECT() *MockProductMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockProduct) Count(ctx context.Context, filters map[string]any) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filters)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockProductMockRecorder) Count(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockProduct)(nil).Count), ctx, filters)
+}
+
+// GetAll mocks base method.
+func (m *MockProduct) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockProductMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockProduct)(nil).GetAll), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockProduct) RetrieveById(ctx context.Context, id string) (domain.Product, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Product)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockProductMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockProduct)(nil).RetrieveById), ctx, id)
+}
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockService) Count(ctx context.Context, filters map[string]any) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, filters)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockServiceMockRecorder) Count(ctx, filters interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockService)(nil).Count), ctx, filters)
+}
+
+// GetAll mocks base method.
+func (m *MockService) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Service, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.Service)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockServiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockService)(nil).GetAll), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockService) RetrieveById(ctx context.Context, id string) (domain.Service, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Service)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockServiceMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockService)(nil).RetrieveById), ctx, id)
+}
+
+// MockProject is a mock of Project interface.
+type MockProject struct {
+	ctrl     *gomock.Controller
+	recorder *MockProjectMockRecorder
+}
+
+// MockProjectMockRecorder is the mock recorder for MockProject.
+type MockProjectMockRecorder struct {
+	mock *MockProject
+}
+
+// NewMockProject creates a new mock instance.
+func NewMockProject(ctrl *gomock.Controller) *MockProject {
+	mock := &MockProject{ctrl: ctrl}
+	mock.recorder = &MockProjectMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProject) EXPECT() *MockProjectMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockProject) Count(ctx context.Context, client, contact string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, client, contact)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockProjectMockRecorder) Count(ctx, client, contact interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockProject)(nil).Count), ctx, client, contact)
+}
+
+// GetAll mocks base method.
+func (m *MockProject) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
+	ret0, _ := ret[0].([]domain.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAll indicates an expected call of GetAll.
+func (mr *MockProjectMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockProject)(nil).GetAll), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockProject) RetrieveById(ctx context.Context, id string) (domain.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockProjectMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockProject)(nil).RetrieveById), ctx, id)
+}
+
+// MockProjectTask is a mock of ProjectTask interface.
+type MockProjectTask struct {
+	ctrl     *gomock.Controller
+	recorder *MockProjectTaskMockRecorder
+}
+
+// MockProjectTaskMockRecorder is the mock recorder for MockProjectTask.
+type MockProjectTaskMockRecorder struct {
+	mock *MockProjectTask
+}
+
+// NewMockProjectTask creates a new mock instance.
+func NewMockProjectTask(ctrl *gomock.Controller) *MockProjectTask {
+	mock := &MockProjectTask{ctrl: ctrl}
+	mock.recorder = &MockProjectTaskMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProjectTask) EXPECT() *MockProjectTaskMockRecorder {
+	return m.recorder
+}
+
+// Count mocks base method.
+func (m *MockProjectTask) Count(ctx context.Context, parent string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, parent)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockProjectTaskMockRecorder) Count(ctx, parent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockProjectTask)(nil).Count), ctx, parent)
+}
+
+// Create mocks base method.
+func (m *MockProjectTask) Create(ctx context.Context, task domain.ProjectTask) (domain.ProjectTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, task)
+	ret0, _ := ret[0].(domain.ProjectTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockProjectTaskMockRecorder) Create(ctx, task interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockProjectTask)(nil).Create), ctx, task)
+}
+
+// GetFromProject mocks base method.
+func (m *MockProjectTask) GetFromProject(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.ProjectTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFromProject", ctx, filter)
+	ret0, _ := ret[0].([]domain.ProjectTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFromProject indicates an expected call of GetFromProject.
+func (mr *MockProjectTaskMockRecorder) GetFromProject(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromProject", reflect.TypeOf((*MockProjectTask)(nil).GetFromProject), ctx, filter)
+}
+
+// RetrieveById mocks base method.
+func (m *MockProjectTask) RetrieveById(ctx context.Context, id string) (domain.ProjectTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.ProjectTask)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveFromModule indicates an expected call of RetrieveFromModule.
-func (mr *MockCommentMockRecorder) RetrieveFromModule(ctx, id interface{}) *gomock.Call {
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockProjectTaskMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveFromModule", reflect.TypeOf((*MockComment)(nil).RetrieveFromModule), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockProjectTask)(nil).RetrieveById), ctx, id)
 }
 
-// MockLead is a mock of Lead interface.
-type MockLead struct {
+// Revise mocks base method.
+func (m *MockProjectTask) Revise(ctx context.Context, task map[string]any) (domain.ProjectTask, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revise", ctx, task)
+	ret0, _ := ret[0].(domain.ProjectTask)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revise indicates an expected call of Revise.
+func (mr *MockProjectTaskMockRecorder) Revise(ctx, task interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revise", reflect.TypeOf((*MockProjectTask)(nil).Revise), ctx, task)
+}
+
+// MockAccount is a mock of Account interface.
+type MockAccount struct {
 	ctrl     *gomock.Controller
-	recorder *MockLeadMockRecorder
+	recorder *MockAccountMockRecorder
 }
 
-// MockLeadMockRecorder is the mock recorder for MockLead.
-type MockLeadMockRecorder struct {
-	mock *MockLead
+// MockAccountMockRecorder is the mock recorder for MockAccount.
+type MockAccountMockRecorder struct {
+	mock *MockAccount
 }
 
-// NewMockLead creates a new mock instance.
-func NewMockLead(ctrl *gomock.Controller) *MockLead {
-	mock := &MockLead{ctrl: ctrl}
-	mock.recorder = &MockLeadMockRecorder{mock}
+// NewMockAccount creates a new mock instance.
+func NewMockAccount(ctrl *gomock.Controller) *MockAccount {
+	mock := &MockAccount{ctrl: ctrl}
+	mock.recorder = &MockAccountMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockLead) EXPECT() *MockLeadMockRecorder {
+func (m *MockAccount) EXPECT() *MockAccountMockRecorder {
 	return m.recorder
 }
 
-// Create mocks base method.
-func (m *MockLead) Create(ctx context.Context, comment domain.Lead) (domain.Lead, error) {
+// RetrieveById mocks base method.
+func (m *MockAccount) RetrieveById(ctx context.Context, id string) (domain.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, comment)
-	ret0, _ := ret[0].(domain.Lead)
+	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
+	ret0, _ := ret[0].(domain.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockLeadMockRecorder) Create(ctx, comment interface{}) *gomock.Call {
+// RetrieveById indicates an expected call of RetrieveById.
+func (mr *MockAccountMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLead)(nil).Create), ctx, comment)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockAccount)(nil).RetrieveById), ctx, id)
 }
 
-// MockDocument is a mock of Document interface.
-type MockDocument struct {
+// MockMirror is a mock of Mirror interface.
+type MockMirror struct {
 	ctrl     *gomock.Controller
-	recorder *MockDocumentMockRecorder
+	recorder *MockMirrorMockRecorder
 }
 
-// MockDocumentMockRecorder is the mock recorder for MockDocument.
-type MockDocumentMockRecorder struct {
-	mock *MockDocument
+// MockMirrorMockRecorder is the mock recorder for MockMirror.
+type MockMirrorMockRecorder struct {
+	mock *MockMirror
 }
 
-// NewMockDocument creates a new mock instance.
-func NewMockDocument(ctrl *gomock.Controller) *MockDocument {
-	mock := &MockDocument{ctrl: ctrl}
-	mock.recorder = &MockDocumentMockRecorder{mock}
+// NewMockMirror creates a new mock instance.
+func NewMockMirror(ctrl *gomock.Controller) *MockMirror {
+	mock := &MockMirror{ctrl: ctrl}
+	mock.recorder = &MockMirrorMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockDocument) EXPECT() *MockDocumentMockRecorder {
+func (m *MockMirror) EXPECT() *MockMirrorMockRecorder {
 	return m.recorder
 }
 
-// AttachFile mocks base method.
-func (m *MockDocument) AttachFile(ctx context.Context, doc domain.Document, parent string) (domain.Document, error) {
+// Count mocks base method.
+func (m *MockMirror) Count(ctx context.Context, module, client, contact, parent string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AttachFile", ctx, doc, parent)
-	ret0, _ := ret[0].(domain.Document)
+	ret := m.ctrl.Call(m, "Count", ctx, module, client, contact, parent)
+	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AttachFile indicates an expected call of AttachFile.
-func (mr *MockDocumentMockRecorder) AttachFile(ctx, doc, parent interface{}) *gomock.Call {
+// Count indicates an expected call of Count.
+func (mr *MockMirrorMockRecorder) Count(ctx, module, client, contact, parent interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachFile", reflect.TypeOf((*MockDocument)(nil).AttachFile), ctx, doc, parent)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockMirror)(nil).Count), ctx, module, client, contact, parent)
 }
 
-// DeleteFile mocks base method.
-func (m *MockDocument) DeleteFile(ctx context.Context, id string) error {
+// Delete mocks base method.
+func (m *MockMirror) Delete(ctx context.Context, ids []string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteFile", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, ids)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteFile indicates an expected call of DeleteFile.
-func (mr *MockDocumentMockRecorder) DeleteFile(ctx, id interface{}) *gomock.Call {
+// Delete indicates an expected call of Delete.
+func (mr *MockMirrorMockRecorder) Delete(ctx, ids interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDocument)(nil).DeleteFile), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockMirror)(nil).Delete), ctx, ids)
 }
 
-// RetrieveFile mocks base method.
-func (m *MockDocument) RetrieveFile(ctx context.Context, id string) (vtiger.File, error) {
+// Find mocks base method.
+func (m *MockMirror) Find(ctx context.Context, module string, filter vtiger.PaginationQueryFilter, fields vtiger.QueryFieldsProps) ([]domain.MirrorRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveFile", ctx, id)
-	ret0, _ := ret[0].(vtiger.File)
+	ret := m.ctrl.Call(m, "Find", ctx, module, filter, fields)
+	ret0, _ := ret[0].([]domain.MirrorRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveFile indicates an expected call of RetrieveFile.
-func (mr *MockDocumentMockRecorder) RetrieveFile(ctx, id interface{}) *gomock.Call {
+// Find indicates an expected call of Find.
+func (mr *MockMirrorMockRecorder) Find(ctx, module, filter, fields interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveFile", reflect.TypeOf((*MockDocument)(nil).RetrieveFile), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockMirror)(nil).Find), ctx, module, filter, fields)
 }
 
-// RetrieveFromModule mocks base method.
-func (m *MockDocument) RetrieveFromModule(ctx context.Context, id string) ([]domain.Document, error) {
+// GetById mocks base method.
+func (m *MockMirror) GetById(ctx context.Context, module, id string) (domain.MirrorRecord, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveFromModule", ctx, id)
-	ret0, _ := ret[0].([]domain.Document)
+	ret := m.ctrl.Call(m, "GetById", ctx, module, id)
+	ret0, _ := ret[0].(domain.MirrorRecord)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveFromModule indicates an expected call of RetrieveFromModule.
-func (mr *MockDocumentMockRecorder) RetrieveFromModule(ctx, id interface{}) *gomock.Call {
+// GetById indicates an expected call of GetById.
+func (mr *MockMirrorMockRecorder) GetById(ctx, module, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveFromModule", reflect.TypeOf((*MockDocument)(nil).RetrieveFromModule), ctx, id)
-}
-
-// MockFaq is a mock of Faq interface.
-type MockFaq struct {
-	ctrl     *gomock.Controller
-	recorder *MockFaqMockRecorder
-}
-
-// MockFaqMockRecorder is the mock recorder for MockFaq.
-type MockFaqMockRecorder struct {
-	mock *MockFaq
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockMirror)(nil).GetById), ctx, module, id)
 }
 
-// NewMockFaq creates a new mock instance.
-func NewMockFaq(ctrl *gomock.Controller) *MockFaq {
-	mock := &MockFaq{ctrl: ctrl}
-	mock.recorder = &MockFaqMockRecorder{mock}
-	return mock
+// GetSyncState mocks base method.
+func (m *MockMirror) GetSyncState(ctx context.Context, module string) (domain.SyncState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSyncState", ctx, module)
+	ret0, _ := ret[0].(domain.SyncState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockFaq) EXPECT() *MockFaqMockRecorder {
-	return m.recorder
+// GetSyncState indicates an expected call of GetSyncState.
+func (mr *MockMirrorMockRecorder) GetSyncState(ctx, module interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSyncState", reflect.TypeOf((*MockMirror)(nil).GetSyncState), ctx, module)
 }
 
-// Count mocks base method.
-func (m *MockFaq) Count(ctx context.Context, client string) (int, error) {
+// SaveSyncState mocks base method.
+func (m *MockMirror) SaveSyncState(ctx context.Context, state domain.SyncState) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, client)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "SaveSyncState", ctx, state)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockFaqMockRecorder) Count(ctx, client interface{}) *gomock.Call {
+// SaveSyncState indicates an expected call of SaveSyncState.
+func (mr *MockMirrorMockRecorder) SaveSyncState(ctx, state interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockFaq)(nil).Count), ctx, client)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveSyncState", reflect.TypeOf((*MockMirror)(nil).SaveSyncState), ctx, state)
 }
 
-// GetAllFaqs mocks base method.
-func (m *MockFaq) GetAllFaqs(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Faq, error) {
+// Upsert mocks base method.
+func (m *MockMirror) Upsert(ctx context.Context, record domain.MirrorRecord) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllFaqs", ctx, filter)
-	ret0, _ := ret[0].([]domain.Faq)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Upsert", ctx, record)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetAllFaqs indicates an expected call of GetAllFaqs.
-func (mr *MockFaqMockRecorder) GetAllFaqs(ctx, filter interface{}) *gomock.Call {
+// Upsert indicates an expected call of Upsert.
+func (mr *MockMirrorMockRecorder) Upsert(ctx, record interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllFaqs", reflect.TypeOf((*MockFaq)(nil).GetAllFaqs), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockMirror)(nil).Upsert), ctx, record)
 }
 
-// MockInvoice is a mock of Invoice interface.
-type MockInvoice struct {
+// MockSync is a mock of Sync interface.
+type MockSync struct {
 	ctrl     *gomock.Controller
-	recorder *MockInvoiceMockRecorder
+	recorder *MockSyncMockRecorder
 }
 
-// MockInvoiceMockRecorder is the mock recorder for MockInvoice.
-type MockInvoiceMockRecorder struct {
-	mock *MockInvoice
+// MockSyncMockRecorder is the mock recorder for MockSync.
+type MockSyncMockRecorder struct {
+	mock *MockSync
 }
 
-// NewMockInvoice creates a new mock instance.
-func NewMockInvoice(ctrl *gomock.Controller) *MockInvoice {
-	mock := &MockInvoice{ctrl: ctrl}
-	mock.recorder = &MockInvoiceMockRecorder{mock}
+// NewMockSync creates a new mock instance.
+func NewMockSync(ctrl *gomock.Controller) *MockSync {
+	mock := &MockSync{ctrl: ctrl}
+	mock.recorder = &MockSyncMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockInvoice) EXPECT() *MockInvoiceMockRecorder {
+func (m *MockSync) EXPECT() *MockSyncMockRecorder {
 	return m.recorder
 }
 
-// Count mocks base method.
-func (m *MockInvoice) Count(ctx context.Context, client string) (int, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, client)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// Count indicates an expected call of Count.
-func (mr *MockInvoiceMockRecorder) Count(ctx, client interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockInvoice)(nil).Count), ctx, client)
-}
-
-// GetAll mocks base method.
-func (m *MockInvoice) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Invoice, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
-	ret0, _ := ret[0].([]domain.Invoice)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// GetAll indicates an expected call of GetAll.
-func (mr *MockInvoiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockInvoice)(nil).GetAll), ctx, filter)
-}
-
-// GetFromSalesOrder mocks base method.
-func (m *MockInvoice) GetFromSalesOrder(ctx context.Context, soId string) ([]domain.Invoice, error) {
+// Retrieve mocks base method.
+func (m *MockSync) Retrieve(ctx context.Context, id string) (map[string]any, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFromSalesOrder", ctx, soId)
-	ret0, _ := ret[0].([]domain.Invoice)
+	ret := m.ctrl.Call(m, "Retrieve", ctx, id)
+	ret0, _ := ret[0].(map[string]any)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetFromSalesOrder indicates an expected call of GetFromSalesOrder.
-func (mr *MockInvoiceMockRecorder) GetFromSalesOrder(ctx, soId interface{}) *gomock.Call {
+// Retrieve indicates an expected call of Retrieve.
+func (mr *MockSyncMockRecorder) Retrieve(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromSalesOrder", reflect.TypeOf((*MockInvoice)(nil).GetFromSalesOrder), ctx, soId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Retrieve", reflect.TypeOf((*MockSync)(nil).Retrieve), ctx, id)
 }
 
-// RetrieveById mocks base method.
-func (m *MockInvoice) RetrieveById(ctx context.Context, id string) (domain.Invoice, error) {
+// Sync mocks base method.
+func (m *MockSync) Sync(ctx context.Context, module string, modifiedTime int64) (vtiger.SyncResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Invoice)
+	ret := m.ctrl.Call(m, "Sync", ctx, module, modifiedTime)
+	ret0, _ := ret[0].(vtiger.SyncResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockInvoiceMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// Sync indicates an expected call of Sync.
+func (mr *MockSyncMockRecorder) Sync(ctx, module, modifiedTime interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockInvoice)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sync", reflect.TypeOf((*MockSync)(nil).Sync), ctx, module, modifiedTime)
 }
 
-// MockServiceContract is a mock of ServiceContract interface.
-type MockServiceContract struct {
+// MockWebhooks is a mock of Webhooks interface.
+type MockWebhooks struct {
 	ctrl     *gomock.Controller
-	recorder *MockServiceContractMockRecorder
+	recorder *MockWebhooksMockRecorder
 }
 
-// MockServiceContractMockRecorder is the mock recorder for MockServiceContract.
-type MockServiceContractMockRecorder struct {
-	mock *MockServiceContract
+// MockWebhooksMockRecorder is the mock recorder for MockWebhooks.
+type MockWebhooksMockRecorder struct {
+	mock *MockWebhooks
 }
 
-// NewMockServiceContract creates a new mock instance.
-func NewMockServiceContract(ctrl *gomock.Controller) *MockServiceContract {
-	mock := &MockServiceContract{ctrl: ctrl}
-	mock.recorder = &MockServiceContractMockRecorder{mock}
+// NewMockWebhooks creates a new mock instance.
+func NewMockWebhooks(ctrl *gomock.Controller) *MockWebhooks {
+	mock := &MockWebhooks{ctrl: ctrl}
+	mock.recorder = &MockWebhooksMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockServiceContract) EXPECT() *MockServiceContractMockRecorder {
+func (m *MockWebhooks) EXPECT() *MockWebhooksMockRecorder {
 	return m.recorder
 }
 
-// Count mocks base method.
-func (m *MockServiceContract) Count(ctx context.Context, client, contact string) (int, error) {
+// ClaimDelivery mocks base method.
+func (m *MockWebhooks) ClaimDelivery(ctx context.Context, id int64, now, until time.Time) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, client, contact)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "ClaimDelivery", ctx, id, now, until)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockServiceContractMockRecorder) Count(ctx, client, contact interface{}) *gomock.Call {
+// ClaimDelivery indicates an expected call of ClaimDelivery.
+func (mr *MockWebhooksMockRecorder) ClaimDelivery(ctx, id, now, until interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockServiceContract)(nil).Count), ctx, client, contact)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDelivery", reflect.TypeOf((*MockWebhooks)(nil).ClaimDelivery), ctx, id, now, until)
 }
 
-// GetAll mocks base method.
-func (m *MockServiceContract) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.ServiceContract, error) {
+// Delete mocks base method.
+func (m *MockWebhooks) Delete(ctx context.Context, id int64, accountId string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
-	ret0, _ := ret[0].([]domain.ServiceContract)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Delete", ctx, id, accountId)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockServiceContractMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+// Delete indicates an expected call of Delete.
+func (mr *MockWebhooksMockRecorder) Delete(ctx, id, accountId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockServiceContract)(nil).GetAll), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockWebhooks)(nil).Delete), ctx, id, accountId)
 }
 
-// RetrieveById mocks base method.
-func (m *MockServiceContract) RetrieveById(ctx context.Context, id string) (domain.ServiceContract, error) {
+// GetActiveForEvent mocks base method.
+func (m *MockWebhooks) GetActiveForEvent(ctx context.Context, accountId, event string) ([]domain.Webhook, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.ServiceContract)
+	ret := m.ctrl.Call(m, "GetActiveForEvent", ctx, accountId, event)
+	ret0, _ := ret[0].([]domain.Webhook)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockServiceContractMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// GetActiveForEvent indicates an expected call of GetActiveForEvent.
+func (mr *MockWebhooksMockRecorder) GetActiveForEvent(ctx, accountId, event interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockServiceContract)(nil).RetrieveById), ctx, id)
-}
-
-// MockCurrency is a mock of Currency interface.
-type MockCurrency struct {
-	ctrl     *gomock.Controller
-	recorder *MockCurrencyMockRecorder
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveForEvent", reflect.TypeOf((*MockWebhooks)(nil).GetActiveForEvent), ctx, accountId, event)
 }
 
-// MockCurrencyMockRecorder is the mock recorder for MockCurrency.
-type MockCurrencyMockRecorder struct {
-	mock *MockCurrency
+// GetAllByAccountId mocks base method.
+func (m *MockWebhooks) GetAllByAccountId(ctx context.Context, accountId string) ([]domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllByAccountId", ctx, accountId)
+	ret0, _ := ret[0].([]domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// NewMockCurrency creates a new mock instance.
-func NewMockCurrency(ctrl *gomock.Controller) *MockCurrency {
-	mock := &MockCurrency{ctrl: ctrl}
-	mock.recorder = &MockCurrencyMockRecorder{mock}
-	return mock
+// GetAllByAccountId indicates an expected call of GetAllByAccountId.
+func (mr *MockWebhooksMockRecorder) GetAllByAccountId(ctx, accountId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByAccountId", reflect.TypeOf((*MockWebhooks)(nil).GetAllByAccountId), ctx, accountId)
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockCurrency) EXPECT() *MockCurrencyMockRecorder {
-	return m.recorder
+// GetById mocks base method.
+func (m *MockWebhooks) GetById(ctx context.Context, id int64, accountId string) (domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetById", ctx, id, accountId)
+	ret0, _ := ret[0].(domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// RetrieveById mocks base method.
-func (m *MockCurrency) RetrieveById(ctx context.Context, id string) (domain.Currency, error) {
+// GetById indicates an expected call of GetById.
+func (mr *MockWebhooksMockRecorder) GetById(ctx, id, accountId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockWebhooks)(nil).GetById), ctx, id, accountId)
+}
+
+// GetDeliveries mocks base method.
+func (m *MockWebhooks) GetDeliveries(ctx context.Context, webhookId int64, limit int) ([]domain.WebhookDelivery, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Currency)
+	ret := m.ctrl.Call(m, "GetDeliveries", ctx, webhookId, limit)
+	ret0, _ := ret[0].([]domain.WebhookDelivery)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockCurrencyMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// GetDeliveries indicates an expected call of GetDeliveries.
+func (mr *MockWebhooksMockRecorder) GetDeliveries(ctx, webhookId, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockCurrency)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeliveries", reflect.TypeOf((*MockWebhooks)(nil).GetDeliveries), ctx, webhookId, limit)
 }
 
-// MockProduct is a mock of Product interface.
-type MockProduct struct {
-	ctrl     *gomock.Controller
-	recorder *MockProductMockRecorder
+// GetDueDeliveries mocks base method.
+func (m *MockWebhooks) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueDeliveries", ctx, now, limit)
+	ret0, _ := ret[0].([]domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockProductMockRecorder is the mock recorder for MockProduct.
-type MockProductMockRecorder struct {
-	mock *MockProduct
+// GetDueDeliveries indicates an expected call of GetDueDeliveries.
+func (mr *MockWebhooksMockRecorder) GetDueDeliveries(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueDeliveries", reflect.TypeOf((*MockWebhooks)(nil).GetDueDeliveries), ctx, now, limit)
 }
 
-// NewMockProduct creates a new mock instance.
-func NewMockProduct(ctrl *gomock.Controller) *MockProduct {
-	mock := &MockProduct{ctrl: ctrl}
-	mock.recorder = &MockProductMockRecorder{mock}
-	return mock
+// Insert mocks base method.
+func (m *MockWebhooks) Insert(ctx context.Context, webhook *domain.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Insert", ctx, webhook)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockProduct) EXPECT() *MockProductMockRecorder {
-	return m.recorder
+// Insert indicates an expected call of Insert.
+func (mr *MockWebhooksMockRecorder) Insert(ctx, webhook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockWebhooks)(nil).Insert), ctx, webhook)
 }
 
-// Count mocks base method.
-func (m *MockProduct) Count(ctx context.Context, filters map[string]any) (int, error) {
+// InsertDelivery mocks base method.
+func (m *MockWebhooks) InsertDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, filters)
-	ret0, _ := ret[0].(int)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "InsertDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockProductMockRecorder) Count(ctx, filters interface{}) *gomock.Call {
+// InsertDelivery indicates an expected call of InsertDelivery.
+func (mr *MockWebhooksMockRecorder) InsertDelivery(ctx, delivery interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockProduct)(nil).Count), ctx, filters)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDelivery", reflect.TypeOf((*MockWebhooks)(nil).InsertDelivery), ctx, delivery)
 }
 
-// GetAll mocks base method.
-func (m *MockProduct) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Product, error) {
+// Update mocks base method.
+func (m *MockWebhooks) Update(ctx context.Context, webhook *domain.Webhook) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
-	ret0, _ := ret[0].([]domain.Product)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Update", ctx, webhook)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockProductMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+// Update indicates an expected call of Update.
+func (mr *MockWebhooksMockRecorder) Update(ctx, webhook interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockProduct)(nil).GetAll), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockWebhooks)(nil).Update), ctx, webhook)
 }
 
-// RetrieveById mocks base method.
-func (m *MockProduct) RetrieveById(ctx context.Context, id string) (domain.Product, error) {
+// UpdateDelivery mocks base method.
+func (m *MockWebhooks) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Product)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "UpdateDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockProductMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockWebhooksMockRecorder) UpdateDelivery(ctx, delivery interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockProduct)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockWebhooks)(nil).UpdateDelivery), ctx, delivery)
 }
 
-// MockService is a mock of Service interface.
-type MockService struct {
+// MockPayments is a mock of Payments interface.
+type MockPayments struct {
 	ctrl     *gomock.Controller
-	recorder *MockServiceMockRecorder
+	recorder *MockPaymentsMockRecorder
 }
 
-// MockServiceMockRecorder is the mock recorder for MockService.
-type MockServiceMockRecorder struct {
-	mock *MockService
+// MockPaymentsMockRecorder is the mock recorder for MockPayments.
+type MockPaymentsMockRecorder struct {
+	mock *MockPayments
 }
 
-// NewMockService creates a new mock instance.
-func NewMockService(ctrl *gomock.Controller) *MockService {
-	mock := &MockService{ctrl: ctrl}
-	mock.recorder = &MockServiceMockRecorder{mock}
+// NewMockPayments creates a new mock instance.
+func NewMockPayments(ctrl *gomock.Controller) *MockPayments {
+	mock := &MockPayments{ctrl: ctrl}
+	mock.recorder = &MockPaymentsMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockService) EXPECT() *MockServiceMockRecorder {
+func (m *MockPayments) EXPECT() *MockPaymentsMockRecorder {
 	return m.recorder
 }
 
-// Count mocks base method.
-func (m *MockService) Count(ctx context.Context, filters map[string]any) (int, error) {
+// ClaimEvent mocks base method.
+func (m *MockPayments) ClaimEvent(ctx context.Context, id int64, now, until time.Time) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, filters)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "ClaimEvent", ctx, id, now, until)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockServiceMockRecorder) Count(ctx, filters interface{}) *gomock.Call {
+// ClaimEvent indicates an expected call of ClaimEvent.
+func (mr *MockPaymentsMockRecorder) ClaimEvent(ctx, id, now, until interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockService)(nil).Count), ctx, filters)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimEvent", reflect.TypeOf((*MockPayments)(nil).ClaimEvent), ctx, id, now, until)
 }
 
-// GetAll mocks base method.
-func (m *MockService) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Service, error) {
+// ClaimReceivedEvent mocks base method.
+func (m *MockPayments) ClaimReceivedEvent(ctx context.Context, id int64, now, until time.Time) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
-	ret0, _ := ret[0].([]domain.Service)
+	ret := m.ctrl.Call(m, "ClaimReceivedEvent", ctx, id, now, until)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockServiceMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+// ClaimReceivedEvent indicates an expected call of ClaimReceivedEvent.
+func (mr *MockPaymentsMockRecorder) ClaimReceivedEvent(ctx, id, now, until interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockService)(nil).GetAll), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimReceivedEvent", reflect.TypeOf((*MockPayments)(nil).ClaimReceivedEvent), ctx, id, now, until)
 }
 
-// RetrieveById mocks base method.
-func (m *MockService) RetrieveById(ctx context.Context, id string) (domain.Service, error) {
+// GetAllocatedAmount mocks base method.
+func (m *MockPayments) GetAllocatedAmount(ctx context.Context, parentId string, reserved domain.PaymentReservation) (float64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Service)
+	ret := m.ctrl.Call(m, "GetAllocatedAmount", ctx, parentId, reserved)
+	ret0, _ := ret[0].(float64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockServiceMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// GetAllocatedAmount indicates an expected call of GetAllocatedAmount.
+func (mr *MockPaymentsMockRecorder) GetAllocatedAmount(ctx, parentId, reserved interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockService)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllocatedAmount", reflect.TypeOf((*MockPayments)(nil).GetAllocatedAmount), ctx, parentId, reserved)
 }
 
-// MockProject is a mock of Project interface.
-type MockProject struct {
-	ctrl     *gomock.Controller
-	recorder *MockProjectMockRecorder
+// GetAllocations mocks base method.
+func (m *MockPayments) GetAllocations(ctx context.Context, paymentId int64) ([]domain.PaymentAllocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllocations", ctx, paymentId)
+	ret0, _ := ret[0].([]domain.PaymentAllocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockProjectMockRecorder is the mock recorder for MockProject.
-type MockProjectMockRecorder struct {
-	mock *MockProject
+// GetAllocations indicates an expected call of GetAllocations.
+func (mr *MockPaymentsMockRecorder) GetAllocations(ctx, paymentId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllocations", reflect.TypeOf((*MockPayments)(nil).GetAllocations), ctx, paymentId)
 }
 
-// NewMockProject creates a new mock instance.
-func NewMockProject(ctrl *gomock.Controller) *MockProject {
-	mock := &MockProject{ctrl: ctrl}
-	mock.recorder = &MockProjectMockRecorder{mock}
-	return mock
+// GetById mocks base method.
+func (m *MockPayments) GetById(ctx context.Context, id int64) (domain.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetById", ctx, id)
+	ret0, _ := ret[0].(domain.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockProject) EXPECT() *MockProjectMockRecorder {
-	return m.recorder
+// GetById indicates an expected call of GetById.
+func (mr *MockPaymentsMockRecorder) GetById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockPayments)(nil).GetById), ctx, id)
 }
 
-// Count mocks base method.
-func (m *MockProject) Count(ctx context.Context, client, contact string) (int, error) {
+// GetByStripeId mocks base method.
+func (m *MockPayments) GetByStripeId(ctx context.Context, id string) (domain.Payment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, client, contact)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "GetByStripeId", ctx, id)
+	ret0, _ := ret[0].(domain.Payment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockProjectMockRecorder) Count(ctx, client, contact interface{}) *gomock.Call {
+// GetByStripeId indicates an expected call of GetByStripeId.
+func (mr *MockPaymentsMockRecorder) GetByStripeId(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockProject)(nil).Count), ctx, client, contact)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByStripeId", reflect.TypeOf((*MockPayments)(nil).GetByStripeId), ctx, id)
 }
 
-// GetAll mocks base method.
-func (m *MockProject) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Project, error) {
+// GetDueEvents mocks base method.
+func (m *MockPayments) GetDueEvents(ctx context.Context, now time.Time, limit int) ([]domain.PaymentEvent, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll", ctx, filter)
-	ret0, _ := ret[0].([]domain.Project)
+	ret := m.ctrl.Call(m, "GetDueEvents", ctx, now, limit)
+	ret0, _ := ret[0].([]domain.PaymentEvent)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAll indicates an expected call of GetAll.
-func (mr *MockProjectMockRecorder) GetAll(ctx, filter interface{}) *gomock.Call {
+// GetDueEvents indicates an expected call of GetDueEvents.
+func (mr *MockPaymentsMockRecorder) GetDueEvents(ctx, now, limit interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockProject)(nil).GetAll), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueEvents", reflect.TypeOf((*MockPayments)(nil).GetDueEvents), ctx, now, limit)
 }
 
-// RetrieveById mocks base method.
-func (m *MockProject) RetrieveById(ctx context.Context, id string) (domain.Project, error) {
+// GetEvent mocks base method.
+func (m *MockPayments) GetEvent(ctx context.Context, provider, eventId string) (domain.PaymentEvent, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Project)
+	ret := m.ctrl.Call(m, "GetEvent", ctx, provider, eventId)
+	ret0, _ := ret[0].(domain.PaymentEvent)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockProjectMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// GetEvent indicates an expected call of GetEvent.
+func (mr *MockPaymentsMockRecorder) GetEvent(ctx, provider, eventId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockProject)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEvent", reflect.TypeOf((*MockPayments)(nil).GetEvent), ctx, provider, eventId)
 }
 
-// MockProjectTask is a mock of ProjectTask interface.
-type MockProjectTask struct {
-	ctrl     *gomock.Controller
-	recorder *MockProjectTaskMockRecorder
+// GetLastEventTime mocks base method.
+func (m *MockPayments) GetLastEventTime(ctx context.Context, paymentId int64) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastEventTime", ctx, paymentId)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockProjectTaskMockRecorder is the mock recorder for MockProjectTask.
-type MockProjectTaskMockRecorder struct {
-	mock *MockProjectTask
+// GetLastEventTime indicates an expected call of GetLastEventTime.
+func (mr *MockPaymentsMockRecorder) GetLastEventTime(ctx, paymentId interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastEventTime", reflect.TypeOf((*MockPayments)(nil).GetLastEventTime), ctx, paymentId)
 }
 
-// NewMockProjectTask creates a new mock instance.
-func NewMockProjectTask(ctrl *gomock.Controller) *MockProjectTask {
-	mock := &MockProjectTask{ctrl: ctrl}
-	mock.recorder = &MockProjectTaskMockRecorder{mock}
-	return mock
+// GetPaymentsFromAccountId mocks base method.
+func (m *MockPayments) GetPaymentsFromAccountId(ctx context.Context, id string) ([]domain.Payment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPaymentsFromAccountId", ctx, id)
+	ret0, _ := ret[0].([]domain.Payment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockProjectTask) EXPECT() *MockProjectTaskMockRecorder {
-	return m.recorder
+// GetPaymentsFromAccountId indicates an expected call of GetPaymentsFromAccountId.
+func (mr *MockPaymentsMockRecorder) GetPaymentsFromAccountId(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPaymentsFromAccountId", reflect.TypeOf((*MockPayments)(nil).GetPaymentsFromAccountId), ctx, id)
 }
 
-// Count mocks base method.
-func (m *MockProjectTask) Count(ctx context.Context, parent string) (int, error) {
+// GetRefunds mocks base method.
+func (m *MockPayments) GetRefunds(ctx context.Context, paymentId int64) ([]domain.PaymentRefund, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Count", ctx, parent)
-	ret0, _ := ret[0].(int)
+	ret := m.ctrl.Call(m, "GetRefunds", ctx, paymentId)
+	ret0, _ := ret[0].([]domain.PaymentRefund)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Count indicates an expected call of Count.
-func (mr *MockProjectTaskMockRecorder) Count(ctx, parent interface{}) *gomock.Call {
+// GetRefunds indicates an expected call of GetRefunds.
+func (mr *MockPaymentsMockRecorder) GetRefunds(ctx, paymentId interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockProjectTask)(nil).Count), ctx, parent)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRefunds", reflect.TypeOf((*MockPayments)(nil).GetRefunds), ctx, paymentId)
 }
 
-// Create mocks base method.
-func (m *MockProjectTask) Create(ctx context.Context, task domain.ProjectTask) (domain.ProjectTask, error) {
+// Insert mocks base method.
+func (m *MockPayments) Insert(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, task)
-	ret0, _ := ret[0].(domain.ProjectTask)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "Insert", ctx, payment, reserved)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Create indicates an expected call of Create.
-func (mr *MockProjectTaskMockRecorder) Create(ctx, task interface{}) *gomock.Call {
+// Insert indicates an expected call of Insert.
+func (mr *MockPaymentsMockRecorder) Insert(ctx, payment, reserved interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockProjectTask)(nil).Create), ctx, task)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockPayments)(nil).Insert), ctx, payment, reserved)
 }
 
-// GetFromProject mocks base method.
-func (m *MockProjectTask) GetFromProject(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.ProjectTask, error) {
+// InsertEvent mocks base method.
+func (m *MockPayments) InsertEvent(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetFromProject", ctx, filter)
-	ret0, _ := ret[0].([]domain.ProjectTask)
+	ret := m.ctrl.Call(m, "InsertEvent", ctx, event)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetFromProject indicates an expected call of GetFromProject.
-func (mr *MockProjectTaskMockRecorder) GetFromProject(ctx, filter interface{}) *gomock.Call {
+// InsertEvent indicates an expected call of InsertEvent.
+func (mr *MockPaymentsMockRecorder) InsertEvent(ctx, event interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromProject", reflect.TypeOf((*MockProjectTask)(nil).GetFromProject), ctx, filter)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertEvent", reflect.TypeOf((*MockPayments)(nil).InsertEvent), ctx, event)
 }
 
-// RetrieveById mocks base method.
-func (m *MockProjectTask) RetrieveById(ctx context.Context, id string) (domain.ProjectTask, error) {
+// InsertRefund mocks base method.
+func (m *MockPayments) InsertRefund(ctx context.Context, refund *domain.PaymentRefund, payment domain.Payment, event *domain.PaymentEvent) (bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.ProjectTask)
+	ret := m.ctrl.Call(m, "InsertRefund", ctx, refund, payment, event)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockProjectTaskMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// InsertRefund indicates an expected call of InsertRefund.
+func (mr *MockPaymentsMockRecorder) InsertRefund(ctx, refund, payment, event interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockProjectTask)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertRefund", reflect.TypeOf((*MockPayments)(nil).InsertRefund), ctx, refund, payment, event)
 }
 
-// Revise mocks base method.
-func (m *MockProjectTask) Revise(ctx context.Context, task map[string]any) (domain.ProjectTask, error) {
+// MarkAllocationRevised mocks base method.
+func (m *MockPayments) MarkAllocationRevised(ctx context.Context, id int64, revisedAt time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Revise", ctx, task)
-	ret0, _ := ret[0].(domain.ProjectTask)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret := m.ctrl.Call(m, "MarkAllocationRevised", ctx, id, revisedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// Revise indicates an expected call of Revise.
-func (mr *MockProjectTaskMockRecorder) Revise(ctx, task interface{}) *gomock.Call {
+// MarkAllocationRevised indicates an expected call of MarkAllocationRevised.
+func (mr *MockPaymentsMockRecorder) MarkAllocationRevised(ctx, id, revisedAt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revise", reflect.TypeOf((*MockProjectTask)(nil).Revise), ctx, task)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAllocationRevised", reflect.TypeOf((*MockPayments)(nil).MarkAllocationRevised), ctx, id, revisedAt)
 }
 
-// MockAccount is a mock of Account interface.
-type MockAccount struct {
-	ctrl     *gomock.Controller
-	recorder *MockAccountMockRecorder
+// SettlePayment mocks base method.
+func (m *MockPayments) SettlePayment(ctx context.Context, id int64, settledAt time.Time, event *domain.PaymentEvent) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SettlePayment", ctx, id, settledAt, event)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// MockAccountMockRecorder is the mock recorder for MockAccount.
-type MockAccountMockRecorder struct {
-	mock *MockAccount
+// SettlePayment indicates an expected call of SettlePayment.
+func (mr *MockPaymentsMockRecorder) SettlePayment(ctx, id, settledAt, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SettlePayment", reflect.TypeOf((*MockPayments)(nil).SettlePayment), ctx, id, settledAt, event)
 }
 
-// NewMockAccount creates a new mock instance.
-func NewMockAccount(ctrl *gomock.Controller) *MockAccount {
-	mock := &MockAccount{ctrl: ctrl}
-	mock.recorder = &MockAccountMockRecorder{mock}
-	return mock
+// UpdateEvent mocks base method.
+func (m *MockPayments) UpdateEvent(ctx context.Context, event *domain.PaymentEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEvent", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockAccount) EXPECT() *MockAccountMockRecorder {
-	return m.recorder
+// UpdateEvent indicates an expected call of UpdateEvent.
+func (mr *MockPaymentsMockRecorder) UpdateEvent(ctx, event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEvent", reflect.TypeOf((*MockPayments)(nil).UpdateEvent), ctx, event)
 }
 
-// RetrieveById mocks base method.
-func (m *MockAccount) RetrieveById(ctx context.Context, id string) (domain.Account, error) {
+// UpdatePayment mocks base method.
+func (m *MockPayments) UpdatePayment(ctx context.Context, payment domain.Payment) (domain.Payment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RetrieveById", ctx, id)
-	ret0, _ := ret[0].(domain.Account)
+	ret := m.ctrl.Call(m, "UpdatePayment", ctx, payment)
+	ret0, _ := ret[0].(domain.Payment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// RetrieveById indicates an expected call of RetrieveById.
-func (mr *MockAccountMockRecorder) RetrieveById(ctx, id interface{}) *gomock.Call {
+// UpdatePayment indicates an expected call of UpdatePayment.
+func (mr *MockPaymentsMockRecorder) UpdatePayment(ctx, payment interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetrieveById", reflect.TypeOf((*MockAccount)(nil).RetrieveById), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePayment", reflect.TypeOf((*MockPayments)(nil).UpdatePayment), ctx, payment)
 }
@@ -5,27 +5,77 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
 )
 
+const defaultNotificationsModule = "PortalNotifications"
+const defaultNotificationsAccountField = "account_id"
+
 type NotificationsCrm struct {
-	vtiger vtiger.VtigerConnector
+	vtiger vtiger.Connector
 	config config.Config
 }
 
 func NewNotificationsCrm(config config.Config, cache cache.Cache) NotificationsCrm {
+	return NewNotificationsConcrete(config, vtiger.NewVtigerConnector(cache, config.Vtiger.Connection, vtiger.NewWebRequest(config.Vtiger.Connection)))
+}
+
+func NewNotificationsConcrete(config config.Config, vtiger vtiger.Connector) NotificationsCrm {
 	return NotificationsCrm{
-		vtiger: vtiger.NewVtigerConnector(cache, config.Vtiger.Connection, vtiger.NewWebRequest(config.Vtiger.Connection)),
+		vtiger: vtiger,
 		config: config,
 	}
 }
 
+// GetAllNotifications returns all notifications, which are waiting to be delivered to portal users.
 func (n NotificationsCrm) GetAllNotifications(ctx context.Context) ([]domain.Notification, error) {
-	// TODO: Implement receiving notification from CRM
-	return []domain.Notification{}, nil
+	items, err := n.vtiger.SelectAll(ctx, vtiger.NewQuery(n.module()).OrderBy("createdtime"))
+	if err != nil {
+		return nil, e.Wrap("can not get notifications from module "+n.module(), err)
+	}
+	notifications := make([]domain.Notification, 0, len(items))
+	for _, data := range items {
+		notification, err := domain.ConvertMapToNotification(data)
+		if err != nil {
+			return notifications, e.Wrap("can not convert map to notification", err)
+		}
+		if id, ok := data["id"].(string); ok {
+			notification.Crmid = id
+		}
+		if assigned, ok := data["assigned_user_id"].(string); ok {
+			notification.AssignedUserId = assigned
+		}
+		if account, ok := data[n.accountField()].(string); ok {
+			notification.AccountId = account
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
 }
 
+// DeleteReceivedNotification removes notification from crm after it was stored for all users of account.
 func (n NotificationsCrm) DeleteReceivedNotification(ctx context.Context, crmid string) error {
-	// TODO: Implement clearing notification in crm
+	if crmid == "" {
+		return ErrWrongCrmId
+	}
+	err := n.vtiger.Delete(ctx, crmid)
+	if err != nil {
+		return e.Wrap("can not delete notification "+crmid, err)
+	}
 	return nil
 }
+
+func (n NotificationsCrm) module() string {
+	if n.config.Notifications.Module == "" {
+		return defaultNotificationsModule
+	}
+	return n.config.Notifications.Module
+}
+
+func (n NotificationsCrm) accountField() string {
+	if n.config.Notifications.AccountField == "" {
+		return defaultNotificationsAccountField
+	}
+	return n.config.Notifications.AccountField
+}
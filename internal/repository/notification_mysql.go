@@ -22,7 +22,7 @@ func (r *NotificationsRepo) Insert(ctx context.Context, notification *domain.Not
 	notification.UpdatedAt = time.Now()
 
 	var query = `
-				INSERT INTO notifications (crmid, module, label, description, assigned_user_id, account_id, user_id, is_read, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+				INSERT INTO notifications (crmid, module, label, description, assigned_user_id, account_id, user_id, is_read, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	var args = []any{notification.Crmid, notification.Module, notification.Label, notification.Description, notification.AssignedUserId, notification.AccountId, notification.UserId, notification.IsRead, notification.CreatedAt, notification.UpdatedAt}
 
 	result, err := r.db.ExecContext(ctx, query, args...)
@@ -38,6 +38,17 @@ func (r *NotificationsRepo) Insert(ctx context.Context, notification *domain.Not
 	return nil
 }
 
+// Exists reports whether notification from crm was already stored for user.
+func (r *NotificationsRepo) Exists(ctx context.Context, crmid string, userId string) (bool, error) {
+	var query = `SELECT EXISTS(SELECT 1 FROM notifications WHERE crmid = ? AND user_id = ?)`
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, crmid, userId).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
 func (r *NotificationsRepo) GetNotificationsFromUserId(ctx context.Context, id string) ([]domain.Notification, error) {
 	var query = `SELECT id, crmid, module, label, description, assigned_user_id, account_id, user_id, is_read, created_at, updated_at FROM notifications WHERE user_id = ? AND is_read = 0`
 	var notifications = make([]domain.Notification, 0)
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"time"
+)
+
+type OtpRepo struct {
+	db *sql.DB
+}
+
+func NewOtpRepo(db *sql.DB) *OtpRepo {
+	return &OtpRepo{
+		db: db,
+	}
+}
+
+func (r *OtpRepo) GetState(ctx context.Context, userId int64) (domain.OtpState, error) {
+	var query = `SELECT otp_failed_attempts, otp_locked_until FROM users WHERE id = ?`
+	var state domain.OtpState
+	var lockedUntil sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, query, userId).Scan(&state.FailedAttempts, &lockedUntil)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return state, ErrRecordNotFound
+		}
+		return state, err
+	}
+	if lockedUntil.Valid {
+		state.LockedUntil = &lockedUntil.Time
+	}
+	return state, nil
+}
+
+// IncrementFailures adds failed attempt and returns number of failed attempts in a row.
+func (r *OtpRepo) IncrementFailures(ctx context.Context, userId int64) (int, error) {
+	var query = `UPDATE users SET otp_failed_attempts = LAST_INSERT_ID(otp_failed_attempts + 1) WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		return 0, err
+	}
+	attempts, err := result.LastInsertId()
+	return int(attempts), err
+}
+
+// Lock forbids OTP checks until passed time and starts counting of failed attempts again.
+func (r *OtpRepo) Lock(ctx context.Context, userId int64, until time.Time) error {
+	var query = `UPDATE users SET otp_failed_attempts = 0, otp_locked_until = ? WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, until, userId)
+	return err
+}
+
+func (r *OtpRepo) ResetFailures(ctx context.Context, userId int64) error {
+	var query = `UPDATE users SET otp_failed_attempts = 0, otp_locked_until = NULL WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+// ReplaceRecoveryCodes deletes all recovery codes of user and stores new ones.
+func (r *OtpRepo) ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM otp_recovery_codes WHERE user_id = ?`, userId); err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO otp_recovery_codes (user_id, hash, created_at) VALUES (?, ?, ?)`, userId, hash, time.Now()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode marks code as used. It returns false, when code does not exist or was already used.
+func (r *OtpRepo) UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error) {
+	var query = `UPDATE otp_recovery_codes SET used_at = ? WHERE user_id = ? AND hash = ? AND used_at IS NULL LIMIT 1`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), userId, hash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *OtpRepo) DeleteRecoveryCodes(ctx context.Context, userId int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM otp_recovery_codes WHERE user_id = ?`, userId)
+	return err
+}
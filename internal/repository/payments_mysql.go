@@ -5,6 +5,10 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"math"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -18,15 +22,43 @@ func NewPaymentsRepo(db *sql.DB) *PaymentsRepo {
 	}
 }
 
-func (r *PaymentsRepo) Insert(ctx context.Context, payment *domain.Payment) error {
+// Insert stores payment with its allocations. Allocations with balance are checked in the same transaction, it fails
+// with ErrBalanceExceeded, when allocation and reserved amounts of its parent are greater than balance.
+func (r *PaymentsRepo) Insert(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error {
 	payment.CreatedAt = time.Now()
 	payment.UpdatedAt = time.Now()
 
 	var query = `
-				INSERT INTO payments (stripe_payment_id, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	var args = []any{payment.StripePaymentId, payment.UserId, payment.Amount, payment.Currency, payment.PaymentMethod, payment.Status, payment.CreatedAt, payment.UpdatedAt, payment.ParentId, payment.AccountId}
+				INSERT INTO payments (stripe_payment_id, provider, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{payment.StripePaymentId, payment.Provider, payment.UserId, payment.Amount, payment.Currency, payment.PaymentMethod, payment.Status, payment.CreatedAt, payment.UpdatedAt, payment.ParentId, payment.AccountId}
 
-	result, err := r.db.ExecContext(ctx, query, args...)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// parents are locked in the same order, so parallel payments of the same invoices do not deadlock
+	checked := make([]domain.PaymentAllocation, 0, len(payment.Allocations))
+	for _, allocation := range payment.Allocations {
+		if allocation.Balance > 0 {
+			checked = append(checked, allocation)
+		}
+	}
+	sort.Slice(checked, func(i, j int) bool {
+		return checked[i].ParentId < checked[j].ParentId
+	})
+	for _, allocation := range checked {
+		amount, err := allocatedAmount(ctx, tx, allocation.ParentId, reserved, true)
+		if err != nil {
+			return err
+		}
+		if math.Round((amount+allocation.Amount)*100) > math.Round(allocation.Balance*100) {
+			return ErrBalanceExceeded
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -34,15 +66,117 @@ func (r *PaymentsRepo) Insert(ctx context.Context, payment *domain.Payment) erro
 	if err != nil {
 		return err
 	}
+	for i := range payment.Allocations {
+		allocation := &payment.Allocations[i]
+		allocation.PaymentId = id
+		allocation.CreatedAt = payment.CreatedAt
+		result, err = tx.ExecContext(ctx, `INSERT INTO payment_allocations (payment_id, parent_id, module, amount, created_at) VALUES (?, ?, ?, ?, ?)`, id, allocation.ParentId, allocation.Module, allocation.Amount, allocation.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if allocation.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
 	payment.ID = id
 
 	return nil
 }
 
+func (r *PaymentsRepo) GetAllocations(ctx context.Context, paymentId int64) ([]domain.PaymentAllocation, error) {
+	var query = `SELECT id, payment_id, parent_id, module, amount, created_at, revised_at FROM payment_allocations WHERE payment_id = ? ORDER BY id`
+	var allocations = make([]domain.PaymentAllocation, 0)
+	rows, err := r.db.QueryContext(ctx, query, paymentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var allocation domain.PaymentAllocation
+		var revisedAt sql.NullTime
+		err = rows.Scan(&allocation.ID, &allocation.PaymentId, &allocation.ParentId, &allocation.Module, &allocation.Amount, &allocation.CreatedAt, &revisedAt)
+		if err != nil {
+			return nil, err
+		}
+		if revisedAt.Valid {
+			allocation.RevisedAt = &revisedAt.Time
+		}
+		allocations = append(allocations, allocation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// GetAllocatedAmount returns sum of allocations to invoice or sales order, which are reserved by payments.
+func (r *PaymentsRepo) GetAllocatedAmount(ctx context.Context, parentId string, reserved domain.PaymentReservation) (float64, error) {
+	return allocatedAmount(ctx, r.db, parentId, reserved, false)
+}
+
+// MarkAllocationRevised sets time, when allocation was revised in crm, so it does not reserve balance of parent.
+func (r *PaymentsRepo) MarkAllocationRevised(ctx context.Context, id int64, revisedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE payment_allocations SET revised_at = ? WHERE id = ? AND revised_at IS NULL`, revisedAt, id)
+	return err
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// allocatedAmount sums allocations of pending payments and allocations of paid payments, which are not revised yet. With
+// lock allocations of parent are locked until the end of transaction, new allocations of parent wait for it too.
+func allocatedAmount(ctx context.Context, db queryRower, parentId string, reserved domain.PaymentReservation, lock bool) (float64, error) {
+	var query = `SELECT COALESCE(SUM(payment_allocations.amount), 0) FROM payment_allocations
+		INNER JOIN payments ON payments.id = payment_allocations.payment_id
+		WHERE payment_allocations.parent_id = ? AND (payments.status IN (` + placeholders(len(reserved.Pending)) + `)
+		OR (payments.status IN (` + placeholders(len(reserved.Paid)) + `) AND payment_allocations.revised_at IS NULL))`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	var args = []any{parentId}
+	for _, status := range reserved.Pending {
+		args = append(args, status)
+	}
+	for _, status := range reserved.Paid {
+		args = append(args, status)
+	}
+	var amount float64
+	err := db.QueryRowContext(ctx, query, args...).Scan(&amount)
+	if err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// placeholders returns placeholders for IN clause, NULL is returned for empty list, so it matches nothing.
+func placeholders(count int) string {
+	if count == 0 {
+		return "NULL"
+	}
+	return "?" + strings.Repeat(", ?", count-1)
+}
+
 func (r *PaymentsRepo) GetByStripeId(ctx context.Context, id string) (domain.Payment, error) {
-	var query = `SELECT id, stripe_payment_id, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id FROM payments WHERE stripe_payment_id = ?`
-	var payment domain.Payment
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&payment.ID, &payment.StripePaymentId, &payment.UserId, &payment.Amount, &payment.Currency, &payment.PaymentMethod, &payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.ParentId, &payment.AccountId)
+	var query = `SELECT id, stripe_payment_id, provider, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id, settled_at FROM payments WHERE stripe_payment_id = ?`
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return payment, ErrRecordNotFound
+		default:
+			return payment, err
+		}
+	}
+	return payment, nil
+}
+
+func (r *PaymentsRepo) GetById(ctx context.Context, id int64) (domain.Payment, error) {
+	var query = `SELECT id, stripe_payment_id, provider, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id, settled_at FROM payments WHERE id = ?`
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -55,7 +189,7 @@ func (r *PaymentsRepo) GetByStripeId(ctx context.Context, id string) (domain.Pay
 }
 
 func (r *PaymentsRepo) GetPaymentsFromAccountId(ctx context.Context, id string) ([]domain.Payment, error) {
-	var query = `SELECT id, stripe_payment_id, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id FROM payments WHERE account_id = ? ORDER BY updated_at DESC LIMIT 20`
+	var query = `SELECT id, stripe_payment_id, provider, user_id, amount, currency, payment_method, status, created_at, updated_at, parent_id, account_id, settled_at FROM payments WHERE account_id = ? ORDER BY updated_at DESC LIMIT 20`
 	var payments = make([]domain.Payment, 0)
 	rows, err := r.db.QueryContext(ctx, query, id)
 	if err != nil {
@@ -63,8 +197,7 @@ func (r *PaymentsRepo) GetPaymentsFromAccountId(ctx context.Context, id string)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var payment domain.Payment
-		err = rows.Scan(&payment.ID, &payment.StripePaymentId, &payment.UserId, &payment.Amount, &payment.Currency, &payment.PaymentMethod, &payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.ParentId, &payment.AccountId)
+		payment, err := scanPayment(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -77,8 +210,8 @@ func (r *PaymentsRepo) GetPaymentsFromAccountId(ctx context.Context, id string)
 }
 
 func (r *PaymentsRepo) UpdatePayment(ctx context.Context, payment domain.Payment) (domain.Payment, error) {
-	var query = `UPDATE payments SET stripe_payment_id = ?, user_id = ?, amount = ?, currency = ?, payment_method = ?, status = ?, updated_at = NOW(), parent_id = ?, account_id = ? WHERE id = ?`
-	var args = []any{payment.StripePaymentId, payment.UserId, payment.Amount, payment.Currency, payment.PaymentMethod, payment.Status, payment.ParentId, payment.AccountId, payment.ID}
+	var query = `UPDATE payments SET stripe_payment_id = ?, provider = ?, user_id = ?, amount = ?, currency = ?, payment_method = ?, status = ?, updated_at = NOW(), parent_id = ?, account_id = ? WHERE id = ?`
+	var args = []any{payment.StripePaymentId, payment.Provider, payment.UserId, payment.Amount, payment.Currency, payment.PaymentMethod, payment.Status, payment.ParentId, payment.AccountId, payment.ID}
 	_, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return payment, err
@@ -86,3 +219,214 @@ func (r *PaymentsRepo) UpdatePayment(ctx context.Context, payment domain.Payment
 	payment.UpdatedAt = time.Now()
 	return payment, nil
 }
+
+// SettlePayment sets time of settlement and saves event, which revises crm, in one transaction. It returns false, when
+// payment is already settled.
+func (r *PaymentsRepo) SettlePayment(ctx context.Context, id int64, settledAt time.Time, event *domain.PaymentEvent) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE payments SET settled_at = ? WHERE id = ? AND settled_at IS NULL`, settledAt, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if err = updateEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// InsertRefund stores refund with status of its payment and event in one transaction, so refund is not stored without
+// revision of crm. It returns false, when refund is already stored.
+func (r *PaymentsRepo) InsertRefund(ctx context.Context, refund *domain.PaymentRefund, payment domain.Payment, event *domain.PaymentEvent) (bool, error) {
+	refund.CreatedAt = time.Now()
+	var query = `INSERT IGNORE INTO payment_refunds (payment_id, refund_id, amount, currency, status, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, refund.PaymentId, refund.RefundId, refund.Amount, refund.Currency, refund.Status, refund.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	if refund.ID, err = result.LastInsertId(); err != nil {
+		return false, err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE payments SET status = ?, updated_at = NOW() WHERE id = ?`, payment.Status, payment.ID); err != nil {
+		return false, err
+	}
+	if err = updateEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *PaymentsRepo) GetRefunds(ctx context.Context, paymentId int64) ([]domain.PaymentRefund, error) {
+	var query = `SELECT id, payment_id, refund_id, amount, currency, status, created_at FROM payment_refunds WHERE payment_id = ? ORDER BY id`
+	var refunds = make([]domain.PaymentRefund, 0)
+	rows, err := r.db.QueryContext(ctx, query, paymentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var refund domain.PaymentRefund
+		err = rows.Scan(&refund.ID, &refund.PaymentId, &refund.RefundId, &refund.Amount, &refund.Currency, &refund.Status, &refund.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, refund)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
+
+// InsertEvent stores event of provider. It returns false, when event with the same id is already stored.
+func (r *PaymentsRepo) InsertEvent(ctx context.Context, event *domain.PaymentEvent) (bool, error) {
+	event.CreatedAt = time.Now()
+	var query = `
+				INSERT IGNORE INTO payment_events (provider, event_id, type, intent_id, payment_id, refund_id, status, attempts, occurred_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{event.Provider, event.EventId, event.Type, event.IntentId, event.PaymentId, event.RefundId, event.Status, event.Attempts, nullTime(event.OccurredAt), event.CreatedAt}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+	event.Id, err = result.LastInsertId()
+	return true, err
+}
+
+func (r *PaymentsRepo) GetEvent(ctx context.Context, provider string, eventId string) (domain.PaymentEvent, error) {
+	var query = `SELECT ` + paymentEventColumns + ` FROM payment_events WHERE provider = ? AND event_id = ?`
+	event, err := scanPaymentEvent(r.db.QueryRowContext(ctx, query, provider, eventId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return event, ErrRecordNotFound
+	}
+	return event, err
+}
+
+// ClaimReceivedEvent marks received event as processing until passed time. Processing event, which lock is expired,
+// can be claimed again. It returns false, when event is claimed by parallel delivery or is already applied.
+func (r *PaymentsRepo) ClaimReceivedEvent(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error) {
+	var query = `UPDATE payment_events SET status = ?, next_attempt_at = ? WHERE id = ? AND (status = ? OR (status = ? AND next_attempt_at <= ?))`
+	result, err := r.db.ExecContext(ctx, query, domain.PaymentEventProcessing, until, id, domain.PaymentEventReceived, domain.PaymentEventProcessing, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *PaymentsRepo) UpdateEvent(ctx context.Context, event *domain.PaymentEvent) error {
+	return updateEvent(ctx, r.db, event)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func updateEvent(ctx context.Context, db execer, event *domain.PaymentEvent) error {
+	var query = `UPDATE payment_events SET intent_id = ?, payment_id = ?, refund_id = ?, status = ?, attempts = ?, error = ?, revised = ?, next_attempt_at = ?, processed_at = ? WHERE id = ?`
+	var args = []any{event.IntentId, event.PaymentId, event.RefundId, event.Status, event.Attempts, event.Error, strings.Join(event.Revised, ","), event.NextAttemptAt, event.ProcessedAt, event.Id}
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetLastEventTime returns time of the latest applied intent event of payment. Zero time is returned, when there are
+// no such events.
+func (r *PaymentsRepo) GetLastEventTime(ctx context.Context, paymentId int64) (time.Time, error) {
+	var query = `SELECT MAX(occurred_at) FROM payment_events WHERE payment_id = ? AND type = ? AND status IN (?, ?, ?)`
+	var last sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, paymentId, payment.EventIntentUpdated, domain.PaymentEventProcessed, domain.PaymentEventPending, domain.PaymentEventFailed).Scan(&last)
+	return last.Time, err
+}
+
+// GetDueEvents returns pending events, which revision of crm should be retried now.
+func (r *PaymentsRepo) GetDueEvents(ctx context.Context, now time.Time, limit int) ([]domain.PaymentEvent, error) {
+	var query = `SELECT ` + paymentEventColumns + ` FROM payment_events WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?`
+	rows, err := r.db.QueryContext(ctx, query, domain.PaymentEventPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events = make([]domain.PaymentEvent, 0)
+	for rows.Next() {
+		event, err := scanPaymentEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ClaimEvent moves next attempt of due event to until. It returns false, when event was claimed by another worker.
+func (r *PaymentsRepo) ClaimEvent(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error) {
+	var query = `UPDATE payment_events SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?`
+	result, err := r.db.ExecContext(ctx, query, until, id, domain.PaymentEventPending, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func scanPayment(row rowScanner) (domain.Payment, error) {
+	var payment domain.Payment
+	var settledAt sql.NullTime
+	err := row.Scan(&payment.ID, &payment.StripePaymentId, &payment.Provider, &payment.UserId, &payment.Amount, &payment.Currency, &payment.PaymentMethod, &payment.Status, &payment.CreatedAt, &payment.UpdatedAt, &payment.ParentId, &payment.AccountId, &settledAt)
+	if settledAt.Valid {
+		payment.SettledAt = &settledAt.Time
+	}
+	return payment, err
+}
+
+const paymentEventColumns = `id, provider, event_id, type, intent_id, payment_id, refund_id, status, attempts, error, revised, next_attempt_at, occurred_at, processed_at, created_at`
+
+func scanPaymentEvent(row rowScanner) (domain.PaymentEvent, error) {
+	var event domain.PaymentEvent
+	var eventError, revised sql.NullString
+	var nextAttemptAt, occurredAt, processedAt sql.NullTime
+	err := row.Scan(&event.Id, &event.Provider, &event.EventId, &event.Type, &event.IntentId, &event.PaymentId, &event.RefundId, &event.Status, &event.Attempts,
+		&eventError, &revised, &nextAttemptAt, &occurredAt, &processedAt, &event.CreatedAt)
+	if err != nil {
+		return event, err
+	}
+	event.Error = eventError.String
+	event.Revised = splitEvents(revised.String)
+	event.OccurredAt = occurredAt.Time
+	if nextAttemptAt.Valid {
+		event.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if processedAt.Valid {
+		event.ProcessedAt = &processedAt.Time
+	}
+	return event, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
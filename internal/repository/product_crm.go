@@ -7,7 +7,6 @@ import (
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
-	"strconv"
 )
 
 type ProductCrm struct {
@@ -31,16 +30,14 @@ func (p ProductCrm) RetrieveById(ctx context.Context, id string) (domain.Product
 }
 
 func (p ProductCrm) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Product, error) {
-	// Calculate the offset for the given page number and page size
-	offset := (filter.Page - 1) * filter.PageSize
 	isActive := GetIsActiveFromFilter(filter.Filters)
-	query := "SELECT id FROM Products WHERE discontinued = " + isActive + " LIMIT " + strconv.Itoa(offset) + ", " + strconv.Itoa(filter.PageSize) + ";"
+	query := vtiger.NewQuery("Products").Select("id").Where(vtiger.Eq("discontinued", isActive)).Page(filter.Page, filter.PageSize)
 	products := make([]domain.Product, 0)
-	result, err := p.vtiger.Query(ctx, query)
+	result, err := p.vtiger.Select(ctx, query)
 	if err != nil {
-		return products, e.Wrap("can not execute query "+query+", got error: "+result.Error.Message, err)
+		return products, e.Wrap("can not get products", err)
 	}
-	for _, data := range result.Result {
+	for _, data := range result {
 		product, err := domain.ConvertMapToProduct(data)
 		if err != nil {
 			return products, e.Wrap("can not convert map to product", err)
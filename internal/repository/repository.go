@@ -24,6 +24,8 @@ type Users interface {
 	VerifyOrInvalidateOtp(ctx context.Context, userId int64, valid bool) error
 	DisableOtp(ctx context.Context, userId int64) error
 	GetAllByAccountId(ctx context.Context, account string) ([]domain.User, error)
+	UpdateRole(ctx context.Context, userId int64, role string) error
+	SetWebauthnEnabled(ctx context.Context, userId int64, enabled bool) error
 }
 
 type UsersCrm interface {
@@ -34,12 +36,53 @@ type UsersCrm interface {
 	Update(ctx context.Context, id string, user domain.User) (domain.User, error)
 	RetrieveContactMap(ctx context.Context, id string) (map[string]any, error)
 	ChangeSettingField(ctx context.Context, id string, field string, value bool) error
+	Create(ctx context.Context, user domain.User) (domain.User, error)
 }
 
 type Tokens interface {
 	Insert(ctx context.Context, token *domain.Token) error
 	DeleteAllForUser(ctx context.Context, scope string, userId int64) error
 	New(ctx context.Context, userId int64, ttl time.Duration, scope string) (*domain.Token, error)
+	NewInFamily(ctx context.Context, userId int64, ttl time.Duration, scope string, family string) (*domain.Token, error)
+	GetByPlaintext(ctx context.Context, scope string, plaintext string) (domain.Token, error)
+	MarkUsed(ctx context.Context, id int64) (bool, error)
+	Delete(ctx context.Context, id int64) error
+	DeleteFamily(ctx context.Context, family string) error
+	DeleteExpiredForUser(ctx context.Context, userId int64) error
+	Touch(ctx context.Context, plaintext string, userAgent string, ip string) error
+	GetSessions(ctx context.Context, userId int64) ([]domain.Session, error)
+	DeleteSession(ctx context.Context, userId int64, family string) error
+	DeleteOtherSessions(ctx context.Context, userId int64, family string) error
+}
+
+type Otp interface {
+	GetState(ctx context.Context, userId int64) (domain.OtpState, error)
+	IncrementFailures(ctx context.Context, userId int64) (int, error)
+	Lock(ctx context.Context, userId int64, until time.Time) error
+	ResetFailures(ctx context.Context, userId int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userId int64, hash string) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userId int64) error
+}
+
+type LoginAttempts interface {
+	Get(ctx context.Context, key string) (domain.LoginAttempts, error)
+	Reserve(ctx context.Context, key string, window time.Duration, allow func(domain.LoginAttempts) error) (domain.LoginAttempts, error)
+	Release(ctx context.Context, key string, lastFailedAt *time.Time) error
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+}
+
+type Audit interface {
+	Insert(ctx context.Context, entry *domain.AuditEntry) error
+	GetAll(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, int, error)
+}
+
+type WebauthnCredentials interface {
+	Insert(ctx context.Context, credential *domain.WebauthnCredential) error
+	GetAllByUser(ctx context.Context, userId int64) ([]domain.WebauthnCredential, error)
+	UpdateUsage(ctx context.Context, id int64, signCount uint32, cloneWarning bool) error
+	Delete(ctx context.Context, userId int64, id int64) error
 }
 
 type Managers interface {
@@ -89,6 +132,13 @@ type Invoice interface {
 	GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.Invoice, error)
 	Count(ctx context.Context, client string) (int, error)
 	GetFromSalesOrder(ctx context.Context, soId string) ([]domain.Invoice, error)
+	Export(ctx context.Context, client string, fn func(invoice domain.Invoice) error) error
+}
+
+type SalesOrder interface {
+	RetrieveById(ctx context.Context, id string) (domain.SalesOrder, error)
+	GetAll(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.SalesOrder, error)
+	Count(ctx context.Context, client string) (int, error)
 }
 
 type ServiceContract interface {
@@ -131,8 +181,59 @@ type Account interface {
 	RetrieveById(ctx context.Context, id string) (domain.Account, error)
 }
 
+type Mirror interface {
+	Upsert(ctx context.Context, record domain.MirrorRecord) error
+	Delete(ctx context.Context, ids []string) error
+	GetById(ctx context.Context, module string, id string) (domain.MirrorRecord, error)
+	Find(ctx context.Context, module string, filter vtiger.PaginationQueryFilter, fields vtiger.QueryFieldsProps) ([]domain.MirrorRecord, error)
+	Count(ctx context.Context, module string, client string, contact string, parent string) (int, error)
+	GetSyncState(ctx context.Context, module string) (domain.SyncState, error)
+	SaveSyncState(ctx context.Context, state domain.SyncState) error
+}
+
+type Sync interface {
+	Sync(ctx context.Context, module string, modifiedTime int64) (vtiger.SyncResult, error)
+	Retrieve(ctx context.Context, id string) (map[string]any, error)
+}
+
+type Webhooks interface {
+	Insert(ctx context.Context, webhook *domain.Webhook) error
+	GetById(ctx context.Context, id int64, accountId string) (domain.Webhook, error)
+	GetAllByAccountId(ctx context.Context, accountId string) ([]domain.Webhook, error)
+	GetActiveForEvent(ctx context.Context, accountId string, event string) ([]domain.Webhook, error)
+	Update(ctx context.Context, webhook *domain.Webhook) error
+	Delete(ctx context.Context, id int64, accountId string) error
+	InsertDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, webhookId int64, limit int) ([]domain.WebhookDelivery, error)
+}
+
+type Payments interface {
+	Insert(ctx context.Context, payment *domain.Payment, reserved domain.PaymentReservation) error
+	GetByStripeId(ctx context.Context, id string) (domain.Payment, error)
+	GetById(ctx context.Context, id int64) (domain.Payment, error)
+	GetPaymentsFromAccountId(ctx context.Context, id string) ([]domain.Payment, error)
+	UpdatePayment(ctx context.Context, payment domain.Payment) (domain.Payment, error)
+	SettlePayment(ctx context.Context, id int64, settledAt time.Time, event *domain.PaymentEvent) (bool, error)
+	GetAllocations(ctx context.Context, paymentId int64) ([]domain.PaymentAllocation, error)
+	GetAllocatedAmount(ctx context.Context, parentId string, reserved domain.PaymentReservation) (float64, error)
+	MarkAllocationRevised(ctx context.Context, id int64, revisedAt time.Time) error
+	InsertRefund(ctx context.Context, refund *domain.PaymentRefund, payment domain.Payment, event *domain.PaymentEvent) (bool, error)
+	GetRefunds(ctx context.Context, paymentId int64) ([]domain.PaymentRefund, error)
+	InsertEvent(ctx context.Context, event *domain.PaymentEvent) (bool, error)
+	GetEvent(ctx context.Context, provider string, eventId string) (domain.PaymentEvent, error)
+	ClaimReceivedEvent(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error)
+	UpdateEvent(ctx context.Context, event *domain.PaymentEvent) error
+	GetLastEventTime(ctx context.Context, paymentId int64) (time.Time, error)
+	GetDueEvents(ctx context.Context, now time.Time, limit int) ([]domain.PaymentEvent, error)
+	ClaimEvent(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error)
+}
+
 var ErrRecordNotFound = errors.New("record not found")
 var ErrEditConflict = errors.New("edit conflict")
+var ErrBalanceExceeded = errors.New("balance is already reserved by other payments")
 var ErrWrongCrmId = errors.New("wrong crm id")
 var ErrCanNotParseCountObject = errors.New("can not parse count object")
 
@@ -140,6 +241,9 @@ type Repositories struct {
 	Users            Users
 	UsersCrm         UsersCrm
 	Tokens           *TokensRepo
+	Otp              Otp
+	LoginAttempts    LoginAttempts
+	Webauthn         WebauthnCredentials
 	Managers         Managers
 	Modules          ModulesCrm
 	Company          Company
@@ -148,28 +252,35 @@ type Repositories struct {
 	Documents        Document
 	Faqs             Faq
 	Invoice          Invoice
-	SalesOrder       SalesOrderCrm
+	SalesOrder       SalesOrder
 	ServiceContract  ServiceContract
 	Currency         CurrencyCrm
 	Product          ProductCrm
 	Service          ServicesCrm
-	Projects         ProjectCrm
-	ProjectTasks     ProjectTaskCrm
+	Projects         Project
+	ProjectTasks     ProjectTask
 	Statistics       StatisticsCrm
 	Leads            LeadCrm
 	Account          AccountCrm
 	Search           SearchCrm
-	Payment          *PaymentsRepo
+	Payment          Payments
 	Notifications    *NotificationsRepo
 	NotificationsCrm NotificationsCrm
 	CustomModule     CustomModuleCrm
+	Mirror           Mirror
+	Sync             Sync
+	Webhooks         Webhooks
+	Audit            Audit
 }
 
 func NewRepositories(db *sql.DB, config config.Config, cache cache.Cache) *Repositories {
-	return &Repositories{
+	repositories := &Repositories{
 		Users:            NewUsersRepo(db),
 		UsersCrm:         NewUsersVtiger(config, cache),
 		Tokens:           NewTokensRepo(db),
+		Otp:              NewOtpRepo(db),
+		LoginAttempts:    NewLoginAttemptsRepo(db),
+		Webauthn:         NewWebauthnRepo(db),
 		Managers:         NewManagersCrm(config, cache),
 		Modules:          NewModulesCrm(config, cache),
 		Company:          NewCompanyCrm(config, cache),
@@ -193,5 +304,17 @@ func NewRepositories(db *sql.DB, config config.Config, cache cache.Cache) *Repos
 		Notifications:    NewNotificationsRepo(db),
 		NotificationsCrm: NewNotificationsCrm(config, cache),
 		CustomModule:     NewCustomModuleCrm(config, cache),
+		Mirror:           NewMirrorRepo(db),
+		Sync:             NewSyncCrm(config, cache),
+		Webhooks:         NewWebhooksRepo(db),
+		Audit:            NewAuditRepo(db),
+	}
+	if config.Sync.ReadFromMirror {
+		repositories.HelpDesk = NewHelpDeskMirror(repositories.HelpDesk, repositories.Mirror, repositories.Sync)
+		repositories.Invoice = NewInvoiceMirror(repositories.Invoice, repositories.Mirror)
+		repositories.SalesOrder = NewSalesOrderMirror(repositories.SalesOrder, repositories.Mirror)
+		repositories.Projects = NewProjectMirror(repositories.Projects, repositories.Mirror)
+		repositories.ProjectTasks = NewProjectTaskMirror(repositories.ProjectTasks, repositories.Mirror, repositories.Sync)
 	}
+	return repositories
 }
@@ -22,13 +22,13 @@ func NewSearchCrm(config config.Config, cache cache.Cache) SearchCrm {
 }
 
 func (s SearchCrm) SearchFaqs(ctx context.Context, query string) ([]domain.Search, error) {
-	sql := "SELECT id, question FROM Faq WHERE faqstatus = 'Published' AND question LIKE '%" + query + "%';"
+	sql := vtiger.NewQuery("Faq").Select("id", "question").Where(vtiger.Eq("faqstatus", "Published"), vtiger.Contains("question", query))
 	searches := make([]domain.Search, 0)
-	result, err := s.vtiger.Query(ctx, sql)
+	result, err := s.vtiger.SelectAll(ctx, sql)
 	if err != nil {
-		return searches, e.Wrap("can not execute query "+sql+", got error: "+result.Error.Message, err)
+		return searches, e.Wrap("can not search faqs", err)
 	}
-	for _, data := range result.Result {
+	for _, data := range result {
 		search := domain.ConvertMapToSearch(data)
 		search.Module = "Faq"
 		searches = append(searches, search)
@@ -37,35 +37,41 @@ func (s SearchCrm) SearchFaqs(ctx context.Context, query string) ([]domain.Searc
 }
 
 func (s SearchCrm) SearchTickets(ctx context.Context, query string, user domain.User) ([]domain.Search, error) {
-	sql := "SELECT id, ticket_title, parent_id FROM HelpDesk WHERE ticket_title LIKE '%" + query + "%' OR ticket_no LIKE '%" + query + "%';"
+	// VTQL does not support grouped conditions, so account is checked in query of every searched field
+	sql := vtiger.NewQuery("HelpDesk").Select("id", "ticket_title", "parent_id")
 	searches := make([]domain.Search, 0)
-	result, err := s.vtiger.Query(ctx, sql)
+	result, err := s.vtiger.SelectUnion(ctx, sql, 0,
+		[]vtiger.Condition{vtiger.Eq("parent_id", user.AccountId), vtiger.Contains("ticket_title", query)},
+		[]vtiger.Condition{vtiger.Eq("parent_id", user.AccountId), vtiger.Contains("ticket_no", query)},
+	)
 	if err != nil {
-		return searches, e.Wrap("can not execute query "+sql+", got error: "+result.Error.Message, err)
+		return searches, e.Wrap("can not search tickets", err)
 	}
-	for _, data := range result.Result {
+	for _, data := range result {
 		search := domain.ConvertMapToSearch(data)
 		search.Module = "HelpDesk"
-		if data["parent_id"] == user.AccountId {
-			searches = append(searches, search)
-		}
+		searches = append(searches, search)
 	}
 	return searches, nil
 }
 
 func (s SearchCrm) SearchProjects(ctx context.Context, query string, user domain.User) ([]domain.Search, error) {
-	sql := "SELECT id, projectname, linktoaccountscontacts FROM Project WHERE projectname LIKE '%" + query + "%' OR project_no LIKE '%" + query + "%';"
+	sql := vtiger.NewQuery("Project").Select("id", "projectname", "linktoaccountscontacts")
+	branches := make([][]vtiger.Condition, 0, 4)
+	for _, owner := range []string{user.AccountId, user.Crmid} {
+		for _, field := range []string{"projectname", "project_no"} {
+			branches = append(branches, []vtiger.Condition{vtiger.Eq("linktoaccountscontacts", owner), vtiger.Contains(field, query)})
+		}
+	}
 	searches := make([]domain.Search, 0)
-	result, err := s.vtiger.Query(ctx, sql)
+	result, err := s.vtiger.SelectUnion(ctx, sql, 0, branches...)
 	if err != nil {
-		return searches, e.Wrap("can not execute query "+sql+", got error: "+result.Error.Message, err)
+		return searches, e.Wrap("can not search projects", err)
 	}
-	for _, data := range result.Result {
+	for _, data := range result {
 		search := domain.ConvertMapToSearch(data)
 		search.Module = "Project"
-		if data["linktoaccountscontacts"] == user.AccountId || data["linktoaccountscontacts"] == user.Crmid {
-			searches = append(searches, search)
-		}
+		searches = append(searches, search)
 	}
 	return searches, nil
 }
@@ -43,42 +43,42 @@ func (s StatisticsCrm) TicketClosedStat(ctx context.Context, userModel domain.Us
 
 func (s StatisticsCrm) CalcTicketTotal(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateTicketsQuery(userModel, "")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcTicketOpen(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateTicketsQuery(userModel, "Open")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcTicketInProgress(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateTicketsQuery(userModel, "In Progress")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcTicketWaitForResponse(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateTicketsQuery(userModel, "Wait For Response")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcTicketClosed(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateTicketsQuery(userModel, "Closed")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcProjectsTotal(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateProjectsQuery(userModel, "")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcProjectsOpen(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateProjectsQuery(userModel, "open")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) CalcProjectsClosed(ctx context.Context, userModel domain.User) (int, error) {
 	query := s.generateProjectsQuery(userModel, "closed")
-	return s.vtiger.ExecuteCount(ctx, query)
+	return s.vtiger.SelectCount(ctx, query)
 }
 
 func (s StatisticsCrm) InvoicesOpenStat(ctx context.Context, userModel domain.User) ([]domain.Invoice, error) {
@@ -100,7 +100,7 @@ func (s StatisticsCrm) TasksFromInProgressProjects(ctx context.Context, userMode
 		return tasks, e.Wrap("can not receive in progress projects", err)
 	}
 	for _, project := range projects {
-		query := "SELECT * FROM ProjectTask WHERE projectid = " + project.Id + ";"
+		query := vtiger.NewQuery("ProjectTask").Where(vtiger.Eq("projectid", project.Id))
 		pt, err := executeQuery[domain.ProjectTask](ctx, query, s.vtiger, domain.ConvertMapToProjectTask)
 
 		if err != nil {
@@ -112,57 +112,60 @@ func (s StatisticsCrm) TasksFromInProgressProjects(ctx context.Context, userMode
 	return tasks, err
 }
 
-func (s StatisticsCrm) generateTicketsQuery(userModel domain.User, status string) string {
-	query := "SELECT COUNT(*) FROM HelpDesk WHERE parent_id = " + userModel.AccountId
+func (s StatisticsCrm) generateTicketsQuery(userModel domain.User, status string) *vtiger.QueryBuilder {
+	query := vtiger.NewQuery("HelpDesk").Where(vtiger.Eq("parent_id", userModel.AccountId))
 	if status != "all" && status != "" && status != "total" {
-		query += " AND ticketstatus = '" + status + "'"
+		query.Where(vtiger.Eq("ticketstatus", status))
 	}
-	query += ";"
 	return query
 }
 
-func (s StatisticsCrm) generateProjectsQuery(userModel domain.User, status string) string {
-	query := "SELECT COUNT(*) FROM Project WHERE linktoaccountscontacts = " + userModel.Crmid + " OR linktoaccountscontacts = " + userModel.AccountId + " "
-	if status == "" {
-		query += ";"
-	} else if status == "open" {
-		query += " AND projectstatus IN ('prospecting', 'initiated', 'in progress', 'waiting for feedback');"
+func (s StatisticsCrm) generateProjectsQuery(userModel domain.User, status string) *vtiger.QueryBuilder {
+	query := vtiger.NewQuery("Project").Where(s.projectOwnerCondition(userModel))
+	if status == "open" {
+		query.Where(vtiger.InStrings("projectstatus", "prospecting", "initiated", "in progress", "waiting for feedback"))
 	} else if status == "closed" {
-		query += " AND projectstatus IN ('completed', 'delivered');"
+		query.Where(vtiger.InStrings("projectstatus", "completed", "delivered"))
 	}
 
 	return query
 }
 
-func (s StatisticsCrm) generateTicketStatsQuery(userModel domain.User, status string) string {
-	query := "SELECT hours, days FROM HelpDesk WHERE parent_id = " + userModel.AccountId
+func (s StatisticsCrm) generateTicketStatsQuery(userModel domain.User, status string) *vtiger.QueryBuilder {
+	query := vtiger.NewQuery("HelpDesk").Select("hours", "days").Where(vtiger.Eq("parent_id", userModel.AccountId))
 	if status != "" {
-		query += " AND ticketstatus = '" + status + "'"
+		query.Where(vtiger.Eq("ticketstatus", status))
 	}
-	query += ";"
 	return query
 }
 
-func (s StatisticsCrm) executeTicketStatsQuery(ctx context.Context, query string) ([]domain.HelpDesk, error) {
+func (s StatisticsCrm) executeTicketStatsQuery(ctx context.Context, query *vtiger.QueryBuilder) ([]domain.HelpDesk, error) {
 	return executeQuery[domain.HelpDesk](ctx, query, s.vtiger, domain.ConvertMapToHelpDesk)
 }
 
-func (s StatisticsCrm) executeInvoiceStatsQuery(ctx context.Context, query string) ([]domain.Invoice, error) {
+func (s StatisticsCrm) executeInvoiceStatsQuery(ctx context.Context, query *vtiger.QueryBuilder) ([]domain.Invoice, error) {
 	return executeQuery[domain.Invoice](ctx, query, s.vtiger, domain.ConvertMapToInvoice)
 }
 
 func (s StatisticsCrm) getInProgressProjects(ctx context.Context, userModel domain.User) ([]domain.Project, error) {
-	query := "SELECT projectname FROM Project WHERE linktoaccountscontacts = " + userModel.Crmid + " OR linktoaccountscontacts = " + userModel.AccountId + " AND projectstatus IN ('in progress', 'Выполняется');"
+	query := vtiger.NewQuery("Project").Select("projectname").Where(
+		s.projectOwnerCondition(userModel),
+		vtiger.InStrings("projectstatus", "in progress", "Выполняется"),
+	)
 	return executeQuery[domain.Project](ctx, query, s.vtiger, domain.ConvertMapToProject)
 }
 
-func executeQuery[T domain.HelpDesk | domain.Invoice | domain.Project | domain.ProjectTask](ctx context.Context, query string, c vtiger.VtigerConnector, fn func(map[string]any) (T, error)) ([]T, error) {
-	result, err := c.Query(ctx, query)
+func (s StatisticsCrm) projectOwnerCondition(userModel domain.User) vtiger.Condition {
+	return vtiger.InStrings("linktoaccountscontacts", userModel.Crmid, userModel.AccountId)
+}
+
+func executeQuery[T domain.HelpDesk | domain.Invoice | domain.Project | domain.ProjectTask](ctx context.Context, query *vtiger.QueryBuilder, c vtiger.VtigerConnector, fn func(map[string]any) (T, error)) ([]T, error) {
+	result, err := c.SelectAll(ctx, query)
 	tickets := make([]T, 0)
 	if err != nil {
-		return nil, e.Wrap("can not execute query "+query+", got error", err)
+		return nil, err
 	}
-	for _, data := range result.Result {
+	for _, data := range result {
 		ticket, err := fn(data)
 		if err != nil {
 			return nil, e.Wrap("can not convert map to type", err)
@@ -172,14 +175,12 @@ func executeQuery[T domain.HelpDesk | domain.Invoice | domain.Project | domain.P
 	return tickets, nil
 }
 
-func (s StatisticsCrm) generateInvoiceStatsQuery(userModel domain.User, status string) string {
-	query := "SELECT hdnGrandTotal FROM Invoice WHERE account_id = " + userModel.AccountId
+func (s StatisticsCrm) generateInvoiceStatsQuery(userModel domain.User, status string) *vtiger.QueryBuilder {
+	query := vtiger.NewQuery("Invoice").Select("hdnGrandTotal").Where(vtiger.Eq("account_id", userModel.AccountId))
 	if status == "Open" {
-		query += " AND invoicestatus IN ('Created', 'Approved', 'Sent')"
+		query.Where(vtiger.InStrings("invoicestatus", "Created", "Approved", "Sent"))
 	} else if status == "Closed" {
-		query += " AND invoicestatus IN ('Paid')"
+		query.Where(vtiger.InStrings("invoicestatus", "Paid"))
 	}
-
-	query += ";"
 	return query
 }
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+)
+
+type SyncCrm struct {
+	vtiger vtiger.VtigerConnector
+	config config.Config
+}
+
+func NewSyncCrm(config config.Config, cache cache.Cache) SyncCrm {
+	return SyncCrm{
+		vtiger: vtiger.NewVtigerConnector(cache, config.Vtiger.Connection, vtiger.NewWebRequest(config.Vtiger.Connection)),
+		config: config,
+	}
+}
+
+func (s SyncCrm) Sync(ctx context.Context, module string, modifiedTime int64) (vtiger.SyncResult, error) {
+	result, err := s.vtiger.Sync(ctx, module, modifiedTime)
+	if err != nil {
+		return vtiger.SyncResult{}, e.Wrap("can not sync module "+module, err)
+	}
+	return result.Result, nil
+}
+
+func (s SyncCrm) Retrieve(ctx context.Context, id string) (map[string]any, error) {
+	result, err := s.vtiger.Retrieve(ctx, id)
+	if err != nil {
+		return nil, e.Wrap("can not retrieve entity "+id, err)
+	}
+	return result.Result, nil
+}
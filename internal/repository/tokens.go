@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"encoding/base32"
 	"encoding/hex"
+	"errors"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
 	"time"
 )
@@ -30,9 +31,20 @@ func (t TokensRepo) New(ctx context.Context, userId int64, ttl time.Duration, sc
 	return token, err
 }
 
+// NewInFamily creates token, which belongs to login session, so all tokens of session can be revoked together.
+func (t TokensRepo) NewInFamily(ctx context.Context, userId int64, ttl time.Duration, scope string, family string) (*domain.Token, error) {
+	token, err := generateToken(userId, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+	token.Family = family
+	err = t.Insert(ctx, token)
+	return token, err
+}
+
 func (t TokensRepo) Insert(ctx context.Context, token *domain.Token) error {
-	var query = `INSERT INTO tokens (hash, user_id, expired_at, scope) VALUES (?,?,?,?)`
-	var args = []any{token.Hash, token.UserId, token.Expiry, token.Scope}
+	var query = `INSERT INTO tokens (hash, user_id, expired_at, scope, family) VALUES (?,?,?,?,?)`
+	var args = []any{token.Hash, token.UserId, token.Expiry, token.Scope, token.Family}
 
 	result, err := t.db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -50,6 +62,127 @@ func (t TokensRepo) DeleteAllForUser(ctx context.Context, scope string, userId i
 	return err
 }
 
+// GetByPlaintext finds token of scope, including expired and used tokens.
+func (t TokensRepo) GetByPlaintext(ctx context.Context, scope string, plaintext string) (domain.Token, error) {
+	var hash = sha256.Sum256([]byte(plaintext))
+	var query = `SELECT id, hash, user_id, expired_at, scope, family, used_at FROM tokens WHERE hash = ? AND scope = ?`
+	var token domain.Token
+	var usedAt sql.NullTime
+
+	err := t.db.QueryRowContext(ctx, query, hex.EncodeToString(hash[:]), scope).Scan(&token.ID, &token.Hash, &token.UserId, &token.Expiry, &token.Scope, &token.Family, &usedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return token, ErrRecordNotFound
+		}
+		return token, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+// MarkUsed marks refresh token as exchanged. It returns false, when token was already used by other request.
+func (t TokensRepo) MarkUsed(ctx context.Context, id int64) (bool, error) {
+	var query = `UPDATE tokens SET used_at = ? WHERE id = ? AND used_at IS NULL`
+	result, err := t.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (t TokensRepo) Delete(ctx context.Context, id int64) error {
+	var query = `DELETE FROM tokens WHERE id = ?`
+
+	_, err := t.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (t TokensRepo) DeleteFamily(ctx context.Context, family string) error {
+	var query = `DELETE FROM tokens WHERE family = ?`
+
+	_, err := t.db.ExecContext(ctx, query, family)
+	return err
+}
+
+func (t TokensRepo) DeleteExpiredForUser(ctx context.Context, userId int64) error {
+	var query = `DELETE FROM tokens WHERE user_id = ? AND expired_at < ?`
+
+	_, err := t.db.ExecContext(ctx, query, userId, time.Now())
+	return err
+}
+
+// Touch stores last usage of session, which access token belongs to. All tokens of session are updated, so metadata
+// survives token rotation.
+func (t TokensRepo) Touch(ctx context.Context, plaintext string, userAgent string, ip string) error {
+	var hash = sha256.Sum256([]byte(plaintext))
+	var query = `UPDATE tokens INNER JOIN (SELECT DISTINCT family FROM tokens WHERE hash = ? AND scope = ? AND family <> '') AS current
+		ON tokens.family = current.family
+		SET tokens.last_used_at = ?, tokens.user_agent = ?, tokens.ip = ?`
+	var args = []any{hex.EncodeToString(hash[:]), domain.ScopeAuthentication, time.Now(), userAgent, ip}
+
+	_, err := t.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// GetSessions returns sessions of user, which still have not expired access token or not used refresh token.
+func (t TokensRepo) GetSessions(ctx context.Context, userId int64) ([]domain.Session, error) {
+	var query = `SELECT family, MIN(created_at), MAX(last_used_at), MAX(user_agent), MAX(ip), MAX(expired_at)
+		FROM tokens
+		WHERE user_id = ? AND scope IN (?, ?) AND family <> ''
+		GROUP BY family
+		HAVING SUM(expired_at > ? AND used_at IS NULL) > 0
+		ORDER BY MAX(last_used_at) DESC, MIN(created_at) DESC`
+	var args = []any{userId, domain.ScopeAuthentication, domain.ScopeRefresh, time.Now()}
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		var session domain.Session
+		var lastUsedAt sql.NullTime
+		if err = rows.Scan(&session.Id, &session.CreatedAt, &lastUsedAt, &session.UserAgent, &session.Ip, &session.Expiry); err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			session.LastUsedAt = &lastUsedAt.Time
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (t TokensRepo) DeleteSession(ctx context.Context, userId int64, family string) error {
+	var query = `DELETE FROM tokens WHERE user_id = ? AND family = ? AND scope IN (?, ?)`
+
+	result, err := t.db.ExecContext(ctx, query, userId, family, domain.ScopeAuthentication, domain.ScopeRefresh)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteOtherSessions signs user out everywhere, except session with passed family.
+func (t TokensRepo) DeleteOtherSessions(ctx context.Context, userId int64, family string) error {
+	var query = `DELETE FROM tokens WHERE user_id = ? AND family <> ? AND scope IN (?, ?)`
+
+	_, err := t.db.ExecContext(ctx, query, userId, family, domain.ScopeAuthentication, domain.ScopeRefresh)
+	return err
+}
+
 func generateToken(userId int64, ttl time.Duration, scope string) (*domain.Token, error) {
 	var token = &domain.Token{
 		UserId: userId,
@@ -42,6 +42,10 @@ func (receiver UsersCrmMock) RetrieveContactMap(ctx context.Context, id string)
 	return map[string]any{}, nil
 }
 
+func (receiver UsersCrmMock) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	return receiver.user, nil
+}
+
 func (receiver UsersCrmMock) ChangeSettingField(ctx context.Context, id string, field string, value bool) error {
 	return nil
 }
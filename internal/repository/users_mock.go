@@ -10,6 +10,7 @@ var MockedUser = domain.User{
 	Id:          1,
 	Crmid:       "12x11",
 	AccountId:   "11x1",
+	Role:        domain.RoleOwner,
 	FirstName:   "Sergey",
 	LastName:    "Emelyanov",
 	Description: "Test Description",
@@ -73,3 +74,11 @@ func (r *UsersMock) RetrieveContactMap(ctx context.Context, id string) (map[stri
 func (r *UsersMock) GetAllByAccountId(ctx context.Context, account string) ([]domain.User, error) {
 	return nil, nil
 }
+
+func (r *UsersMock) UpdateRole(ctx context.Context, userId int64, role string) error {
+	return nil
+}
+
+func (r *UsersMock) SetWebauthnEnabled(ctx context.Context, userId int64, enabled bool) error {
+	return nil
+}
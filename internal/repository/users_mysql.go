@@ -30,9 +30,9 @@ func (r *UsersRepo) Insert(ctx context.Context, user *domain.User) error {
 	user.Version = 1
 
 	var query = `
-				INSERT INTO users (crmid, first_name, last_name, description, account_id, account_name, title, department, email, password, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id) 
-				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	var args = []any{user.Crmid, user.FirstName, user.LastName, user.Description, user.AccountId, user.AccountName, user.Title, user.Department, user.Email, user.Password.Hash, user.CreatedAt, user.UpdatedAt, user.IsActive, user.MailingCity, user.MailingStreet, user.MailingCountry, user.OtherCountry, user.MailingState, user.MailingPoBox, user.OtherCity, user.OtherState, user.MailingZip, user.OtherZip, user.OtherStreet, user.OtherPoBox, user.Image, user.Imageattachmentids, user.Version, user.Phone, user.AssignedUserId}
+				INSERT INTO users (crmid, first_name, last_name, description, account_id, account_name, title, department, email, password, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, role) 
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{user.Crmid, user.FirstName, user.LastName, user.Description, user.AccountId, user.AccountName, user.Title, user.Department, user.Email, user.Password.Hash, user.CreatedAt, user.UpdatedAt, user.IsActive, user.MailingCity, user.MailingStreet, user.MailingCountry, user.OtherCountry, user.MailingState, user.MailingPoBox, user.OtherCity, user.OtherState, user.MailingZip, user.OtherZip, user.OtherStreet, user.OtherPoBox, user.Image, user.Imageattachmentids, user.Version, user.Phone, user.AssignedUserId, user.RoleOrDefault()}
 
 	result, err := r.db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -54,7 +54,7 @@ func (r *UsersRepo) Insert(ctx context.Context, user *domain.User) error {
 }
 
 func (r *UsersRepo) GetByEmail(ctx context.Context, email string) (domain.User, error) {
-	var query = `SELECT id, crmid, first_name, last_name, description, account_id, account_name, title, department, email, password, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, otp_enabled, otp_verified FROM users WHERE email = ?`
+	var query = `SELECT id, crmid, first_name, last_name, description, account_id, account_name, role, title, department, email, password, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, otp_enabled, otp_verified, webauthn_enabled FROM users WHERE email = ?`
 	var user domain.User
 
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
@@ -65,11 +65,12 @@ func (r *UsersRepo) GetByEmail(ctx context.Context, email string) (domain.User,
 		&user.Description,
 		&user.AccountId,
 		&user.AccountName,
+		&user.Role,
 		&user.Title,
 		&user.Department,
 		&user.Email, &user.Password.Hash,
 		&user.CreatedAt, &user.UpdatedAt,
-		&user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Imageattachmentids, &user.Version, &user.Phone, &user.AssignedUserId, &user.Otp_enabled, &user.Otp_verified,
+		&user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Imageattachmentids, &user.Version, &user.Phone, &user.AssignedUserId, &user.Otp_enabled, &user.Otp_verified, &user.Webauthn_enabled,
 	)
 	if err != nil {
 		switch {
@@ -83,7 +84,7 @@ func (r *UsersRepo) GetByEmail(ctx context.Context, email string) (domain.User,
 }
 
 func (r *UsersRepo) GetById(ctx context.Context, id int64) (domain.User, error) {
-	var query = `SELECT id, crmid, first_name, last_name, description, account_id, account_name, title, department, email, password, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, otp_verified, otp_enabled, otp_secret, otp_auth_url FROM users WHERE id = ?`
+	var query = `SELECT id, crmid, first_name, last_name, description, account_id, account_name, role, title, department, email, password, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, otp_verified, otp_enabled, otp_secret, otp_auth_url, webauthn_enabled FROM users WHERE id = ?`
 	var user domain.User
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -94,11 +95,12 @@ func (r *UsersRepo) GetById(ctx context.Context, id int64) (domain.User, error)
 		&user.Description,
 		&user.AccountId,
 		&user.AccountName,
+		&user.Role,
 		&user.Title,
 		&user.Department,
 		&user.Email, &user.Password.Hash,
 		&user.CreatedAt, &user.UpdatedAt,
-		&user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Imageattachmentids, &user.Version, &user.Phone, &user.AssignedUserId, &user.Otp_verified, &user.Otp_enabled, &user.Otp_secret, &user.Otp_auth_url,
+		&user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Imageattachmentids, &user.Version, &user.Phone, &user.AssignedUserId, &user.Otp_verified, &user.Otp_enabled, &user.Otp_secret, &user.Otp_auth_url, &user.Webauthn_enabled,
 	)
 	if err != nil {
 		switch {
@@ -137,7 +139,7 @@ func (r *UsersRepo) GetForToken(ctx context.Context, tokenScope, tokenPlaintext
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-        SELECT users.id, crmid, first_name, last_name, description, account_id, account_name, title, department, email, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, otp_enabled, otp_verified, otp_auth_url, otp_secret
+        SELECT users.id, crmid, first_name, last_name, description, account_id, account_name, role, title, department, email, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, imageattachmentids, version, phone, assigned_user_id, otp_enabled, otp_verified, otp_auth_url, otp_secret, webauthn_enabled
         FROM users
         INNER JOIN tokens
         ON users.id = tokens.user_id
@@ -150,7 +152,7 @@ func (r *UsersRepo) GetForToken(ctx context.Context, tokenScope, tokenPlaintext
 	var user domain.User
 
 	err := r.db.QueryRowContext(ctx, query, args...).Scan(
-		&user.Id, &user.Crmid, &user.FirstName, &user.LastName, &user.Description, &user.AccountId, &user.AccountName, &user.Title, &user.Department, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Imageattachmentids, &user.Version, &user.Phone, &user.AssignedUserId, &user.Otp_enabled, &user.Otp_verified, &user.Otp_auth_url, &user.Otp_secret,
+		&user.Id, &user.Crmid, &user.FirstName, &user.LastName, &user.Description, &user.AccountId, &user.AccountName, &user.Role, &user.Title, &user.Department, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Imageattachmentids, &user.Version, &user.Phone, &user.AssignedUserId, &user.Otp_enabled, &user.Otp_verified, &user.Otp_auth_url, &user.Otp_secret, &user.Webauthn_enabled,
 	)
 	if err != nil {
 		switch {
@@ -230,8 +232,31 @@ func (r *UsersRepo) DisableOtp(ctx context.Context, userId int64) error {
 	return nil
 }
 
+func (r *UsersRepo) UpdateRole(ctx context.Context, userId int64, role string) error {
+	var query = `UPDATE users SET role = ?, version = version + 1, updated_at = NOW() WHERE id = ?`
+	result, err := r.db.ExecContext(ctx, query, role, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UsersRepo) SetWebauthnEnabled(ctx context.Context, userId int64, enabled bool) error {
+	var query = `UPDATE users SET webauthn_enabled = ?, version = version + 1, updated_at = NOW() WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, enabled, userId)
+	return err
+}
+
 func (r *UsersRepo) GetAllByAccountId(ctx context.Context, account string) ([]domain.User, error) {
-	var query = `SELECT id, crmid, first_name, last_name, description, account_id, account_name, title, department, email, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, version, imageattachmentids, phone, assigned_user_id, otp_enabled, otp_verified, otp_secret, otp_auth_url FROM users WHERE account_id = ?`
+	var query = `SELECT id, crmid, first_name, last_name, description, account_id, account_name, role, title, department, email, created_at, updated_at, is_active, mailingcity, mailingstreet, mailingcountry, othercountry, mailingstate, mailingpobox, othercity, otherstate, mailingzip, otherzip, otherstreet, otherpobox, image, version, imageattachmentids, phone, assigned_user_id, otp_enabled, otp_verified, otp_secret, otp_auth_url, webauthn_enabled FROM users WHERE account_id = ?`
 	var users = make([]domain.User, 0)
 	rows, err := r.db.QueryContext(ctx, query, account)
 	if err != nil {
@@ -240,7 +265,7 @@ func (r *UsersRepo) GetAllByAccountId(ctx context.Context, account string) ([]do
 	defer rows.Close()
 	for rows.Next() {
 		var user domain.User
-		err = rows.Scan(&user.Id, &user.Crmid, &user.FirstName, &user.LastName, &user.Description, &user.AccountId, &user.AccountName, &user.Title, &user.Department, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Version, &user.Imageattachmentids, &user.Phone, &user.AssignedUserId, &user.Otp_enabled, &user.Otp_verified, &user.Otp_secret, &user.Otp_auth_url)
+		err = rows.Scan(&user.Id, &user.Crmid, &user.FirstName, &user.LastName, &user.Description, &user.AccountId, &user.AccountName, &user.Role, &user.Title, &user.Department, &user.Email, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.MailingCity, &user.MailingStreet, &user.MailingCountry, &user.OtherCountry, &user.MailingState, &user.MailingPoBox, &user.OtherCity, &user.OtherState, &user.MailingZip, &user.OtherZip, &user.OtherStreet, &user.OtherPoBox, &user.Image, &user.Version, &user.Imageattachmentids, &user.Phone, &user.AssignedUserId, &user.Otp_enabled, &user.Otp_verified, &user.Otp_secret, &user.Otp_auth_url, &user.Webauthn_enabled)
 		if err != nil {
 			return nil, err
 		}
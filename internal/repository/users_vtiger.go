@@ -22,14 +22,14 @@ func NewUsersVtiger(config config.Config, cache cache.Cache) UsersVtiger {
 }
 
 func (receiver UsersVtiger) FindByEmail(ctx context.Context, email string) ([]domain.User, error) {
-	result, err := receiver.vtiger.Query(ctx, "SELECT * FROM Contacts WHERE email = '"+email+"';")
+	result, err := receiver.vtiger.Select(ctx, vtiger.NewQuery("Contacts").Where(vtiger.Eq("email", email)))
 
 	users := make([]domain.User, 0)
 	if err != nil {
 		return users, e.Wrap("can not get user by email "+email, err)
 	}
 
-	for _, m := range result.Result {
+	for _, m := range result {
 		curUser := domain.ConvertMapToUser(m)
 		curUser.Code = m[receiver.config.Vtiger.Business.CodeField].(string)
 		users = append(users, curUser)
@@ -92,6 +92,22 @@ func (receiver UsersVtiger) Update(ctx context.Context, id string, user domain.U
 	return user, nil
 }
 
+// Create adds contact to vtiger. Only fields, which are known before contact registers in portal, are sent.
+func (receiver UsersVtiger) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	input := map[string]any{
+		"firstname":        user.FirstName,
+		"lastname":         user.LastName,
+		"email":            user.Email,
+		"account_id":       user.AccountId,
+		"assigned_user_id": user.AssignedUserId,
+	}
+	result, err := receiver.vtiger.Create(ctx, "Contacts", input)
+	if err != nil {
+		return user, e.Wrap("can not create contact "+user.Email, err)
+	}
+	return domain.ConvertMapToUser(result.Result), nil
+}
+
 func (receiver UsersVtiger) RetrieveContactMap(ctx context.Context, id string) (map[string]any, error) {
 	result, err := receiver.vtiger.Retrieve(ctx, id)
 	if err != nil {
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"strings"
+	"time"
+)
+
+type WebauthnRepo struct {
+	db *sql.DB
+}
+
+func NewWebauthnRepo(db *sql.DB) *WebauthnRepo {
+	return &WebauthnRepo{
+		db: db,
+	}
+}
+
+func (r *WebauthnRepo) Insert(ctx context.Context, credential *domain.WebauthnCredential) error {
+	credential.CreatedAt = time.Now()
+
+	var query = `INSERT INTO webauthn_credentials (user_id, name, credential_id, public_key, attestation_type, transports, aaguid, sign_count, clone_warning, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{credential.UserId, credential.Name, credential.CredentialId, credential.PublicKey, credential.AttestationType, strings.Join(credential.Transports, ","), credential.Aaguid, credential.SignCount, credential.CloneWarning, credential.CreatedAt}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	credential.Id, err = result.LastInsertId()
+	return err
+}
+
+func (r *WebauthnRepo) GetAllByUser(ctx context.Context, userId int64) ([]domain.WebauthnCredential, error) {
+	var query = `SELECT id, user_id, name, credential_id, public_key, attestation_type, transports, aaguid, sign_count, clone_warning, created_at, last_used_at FROM webauthn_credentials WHERE user_id = ? ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := make([]domain.WebauthnCredential, 0)
+	for rows.Next() {
+		var credential domain.WebauthnCredential
+		var transports string
+		var lastUsedAt sql.NullTime
+		err = rows.Scan(&credential.Id, &credential.UserId, &credential.Name, &credential.CredentialId, &credential.PublicKey, &credential.AttestationType, &transports, &credential.Aaguid, &credential.SignCount, &credential.CloneWarning, &credential.CreatedAt, &lastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		credential.Transports = make([]string, 0)
+		if transports != "" {
+			credential.Transports = strings.Split(transports, ",")
+		}
+		if lastUsedAt.Valid {
+			credential.LastUsedAt = &lastUsedAt.Time
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// UpdateUsage stores signature counter of authenticator after successful login.
+func (r *WebauthnRepo) UpdateUsage(ctx context.Context, id int64, signCount uint32, cloneWarning bool) error {
+	var query = `UPDATE webauthn_credentials SET sign_count = ?, clone_warning = ?, last_used_at = ? WHERE id = ?`
+
+	_, err := r.db.ExecContext(ctx, query, signCount, cloneWarning, time.Now(), id)
+	return err
+}
+
+func (r *WebauthnRepo) Delete(ctx context.Context, userId int64, id int64) error {
+	var query = `DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"strings"
+	"time"
+)
+
+type WebhooksRepo struct {
+	db *sql.DB
+}
+
+func NewWebhooksRepo(db *sql.DB) *WebhooksRepo {
+	return &WebhooksRepo{
+		db: db,
+	}
+}
+
+const webhookColumns = `id, account_id, user_id, url, secret, events, is_active, created_at, updated_at`
+
+func (r *WebhooksRepo) Insert(ctx context.Context, webhook *domain.Webhook) error {
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = time.Now()
+
+	var query = `
+				INSERT INTO webhooks (account_id, user_id, url, secret, events, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{webhook.AccountId, webhook.UserId, webhook.Url, webhook.Secret, strings.Join(webhook.Events, ","), webhook.IsActive, webhook.CreatedAt, webhook.UpdatedAt}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	webhook.Id = id
+	return nil
+}
+
+func (r *WebhooksRepo) GetById(ctx context.Context, id int64, accountId string) (domain.Webhook, error) {
+	var query = `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ? AND account_id = ?`
+	webhook, err := r.scanWebhook(r.db.QueryRowContext(ctx, query, id, accountId))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return webhook, ErrRecordNotFound
+		default:
+			return webhook, err
+		}
+	}
+	return webhook, nil
+}
+
+func (r *WebhooksRepo) GetAllByAccountId(ctx context.Context, accountId string) ([]domain.Webhook, error) {
+	var query = `SELECT ` + webhookColumns + ` FROM webhooks WHERE account_id = ? ORDER BY id`
+	return r.queryWebhooks(ctx, query, accountId)
+}
+
+// GetActiveForEvent returns active webhooks of account, which are subscribed to event.
+func (r *WebhooksRepo) GetActiveForEvent(ctx context.Context, accountId string, event string) ([]domain.Webhook, error) {
+	var query = `SELECT ` + webhookColumns + ` FROM webhooks WHERE account_id = ? AND is_active = 1 AND FIND_IN_SET(?, events) > 0`
+	return r.queryWebhooks(ctx, query, accountId, event)
+}
+
+func (r *WebhooksRepo) Update(ctx context.Context, webhook *domain.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+	var query = `UPDATE webhooks SET url = ?, events = ?, is_active = ?, updated_at = ? WHERE id = ? AND account_id = ?`
+	var args = []any{webhook.Url, strings.Join(webhook.Events, ","), webhook.IsActive, webhook.UpdatedAt, webhook.Id, webhook.AccountId}
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *WebhooksRepo) Delete(ctx context.Context, id int64, accountId string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ? AND account_id = ?`, id, accountId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *WebhooksRepo) InsertDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	delivery.CreatedAt = time.Now()
+	delivery.UpdatedAt = time.Now()
+
+	var query = `
+				INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts, next_attempt_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	var args = []any{delivery.WebhookId, delivery.Event, []byte(delivery.Payload), delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt, delivery.UpdatedAt}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	delivery.Id = id
+	return nil
+}
+
+// GetDueDeliveries returns pending deliveries, which should be sent now, together with their webhooks.
+func (r *WebhooksRepo) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var query = `SELECT d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_code, d.error, d.next_attempt_at, d.delivered_at, d.created_at, d.updated_at,
+					w.id, w.account_id, w.user_id, w.url, w.secret, w.events, w.is_active, w.created_at, w.updated_at
+					FROM webhook_deliveries d INNER JOIN webhooks w ON w.id = d.webhook_id
+					WHERE d.status = ? AND w.is_active = 1 AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at LIMIT ?`
+	rows, err := r.db.QueryContext(ctx, query, domain.WebhookDeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries = make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		var webhook domain.Webhook
+		var events string
+		delivery, err := r.scanDelivery(rows, &webhook.Id, &webhook.AccountId, &webhook.UserId, &webhook.Url, &webhook.Secret, &events, &webhook.IsActive, &webhook.CreatedAt, &webhook.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Events = splitEvents(events)
+		delivery.Webhook = webhook
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery moves next attempt of due delivery to until. It returns false, when delivery was claimed by another worker.
+func (r *WebhooksRepo) ClaimDelivery(ctx context.Context, id int64, now time.Time, until time.Time) (bool, error) {
+	var query = `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?`
+	result, err := r.db.ExecContext(ctx, query, until, id, domain.WebhookDeliveryPending, now)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *WebhooksRepo) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	var query = `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?, error = ?, next_attempt_at = ?, delivered_at = ?, updated_at = ? WHERE id = ?`
+	var args = []any{delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error, delivery.NextAttemptAt, delivery.DeliveredAt, delivery.UpdatedAt, delivery.Id}
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *WebhooksRepo) GetDeliveries(ctx context.Context, webhookId int64, limit int) ([]domain.WebhookDelivery, error) {
+	var query = `SELECT id, webhook_id, event, payload, status, attempts, response_code, error, next_attempt_at, delivered_at, created_at, updated_at
+					FROM webhook_deliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`
+	rows, err := r.db.QueryContext(ctx, query, webhookId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries = make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := r.scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhooksRepo) queryWebhooks(ctx context.Context, query string, args ...any) ([]domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var webhooks = make([]domain.Webhook, 0)
+	for rows.Next() {
+		webhook, err := r.scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhooksRepo) scanWebhook(row rowScanner) (domain.Webhook, error) {
+	var webhook domain.Webhook
+	var events string
+	err := row.Scan(&webhook.Id, &webhook.AccountId, &webhook.UserId, &webhook.Url, &webhook.Secret, &events, &webhook.IsActive, &webhook.CreatedAt, &webhook.UpdatedAt)
+	webhook.Events = splitEvents(events)
+	return webhook, err
+}
+
+// scanDelivery reads columns of delivery, extra destinations are used for joined columns.
+func (r *WebhooksRepo) scanDelivery(row rowScanner, extra ...any) (domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	var payload []byte
+	var deliveryError sql.NullString
+	var nextAttemptAt, deliveredAt sql.NullTime
+	dest := []any{&delivery.Id, &delivery.WebhookId, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts, &delivery.ResponseCode,
+		&deliveryError, &nextAttemptAt, &deliveredAt, &delivery.CreatedAt, &delivery.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return delivery, err
+	}
+	delivery.Payload = payload
+	delivery.Error = deliveryError.String
+	if nextAttemptAt.Valid {
+		delivery.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return delivery, nil
+}
+
+func splitEvents(events string) []string {
+	if events == "" {
+		return []string{}
+	}
+	return strings.Split(events, ",")
+}
@@ -2,6 +2,10 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
 	"errors"
 	"github.com/pquerna/otp/totp"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
@@ -9,25 +13,52 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"strings"
 	"sync"
+	"time"
 )
 
 type AuthService struct {
-	repo   repository.Users
-	wg     *sync.WaitGroup
-	cache  cache.Cache
-	config config.Config
+	repo    repository.Users
+	otp     repository.Otp
+	wg      *sync.WaitGroup
+	cache   cache.Cache
+	config  config.Config
+	emails  EmailServiceInterface
+	company Company
 }
 
 var ErrTokenNotExist = errors.New("token not exist or invalid")
 
-func NewAuthService(repo repository.Users, wg *sync.WaitGroup, cache cache.Cache, config config.Config) AuthService {
-	return AuthService{repo: repo, wg: wg, cache: cache, config: config}
+var ErrOtpLocked = errors.New("too many wrong one-time passwords, try again later")
+
+var ErrOtpNotEnabled = errors.New("one-time password is not enabled")
+
+const (
+	defaultOtpMaxAttempts     = 5
+	defaultOtpLockoutDuration = 15 * time.Minute
+	defaultOtpRecoveryCodes   = 10
+)
+
+func NewAuthService(repo repository.Users, otp repository.Otp, wg *sync.WaitGroup, cache cache.Cache, config config.Config, emails EmailServiceInterface, company Company) AuthService {
+	return AuthService{repo: repo, otp: otp, wg: wg, cache: cache, config: config, emails: emails, company: company}
 }
 
 type OTPInput struct {
-	UserId int64  `json:"id"`
-	Token  string `json:"token"`
+	UserId       int64  `json:"id"`
+	Token        string `json:"token"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// OtpVerifyResult is a user with recovery codes, which are shown only once, when OTP is enabled.
+type OtpVerifyResult struct {
+	domain.User
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type RecoveryCodesResult struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 type OtpRegisterResult struct {
@@ -59,25 +90,32 @@ func (a AuthService) GenerateOtp(ctx context.Context, input OTPInput) (OtpRegist
 	return otpResult, err
 }
 
-func (a AuthService) VerifyOtp(ctx context.Context, input OTPInput) (domain.User, error) {
+// VerifyOtp checks one-time password or recovery code. Recovery codes are generated, when OTP is verified first time.
+func (a AuthService) VerifyOtp(ctx context.Context, input OTPInput) (OtpVerifyResult, error) {
 	user, err := a.repo.GetById(ctx, input.UserId)
 
 	if err != nil {
-		return user, ErrUserNotFound
+		return OtpVerifyResult{User: user}, ErrUserNotFound
 	}
 
-	valid := totp.Validate(input.Token, user.Otp_secret)
-	if !valid {
-		return user, ErrTokenNotExist
+	if err = a.checkOtp(ctx, user, input, user.Otp_enabled); err != nil {
+		return OtpVerifyResult{User: user}, err
 	}
 	err = a.repo.EnableAndVerifyOtp(ctx, input.UserId)
 	if err != nil {
-		return user, e.Wrap("can not update user", err)
+		return OtpVerifyResult{User: user}, e.Wrap("can not update user", err)
 	}
-	user.Otp_enabled = true
-	user.Otp_verified = true
-	StoreInCache[*domain.User](user.Crmid, &user, CacheUsersTTL, a.cache)
-	return user, nil
+	result := OtpVerifyResult{User: user}
+	if !user.Otp_enabled {
+		result.RecoveryCodes, err = a.newRecoveryCodes(ctx, user.Id)
+		if err != nil {
+			return result, err
+		}
+	}
+	result.Otp_enabled = true
+	result.Otp_verified = true
+	StoreInCache[*domain.User](user.Crmid, &result.User, CacheUsersTTL, a.cache)
+	return result, nil
 }
 
 func (a AuthService) DisableOtp(ctx context.Context, input OTPInput) (domain.User, error) {
@@ -87,17 +125,149 @@ func (a AuthService) DisableOtp(ctx context.Context, input OTPInput) (domain.Use
 		return user, ErrUserNotFound
 	}
 
-	valid := totp.Validate(input.Token, user.Otp_secret)
-	if !valid {
-		return user, ErrTokenNotExist
+	input.RecoveryCode = ""
+	if err = a.checkOtp(ctx, user, input, false); err != nil {
+		return user, err
 	}
 	err = a.repo.DisableOtp(ctx, input.UserId)
 	if err != nil {
 		return user, e.Wrap("can not update user", err)
 	}
+	if err = a.otp.DeleteRecoveryCodes(ctx, input.UserId); err != nil {
+		return user, e.Wrap("can not delete recovery codes", err)
+	}
 	user.Otp_enabled = false
 	user.Otp_auth_url = ""
 	user.Otp_secret = ""
 	StoreInCache[*domain.User](user.Crmid, &user, CacheUsersTTL, a.cache)
 	return user, nil
 }
+
+// RegenerateRecoveryCodes replaces all recovery codes of user. Current one-time password is required.
+func (a AuthService) RegenerateRecoveryCodes(ctx context.Context, input OTPInput) (RecoveryCodesResult, error) {
+	user, err := a.repo.GetById(ctx, input.UserId)
+	if err != nil {
+		return RecoveryCodesResult{}, ErrUserNotFound
+	}
+	if !user.Otp_enabled {
+		return RecoveryCodesResult{}, ErrOtpNotEnabled
+	}
+	input.RecoveryCode = ""
+	if err = a.checkOtp(ctx, user, input, false); err != nil {
+		return RecoveryCodesResult{}, err
+	}
+	codes, err := a.newRecoveryCodes(ctx, user.Id)
+	return RecoveryCodesResult{RecoveryCodes: codes}, err
+}
+
+// checkOtp validates one-time password or, when allowed, recovery code. Every wrong code is counted and after
+// otp.maxAttempts wrong codes in a row checks are locked for otp.lockoutDuration.
+func (a AuthService) checkOtp(ctx context.Context, user domain.User, input OTPInput, allowRecovery bool) error {
+	state, err := a.otp.GetState(ctx, user.Id)
+	if err != nil {
+		return e.Wrap("can not get otp state", err)
+	}
+	if state.IsLocked() {
+		return ErrOtpLocked
+	}
+
+	var valid bool
+	if allowRecovery && input.Token == "" && input.RecoveryCode != "" {
+		valid, err = a.otp.UseRecoveryCode(ctx, user.Id, hashRecoveryCode(input.RecoveryCode))
+		if err != nil {
+			return e.Wrap("can not use recovery code", err)
+		}
+	} else {
+		valid = totp.Validate(input.Token, user.Otp_secret)
+	}
+	if valid {
+		if state.FailedAttempts > 0 || state.LockedUntil != nil {
+			if err = a.otp.ResetFailures(ctx, user.Id); err != nil {
+				return e.Wrap("can not reset failed otp attempts", err)
+			}
+		}
+		return nil
+	}
+	if err = a.registerFailure(ctx, user); err != nil {
+		return err
+	}
+	return ErrTokenNotExist
+}
+
+func (a AuthService) registerFailure(ctx context.Context, user domain.User) error {
+	attempts, err := a.otp.IncrementFailures(ctx, user.Id)
+	if err != nil {
+		return e.Wrap("can not count failed otp attempt", err)
+	}
+	maxAttempts := a.config.Otp.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOtpMaxAttempts
+	}
+	if attempts < maxAttempts {
+		return nil
+	}
+	lockout := a.config.Otp.LockoutDuration
+	if lockout <= 0 {
+		lockout = defaultOtpLockoutDuration
+	}
+	until := time.Now().Add(lockout)
+	if err = a.otp.Lock(ctx, user.Id, until); err != nil {
+		return e.Wrap("can not lock otp", err)
+	}
+	a.sendLockedAlert(user, until)
+	return ErrOtpLocked
+}
+
+func (a AuthService) sendLockedAlert(user domain.User, until time.Time) {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		companyData, err := a.company.GetCompany(ctx)
+		if err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not send otp lock alert: " + err.Error()))
+			return
+		}
+		err = a.emails.SendOtpLocked(OtpLockedData{
+			Name:    user.FirstName + " " + user.LastName,
+			Until:   until,
+			Company: companyData.OrganizationName,
+			Support: a.config.Vtiger.Business.SupportEmail,
+			Email:   user.Email,
+			Subject: a.config.Email.Subjects.OtpLocked,
+		})
+		if err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+		}
+	}()
+}
+
+func (a AuthService) newRecoveryCodes(ctx context.Context, userId int64) ([]string, error) {
+	count := a.config.Otp.RecoveryCodes
+	if count <= 0 {
+		count = defaultOtpRecoveryCodes
+	}
+	codes := make([]string, count)
+	hashes := make([]string, count)
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, e.Wrap("can not generate recovery code", err)
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(random))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	if err := a.otp.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, e.Wrap("can not store recovery codes", err)
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, so code can be typed in any form.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
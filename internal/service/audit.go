@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Actions of audit log.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+	AuditFail   = "fail"
+	AuditLock   = "lock"
+)
+
+// Modules of audit log, which have no own permissions.
+const (
+	AuditModuleComments = "ModComments"
+	AuditModuleSettings = "Settings"
+	AuditModuleOtp      = "Otp"
+	AuditModulePasskeys = "Passkeys"
+	AuditModuleSessions = "Sessions"
+	AuditModuleLogin    = "Login"
+)
+
+// auditHiddenFields are not written to audit log, only the fact of change is kept.
+var auditHiddenFields = map[string]bool{
+	"secret":         true,
+	"password":       true,
+	"token":          true,
+	"recovery_codes": true,
+	"client_secret":  true,
+}
+
+const auditHiddenValue = "***"
+
+// Audit records changes, made by portal users. Entries are written in background, so slow database does not delay
+// responses. Zero Audit records nothing.
+type Audit struct {
+	repo repository.Audit
+	wg   *sync.WaitGroup
+}
+
+func NewAuditService(repo repository.Audit, wg *sync.WaitGroup) Audit {
+	return Audit{
+		repo: repo,
+		wg:   wg,
+	}
+}
+
+// Record writes entry to audit log in background.
+func (a Audit) Record(entry domain.AuditEntry) {
+	if a.repo == nil || a.wg == nil {
+		return
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := a.repo.Insert(ctx, &entry); err != nil {
+			logger.Error(logger.LogMessage{Msg: "can not write audit log: " + err.Error(), Code: "500", Properties: map[string]string{"action": entry.Action, "module": entry.Module, "entity_id": entry.EntityId}})
+		}
+	}()
+}
+
+// GetAll returns audit log of account of user. Only owners and admins can read it.
+func (a Audit) GetAll(ctx context.Context, filter domain.AuditFilter, user domain.User) ([]domain.AuditEntry, int, error) {
+	if err := CheckPermission(user, domain.ModuleAudit, domain.PermissionRead); err != nil {
+		return nil, 0, err
+	}
+	filter.AccountId = user.AccountId
+	return a.repo.GetAll(ctx, filter)
+}
+
+// NewAuditEntry describes change of entity by user. Before is nil for created entities and after is nil for deleted ones.
+func NewAuditEntry(user domain.User, ip string, action string, module string, entityId string, before any, after any) domain.AuditEntry {
+	return domain.AuditEntry{
+		UserId:    user.Id,
+		UserCrmid: user.Crmid,
+		UserName:  user.FirstName + " " + user.LastName,
+		AccountId: user.AccountId,
+		Ip:        ip,
+		Action:    action,
+		Module:    module,
+		EntityId:  entityId,
+		Changes:   AuditDiff(before, after),
+	}
+}
+
+// AuditDiff compares json representations of two values and returns changed fields. Empty fields of created and
+// deleted entities are skipped.
+func AuditDiff(before any, after any) map[string]domain.AuditChange {
+	old := auditFields(before)
+	current := auditFields(after)
+	changes := make(map[string]domain.AuditChange)
+	for field, value := range current {
+		previous, existed := old[field]
+		if existed && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		if !existed && isEmptyAuditValue(value) {
+			continue
+		}
+		changes[field] = auditChange(field, previous, value)
+	}
+	for field, previous := range old {
+		if _, exists := current[field]; exists || isEmptyAuditValue(previous) {
+			continue
+		}
+		changes[field] = auditChange(field, previous, nil)
+	}
+	return changes
+}
+
+func auditChange(field string, old any, new any) domain.AuditChange {
+	if auditHiddenFields[field] {
+		return domain.AuditChange{Old: hiddenAuditValue(old), New: hiddenAuditValue(new)}
+	}
+	return domain.AuditChange{Old: old, New: new}
+}
+
+func hiddenAuditValue(value any) any {
+	if isEmptyAuditValue(value) {
+		return nil
+	}
+	return auditHiddenValue
+}
+
+// auditFields converts value to map of its json fields. Values, which are not json objects, are kept in "value" field.
+func auditFields(value any) map[string]any {
+	if value == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return map[string]any{"value": err.Error()}
+	}
+	var fields map[string]any
+	if err = json.Unmarshal(data, &fields); err != nil {
+		var plain any
+		_ = json.Unmarshal(data, &plain)
+		return map[string]any{"value": plain}
+	}
+	return fields
+}
+
+func isEmptyAuditValue(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
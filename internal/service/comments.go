@@ -7,6 +7,8 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
 )
 
 type Comments struct {
@@ -15,15 +17,17 @@ type Comments struct {
 	config          config.Config
 	usersService    UsersService
 	managersService ManagerService
+	events          *pubsub.Hub
 }
 
-func NewComments(repository repository.Comment, cache cache.Cache, config config.Config, usersService UsersService, managerService ManagerService) Comments {
+func NewComments(repository repository.Comment, cache cache.Cache, config config.Config, usersService UsersService, managerService ManagerService, events *pubsub.Hub) Comments {
 	return Comments{
 		repository:      repository,
 		cache:           cache,
 		config:          config,
 		usersService:    usersService,
 		managersService: managerService,
+		events:          events,
 	}
 }
 
@@ -74,5 +78,18 @@ func (c Comments) Create(ctx context.Context, content string, related string, us
 	if err != nil {
 		return createdComment, e.Wrap("can not create comment in repository", err)
 	}
+	c.publish(ctx, createdComment, userId)
 	return createdComment, nil
 }
+
+func (c Comments) publish(ctx context.Context, comment domain.Comment, userId string) {
+	if c.events == nil {
+		return
+	}
+	user, err := c.usersService.FindByCrmid(ctx, userId)
+	if err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not find author of comment " + comment.Id + ": " + err.Error()))
+		return
+	}
+	c.events.Publish(pubsub.Event{Type: EventComment, AccountId: user.AccountId, Data: comment})
+}
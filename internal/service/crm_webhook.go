@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"github.com/semelyanov86/vtiger-portal/pkg/webhook"
+	"sort"
+	"strings"
+	"time"
+)
+
+const CacheCrmWebhookDelivery = "crm_webhook_"
+
+var ErrDuplicateWebhook = errors.New("webhook is already received")
+
+// CrmWebhook handles record changes, which vtiger workflow sends to portal.
+type CrmWebhook struct {
+	cache         cache.Cache
+	config        config.Config
+	notifications Notifications
+	crm           repository.Sync
+	mirror        repository.Mirror
+	events        *pubsub.Hub
+}
+
+func NewCrmWebhook(cache cache.Cache, config config.Config, notifications Notifications, crm repository.Sync, mirror repository.Mirror, events *pubsub.Hub) CrmWebhook {
+	return CrmWebhook{
+		cache:         cache,
+		config:        config,
+		notifications: notifications,
+		crm:           crm,
+		mirror:        mirror,
+		events:        events,
+	}
+}
+
+// Handle drops cached data of changed record and notifies users of account, which owns the record. Signed request is
+// handled once, replayed one is rejected.
+func (w CrmWebhook) Handle(ctx context.Context, event vtiger.WebhookEvent) error {
+	if event.Module == "" || !strings.Contains(event.Id, "x") {
+		return e.Wrap("webhook should contain module and id of record", ErrValidation)
+	}
+	if event.Delivery != "" {
+		key := CacheCrmWebhookDelivery + event.Delivery
+		if received, err := w.cache.Get(key); err == nil && received != nil {
+			return e.Wrap(event.Id, ErrDuplicateWebhook)
+		}
+		if err := w.cache.Set(key, []byte{1}, int64(w.deliveryTtl()/time.Second)); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not store crm webhook delivery: " + err.Error()))
+		}
+	}
+	err := w.handle(ctx, event)
+	if err != nil && event.Delivery != "" {
+		// vtiger can send the same request again, when it was not handled
+		DeleteFromCache(w.cache, CacheCrmWebhookDelivery+event.Delivery)
+	}
+	return err
+}
+
+// deliveryTtl is a time, while signature of request is valid: timestamp can differ from now by tolerance in both
+// directions.
+func (w CrmWebhook) deliveryTtl() time.Duration {
+	tolerance := w.config.Vtiger.Webhook.Tolerance
+	if tolerance <= 0 {
+		tolerance = webhook.DefaultTolerance
+	}
+	return 2 * tolerance
+}
+
+func (w CrmWebhook) handle(ctx context.Context, event vtiger.WebhookEvent) error {
+	accountId, err := w.resolveAccount(ctx, event)
+	if err != nil {
+		return e.Wrap("can not find account of "+event.Id, err)
+	}
+
+	DeleteFromCache(w.cache, event.Id, CacheDocuments+event.Id)
+	if accountId != "" {
+		DeleteStatisticsFromCache(w.cache, accountId)
+		if event.Module == "Contacts" || event.Module == "Accounts" {
+			DeleteFromCache(w.cache, CacheUsersAccount+accountId)
+		}
+	}
+	if accountId == "" || len(event.Changed) == 0 {
+		return nil
+	}
+	if event.Module == "HelpDesk" {
+		w.events.Publish(pubsub.Event{Type: EventTicketUpdated, AccountId: accountId, Data: event})
+	}
+
+	label := event.Label
+	if label == "" {
+		label = event.Module + " " + event.Id
+	}
+	return w.notifications.NotifyAccount(ctx, domain.Notification{
+		Crmid:          event.Id,
+		Module:         event.Module,
+		Label:          label,
+		Description:    describeChanges(event.Changed),
+		AssignedUserId: event.AssignedUserId,
+		AccountId:      accountId,
+	})
+}
+
+// resolveAccount takes account from event. When it is empty, record is retrieved from crm and, if module is mirrored,
+// stored in mirror, so it does not wait for next sync.
+func (w CrmWebhook) resolveAccount(ctx context.Context, event vtiger.WebhookEvent) (string, error) {
+	if event.Module == "Accounts" {
+		return event.Id, nil
+	}
+	scope, mirrored := repository.MirrorScopes[event.Module]
+	refresh := mirrored && w.config.Sync.Enabled
+	if event.AccountId != "" && !refresh {
+		return event.AccountId, nil
+	}
+	if !mirrored && event.Module != "Contacts" {
+		return "", nil
+	}
+	data, err := w.crm.Retrieve(ctx, event.Id)
+	if err != nil {
+		return "", err
+	}
+	if event.Module == "Contacts" {
+		account, _ := data["account_id"].(string)
+		return account, nil
+	}
+	record, err := repository.MirrorRecordFromData(ctx, event.Module, scope, data, w.mirror)
+	if err != nil {
+		return "", err
+	}
+	if refresh {
+		if err = w.mirror.Upsert(ctx, record); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not store mirror record " + record.Id + ": " + err.Error()))
+		}
+	}
+	if event.AccountId != "" {
+		return event.AccountId, nil
+	}
+	return record.AccountId, nil
+}
+
+func describeChanges(changed map[string]any) string {
+	fields := make([]string, 0, len(changed))
+	for field := range changed {
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	sort.Strings(fields)
+	return "Changed fields: " + strings.Join(fields, ", ")
+}
@@ -70,6 +70,9 @@ func (c CustomModule) GetAll(ctx context.Context, filter vtiger.PaginationQueryF
 }
 
 func (c CustomModule) GetById(ctx context.Context, moduleName string, id string, user domain.User) (map[string]any, error) {
+	if err := CheckPermission(user, moduleName, domain.PermissionRead); err != nil {
+		return nil, err
+	}
 	module, err := c.module.Describe(ctx, moduleName)
 	if err != nil {
 		return nil, e.Wrap("can not describe module "+moduleName, err)
@@ -82,6 +85,9 @@ func (c CustomModule) GetById(ctx context.Context, moduleName string, id string,
 }
 
 func (c CustomModule) CreateEntity(ctx context.Context, input map[string]any, user domain.User, module string) (map[string]any, error) {
+	if err := CheckPermission(user, module, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
 	custom, err := c.module.Describe(ctx, module)
 	if err != nil {
 		return nil, e.Wrap("can not describe module "+module, err)
@@ -100,10 +106,18 @@ func (c CustomModule) CreateEntity(ctx context.Context, input map[string]any, us
 		return input, err
 	}
 
-	return c.repository.Create(ctx, input, custom, user)
+	entity, err := c.repository.Create(ctx, input, custom, user)
+	if err != nil {
+		return entity, err
+	}
+	DeleteStatisticsFromCache(c.cache, user.AccountId)
+	return entity, nil
 }
 
 func (c CustomModule) UpdateEntity(ctx context.Context, input map[string]any, id string, user domain.User, module string) (map[string]any, error) {
+	if err := CheckPermission(user, module, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
 	custom, err := c.module.Describe(ctx, module)
 	if err != nil {
 		return nil, e.Wrap("can not describe module "+module, err)
@@ -132,11 +146,15 @@ func (c CustomModule) UpdateEntity(ctx context.Context, input map[string]any, id
 	if err != nil {
 		return entity, err
 	}
+	c.invalidate(id, user)
 
 	return entity, err
 }
 
 func (c CustomModule) Revise(ctx context.Context, input map[string]any, id string, user domain.User, module string) (map[string]any, error) {
+	if err := CheckPermission(user, module, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
 	ticket, err := c.GetById(ctx, module, id, user)
 	if err != nil {
 		return ticket, e.Wrap("can not retrieve helpdesk during update", err)
@@ -151,9 +169,17 @@ func (c CustomModule) Revise(ctx context.Context, input map[string]any, id strin
 	if err != nil {
 		return ticket, err
 	}
+	c.invalidate(id, user)
 	return ticket, err
 }
 
+// invalidate removes entity cached under its id, because custom module can be a module cached by other services,
+// and statistics of account, which can count this entity.
+func (c CustomModule) invalidate(id string, user domain.User) {
+	DeleteFromCache(c.cache, id)
+	DeleteStatisticsFromCache(c.cache, user.AccountId)
+}
+
 func (c CustomModule) GetRelatedDocuments(ctx context.Context, id string, module string, user domain.User) ([]domain.Document, error) {
 	_, err := c.GetById(ctx, module, id, user)
 	if err != nil {
@@ -173,6 +199,9 @@ func (c CustomModule) GetRelatedComments(ctx context.Context, id string, module
 }
 
 func (c CustomModule) AddComment(ctx context.Context, content string, related string, module string, userModel domain.User) (domain.Comment, error) {
+	if err := CheckPermission(userModel, module, domain.PermissionWrite); err != nil {
+		return domain.Comment{}, err
+	}
 	_, err := c.GetById(ctx, module, related, userModel)
 	if err != nil {
 		return domain.Comment{}, err
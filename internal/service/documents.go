@@ -8,6 +8,7 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
 	"io"
 	"mime/multipart"
@@ -21,17 +22,19 @@ type Documents struct {
 	repository repository.Document
 	cache      cache.Cache
 	config     config.Config
+	events     *pubsub.Hub
 }
 
 const CacheDocuments = "documents-"
 
 const CacheDocumentTtl = 500
 
-func NewDocuments(repository repository.Document, cache cache.Cache, config config.Config) Documents {
+func NewDocuments(repository repository.Document, cache cache.Cache, config config.Config, events *pubsub.Hub) Documents {
 	return Documents{
 		repository: repository,
 		cache:      cache,
 		config:     config,
+		events:     events,
 	}
 }
 
@@ -72,6 +75,9 @@ func (d Documents) GetFile(ctx context.Context, id string, relatedId string) (vt
 }
 
 func (d Documents) AttachFile(ctx context.Context, file multipart.File, id string, userModel domain.User, header *multipart.FileHeader) (domain.Document, error) {
+	if err := CheckPermission(userModel, domain.ModuleDocuments, domain.PermissionWrite); err != nil {
+		return domain.Document{}, err
+	}
 	fileBytes, err := io.ReadAll(file)
 	if err != nil {
 		return domain.Document{}, err
@@ -108,19 +114,31 @@ func (d Documents) AttachFile(ctx context.Context, file multipart.File, id strin
 		Filestatus:       "1",
 	}
 
-	StoreInCache[*[]domain.Document](CacheDocuments+id, nil, 0, d.cache)
-
-	return d.repository.AttachFile(ctx, doc, id)
+	doc, err = d.repository.AttachFile(ctx, doc, id)
+	if err != nil {
+		return doc, err
+	}
+	DeleteFromCache(d.cache, CacheDocuments+id)
+	d.events.Publish(pubsub.Event{Type: EventDocumentUploaded, AccountId: userModel.AccountId, UserId: userModel.Crmid, Data: doc})
+	return doc, nil
 }
 
-func (d Documents) DeleteFile(ctx context.Context, id string, related string) error {
+func (d Documents) DeleteFile(ctx context.Context, id string, related string, userModel domain.User) error {
+	if err := CheckPermission(userModel, domain.ModuleDocuments, domain.PermissionWrite); err != nil {
+		return err
+	}
 	documents, err := d.GetRelated(ctx, related)
 	if err != nil {
 		return e.Wrap("can not check for related documents", err)
 	}
 	for _, document := range documents {
 		if document.Id == id {
-			return d.repository.DeleteFile(ctx, id)
+			err = d.repository.DeleteFile(ctx, id)
+			if err != nil {
+				return err
+			}
+			DeleteFromCache(d.cache, CacheDocuments+related)
+			return nil
 		}
 	}
 	return ErrOperationNotPermitted
@@ -32,9 +32,70 @@ type PasswordRestoreData struct {
 	Subject string
 }
 
+type OtpLockedData struct {
+	Name    string
+	Until   time.Time
+	Company string
+	Support string
+	Email   string
+	Subject string
+}
+
+type MagicLinkData struct {
+	Name    string
+	Token   string
+	Valid   time.Time
+	Company string
+	Support string
+	Domain  string
+	Email   string
+	Subject string
+}
+
+type InvitationData struct {
+	Name      string
+	InvitedBy string
+	Account   string
+	Token     string
+	Valid     time.Time
+	Company   string
+	Support   string
+	Domain    string
+	Email     string
+	Subject   string
+}
+
+type LoginLockedData struct {
+	Name    string
+	Ip      string
+	Until   time.Time
+	Token   string
+	Company string
+	Support string
+	Domain  string
+	Email   string
+	Subject string
+}
+
+type PaymentRefundedData struct {
+	Name     string
+	Amount   float64
+	Currency string
+	Paid     string
+	Company  string
+	Support  string
+	Email    string
+	Subject  string
+}
+
 type EmailServiceInterface interface {
 	SendGreetingsToUser(input VerificationEmailInput) error
 	SendPasswordReset(input PasswordRestoreData) error
+	SendOtpLocked(input OtpLockedData) error
+	SendMagicLink(input MagicLinkData) error
+	SendInvitation(input InvitationData) error
+	SendLoginLocked(input LoginLockedData) error
+	SendPaymentRefunded(input PaymentRefundedData) error
 }
 
 func NewEmailsService(sender email.Sender, config config.EmailConfig, cache cache.Cache) *EmailService {
@@ -53,6 +114,26 @@ func (s EmailService) SendPasswordReset(input PasswordRestoreData) error {
 	return s.sender.Send(input.Email, s.config.Templates.RestorePasswordEmail, input)
 }
 
+func (s EmailService) SendOtpLocked(input OtpLockedData) error {
+	return s.sender.Send(input.Email, s.config.Templates.OtpLocked, input)
+}
+
+func (s EmailService) SendMagicLink(input MagicLinkData) error {
+	return s.sender.Send(input.Email, s.config.Templates.MagicLink, input)
+}
+
+func (s EmailService) SendInvitation(input InvitationData) error {
+	return s.sender.Send(input.Email, s.config.Templates.Invitation, input)
+}
+
+func (s EmailService) SendLoginLocked(input LoginLockedData) error {
+	return s.sender.Send(input.Email, s.config.Templates.LoginLocked, input)
+}
+
+func (s EmailService) SendPaymentRefunded(input PaymentRefundedData) error {
+	return s.sender.Send(input.Email, s.config.Templates.PaymentRefunded, input)
+}
+
 type MockEmailService struct {
 }
 
@@ -67,3 +148,23 @@ func (s MockEmailService) SendGreetingsToUser(input VerificationEmailInput) erro
 func (s MockEmailService) SendPasswordReset(input PasswordRestoreData) error {
 	return nil
 }
+
+func (s MockEmailService) SendOtpLocked(input OtpLockedData) error {
+	return nil
+}
+
+func (s MockEmailService) SendMagicLink(input MagicLinkData) error {
+	return nil
+}
+
+func (s MockEmailService) SendInvitation(input InvitationData) error {
+	return nil
+}
+
+func (s MockEmailService) SendLoginLocked(input LoginLockedData) error {
+	return nil
+}
+
+func (s MockEmailService) SendPaymentRefunded(input PaymentRefundedData) error {
+	return nil
+}
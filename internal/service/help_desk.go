@@ -8,6 +8,7 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
 )
 
@@ -22,9 +23,10 @@ type HelpDesk struct {
 	document   DocumentServiceInterface
 	module     ModulesService
 	config     config.Config
+	events     *pubsub.Hub
 }
 
-func NewHelpDeskService(repository repository.HelpDesk, cache cache.Cache, comments CommentServiceInterface, document DocumentServiceInterface, module ModulesService, config config.Config) HelpDesk {
+func NewHelpDeskService(repository repository.HelpDesk, cache cache.Cache, comments CommentServiceInterface, document DocumentServiceInterface, module ModulesService, config config.Config, events *pubsub.Hub) HelpDesk {
 	return HelpDesk{
 		repository: repository,
 		cache:      cache,
@@ -32,10 +34,14 @@ func NewHelpDeskService(repository repository.HelpDesk, cache cache.Cache, comme
 		document:   document,
 		module:     module,
 		config:     config,
+		events:     events,
 	}
 }
 
 func (h HelpDesk) GetHelpDeskById(ctx context.Context, id string, userModel domain.User) (domain.HelpDesk, error) {
+	if err := CheckPermission(userModel, domain.ModuleHelpDesk, domain.PermissionRead); err != nil {
+		return domain.HelpDesk{}, err
+	}
 	helpDesk := &domain.HelpDesk{}
 	err := GetFromCache[*domain.HelpDesk](id, helpDesk, h.cache)
 	if err == nil {
@@ -80,6 +86,9 @@ func (h HelpDesk) GetRelatedComments(ctx context.Context, id string, userModel d
 }
 
 func (h HelpDesk) AddComment(ctx context.Context, content string, related string, userModel domain.User) (domain.Comment, error) {
+	if err := CheckPermission(userModel, domain.ModuleHelpDesk, domain.PermissionWrite); err != nil {
+		return domain.Comment{}, err
+	}
 	_, err := h.GetHelpDeskById(ctx, related, userModel)
 	if err != nil {
 		return domain.Comment{}, err
@@ -114,6 +123,9 @@ type CreateTicketInput struct {
 }
 
 func (h HelpDesk) CreateTicket(ctx context.Context, input CreateTicketInput, user domain.User) (domain.HelpDesk, error) {
+	if err := CheckPermission(user, domain.ModuleHelpDesk, domain.PermissionWrite); err != nil {
+		return domain.HelpDesk{}, err
+	}
 	var helpDesk domain.HelpDesk
 
 	helpDesk.TicketTitle = input.TicketTitle
@@ -132,7 +144,13 @@ func (h HelpDesk) CreateTicket(ctx context.Context, input CreateTicketInput, use
 		return helpDesk, err
 	}
 
-	return h.repository.Create(ctx, helpDesk)
+	helpDesk, err = h.repository.Create(ctx, helpDesk)
+	if err != nil {
+		return helpDesk, err
+	}
+	DeleteStatisticsFromCache(h.cache, user.AccountId)
+	h.events.Publish(pubsub.Event{Type: EventTicketCreated, AccountId: helpDesk.ParentID, UserId: user.Crmid, Data: helpDesk})
+	return helpDesk, nil
 }
 
 func (h HelpDesk) validateInputFields(ctx context.Context, helpDesk *domain.HelpDesk) error {
@@ -163,6 +181,9 @@ func (h HelpDesk) validateInputFields(ctx context.Context, helpDesk *domain.Help
 }
 
 func (h HelpDesk) UpdateTicket(ctx context.Context, input CreateTicketInput, id string, user domain.User) (domain.HelpDesk, error) {
+	if err := CheckPermission(user, domain.ModuleHelpDesk, domain.PermissionWrite); err != nil {
+		return domain.HelpDesk{}, err
+	}
 	ticket, err := h.retrieveHelpDesk(ctx, id)
 	if err != nil {
 		return ticket, e.Wrap("can not retrieve helpdesk during update", err)
@@ -182,6 +203,7 @@ func (h HelpDesk) UpdateTicket(ctx context.Context, input CreateTicketInput, id
 	if input.Ticketseverities != "" {
 		ticket.TicketSeverities = input.Ticketseverities
 	}
+	status := ticket.TicketStatus
 
 	err = h.validateInputFields(ctx, &ticket)
 	if err != nil {
@@ -191,11 +213,17 @@ func (h HelpDesk) UpdateTicket(ctx context.Context, input CreateTicketInput, id
 	if err != nil {
 		return ticket, err
 	}
+	PublishTicketStatus(h.events, status, ticket)
+	h.events.Publish(pubsub.Event{Type: EventTicketUpdated, AccountId: ticket.ParentID, UserId: user.Crmid, Data: ticket})
+	DeleteStatisticsFromCache(h.cache, user.AccountId)
 	err = StoreInCache[*domain.HelpDesk](id, &ticket, CacheHelpDeskTtl, h.cache)
 	return ticket, err
 }
 
 func (h HelpDesk) Revise(ctx context.Context, input map[string]any, id string, user domain.User) (domain.HelpDesk, error) {
+	if err := CheckPermission(user, domain.ModuleHelpDesk, domain.PermissionWrite); err != nil {
+		return domain.HelpDesk{}, err
+	}
 	ticket, err := h.retrieveHelpDesk(ctx, id)
 	if err != nil {
 		return ticket, e.Wrap("can not retrieve helpdesk during update", err)
@@ -204,11 +232,23 @@ func (h HelpDesk) Revise(ctx context.Context, input map[string]any, id string, u
 		return domain.HelpDesk{}, ErrOperationNotPermitted
 	}
 	input["id"] = id
+	status := ticket.TicketStatus
 
 	ticket, err = h.repository.Revise(ctx, input)
 	if err != nil {
 		return ticket, err
 	}
+	PublishTicketStatus(h.events, status, ticket)
+	h.events.Publish(pubsub.Event{Type: EventTicketUpdated, AccountId: ticket.ParentID, UserId: user.Crmid, Data: ticket})
+	DeleteStatisticsFromCache(h.cache, user.AccountId)
 	err = StoreInCache[*domain.HelpDesk](id, &ticket, CacheHelpDeskTtl, h.cache)
 	return ticket, err
 }
+
+// PublishTicketStatus notifies users of account, when status of ticket was changed.
+func PublishTicketStatus(events *pubsub.Hub, previous string, ticket domain.HelpDesk) {
+	if previous == ticket.TicketStatus {
+		return
+	}
+	events.Publish(pubsub.Event{Type: EventTicketStatus, AccountId: ticket.ParentID, Data: ticket})
+}
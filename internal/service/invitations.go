@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"strings"
+	"time"
+)
+
+var ErrInvalidInvitation = errors.New("invitation is invalid, expired or already accepted")
+
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+type InviteInput struct {
+	Email     string `json:"email" binding:"required,email,max=64"`
+	FirstName string `json:"firstname" binding:"omitempty,max=50"`
+	LastName  string `json:"lastname" binding:"required,min=2,max=50"`
+	Role      string `json:"role"`
+}
+
+type AcceptInviteInput struct {
+	Token    string `json:"token" binding:"required,len=26"`
+	Password string `json:"password" binding:"required,min=5,max=20"`
+}
+
+// Invite registers colleague of user in portal without code from crm. Contact is created in account of user, when
+// it does not exist yet. Portal user stays inactive until invitation is accepted.
+func (s UsersService) Invite(ctx context.Context, input InviteInput, inviter domain.User) (domain.User, error) {
+	if err := CheckPermission(inviter, domain.ModuleContacts, domain.PermissionWrite); err != nil {
+		return domain.User{}, err
+	}
+	role := input.Role
+	if role == "" {
+		role = domain.RoleMember
+	}
+	if !domain.IsValidRole(role) {
+		return domain.User{}, e.Wrap(ErrWrongRole.Error(), ErrValidation)
+	}
+	if role == domain.RoleOwner && inviter.RoleOrDefault() != domain.RoleOwner {
+		return domain.User{}, e.Wrap("only owner can invite owners", ErrOperationNotPermitted)
+	}
+	email := strings.ToLower(input.Email)
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if user.IsActive || user.AccountId != inviter.AccountId {
+			return domain.User{}, repository.ErrDuplicateEmail
+		}
+		if user.Role != role {
+			if err = s.repo.UpdateRole(ctx, user.Id, role); err != nil {
+				return user, e.Wrap("can not update role of invited user", err)
+			}
+			user.Role = role
+		}
+	case errors.Is(err, repository.ErrRecordNotFound):
+		user, err = s.createInvitedUser(ctx, email, input, role, inviter)
+		if err != nil {
+			return user, err
+		}
+	default:
+		return domain.User{}, e.Wrap("can not get user by email", err)
+	}
+
+	if err = s.tokenRepository.DeleteAllForUser(ctx, domain.ScopeInvitation, user.Id); err != nil {
+		return user, e.Wrap("can not delete previous invitations", err)
+	}
+	ttl := s.config.Tokens.InvitationTTL
+	if ttl <= 0 {
+		ttl = defaultInvitationTTL
+	}
+	token, err := s.tokenRepository.New(ctx, user.Id, ttl, domain.ScopeInvitation)
+	if err != nil {
+		return user, e.Wrap("can not create invitation token", err)
+	}
+	companyData, err := s.company.GetCompany(ctx)
+	if err != nil {
+		return user, e.Wrap("can not send email because company data not received", err)
+	}
+	err = s.email.SendInvitation(InvitationData{
+		Name:      strings.TrimSpace(user.FirstName + " " + user.LastName),
+		InvitedBy: inviter.FirstName + " " + inviter.LastName,
+		Account:   inviter.AccountName,
+		Token:     token.Plaintext,
+		Valid:     token.Expiry,
+		Company:   companyData.OrganizationName,
+		Support:   s.config.Vtiger.Business.SupportEmail,
+		Domain:    s.config.Domain,
+		Email:     user.Email,
+		Subject:   s.config.Email.Subjects.Invitation,
+	})
+	if err != nil {
+		return user, e.Wrap("can not send invitation", err)
+	}
+	return user, nil
+}
+
+// AcceptInvitation sets password of invited user and activates it.
+func (s UsersService) AcceptInvitation(ctx context.Context, input AcceptInviteInput) (domain.User, error) {
+	invitation, err := s.tokenRepository.GetByPlaintext(ctx, domain.ScopeInvitation, input.Token)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return domain.User{}, ErrInvalidInvitation
+	}
+	if err != nil {
+		return domain.User{}, e.Wrap("can not get invitation token", err)
+	}
+	if invitation.UsedAt != nil || invitation.Expiry.Before(time.Now()) {
+		return domain.User{}, ErrInvalidInvitation
+	}
+	claimed, err := s.tokenRepository.MarkUsed(ctx, invitation.ID)
+	if err != nil {
+		return domain.User{}, e.Wrap("can not mark invitation as used", err)
+	}
+	if !claimed {
+		return domain.User{}, ErrInvalidInvitation
+	}
+
+	user, err := s.repo.GetById(ctx, invitation.UserId)
+	if err != nil {
+		return user, e.Wrap("can not find invited user", err)
+	}
+	if err = user.Password.Set(input.Password); err != nil {
+		return user, e.Wrap("can not hash password", err)
+	}
+	user.IsActive = true
+	if err = s.repo.Update(ctx, &user); err != nil {
+		return user, e.Wrap("can not activate invited user", err)
+	}
+	if err = s.tokenRepository.DeleteAllForUser(ctx, domain.ScopeInvitation, user.Id); err != nil {
+		return user, e.Wrap("can not delete invitations", err)
+	}
+	DeleteFromCache(s.cache, CacheUsersAccount+user.AccountId)
+	return user, nil
+}
+
+func (s UsersService) createInvitedUser(ctx context.Context, email string, input InviteInput, role string, inviter domain.User) (domain.User, error) {
+	contacts, err := s.crm.FindByEmail(ctx, email)
+	if err != nil {
+		return domain.User{}, e.Wrap("can not find current email in crm", err)
+	}
+	var contact *domain.User
+	for i := range contacts {
+		if contacts[i].AccountId == inviter.AccountId {
+			contact = &contacts[i]
+			break
+		}
+	}
+	if contact == nil {
+		if len(contacts) > 0 {
+			return domain.User{}, e.Wrap("contact with this email belongs to other account", repository.ErrDuplicateEmail)
+		}
+		created, err := s.crm.Create(ctx, domain.User{
+			FirstName:      input.FirstName,
+			LastName:       input.LastName,
+			Email:          email,
+			AccountId:      inviter.AccountId,
+			AssignedUserId: s.config.Vtiger.Business.DefaultUser,
+		})
+		if err != nil {
+			return domain.User{}, e.Wrap("can not create contact in crm", err)
+		}
+		contact = &created
+	}
+
+	password := make([]byte, 24)
+	if _, err = rand.Read(password); err != nil {
+		return domain.User{}, e.Wrap("can not generate password", err)
+	}
+	if err = FillVtigerContactWithAdditionalValues(contact, base64.RawURLEncoding.EncodeToString(password)); err != nil {
+		return domain.User{}, e.Wrap("can not fill data with additional values", err)
+	}
+	contact.IsActive = false
+	contact.AccountName = inviter.AccountName
+	contact.Role = role
+	if err = s.repo.Insert(ctx, contact); err != nil {
+		return *contact, e.Wrap("can not store invited user", err)
+	}
+	DeleteFromCache(s.cache, CacheUsersAccount+inviter.AccountId)
+	return *contact, nil
+}
@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"encoding/csv"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
+	"io"
+	"strconv"
+	"time"
 )
 
 type Invoices struct {
@@ -60,3 +64,40 @@ func (h Invoices) GetAll(ctx context.Context, filter vtiger.PaginationQueryFilte
 	}
 	return invoices, count, err
 }
+
+// Export writes all invoices of account of user to w as csv. Invoices are written while next pages are fetched from
+// vtiger, so nothing is written, when user can not read invoices.
+func (h Invoices) Export(ctx context.Context, user domain.User, w io.Writer) error {
+	if err := CheckPermission(user, domain.ModuleInvoice, domain.PermissionRead); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"invoice_no", "subject", "invoicestatus", "invoicedate", "duedate", "hdnGrandTotal", "received", "balance"})
+	if err != nil {
+		return err
+	}
+	err = h.repository.Export(ctx, user.AccountId, func(invoice domain.Invoice) error {
+		return writer.Write([]string{
+			invoice.InvoiceNo,
+			invoice.Subject,
+			invoice.InvoiceStatus,
+			formatExportDate(time.Time(invoice.InvoiceDate)),
+			formatExportDate(time.Time(invoice.DueDate)),
+			strconv.FormatFloat(float64(invoice.HdnGrandTotal), 'f', 2, 64),
+			strconv.FormatFloat(float64(invoice.Received), 'f', 2, 64),
+			strconv.FormatFloat(float64(invoice.Balance), 'f', 2, 64),
+		})
+	})
+	if err != nil {
+		return e.Wrap("can not export invoices", err)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatExportDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format("2006-01-02")
+}
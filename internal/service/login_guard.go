@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrLoginLocked = errors.New("too many failed sign in attempts, sign in is temporarily blocked")
+
+var ErrLoginThrottled = errors.New("too many failed sign in attempts, wait before next attempt")
+
+var ErrInvalidUnlockToken = errors.New("unlock token is invalid, expired or already used")
+
+const (
+	defaultLoginMaxAttempts     = 5
+	defaultLoginMaxIpAttempts   = 20
+	defaultLoginLockoutDuration = 15 * time.Minute
+	defaultLoginDelay           = time.Second
+	defaultLoginMaxDelay        = time.Minute
+	defaultLoginWindow          = time.Hour
+	loginUnlockTTL              = 24 * time.Hour
+)
+
+type UnlockInput struct {
+	Token string `json:"token" binding:"required,len=26"`
+}
+
+// LoginGuard protects password sign in from guessing. Failed attempts are counted per email and per ip address.
+// Every failure doubles delay before next attempt is allowed, after limiter.login.maxAttempts failures email is locked
+// for limiter.login.lockoutDuration and user gets an email with unlock link. Ip address is allowed some more attempts,
+// because many users can share it.
+type LoginGuard struct {
+	attempts repository.LoginAttempts
+	tokens   TokensService
+	audit    Audit
+	wg       *sync.WaitGroup
+	config   config.Config
+}
+
+func NewLoginGuard(attempts repository.LoginAttempts, tokens TokensService, audit Audit, wg *sync.WaitGroup, config config.Config) LoginGuard {
+	return LoginGuard{
+		attempts: attempts,
+		tokens:   tokens,
+		audit:    audit,
+		wg:       wg,
+		config:   config,
+	}
+}
+
+// SignIn reserves attempt for email and ip address within their limits and creates auth token. Reserved attempt
+// is counted as failure until password is checked, so parallel requests can not check more passwords than allowed.
+func (g LoginGuard) SignIn(ctx context.Context, email string, password string, ip string) (*domain.Token, error) {
+	limits := g.limits()
+	emailKey := loginEmailKey(email)
+	ipKey := loginIpKey(ip)
+	emailBefore, emailAttempts, err := g.reserve(ctx, emailKey, 0, limits.MaxAttempts, limits)
+	if err != nil {
+		return nil, err
+	}
+	ipBefore, ipAttempts, err := g.reserve(ctx, ipKey, limits.MaxAttempts, limits.MaxIpAttempts, limits)
+	if err != nil {
+		g.release(ctx, emailKey, emailBefore)
+		return nil, err
+	}
+
+	user, err := g.tokens.authenticate(ctx, email, password)
+	if errors.Is(err, ErrPasswordDoesNotMatch) || errors.Is(err, repository.ErrRecordNotFound) {
+		if failErr := g.registerFailure(ctx, user, email, ip, emailAttempts.FailedAttempts, ipAttempts.FailedAttempts, limits); failErr != nil {
+			return nil, failErr
+		}
+		return nil, err
+	}
+	g.release(ctx, ipKey, ipBefore)
+	var token *domain.Token
+	if err == nil {
+		token, err = g.tokens.CreateAuthTokenForUser(ctx, user)
+	}
+	if err != nil {
+		g.release(ctx, emailKey, emailBefore)
+		return nil, err
+	}
+	if err = g.attempts.Reset(ctx, emailKey); err != nil {
+		return token, e.Wrap("can not reset failed sign in attempts", err)
+	}
+	return token, nil
+}
+
+// Unlock removes lock of email by token from lock notification.
+func (g LoginGuard) Unlock(ctx context.Context, input UnlockInput) error {
+	unlock, err := g.tokens.repo.GetByPlaintext(ctx, domain.ScopeLoginUnlock, input.Token)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return ErrInvalidUnlockToken
+	}
+	if err != nil {
+		return e.Wrap("can not get unlock token", err)
+	}
+	if unlock.UsedAt != nil || unlock.Expiry.Before(time.Now()) {
+		return ErrInvalidUnlockToken
+	}
+	claimed, err := g.tokens.repo.MarkUsed(ctx, unlock.ID)
+	if err != nil {
+		return e.Wrap("can not mark unlock token as used", err)
+	}
+	if !claimed {
+		return ErrInvalidUnlockToken
+	}
+	user, err := g.tokens.userRepo.GetById(ctx, unlock.UserId)
+	if err != nil {
+		return e.Wrap("can not find user of unlock token", err)
+	}
+	if err = g.attempts.Reset(ctx, loginEmailKey(user.Email)); err != nil {
+		return e.Wrap("can not reset failed sign in attempts", err)
+	}
+	if err = g.tokens.repo.DeleteAllForUser(ctx, domain.ScopeLoginUnlock, user.Id); err != nil {
+		return e.Wrap("can not delete unlock tokens", err)
+	}
+	logger.Info(logger.LogMessage{Msg: "sign in is unlocked by email link", Code: "200", Properties: map[string]string{"email": user.Email}})
+	return nil
+}
+
+// reserve counts attempt of key, when limits allow it. Attempts before and after reservation are returned.
+func (g LoginGuard) reserve(ctx context.Context, key string, free int, max int, limits config.LoginLimiter) (domain.LoginAttempts, domain.LoginAttempts, error) {
+	var before domain.LoginAttempts
+	after, err := g.attempts.Reserve(ctx, key, limits.Window, func(attempts domain.LoginAttempts) error {
+		before = attempts
+		return checkLoginAttempts(attempts, free, max, limits)
+	})
+	if err != nil && !errors.Is(err, ErrLoginLocked) && !errors.Is(err, ErrLoginThrottled) {
+		return before, after, e.Wrap("can not reserve sign in attempt", err)
+	}
+	return before, after, err
+}
+
+// release takes back reserved attempt, which was not a failure. Error is only logged, because attempt is already made.
+func (g LoginGuard) release(ctx context.Context, key string, before domain.LoginAttempts) {
+	if err := g.attempts.Release(ctx, key, before.LastFailedAt); err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not release sign in attempt of " + key + ": " + err.Error()))
+	}
+}
+
+// checkLoginAttempts returns error, when key is locked, previous failure was too recent or max attempts are already taken by requests
+// in progress. First free failures are not delayed.
+func checkLoginAttempts(attempts domain.LoginAttempts, free int, max int, limits config.LoginLimiter) error {
+	if attempts.IsLocked() {
+		return e.Wrap("sign in is blocked until "+attempts.LockedUntil.Format("02.01.2006 15:04 MST"), ErrLoginLocked)
+	}
+	if attempts.LastFailedAt == nil || attempts.LastFailedAt.Before(time.Now().Add(-limits.Window)) {
+		return nil
+	}
+	if attempts.FailedAttempts >= max {
+		return e.Wrap("previous attempts are not finished", ErrLoginThrottled)
+	}
+	retryAt := attempts.LastFailedAt.Add(loginDelay(attempts.FailedAttempts-free, limits))
+	if wait := time.Until(retryAt); wait > 0 {
+		return e.Wrap("next attempt is allowed in "+strconv.Itoa(int(wait.Seconds())+1)+"s", ErrLoginThrottled)
+	}
+	return nil
+}
+
+// registerFailure locks email and ip address, when their reserved attempts reach limits. Failures and lock of existing
+// user are written to audit log of user account, unknown emails have no account and are only logged.
+func (g LoginGuard) registerFailure(ctx context.Context, user domain.User, email string, ip string, emailFailures int, ipFailures int, limits config.LoginLimiter) error {
+	var err error
+	if user.Id != 0 {
+		g.audit.Record(NewAuditEntry(user, ip, AuditFail, AuditModuleLogin, user.Crmid, nil, map[string]any{"failed_attempts": emailFailures}))
+	}
+	until := time.Now().Add(limits.LockoutDuration)
+	properties := map[string]string{"email": email, "ip": ip, "until": until.Format(time.RFC3339)}
+	if ipFailures >= limits.MaxIpAttempts {
+		if err = g.attempts.Lock(ctx, loginIpKey(ip), until); err != nil {
+			return e.Wrap("can not lock ip address", err)
+		}
+		logger.Warn(logger.LogMessage{Msg: "sign in from ip address is locked after failed attempts", Code: "429", Properties: properties})
+	}
+	if emailFailures < limits.MaxAttempts {
+		return nil
+	}
+	if err = g.attempts.Lock(ctx, loginEmailKey(email), until); err != nil {
+		return e.Wrap("can not lock email", err)
+	}
+	logger.Warn(logger.LogMessage{Msg: "sign in with email is locked after failed attempts", Code: "429", Properties: properties})
+	if user.Id != 0 {
+		g.audit.Record(NewAuditEntry(user, ip, AuditLock, AuditModuleLogin, user.Crmid, nil, map[string]any{"locked_until": until}))
+	}
+	g.sendLockedAlert(email, ip, until)
+	return e.Wrap("sign in is blocked until "+until.Format("02.01.2006 15:04 MST"), ErrLoginLocked)
+}
+
+// sendLockedAlert sends email with unlock link to owner of locked email, when such user exists.
+func (g LoginGuard) sendLockedAlert(email string, ip string, until time.Time) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		user, err := g.tokens.userRepo.GetByEmail(ctx, email)
+		if err != nil || !user.IsActive {
+			return
+		}
+		if err = g.tokens.repo.DeleteAllForUser(ctx, domain.ScopeLoginUnlock, user.Id); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not delete previous unlock tokens: " + err.Error()))
+			return
+		}
+		token, err := g.tokens.repo.New(ctx, user.Id, loginUnlockTTL, domain.ScopeLoginUnlock)
+		if err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not create unlock token: " + err.Error()))
+			return
+		}
+		companyData, err := g.tokens.company.GetCompany(ctx)
+		if err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not send sign in lock alert: " + err.Error()))
+			return
+		}
+		err = g.tokens.emails.SendLoginLocked(LoginLockedData{
+			Name:    user.FirstName + " " + user.LastName,
+			Ip:      ip,
+			Until:   until,
+			Token:   token.Plaintext,
+			Company: companyData.OrganizationName,
+			Support: g.config.Vtiger.Business.SupportEmail,
+			Domain:  g.config.Domain,
+			Email:   user.Email,
+			Subject: g.config.Email.Subjects.LoginLocked,
+		})
+		if err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+		}
+	}()
+}
+
+func (g LoginGuard) limits() config.LoginLimiter {
+	limits := g.config.Limiter.Login
+	if limits.MaxAttempts <= 0 {
+		limits.MaxAttempts = defaultLoginMaxAttempts
+	}
+	if limits.MaxIpAttempts <= 0 {
+		limits.MaxIpAttempts = defaultLoginMaxIpAttempts
+	}
+	if limits.LockoutDuration <= 0 {
+		limits.LockoutDuration = defaultLoginLockoutDuration
+	}
+	if limits.Delay <= 0 {
+		limits.Delay = defaultLoginDelay
+	}
+	if limits.MaxDelay <= 0 {
+		limits.MaxDelay = defaultLoginMaxDelay
+	}
+	if limits.Window <= 0 {
+		limits.Window = defaultLoginWindow
+	}
+	return limits
+}
+
+// loginDelay is a pause after failures in a row, it doubles with every failure up to limiter.login.maxDelay.
+func loginDelay(failures int, limits config.LoginLimiter) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := limits.Delay
+	for i := 1; i < failures && delay < limits.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > limits.MaxDelay {
+		return limits.MaxDelay
+	}
+	return delay
+}
+
+func loginEmailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func loginIpKey(ip string) string {
+	return "ip:" + ip
+}
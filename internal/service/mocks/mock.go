@@ -158,17 +158,17 @@ func (mr *MockDocumentServiceInterfaceMockRecorder) AttachFile(ctx, file, id, us
 }
 
 // DeleteFile mocks base method.
-func (m *MockDocumentServiceInterface) DeleteFile(ctx context.Context, id, related string) error {
+func (m *MockDocumentServiceInterface) DeleteFile(ctx context.Context, id, related string, userModel domain.User) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteFile", ctx, id, related)
+	ret := m.ctrl.Call(m, "DeleteFile", ctx, id, related, userModel)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteFile indicates an expected call of DeleteFile.
-func (mr *MockDocumentServiceInterfaceMockRecorder) DeleteFile(ctx, id, related interface{}) *gomock.Call {
+func (mr *MockDocumentServiceInterfaceMockRecorder) DeleteFile(ctx, id, related, userModel interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDocumentServiceInterface)(nil).DeleteFile), ctx, id, related)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFile", reflect.TypeOf((*MockDocumentServiceInterface)(nil).DeleteFile), ctx, id, related, userModel)
 }
 
 // GetFile mocks base method.
@@ -2,14 +2,34 @@ package service
 
 import (
 	"context"
+	"errors"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"sync"
 	"time"
 )
 
+const defaultNotificationsInterval = time.Minute
+const notificationsImportTimeout = 20 * time.Second
+
+const CacheNotificationsImport = "notifications_import"
+
+const (
+	EventNotification     = "notification"
+	EventTicketStatus     = "ticket.status"
+	EventTicketCreated    = "ticket.created"
+	EventTicketUpdated    = "ticket.updated"
+	EventComment          = "comment.created"
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentRefunded  = "payment.refunded"
+	EventDocumentUploaded = "document.uploaded"
+)
+
 type Notifications struct {
 	cache          cache.Cache
 	config         config.Config
@@ -17,11 +37,11 @@ type Notifications struct {
 	repository     repository.NotificationsRepo
 	crm            repository.NotificationsCrm
 	userRepository repository.Users
+	wg             *sync.WaitGroup
+	events         *pubsub.Hub
 }
 
-var cachedAccounts map[string][]domain.User
-
-func NewNotificationsService(cache cache.Cache, config config.Config, manager ManagerService, repository repository.NotificationsRepo, crm repository.NotificationsCrm, userRepository repository.Users) Notifications {
+func NewNotificationsService(cache cache.Cache, config config.Config, manager ManagerService, repository repository.NotificationsRepo, crm repository.NotificationsCrm, userRepository repository.Users, wg *sync.WaitGroup, events *pubsub.Hub) Notifications {
 	return Notifications{
 		cache:          cache,
 		config:         config,
@@ -29,38 +49,101 @@ func NewNotificationsService(cache cache.Cache, config config.Config, manager Ma
 		repository:     repository,
 		crm:            crm,
 		userRepository: userRepository,
+		wg:             wg,
+		events:         events,
 	}
 }
 
-func (n Notifications) ImportNotifications() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+// StartImport imports notifications from crm every configured interval until context is cancelled.
+func (n Notifications) StartImport(ctx context.Context) {
+	if !n.config.Notifications.Enabled {
+		return
+	}
+	interval := n.config.Notifications.Interval
+	if interval <= 0 {
+		interval = defaultNotificationsInterval
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := n.ImportNotifications(ctx); err != nil {
+				logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ImportNotifications copies notifications from crm to every user of account. Only one import runs at a time,
+// also between portal instances sharing cache, concurrent calls return immediately.
+// Notification, which can not be delivered, stays in crm for next import and does not stop the others.
+func (n Notifications) ImportNotifications(ctx context.Context) error {
+	locked, err := n.cache.Add(CacheNotificationsImport, []byte("1"), int64(2*notificationsImportTimeout/time.Second))
+	if err != nil {
+		return e.Wrap("can not lock notifications import", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer DeleteFromCache(n.cache, CacheNotificationsImport)
+
+	ctx, cancel := context.WithTimeout(ctx, notificationsImportTimeout)
 	defer cancel()
 	notifications, err := n.crm.GetAllNotifications(ctx)
 	if err != nil {
 		return e.Wrap("can not get notifications from crm", err)
 	}
+	var errs []error
+	cachedAccounts := make(map[string][]domain.User)
 	for _, notification := range notifications {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		// notification without account has no receivers, so it is only removed from crm
 		if notification.AccountId != "" {
 			contacts, ok := cachedAccounts[notification.AccountId]
 			if !ok {
 				contacts, err = n.userRepository.GetAllByAccountId(ctx, notification.AccountId)
 				if err != nil {
-					return e.Wrap("can not get related contacts for account "+notification.AccountId, err)
+					errs = append(errs, e.Wrap("can not get related contacts for account "+notification.AccountId, err))
+					continue
 				}
 				cachedAccounts[notification.AccountId] = contacts
 			}
-			for _, contact := range contacts {
-				notification.UserId = contact.Crmid
-				err = n.repository.Insert(ctx, &notification)
-				if err != nil {
-					return e.Wrap("can not insert in database with id "+notification.Crmid+" for user"+contact.Crmid, err)
-				}
+			if err = n.importNotification(ctx, notification, contacts); err != nil {
+				errs = append(errs, err)
+				continue
 			}
+		}
 
-			err = n.crm.DeleteReceivedNotification(ctx, notification.Crmid)
-			if err != nil {
-				return e.Wrap("can not insert delete notification in crm "+notification.Crmid, err)
-			}
+		err = n.crm.DeleteReceivedNotification(ctx, notification.Crmid)
+		if err != nil {
+			errs = append(errs, e.Wrap("can not insert delete notification in crm "+notification.Crmid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// importNotification skips users, who already received notification, when it was not deleted from crm by previous import.
+func (n Notifications) importNotification(ctx context.Context, notification domain.Notification, contacts []domain.User) error {
+	for _, contact := range contacts {
+		exists, err := n.repository.Exists(ctx, notification.Crmid, contact.Crmid)
+		if err != nil {
+			return e.Wrap("can not check notification "+notification.Crmid+" for user "+contact.Crmid, err)
+		}
+		if exists {
+			continue
+		}
+		if err = n.notifyUsers(ctx, notification, []domain.User{contact}); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -86,3 +169,32 @@ func (n Notifications) GetNotificationsByUserId(ctx context.Context, userId stri
 func (n Notifications) MarkNotificationRead(ctx context.Context, id int64, userId string) error {
 	return n.repository.MarkNotificationAsRead(ctx, id, userId)
 }
+
+// NotifyAccount stores notification for every user of account and sends it to their streams.
+func (n Notifications) NotifyAccount(ctx context.Context, notification domain.Notification) error {
+	contacts, err := n.userRepository.GetAllByAccountId(ctx, notification.AccountId)
+	if err != nil {
+		return e.Wrap("can not get related contacts for account "+notification.AccountId, err)
+	}
+	return n.notifyUsers(ctx, notification, contacts)
+}
+
+func (n Notifications) notifyUsers(ctx context.Context, notification domain.Notification, contacts []domain.User) error {
+	for _, contact := range contacts {
+		notification.UserId = contact.Crmid
+		err := n.repository.Insert(ctx, &notification)
+		if err != nil {
+			return e.Wrap("can not insert in database with id "+notification.Crmid+" for user"+contact.Crmid, err)
+		}
+		n.events.Publish(pubsub.Event{Type: EventNotification, AccountId: notification.AccountId, UserId: contact.Crmid, Data: notification})
+	}
+	return nil
+}
+
+// Subscribe returns stream of events for user. Events published after lastEventId are returned by Missed.
+func (n Notifications) Subscribe(user domain.User, lastEventId int64) *pubsub.Subscription {
+	if n.events == nil {
+		return nil
+	}
+	return n.events.Subscribe(user.AccountId, user.Crmid, lastEventId)
+}
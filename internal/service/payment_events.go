@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPaymentEvent = errors.New("payment event is not valid")
+
+var ErrDuplicatePaymentEvent = errors.New("payment event is already processed")
+
+const (
+	defaultRevisionAttempts     = 10
+	defaultRevisionBackoff      = time.Minute
+	defaultRevisionMaxBackoff   = 6 * time.Hour
+	defaultRevisionPollInterval = 30 * time.Second
+	defaultRevisionBatchSize    = 20
+	revisionLock                = 5 * time.Minute
+)
+
+// HandleEvent stores webhook event of provider and applies it to payment once. Intent events, which are older than the
+// last applied one, do not change payment. Invoices and sales orders are revised in crm, when provider confirms
+// payment or refund, failed revisions are retried by Start.
+func (p Payments) HandleEvent(ctx context.Context, providerName string, event payment.Event) error {
+	if event.Type != payment.EventIntentUpdated && event.Type != payment.EventRefunded {
+		return nil
+	}
+	if event.Id == "" {
+		return e.Wrap("event of "+providerName+" has no id", ErrInvalidPaymentEvent)
+	}
+	if len(event.Refunds) < 2 {
+		var refund *payment.Refund
+		if len(event.Refunds) == 1 {
+			refund = &event.Refunds[0]
+		}
+		return p.handleEvent(ctx, providerName, event, event.Id, refund)
+	}
+	// every refund of event is stored as own event, so refunds, which are already stored, do not stop new ones
+	var errs []error
+	duplicates := 0
+	for i := range event.Refunds {
+		err := p.handleEvent(ctx, providerName, event, event.Id+":"+event.Refunds[i].Id, &event.Refunds[i])
+		if errors.Is(err, ErrDuplicatePaymentEvent) {
+			duplicates++
+		} else if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if duplicates == len(event.Refunds) {
+		return e.Wrap(event.Id, ErrDuplicatePaymentEvent)
+	}
+	return errors.Join(errs...)
+}
+
+func (p Payments) handleEvent(ctx context.Context, providerName string, event payment.Event, eventId string, refund *payment.Refund) error {
+	record := domain.PaymentEvent{
+		Provider:   providerName,
+		EventId:    eventId,
+		Type:       event.Type,
+		IntentId:   event.Intent.Id,
+		Status:     domain.PaymentEventReceived,
+		OccurredAt: event.Created,
+	}
+	if refund != nil {
+		record.RefundId = refund.Id
+		if refund.IntentId != "" {
+			record.IntentId = refund.IntentId
+		}
+	}
+	inserted, err := p.repository.InsertEvent(ctx, &record)
+	if err != nil {
+		return e.Wrap("can not store payment event "+eventId, err)
+	}
+	if !inserted {
+		record, err = p.repository.GetEvent(ctx, providerName, eventId)
+		if err != nil {
+			return e.Wrap("can not get payment event "+eventId, err)
+		}
+		// event stays received, when it has failed before, so it is applied again on redelivery
+		if record.Status != domain.PaymentEventReceived && record.Status != domain.PaymentEventProcessing {
+			return e.Wrap(eventId, ErrDuplicatePaymentEvent)
+		}
+	}
+	// provider can deliver the same event in parallel, only one delivery applies it
+	now := time.Now()
+	claimed, err := p.repository.ClaimReceivedEvent(ctx, record.Id, now, now.Add(revisionLock))
+	if err != nil {
+		return e.Wrap("can not claim payment event "+eventId, err)
+	}
+	if !claimed {
+		return e.Wrap(eventId, ErrDuplicatePaymentEvent)
+	}
+	record.Status = domain.PaymentEventProcessing
+	record.NextAttemptAt = nil
+
+	if event.Type == payment.EventRefunded {
+		err = p.applyRefund(ctx, refund, &record)
+	} else {
+		err = p.applyIntent(ctx, event, &record)
+	}
+	if errors.Is(err, repository.ErrRecordNotFound) || errors.Is(err, ErrPaymentMismatch) || errors.Is(err, ErrInvalidPaymentEvent) {
+		record.Status = domain.PaymentEventSkipped
+		record.Error = err.Error()
+	} else if err != nil {
+		// event is released, so it is applied again on redelivery
+		record.Status = domain.PaymentEventReceived
+		record.NextAttemptAt = nil
+		record.ProcessedAt = nil
+		if updateErr := p.repository.UpdateEvent(ctx, &record); updateErr != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not release payment event " + eventId + ": " + updateErr.Error()))
+		}
+		return err
+	}
+	if updateErr := p.repository.UpdateEvent(ctx, &record); updateErr != nil {
+		return e.Wrap("can not update payment event "+eventId, updateErr)
+	}
+	// failed revision is retried by Start, so provider does not need to send event again
+	if record.Status == domain.PaymentEventPending {
+		if reviseErr := p.revise(ctx, &record); reviseErr != nil {
+			logger.Error(logger.GenerateErrorMessageFromString(reviseErr.Error()))
+		}
+	}
+	return err
+}
+
+// applyIntent changes status of payment from intent of event. Succeeded payment is settled once, its revision of crm
+// is left pending for revise.
+func (p Payments) applyIntent(ctx context.Context, event payment.Event, record *domain.PaymentEvent) error {
+	paymentModel, err := p.repository.GetByStripeId(ctx, event.Intent.Id)
+	if err != nil {
+		return e.Wrap("can not get payment by intent "+event.Intent.Id, err)
+	}
+	record.PaymentId = paymentModel.ID
+	if paymentModel.Provider != record.Provider {
+		return e.Wrap("event of "+record.Provider+" for payment of "+paymentModel.Provider, ErrPaymentMismatch)
+	}
+	if !event.Created.IsZero() {
+		last, err := p.repository.GetLastEventTime(ctx, paymentModel.ID)
+		if err != nil {
+			return e.Wrap("can not get last event of payment", err)
+		}
+		if event.Created.Before(last) {
+			record.Status = domain.PaymentEventSkipped
+			record.Error = "event is older than the last applied event"
+			return nil
+		}
+	}
+	status := p.getNumericIntentStatus(event.Intent.Status)
+	if status == SUCCEEDED && (event.Intent.Amount != toMinorUnits(paymentModel.Amount) || !strings.EqualFold(event.Intent.Currency, paymentModel.Currency)) {
+		logger.Warn(logger.LogMessage{Msg: "payment intent does not match payment", Code: "409", Properties: map[string]string{"intent": event.Intent.Id, "account_id": paymentModel.AccountId}})
+		return e.Wrap("intent "+event.Intent.Id, ErrPaymentMismatch)
+	}
+	before := map[string]any{"status": paymentModel.Status, "settled_at": paymentModel.SettledAt}
+	if canChangeStatus(paymentModel.Status, status) {
+		paymentModel.Status = status
+		if paymentModel, err = p.repository.UpdatePayment(ctx, paymentModel); err != nil {
+			return e.Wrap("can not update payment "+paymentModel.StripePaymentId, err)
+		}
+	}
+	// payment can already be succeeded by ConfirmPayment, but it is settled only by event of provider. Payment is
+	// settled once, so another event of the same intent does not revise crm again. Event is saved as pending together
+	// with settlement, so revision of crm is not lost, when event can not be saved after it.
+	settled := false
+	if status == SUCCEEDED && paymentModel.SettledAt == nil {
+		now := time.Now()
+		p.markPending(record)
+		if settled, err = p.repository.SettlePayment(ctx, paymentModel.ID, now, record); err != nil {
+			return e.Wrap("can not settle payment "+paymentModel.StripePaymentId, err)
+		}
+		if settled {
+			paymentModel.SettledAt = &now
+		}
+	}
+	p.auditEvent(record.Provider, paymentModel, before, map[string]any{"status": paymentModel.Status, "settled_at": paymentModel.SettledAt})
+	if !settled {
+		p.markProcessed(record)
+		return nil
+	}
+	p.events.Publish(pubsub.Event{Type: EventPaymentSucceeded, AccountId: paymentModel.AccountId, UserId: paymentModel.UserId, Data: paymentModel})
+	return nil
+}
+
+// applyRefund stores refund and notifies payer. Refunds, which are already stored, are skipped. Revision of crm is left
+// pending for revise, when payment was settled.
+func (p Payments) applyRefund(ctx context.Context, refund *payment.Refund, record *domain.PaymentEvent) error {
+	if refund == nil {
+		return e.Wrap("event "+record.EventId+" does not contain refund", ErrInvalidPaymentEvent)
+	}
+	paymentModel, err := p.repository.GetByStripeId(ctx, record.IntentId)
+	if err != nil {
+		return e.Wrap("can not get payment by intent "+record.IntentId, err)
+	}
+	record.PaymentId = paymentModel.ID
+	if paymentModel.Provider != record.Provider {
+		return e.Wrap("refund of "+record.Provider+" for payment of "+paymentModel.Provider, ErrPaymentMismatch)
+	}
+	if refund.Status == "failed" || refund.Status == payment.StatusCanceled {
+		record.Status = domain.PaymentEventSkipped
+		record.Error = "refund is " + refund.Status
+		return nil
+	}
+	refunds, err := p.repository.GetRefunds(ctx, paymentModel.ID)
+	if err != nil {
+		return e.Wrap("can not get refunds of payment", err)
+	}
+	var refunded int64
+	for _, stored := range refunds {
+		if stored.RefundId == refund.Id {
+			record.Status = domain.PaymentEventSkipped
+			record.Error = "refund is already stored"
+			return nil
+		}
+		refunded += toMinorUnits(stored.Amount)
+	}
+	stored := domain.PaymentRefund{
+		PaymentId: paymentModel.ID,
+		RefundId:  refund.Id,
+		Amount:    float64(refund.Amount) / 100,
+		Currency:  paymentModel.Currency,
+		Status:    refund.Status,
+	}
+	before := map[string]any{"status": paymentModel.Status, "refunded": float64(refunded) / 100}
+	if refunded+refund.Amount >= toMinorUnits(paymentModel.Amount) {
+		paymentModel.Status = REFUNDED
+	}
+	// invoices of payment, which was not settled, were not revised, so there is nothing to revert
+	if paymentModel.SettledAt == nil {
+		p.markProcessed(record)
+	} else {
+		p.markPending(record)
+	}
+	inserted, err := p.repository.InsertRefund(ctx, &stored, paymentModel, record)
+	if err != nil {
+		return e.Wrap("can not store refund "+refund.Id, err)
+	}
+	if !inserted {
+		record.Status = domain.PaymentEventSkipped
+		record.Error = "refund is already stored"
+		record.ProcessedAt = nil
+		record.NextAttemptAt = nil
+		return nil
+	}
+	p.auditEvent(record.Provider, paymentModel, before, map[string]any{"status": paymentModel.Status, "refunded": float64(refunded+refund.Amount) / 100})
+	p.events.Publish(pubsub.Event{Type: EventPaymentRefunded, AccountId: paymentModel.AccountId, UserId: paymentModel.UserId, Data: stored})
+	p.notifyRefund(paymentModel, stored)
+	return nil
+}
+
+// Start retries pending revisions of crm in background until ctx is done.
+func (p Payments) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		interval := p.config.Payment.RevisionPollInterval
+		if interval <= 0 {
+			interval = defaultRevisionPollInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := p.ReviseDue(ctx); err != nil {
+					logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// ReviseDue retries revisions of crm, which are waiting for next attempt.
+func (p Payments) ReviseDue(ctx context.Context) error {
+	now := time.Now()
+	events, err := p.repository.GetDueEvents(ctx, now, defaultRevisionBatchSize)
+	if err != nil {
+		return e.Wrap("can not get due payment events", err)
+	}
+	for _, event := range events {
+		claimed, err := p.repository.ClaimEvent(ctx, event.Id, now, now.Add(revisionLock))
+		if err != nil {
+			return e.Wrap("can not claim payment event", err)
+		}
+		if !claimed {
+			continue
+		}
+		if err = p.revise(ctx, &event); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+		}
+	}
+	return nil
+}
+
+// revise runs revision of crm for pending event. Invoices and sales orders, which were revised by previous attempts,
+// are skipped. Event fails, when attempts are over.
+func (p Payments) revise(ctx context.Context, record *domain.PaymentEvent) error {
+	rev := revision{repository: p.repository, record: record}
+	paymentModel, err := p.repository.GetById(ctx, record.PaymentId)
+	if err == nil {
+		if record.Type == payment.EventRefunded {
+			err = p.revertRefund(ctx, paymentModel, record.RefundId, rev)
+		} else {
+			err = p.reviseParents(ctx, paymentModel, rev)
+		}
+	}
+	record.Attempts++
+	if err == nil {
+		p.markProcessed(record)
+	} else {
+		record.Error = err.Error()
+		if record.Attempts >= p.revisionAttempts() {
+			record.Status = domain.PaymentEventFailed
+			record.NextAttemptAt = nil
+		} else {
+			next := time.Now().Add(p.revisionBackoff(record.Attempts))
+			record.NextAttemptAt = &next
+		}
+	}
+	if updateErr := p.repository.UpdateEvent(ctx, record); updateErr != nil {
+		return e.Wrap("can not update payment event "+record.EventId, updateErr)
+	}
+	if err != nil {
+		return e.Wrap("can not revise crm for payment event "+record.EventId, err)
+	}
+	return nil
+}
+
+// revertRefund reverts parents of payment by refund, previous refunds of payment are already reverted.
+func (p Payments) revertRefund(ctx context.Context, paymentModel domain.Payment, refundId string, rev revision) error {
+	refunds, err := p.repository.GetRefunds(ctx, paymentModel.ID)
+	if err != nil {
+		return e.Wrap("can not get refunds of payment", err)
+	}
+	var refunded int64
+	for _, refund := range refunds {
+		if refund.RefundId != refundId {
+			refunded += toMinorUnits(refund.Amount)
+			continue
+		}
+		allocations, err := p.repository.GetAllocations(ctx, paymentModel.ID)
+		if err != nil {
+			return e.Wrap("can not get allocations of payment", err)
+		}
+		return p.revertParents(ctx, paymentModel, allocations, refunded, toMinorUnits(refund.Amount), rev)
+	}
+	return e.Wrap("refund "+refundId, repository.ErrRecordNotFound)
+}
+
+// auditEvent records change of payment, which was made by webhook of provider. Nothing is recorded, when event did
+// not change payment.
+func (p Payments) auditEvent(provider string, paymentModel domain.Payment, before map[string]any, after map[string]any) {
+	entry := NewAuditEntry(domain.User{AccountId: paymentModel.AccountId}, "", AuditUpdate, domain.ModulePayments, strconv.FormatInt(paymentModel.ID, 10), before, after)
+	if len(entry.Changes) == 0 {
+		return
+	}
+	entry.UserName = provider
+	p.audit.Record(entry)
+}
+
+// revision keeps invoices and sales orders, which are revised in crm by event. Id is saved with event before crm is
+// changed, so received amount of invoice is not changed twice, when event can not be saved after revision. Id of failed
+// revision is removed again.
+type revision struct {
+	repository repository.Payments
+	record     *domain.PaymentEvent
+}
+
+func (r revision) done(id string) bool {
+	for _, revised := range r.record.Revised {
+		if revised == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r revision) apply(ctx context.Context, id string, revise func() error) error {
+	r.record.Revised = append(r.record.Revised, id)
+	sort.Strings(r.record.Revised)
+	if err := r.repository.UpdateEvent(ctx, r.record); err != nil {
+		r.remove(id)
+		return e.Wrap("can not save revision of payment event "+r.record.EventId, err)
+	}
+	if err := revise(); err != nil {
+		r.remove(id)
+		if updateErr := r.repository.UpdateEvent(ctx, r.record); updateErr != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("revision of " + id + " is saved, but it has failed: " + updateErr.Error()))
+		}
+		return err
+	}
+	return nil
+}
+
+func (r revision) remove(id string) {
+	revised := r.record.Revised[:0]
+	for _, existing := range r.record.Revised {
+		if existing != id {
+			revised = append(revised, existing)
+		}
+	}
+	r.record.Revised = revised
+}
+
+// markPending leaves revision of crm for revise. Event is locked for time of revision, so worker does not take it in
+// parallel.
+func (p Payments) markPending(record *domain.PaymentEvent) {
+	next := time.Now().Add(revisionLock)
+	record.Status = domain.PaymentEventPending
+	record.NextAttemptAt = &next
+}
+
+func (p Payments) markProcessed(record *domain.PaymentEvent) {
+	now := time.Now()
+	record.Status = domain.PaymentEventProcessed
+	record.Error = ""
+	record.ProcessedAt = &now
+	record.NextAttemptAt = nil
+}
+
+// revisionBackoff returns delay before next attempt: revisionBackoff, 2*revisionBackoff and so on.
+func (p Payments) revisionBackoff(attempts int) time.Duration {
+	base := p.config.Payment.RevisionBackoff
+	if base <= 0 {
+		base = defaultRevisionBackoff
+	}
+	delay := base << (attempts - 1)
+	if delay > defaultRevisionMaxBackoff || delay <= 0 {
+		delay = defaultRevisionMaxBackoff
+	}
+	return delay
+}
+
+func (p Payments) revisionAttempts() int {
+	if p.config.Payment.RevisionAttempts < 1 {
+		return defaultRevisionAttempts
+	}
+	return p.config.Payment.RevisionAttempts
+}
@@ -2,27 +2,50 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
 	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment/stripe"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
-	"github.com/stripe/stripe-go/v72"
-	"github.com/stripe/stripe-go/v72/paymentintent"
-	_ "github.com/stripe/stripe-go/v72/webhook"
-	"runtime"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+var ErrNothingToPay = errors.New("invoice or sales order is already paid")
+
+var ErrPaymentMismatch = errors.New("payment intent does not match payment")
+
+var ErrUnknownPaymentProvider = errors.New("payment provider is not configured")
+
+var ErrInvalidAllocation = errors.New("allocation of payment is not valid")
+
+var ErrPaymentNotCancelable = errors.New("only pending payment can be canceled")
+
 type Payments struct {
-	cache      cache.Cache
-	config     config.Config
-	currency   CurrencyService
-	repository repository.PaymentsRepo
-	vtiger     vtiger.VtigerConnector
+	cache       cache.Cache
+	config      config.Config
+	currency    CurrencyService
+	repository  repository.Payments
+	invoices    repository.Invoice
+	salesOrders repository.SalesOrder
+	providers   map[string]payment.Provider
+	vtiger      vtiger.Connector
+	events      *pubsub.Hub
+	users       repository.Users
+	emails      EmailServiceInterface
+	company     Company
+	audit       Audit
+	wg          *sync.WaitGroup
 }
 
 const (
@@ -35,8 +58,16 @@ const (
 	REQUIRES_CONFIRMATION
 	REQUIRES_PAYMENT_METHOD
 	CREATED
+	REFUNDED
 )
 
+// reservedStatuses are statuses of intents, which amounts are reserved on invoices and sales orders. Pending intents can
+// still be paid, succeeded intents are reserved until crm is revised by webhook of provider.
+var reservedStatuses = domain.PaymentReservation{
+	Pending: []int{PENDING, PROCESSING, REQUIRES_ACTION, REQUIRES_CAPTURE, REQUIRES_CONFIRMATION, REQUIRES_PAYMENT_METHOD, CREATED},
+	Paid:    []int{SUCCEEDED},
+}
+
 type PaymentIntent struct {
 	Currency          string  `json:"currency"`
 	PaymentMethodType string  `json:"paymentMethodType"`
@@ -45,115 +76,236 @@ type PaymentIntent struct {
 	Amount            float64 `json:"amount"`
 	UserId            string  `json:"userId"`
 	AccountId         string  `json:"accountId"`
+	// Invoices are paid with one intent. When amount of invoice is 0, its whole balance is paid.
+	Invoices []PaymentAllocationInput `json:"invoices" binding:"omitempty,max=20,dive"`
 }
 
-func NewPaymentsService(cache cache.Cache, config config.Config, currency CurrencyService, repository repository.PaymentsRepo) Payments {
-	stripe.Key = config.Payment.StripeKey
+type PaymentAllocationInput struct {
+	InvoiceId string  `json:"invoice_id" binding:"required"`
+	Amount    float64 `json:"amount" binding:"gte=0"`
+}
 
-	// For sample support and debugging, not required for production:
-	stripe.SetAppInfo(&stripe.AppInfo{
-		Name:    "customer-portal",
-		Version: runtime.Version(),
-		URL:     config.Domain,
-	})
+// PaymentIntentResult is a created payment with data, which client needs to finish it at provider.
+type PaymentIntentResult struct {
+	Payment         domain.Payment
+	ClientSecret    string
+	ConfirmationUrl string
+}
+
+// payable is an amount of invoice or sales order, which is paid. Balance is an amount, which is left to pay in crm.
+type payable struct {
+	ParentId string
+	Module   string
+	Amount   float64
+	Balance  float64
+	Currency string
+}
 
+func NewPaymentsService(cache cache.Cache, config config.Config, currency CurrencyService, repository repository.Payments, invoices repository.Invoice, salesOrders repository.SalesOrder, providers []payment.Provider, connector vtiger.Connector, events *pubsub.Hub, users repository.Users, emails EmailServiceInterface, company Company, audit Audit, wg *sync.WaitGroup) Payments {
+	byName := make(map[string]payment.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
 	return Payments{
-		cache:      cache,
-		config:     config,
-		currency:   currency,
-		repository: repository,
-		vtiger:     vtiger.NewVtigerConnector(cache, config.Vtiger.Connection, vtiger.NewWebRequest(config.Vtiger.Connection)),
+		cache:       cache,
+		config:      config,
+		currency:    currency,
+		repository:  repository,
+		invoices:    invoices,
+		salesOrders: salesOrders,
+		providers:   byName,
+		vtiger:      connector,
+		events:      events,
+		users:       users,
+		emails:      emails,
+		company:     company,
+		audit:       audit,
+		wg:          wg,
 	}
 }
 
-func (p Payments) CreatePaymentIntent(ctx context.Context, req PaymentIntent) (*stripe.PaymentIntent, error) {
-	var formattedPaymentMethodType []*string
-
-	if req.PaymentMethodType == "link" {
-		formattedPaymentMethodType = append(formattedPaymentMethodType, stripe.String("link"), stripe.String("card"))
-	} else {
-		formattedPaymentMethodType = append(formattedPaymentMethodType, stripe.String(req.PaymentMethodType))
+// CreatePaymentIntent creates intent for balance of invoice or sales order, or for several invoices with amounts from
+// req.Invoices. Balances and currency are taken from crm, amount of single invoice or sales order from request is
+// ignored. Provider is chosen by currency.
+func (p Payments) CreatePaymentIntent(ctx context.Context, req PaymentIntent, user domain.User) (PaymentIntentResult, error) {
+	if err := CheckPermission(user, domain.ModulePayments, domain.PermissionWrite); err != nil {
+		return PaymentIntentResult{}, err
 	}
-
-	params := &stripe.PaymentIntentParams{
-		Amount:             stripe.Int64(int64(req.Amount * 100)),
-		Currency:           stripe.String(req.Currency),
-		PaymentMethodTypes: formattedPaymentMethodType,
+	payables, err := p.getPayables(ctx, req, user)
+	if err != nil {
+		return PaymentIntentResult{}, err
+	}
+	var amount float64
+	parentIds := make([]string, 0, len(payables))
+	allocations := make([]domain.PaymentAllocation, 0, len(payables))
+	for _, due := range payables {
+		amount += due.Amount
+		parentIds = append(parentIds, due.ParentId)
+		allocations = append(allocations, domain.PaymentAllocation{ParentId: due.ParentId, Module: due.Module, Amount: due.Amount, Balance: due.Balance})
+	}
+	amount = math.Round(amount*100) / 100
+	currency := payables[0].Currency
+	provider, err := p.providerForCurrency(currency)
+	if err != nil {
+		return PaymentIntentResult{}, err
 	}
 
-	// If this is for an ACSS payment, we add payment_method_options to create
-	// the Mandate.
-	if req.PaymentMethodType == "acss_debit" {
-		params.PaymentMethodOptions = &stripe.PaymentIntentPaymentMethodOptionsParams{
-			ACSSDebit: &stripe.PaymentIntentPaymentMethodOptionsACSSDebitParams{
-				MandateOptions: &stripe.PaymentIntentPaymentMethodOptionsACSSDebitMandateOptionsParams{
-					PaymentSchedule: stripe.String("sporadic"),
-					TransactionType: stripe.String("personal"),
-				},
-			},
+	intent, err := provider.CreateIntent(ctx, payment.IntentInput{
+		Amount:            toMinorUnits(amount),
+		Currency:          currency,
+		PaymentMethodType: req.PaymentMethodType,
+		Description:       "Payment of " + strings.Join(parentIds, ", "),
+		ReturnUrl:         p.returnUrl(),
+		Metadata:          map[string]string{"parent_id": strings.Join(parentIds, ","), "account_id": user.AccountId},
+	})
+	if err != nil {
+		return PaymentIntentResult{}, err
+	}
+	result := PaymentIntentResult{
+		Payment: domain.Payment{
+			StripePaymentId: intent.Id,
+			Provider:        provider.Name(),
+			UserId:          req.UserId,
+			AccountId:       req.AccountId,
+			Amount:          amount,
+			Currency:        currency,
+			PaymentMethod:   req.PaymentMethodType,
+			Status:          PENDING,
+			ParentId:        parentIds[0],
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+			Allocations:     allocations,
+		},
+		ClientSecret:    intent.ClientSecret,
+		ConfirmationUrl: intent.ConfirmationUrl,
+	}
+	// parallel request can reserve the same balance after it was checked, then intent is not needed
+	err = p.repository.Insert(ctx, &result.Payment, reservedStatuses)
+	if errors.Is(err, repository.ErrBalanceExceeded) {
+		if _, cancelErr := provider.Cancel(ctx, intent.Id); cancelErr != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not cancel intent " + intent.Id + ": " + cancelErr.Error()))
 		}
+		return PaymentIntentResult{}, e.Wrap(strings.Join(parentIds, ", "), ErrNothingToPay)
 	}
+	return result, err
+}
+
+// ParseWebhook checks notification of provider and converts it to event.
+func (p Payments) ParseWebhook(ctx context.Context, providerName string, payload []byte, header http.Header) (payment.Event, error) {
+	provider, ok := p.providers[providerName]
+	if !ok {
+		return payment.Event{}, e.Wrap(providerName, ErrUnknownPaymentProvider)
+	}
+	return provider.ParseWebhook(ctx, payload, header)
+}
 
-	res, err := paymentintent.New(params)
+// ConfirmPayment updates status of payment from intent, which is fetched from payment provider. Status, passed by
+// client, is not trusted. Invoices and sales orders are revised in crm only by webhook of provider.
+func (p Payments) ConfirmPayment(ctx context.Context, id string, user domain.User) (domain.Payment, error) {
+	payment, err := p.repository.GetByStripeId(ctx, id)
 	if err != nil {
-		return res, err
-	}
-	var parentId = req.SoId
-	if parentId == "" {
-		parentId = req.InvoiceId
-	}
-	payment := domain.Payment{
-		StripePaymentId: res.ID,
-		UserId:          req.UserId,
-		AccountId:       req.AccountId,
-		Amount:          req.Amount,
-		Currency:        req.Currency,
-		PaymentMethod:   req.PaymentMethodType,
-		Status:          PENDING,
-		ParentId:        parentId,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-	}
-	err = p.repository.Insert(ctx, &payment)
-	return res, err
-}
-
-func (p Payments) AcceptPayment(ctx context.Context, e stripe.Event) (domain.Payment, error) {
-	var paymentIntent stripe.PaymentIntent
-	err := json.Unmarshal(e.Data.Raw, &paymentIntent)
+		return payment, e.Wrap("can not get payment by stripe id "+id, err)
+	}
+	if payment.AccountId != user.AccountId {
+		return payment, ErrOperationNotPermitted
+	}
+	provider, ok := p.providers[payment.Provider]
+	if !ok {
+		return payment, e.Wrap(payment.Provider, ErrUnknownPaymentProvider)
+	}
+	intent, err := provider.GetIntent(ctx, id)
 	if err != nil {
+		return payment, e.Wrap("can not retrieve payment intent "+id, err)
+	}
+	if intent.Amount != toMinorUnits(payment.Amount) || !strings.EqualFold(intent.Currency, payment.Currency) {
+		logger.Warn(logger.LogMessage{Msg: "payment intent does not match payment", Code: "409", Properties: map[string]string{"intent": id, "account_id": payment.AccountId}})
+		return payment, e.Wrap("intent "+id, ErrPaymentMismatch)
+	}
+	status := p.getNumericIntentStatus(intent.Status)
+	if !canChangeStatus(payment.Status, status) {
+		return payment, nil
+	}
+	payment.Status = status
+	return p.repository.UpdatePayment(ctx, payment)
+}
+
+// CancelPayment cancels pending payment at provider. Payments, which are already processed by provider, can not be
+// canceled.
+func (p Payments) CancelPayment(ctx context.Context, id int64, user domain.User) (domain.Payment, error) {
+	if err := CheckPermission(user, domain.ModulePayments, domain.PermissionWrite); err != nil {
 		return domain.Payment{}, err
 	}
-	payment, err := p.repository.GetByStripeId(ctx, paymentIntent.ID)
+	paymentModel, err := p.repository.GetById(ctx, id)
 	if err != nil {
-		return payment, err
+		return paymentModel, e.Wrap("can not get payment "+strconv.FormatInt(id, 10), err)
 	}
-	payment.Status = p.getNumericIntentStatus(paymentIntent)
+	if paymentModel.AccountId != user.AccountId {
+		return paymentModel, ErrOperationNotPermitted
+	}
+	switch paymentModel.Status {
+	case SUCCEEDED, CANCELLED, PROCESSING, REFUNDED:
+		return paymentModel, ErrPaymentNotCancelable
+	}
+	provider, ok := p.providers[paymentModel.Provider]
+	if !ok {
+		return paymentModel, e.Wrap(paymentModel.Provider, ErrUnknownPaymentProvider)
+	}
+	intent, err := provider.Cancel(ctx, paymentModel.StripePaymentId)
+	if errors.Is(err, payment.ErrNotCancelable) {
+		return paymentModel, e.Wrap(err.Error(), ErrPaymentNotCancelable)
+	}
+	if err != nil {
+		return paymentModel, err
+	}
+	paymentModel.Status = p.getNumericIntentStatus(intent.Status)
+	return p.repository.UpdatePayment(ctx, paymentModel)
+}
 
-	return p.repository.UpdatePayment(ctx, payment)
+// GetConfig returns public settings of payments, which client needs to choose payment flow.
+func (p Payments) GetConfig() domain.PaymentConfig {
+	return domain.PaymentConfig{
+		PublishableKey:  p.config.Payment.StripePublic,
+		DefaultProvider: p.defaultProvider(),
+		Providers:       p.config.Payment.Providers,
+	}
 }
 
-func (p Payments) ConfirmPayment(ctx context.Context, event stripe.PaymentIntent) (domain.Payment, error) {
-	payment, err := p.repository.GetByStripeId(ctx, event.ID)
+// reviseParents marks invoices and sales orders of payment as paid in crm. Revised ids are saved by revision, so retry
+// skips them.
+func (p Payments) reviseParents(ctx context.Context, payment domain.Payment, rev revision) error {
+	allocations, err := p.repository.GetAllocations(ctx, payment.ID)
 	if err != nil {
-		return payment, e.Wrap("can not get payment by stripe id "+event.ID, err)
+		return e.Wrap("can not get allocations of payment", err)
 	}
-	payment.Status = p.getNumericIntentStatus(event)
-
-	if payment.Status == SUCCEEDED {
-		go func() {
-			err := p.reviseModuleSuccessStatus(payment.ParentId)
+	// payments, created before allocations, are paid in full
+	if len(allocations) == 0 {
+		allocations = []domain.PaymentAllocation{{ParentId: payment.ParentId}}
+	}
+	var errs []error
+	for _, allocation := range allocations {
+		if !rev.done(allocation.ParentId) {
+			err = rev.apply(ctx, allocation.ParentId, func() error {
+				if allocation.Module == "" || allocation.Module == domain.ModuleSalesOrder {
+					return p.reviseModuleSuccessStatus(ctx, allocation.ParentId)
+				}
+				return p.reviseInvoiceBalance(ctx, allocation.ParentId, allocation.Amount)
+			})
 			if err != nil {
-				logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+				errs = append(errs, e.Wrap(allocation.ParentId, err))
+				continue
+			}
+		}
+		// revised allocation does not reserve balance of parent anymore
+		if allocation.ID != 0 && allocation.RevisedAt == nil {
+			if err = p.repository.MarkAllocationRevised(ctx, allocation.ID, time.Now()); err != nil {
+				errs = append(errs, e.Wrap("can not mark allocation of "+allocation.ParentId+" as revised", err))
 			}
-		}()
+		}
 	}
-	return p.repository.UpdatePayment(ctx, payment)
+	return errors.Join(errs...)
 }
 
-func (p Payments) reviseModuleSuccessStatus(id string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (p Payments) reviseModuleSuccessStatus(ctx context.Context, id string) error {
 	m := make(map[string]any)
 	m["id"] = id
 	m["invoicestatus"] = p.config.Payment.PaidInvoiceStatus
@@ -162,23 +314,298 @@ func (p Payments) reviseModuleSuccessStatus(id string) error {
 	return err
 }
 
+// reviseInvoiceBalance adds amount to received amount of invoice, refunds are passed with negative amount. Invoice gets
+// paid status only when nothing is left to pay, and loses it, when refund leaves some balance.
+func (p Payments) reviseInvoiceBalance(ctx context.Context, id string, amount float64) error {
+	invoice, err := p.invoices.RetrieveById(ctx, id)
+	if err != nil {
+		return err
+	}
+	received := math.Max(math.Round((float64(invoice.Received)+amount)*100)/100, 0)
+	balance := math.Max(math.Round((float64(invoice.HdnGrandTotal)-received)*100)/100, 0)
+	m := make(map[string]any)
+	m["id"] = id
+	m["received"] = received
+	m["balance"] = balance
+	if balance == 0 {
+		m["invoicestatus"] = p.config.Payment.PaidInvoiceStatus
+	} else if invoice.InvoiceStatus == p.config.Payment.PaidInvoiceStatus && p.config.Payment.RefundedInvoiceStatus != "" {
+		m["invoicestatus"] = p.config.Payment.RefundedInvoiceStatus
+	}
+	_, err = p.vtiger.Revise(ctx, m)
+	return err
+}
+
+// revertParents returns refunded amount back to balances of invoices. Refunds are taken from the last allocation, so
+// previous refunds of payment are skipped. Sales orders and payments without allocations lose paid status, when they
+// are refunded in full. Reverted ids are saved by revision, so retry skips them.
+func (p Payments) revertParents(ctx context.Context, payment domain.Payment, allocations []domain.PaymentAllocation, refunded int64, amount int64, rev revision) error {
+	if len(allocations) == 0 {
+		if refunded+amount < toMinorUnits(payment.Amount) || rev.done(payment.ParentId) {
+			return nil
+		}
+		return rev.apply(ctx, payment.ParentId, func() error {
+			return p.reviseModuleRefundedStatus(ctx, payment.ParentId)
+		})
+	}
+	var errs []error
+	var start int64
+	for i := len(allocations) - 1; i >= 0; i-- {
+		allocation := allocations[i]
+		end := start + toMinorUnits(allocation.Amount)
+		from, to := start, end
+		if refunded > from {
+			from = refunded
+		}
+		if refunded+amount < to {
+			to = refunded + amount
+		}
+		start = end
+		if to <= from || rev.done(allocation.ParentId) {
+			continue
+		}
+		if allocation.Module == domain.ModuleSalesOrder && to != end {
+			continue
+		}
+		err := rev.apply(ctx, allocation.ParentId, func() error {
+			if allocation.Module == domain.ModuleSalesOrder {
+				return p.reviseModuleRefundedStatus(ctx, allocation.ParentId)
+			}
+			return p.reviseInvoiceBalance(ctx, allocation.ParentId, -float64(to-from)/100)
+		})
+		if err != nil {
+			errs = append(errs, e.Wrap(allocation.ParentId, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p Payments) reviseModuleRefundedStatus(ctx context.Context, id string) error {
+	m := make(map[string]any)
+	if p.config.Payment.RefundedInvoiceStatus != "" {
+		m["invoicestatus"] = p.config.Payment.RefundedInvoiceStatus
+	}
+	if p.config.Payment.RefundedSoStatus != "" {
+		m["sostatus"] = p.config.Payment.RefundedSoStatus
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	m["id"] = id
+	_, err := p.vtiger.Revise(ctx, m)
+	return err
+}
+
+// notifyRefund sends email about refund to user, who has made the payment.
+func (p Payments) notifyRefund(payment domain.Payment, refund domain.PaymentRefund) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		parentIds := []string{payment.ParentId}
+		allocations, err := p.repository.GetAllocations(ctx, payment.ID)
+		if err == nil && len(allocations) > 0 {
+			parentIds = parentIds[:0]
+			for _, allocation := range allocations {
+				parentIds = append(parentIds, allocation.ParentId)
+			}
+		}
+		users, err := p.users.GetAllByAccountId(ctx, payment.AccountId)
+		if err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not get payer of refund: " + err.Error()))
+			return
+		}
+		for _, user := range users {
+			if user.Crmid != payment.UserId {
+				continue
+			}
+			companyData, err := p.company.GetCompany(ctx)
+			if err != nil {
+				logger.Error(logger.GenerateErrorMessageFromString("can not send refund email: " + err.Error()))
+				return
+			}
+			err = p.emails.SendPaymentRefunded(PaymentRefundedData{
+				Name:     user.FirstName + " " + user.LastName,
+				Amount:   refund.Amount,
+				Currency: strings.ToUpper(refund.Currency),
+				Paid:     strings.Join(parentIds, ", "),
+				Company:  companyData.OrganizationName,
+				Support:  p.config.Vtiger.Business.SupportEmail,
+				Email:    user.Email,
+				Subject:  p.config.Email.Subjects.PaymentRefunded,
+			})
+			if err != nil {
+				logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+			}
+			return
+		}
+	}()
+}
+
+// providerForCurrency returns provider from payment.providers for currency or default provider.
+func (p Payments) providerForCurrency(currency string) (payment.Provider, error) {
+	name, ok := p.config.Payment.Providers[strings.ToLower(currency)]
+	if !ok {
+		name = p.defaultProvider()
+	}
+	provider, ok := p.providers[name]
+	if !ok {
+		return nil, e.Wrap(name+" for currency "+currency, ErrUnknownPaymentProvider)
+	}
+	return provider, nil
+}
+
+func (p Payments) defaultProvider() string {
+	if p.config.Payment.DefaultProvider == "" {
+		return stripe.Name
+	}
+	return p.config.Payment.DefaultProvider
+}
+
+func (p Payments) returnUrl() string {
+	if p.config.Payment.ReturnUrl != "" {
+		return p.config.Payment.ReturnUrl
+	}
+	return p.config.Domain
+}
+
+// getPayables loads invoices or sales order of payment, checks that they belong to account of user and returns amounts,
+// which are paid. All invoices should have the same currency and can not be paid over their balance.
+func (p Payments) getPayables(ctx context.Context, req PaymentIntent, user domain.User) ([]payable, error) {
+	if req.SoId != "" {
+		due, err := p.getSalesOrderPayable(ctx, req.SoId, user)
+		if err != nil {
+			return nil, err
+		}
+		return []payable{due}, nil
+	}
+	inputs := req.Invoices
+	if len(inputs) == 0 {
+		inputs = []PaymentAllocationInput{{InvoiceId: req.InvoiceId}}
+	}
+	payables := make([]payable, 0, len(inputs))
+	for _, input := range inputs {
+		for _, due := range payables {
+			if due.ParentId == input.InvoiceId {
+				return nil, e.Wrap("invoice "+input.InvoiceId+" is passed twice", ErrInvalidAllocation)
+			}
+		}
+		due, err := p.getInvoicePayable(ctx, input, user)
+		if err != nil {
+			return nil, err
+		}
+		if len(payables) > 0 && payables[0].Currency != due.Currency {
+			return nil, e.Wrap("invoices have different currencies", ErrInvalidAllocation)
+		}
+		payables = append(payables, due)
+	}
+	return payables, nil
+}
+
+func (p Payments) getSalesOrderPayable(ctx context.Context, id string, user domain.User) (payable, error) {
+	so, err := p.salesOrders.RetrieveById(ctx, id)
+	if err != nil {
+		return payable{}, e.Wrap("can not get sales order "+id, err)
+	}
+	if so.AccountID != user.AccountId {
+		return payable{}, ErrOperationNotPermitted
+	}
+	if so.SoStatus == p.config.Payment.PaidSoStatus {
+		return payable{}, ErrNothingToPay
+	}
+	// sales order is paid in full, so any reserved amount means that it is already paid
+	reserved, err := p.repository.GetAllocatedAmount(ctx, id, reservedStatuses)
+	if err != nil {
+		return payable{}, e.Wrap("can not get reserved allocations of sales order "+id, err)
+	}
+	if reserved > 0 {
+		return payable{}, e.Wrap("sales order "+id+" is paid by other payment", ErrNothingToPay)
+	}
+	return p.newPayable(ctx, id, domain.ModuleSalesOrder, float64(so.HdnGrandTotal), float64(so.HdnGrandTotal), so.CurrencyID)
+}
+
+// getInvoicePayable returns amount of input or whole balance of invoice, when amount is not passed. Amounts of pending
+// intents and of succeeded intents, which are not revised in crm yet, are not available for new payments.
+func (p Payments) getInvoicePayable(ctx context.Context, input PaymentAllocationInput, user domain.User) (payable, error) {
+	invoice, err := p.invoices.RetrieveById(ctx, input.InvoiceId)
+	if err != nil {
+		return payable{}, e.Wrap("can not get invoice "+input.InvoiceId, err)
+	}
+	if invoice.AccountID != user.AccountId {
+		return payable{}, ErrOperationNotPermitted
+	}
+	if invoice.InvoiceStatus == p.config.Payment.PaidInvoiceStatus {
+		return payable{}, ErrNothingToPay
+	}
+	balance := float64(invoice.Balance)
+	if balance <= 0 {
+		balance = float64(invoice.HdnGrandTotal - invoice.Received)
+	}
+	reserved, err := p.repository.GetAllocatedAmount(ctx, input.InvoiceId, reservedStatuses)
+	if err != nil {
+		return payable{}, e.Wrap("can not get reserved allocations of invoice "+input.InvoiceId, err)
+	}
+	amount := balance - reserved
+	if input.Amount > 0 {
+		if toMinorUnits(input.Amount) > toMinorUnits(amount) {
+			return payable{}, e.Wrap("amount of invoice "+input.InvoiceId+" is greater than its balance", ErrInvalidAllocation)
+		}
+		amount = input.Amount
+	}
+	return p.newPayable(ctx, input.InvoiceId, domain.ModuleInvoice, amount, balance, invoice.CurrencyID)
+}
+
+func (p Payments) newPayable(ctx context.Context, parentId string, module string, amount float64, balance float64, currencyId string) (payable, error) {
+	amount = math.Round(amount*100) / 100
+	if amount <= 0 {
+		return payable{}, ErrNothingToPay
+	}
+	if currencyId == "" {
+		return payable{}, errors.New("currency of " + parentId + " is not set")
+	}
+	currency, err := p.currency.GetCurrencyById(ctx, currencyId)
+	if err != nil {
+		return payable{}, e.Wrap("can not get a currency by id "+currencyId, err)
+	}
+	return payable{ParentId: parentId, Module: module, Amount: amount, Balance: balance, Currency: strings.ToLower(currency.CurrencyCode)}, nil
+}
+
+// canChangeStatus does not let payment leave final status. Refunded status is set only by refund.
+func canChangeStatus(from int, to int) bool {
+	if from == to {
+		return false
+	}
+	switch from {
+	case SUCCEEDED, CANCELLED, REFUNDED:
+		return false
+	}
+	return true
+}
+
+func toMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
 func (p Payments) GetPayments(ctx context.Context, id string) ([]domain.Payment, error) {
 	return p.repository.GetPaymentsFromAccountId(ctx, id)
 }
 
-func (p Payments) getNumericIntentStatus(intent stripe.PaymentIntent) int {
-	switch intent.Status {
-	case stripe.PaymentIntentStatusCanceled:
+func (p Payments) getNumericIntentStatus(status string) int {
+	switch status {
+	case payment.StatusCanceled:
 		return CANCELLED
-	case stripe.PaymentIntentStatusProcessing:
+	case payment.StatusProcessing:
 		return PROCESSING
-	case stripe.PaymentIntentStatusRequiresAction:
+	case payment.StatusRequiresAction:
 		return REQUIRES_ACTION
-	case stripe.PaymentIntentStatusRequiresCapture:
+	case payment.StatusRequiresCapture:
 		return REQUIRES_CAPTURE
-	case stripe.PaymentIntentStatusRequiresPaymentMethod:
+	case payment.StatusRequiresConfirmation:
+		return REQUIRES_CONFIRMATION
+	case payment.StatusRequiresPaymentMethod:
 		return REQUIRES_PAYMENT_METHOD
-	case stripe.PaymentIntentStatusSucceeded:
+	case payment.StatusSucceeded:
 		return SUCCEEDED
 	}
 	return CREATED
@@ -65,6 +65,9 @@ func (p ProjectTasksService) GetProjectTaskById(ctx context.Context, id string)
 }
 
 func (p ProjectTasksService) GetAllFromProject(ctx context.Context, filter vtiger.PaginationQueryFilter, userModel *domain.User) ([]domain.ProjectTask, int, error) {
+	if err := CheckPermission(*userModel, domain.ModuleProjectTask, domain.PermissionRead); err != nil {
+		return []domain.ProjectTask{}, 0, err
+	}
 	err := p.validateProjectPermissions(ctx, filter.Parent, userModel)
 	if err != nil {
 		return nil, 0, err
@@ -104,6 +107,9 @@ func (p ProjectTasksService) GetRelatedDocuments(ctx context.Context, id string,
 }
 
 func (p ProjectTasksService) AddComment(ctx context.Context, content string, related string, userModel domain.User) (domain.Comment, error) {
+	if err := CheckPermission(userModel, domain.ModuleProjectTask, domain.PermissionWrite); err != nil {
+		return domain.Comment{}, err
+	}
 	projectTask, err := p.GetProjectTaskById(ctx, related)
 	if err != nil {
 		return domain.Comment{}, err
@@ -121,7 +127,10 @@ func (p ProjectTasksService) validateProjectPermissions(ctx context.Context, id
 	return err
 }
 
-func (p ProjectTasksService) CreateProjectTask(ctx context.Context, input ProjectTaskInput, projectId string) (domain.ProjectTask, error) {
+func (p ProjectTasksService) CreateProjectTask(ctx context.Context, input ProjectTaskInput, projectId string, user domain.User) (domain.ProjectTask, error) {
+	if err := CheckPermission(user, domain.ModuleProjectTask, domain.PermissionWrite); err != nil {
+		return domain.ProjectTask{}, err
+	}
 	var projectTask domain.ProjectTask
 
 	projectTask.Projecttaskname = input.Projecttaskname
@@ -138,10 +147,18 @@ func (p ProjectTasksService) CreateProjectTask(ctx context.Context, input Projec
 		return projectTask, err
 	}
 
-	return p.repository.Create(ctx, projectTask)
+	projectTask, err = p.repository.Create(ctx, projectTask)
+	if err != nil {
+		return projectTask, err
+	}
+	p.invalidate(user, projectId)
+	return projectTask, nil
 }
 
 func (p ProjectTasksService) Revise(ctx context.Context, input map[string]any, id string, project string, user domain.User) (domain.ProjectTask, error) {
+	if err := CheckPermission(user, domain.ModuleProjectTask, domain.PermissionWrite); err != nil {
+		return domain.ProjectTask{}, err
+	}
 	err := p.validateProjectPermissions(ctx, project, &user)
 	if err != nil {
 		return domain.ProjectTask{}, err
@@ -152,8 +169,14 @@ func (p ProjectTasksService) Revise(ctx context.Context, input map[string]any, i
 	if err != nil {
 		return task, err
 	}
-	err = StoreInCache[*domain.ProjectTask](id, &task, CacheProjectTaskTtl, p.cache)
-	return task, err
+	p.invalidate(user, id, project)
+	return task, nil
+}
+
+// invalidate removes changed task with its project and statistics of account, which count tasks in progress.
+func (p ProjectTasksService) invalidate(user domain.User, keys ...string) {
+	DeleteFromCache(p.cache, keys...)
+	DeleteStatisticsFromCache(p.cache, user.AccountId)
 }
 
 func (p ProjectTasksService) validateInputFields(ctx context.Context, projectTask *domain.ProjectTask) error {
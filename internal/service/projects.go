@@ -37,6 +37,9 @@ func NewProjectsService(repository repository.Project, cache cache.Cache, commen
 }
 
 func (p ProjectsService) GetProjectById(ctx context.Context, id string, calcStat bool, userModel *domain.User) (domain.Project, error) {
+	if err := CheckPermission(*userModel, domain.ModuleProject, domain.PermissionRead); err != nil {
+		return domain.Project{}, err
+	}
 	project := &domain.Project{}
 	err := GetFromCache[*domain.Project](id, project, p.cache)
 	if err == nil {
@@ -149,6 +152,9 @@ func (p ProjectsService) GetRelatedDocuments(ctx context.Context, id string, use
 }
 
 func (p ProjectsService) AddComment(ctx context.Context, content string, related string, userModel *domain.User) (domain.Comment, error) {
+	if err := CheckPermission(*userModel, domain.ModuleProject, domain.PermissionWrite); err != nil {
+		return domain.Comment{}, err
+	}
 	_, err := p.GetProjectById(ctx, related, false, userModel)
 	if err != nil {
 		return domain.Comment{}, err
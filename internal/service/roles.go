@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+)
+
+var ErrWrongRole = errors.New("role should be one of owner, admin, billing, member, read-only")
+
+type RoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// CheckPermission returns ErrOperationNotPermitted, when role of user does not give required access to module.
+func CheckPermission(user domain.User, module string, permission domain.Permission) error {
+	if user.Can(module, permission) {
+		return nil
+	}
+	return ErrOperationNotPermitted
+}
+
+// GetRole returns role of registered contact from the same account.
+func (s UsersService) GetRole(ctx context.Context, crmid string, user domain.User) (domain.UserRole, error) {
+	if err := CheckPermission(user, domain.ModuleContacts, domain.PermissionRead); err != nil {
+		return domain.UserRole{}, err
+	}
+	contact, err := s.findAccountUser(ctx, crmid, user.AccountId)
+	if err != nil {
+		return domain.UserRole{}, err
+	}
+	return domain.NewUserRole(contact), nil
+}
+
+// ChangeRole sets role of contact. Only owners can grant or take owner role and nobody can change own role,
+// so account always keeps its owner.
+func (s UsersService) ChangeRole(ctx context.Context, crmid string, role string, user domain.User) (domain.UserRole, error) {
+	if !domain.IsValidRole(role) {
+		return domain.UserRole{}, e.Wrap(ErrWrongRole.Error(), ErrValidation)
+	}
+	if err := CheckPermission(user, domain.ModuleContacts, domain.PermissionWrite); err != nil {
+		return domain.UserRole{}, err
+	}
+	if crmid == user.Crmid {
+		return domain.UserRole{}, e.Wrap("you can not change own role", ErrOperationNotPermitted)
+	}
+	contact, err := s.findAccountUser(ctx, crmid, user.AccountId)
+	if err != nil {
+		return domain.UserRole{}, err
+	}
+	if (role == domain.RoleOwner || contact.RoleOrDefault() == domain.RoleOwner) && user.RoleOrDefault() != domain.RoleOwner {
+		return domain.UserRole{}, e.Wrap("only owner can change owner role", ErrOperationNotPermitted)
+	}
+	if err = s.repo.UpdateRole(ctx, contact.Id, role); err != nil {
+		return domain.UserRole{}, e.Wrap("can not update role of "+crmid, err)
+	}
+	contact.Role = role
+	return domain.NewUserRole(contact), nil
+}
+
+func (s UsersService) findAccountUser(ctx context.Context, crmid string, accountId string) (domain.User, error) {
+	users, err := s.repo.GetAllByAccountId(ctx, accountId)
+	if err != nil {
+		return domain.User{}, e.Wrap("can not get users of account "+accountId, err)
+	}
+	for _, user := range users {
+		if user.Crmid == crmid {
+			return user, nil
+		}
+	}
+	return domain.User{}, repository.ErrRecordNotFound
+}
+
+// initialRole makes first registered contact of account its owner.
+func (s UsersService) initialRole(ctx context.Context, accountId string) (string, error) {
+	if accountId == "" {
+		return domain.RoleMember, nil
+	}
+	users, err := s.repo.GetAllByAccountId(ctx, accountId)
+	if err != nil {
+		return "", err
+	}
+	if len(users) == 0 {
+		return domain.RoleOwner, nil
+	}
+	return domain.RoleMember, nil
+}
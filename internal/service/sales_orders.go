@@ -11,7 +11,7 @@ import (
 )
 
 type SalesOrders struct {
-	repository        repository.SalesOrderCrm
+	repository        repository.SalesOrder
 	invoiceRepository repository.Invoice
 	cache             cache.Cache
 	module            ModulesService
@@ -19,7 +19,7 @@ type SalesOrders struct {
 	currency          CurrencyService
 }
 
-func NewSalesOrderService(repository repository.SalesOrderCrm, cache cache.Cache, module ModulesService, config config.Config, currency CurrencyService, invoice repository.Invoice) SalesOrders {
+func NewSalesOrderService(repository repository.SalesOrder, cache cache.Cache, module ModulesService, config config.Config, currency CurrencyService, invoice repository.Invoice) SalesOrders {
 	return SalesOrders{
 		repository:        repository,
 		cache:             cache,
@@ -11,6 +11,11 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/email"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment/stripe"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment/yookassa"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
 	"github.com/semelyanov86/vtiger-portal/pkg/vtiger"
 	"mime/multipart"
 	"sync"
@@ -47,31 +52,46 @@ type Services struct {
 	Payments         Payments
 	Notifications    Notifications
 	CustomModules    CustomModule
+	Sync             SyncService
+	CrmWebhook       CrmWebhook
+	Webhooks         Webhooks
+	Webauthn         Webauthn
+	Sso              Sso
+	LoginGuard       LoginGuard
+	Audit            Audit
 }
 
 var ErrOperationNotPermitted = errors.New("you are not permitted to view this record")
 
 func NewServices(repos repository.Repositories, email email.Sender, wg *sync.WaitGroup, config config.Config, cache cache.Cache) *Services {
+	events := pubsub.NewHub(config.Notifications.StreamHistory, 0)
 	emailService := *NewEmailsService(email, config.Email, cache)
 	companyService := NewCompanyService(repos.Company, cache)
 	managersService := NewManagerService(repos.Managers, cache)
 	accountService := NewAccountService(repos.Account, cache)
 	usersService := NewUsersService(repos.Users, repos.UsersCrm, wg, emailService, companyService, repos.Tokens, repos.Documents, cache, accountService, config)
-	commentsService := NewComments(repos.Comments, cache, config, usersService, managersService)
-	documentService := NewDocuments(repos.Documents, cache, config)
+	commentsService := NewComments(repos.Comments, cache, config, usersService, managersService, events)
+	documentService := NewDocuments(repos.Documents, cache, config, events)
 	modulesService := NewModulesService(repos.Modules, cache)
 	currencyService := NewCurrencyService(repos.Currency, cache)
+	paymentProviders := []payment.Provider{
+		stripe.NewProvider(config.Payment.StripeKey, config.Payment.StripeWebhookSecret, config.Domain),
+		yookassa.NewProvider(config.Payment.YooKassa.ShopId, config.Payment.YooKassa.SecretKey, config.Payment.YooKassa.Url, config.Payment.YooKassa.Timeout),
+	}
+	notificationsService := NewNotificationsService(cache, config, managersService, *repos.Notifications, repos.NotificationsCrm, repos.Users, wg, events)
+	tokensService := NewTokensService(repos.Tokens, repos.Users, emailService, config, companyService, cache)
 	projectService := NewProjectsService(repos.Projects, cache, commentsService, documentService, modulesService, config, repos.ProjectTasks)
+	auditService := NewAuditService(repos.Audit, wg)
 	return &Services{
 		Users:            usersService,
-		Auth:             NewAuthService(repos.Users, wg, cache, config),
+		Auth:             NewAuthService(repos.Users, repos.Otp, wg, cache, config, emailService, companyService),
 		Emails:           *NewEmailsService(email, config.Email, cache),
-		Tokens:           NewTokensService(repos.Tokens, repos.Users, emailService, config, companyService),
+		Tokens:           tokensService,
 		Context:          NewContextService(),
 		Managers:         managersService,
 		Modules:          modulesService,
 		Company:          companyService,
-		HelpDesk:         NewHelpDeskService(repos.HelpDesk, cache, commentsService, documentService, modulesService, config),
+		HelpDesk:         NewHelpDeskService(repos.HelpDesk, cache, commentsService, documentService, modulesService, config, events),
 		Comments:         commentsService,
 		Documents:        documentService,
 		Faqs:             NewFaqsService(repos.Faqs, cache, modulesService, config),
@@ -87,9 +107,16 @@ func NewServices(repos repository.Repositories, email email.Sender, wg *sync.Wai
 		Leads:            NewLeads(repos.Leads, config),
 		Accounts:         accountService,
 		Searches:         NewSearchService(repos.Search, cache, config),
-		Payments:         NewPaymentsService(cache, config, currencyService, *repos.Payment),
-		Notifications:    NewNotificationsService(cache, config, managersService, *repos.Notifications, repos.NotificationsCrm, repos.Users),
+		Payments:         NewPaymentsService(cache, config, currencyService, repos.Payment, repos.Invoice, repos.SalesOrder, paymentProviders, vtiger.NewVtigerConnector(cache, config.Vtiger.Connection, vtiger.NewWebRequest(config.Vtiger.Connection)), events, repos.Users, emailService, companyService, auditService, wg),
+		Notifications:    notificationsService,
 		CustomModules:    NewCustomModuleService(repos.CustomModule, cache, commentsService, documentService, modulesService, config),
+		CrmWebhook:       NewCrmWebhook(cache, config, notificationsService, repos.Sync, repos.Mirror, events),
+		Sync:             NewSyncService(repos.Mirror, repos.Sync, modulesService, config, wg, events),
+		Webhooks:         NewWebhooksService(repos.Webhooks, config, events, wg),
+		Webauthn:         NewWebauthnService(repos.Webauthn, repos.Users, cache, config),
+		Sso:              NewSsoService(usersService, tokensService, cache, config),
+		LoginGuard:       NewLoginGuard(repos.LoginAttempts, tokensService, auditService, wg, config),
+		Audit:            auditService,
 	}
 }
 
@@ -107,7 +134,7 @@ type DocumentServiceInterface interface {
 	GetRelated(ctx context.Context, id string) ([]domain.Document, error)
 	GetFile(ctx context.Context, id string, relatedId string) (vtiger.File, error)
 	AttachFile(ctx context.Context, file multipart.File, id string, userModel domain.User, header *multipart.FileHeader) (domain.Document, error)
-	DeleteFile(ctx context.Context, id string, related string) error
+	DeleteFile(ctx context.Context, id string, related string, userModel domain.User) error
 }
 
 func GetFromCache[T any](key string, dest T, c cache.Cache) error {
@@ -127,6 +154,15 @@ func GetFromCache[T any](key string, dest T, c cache.Cache) error {
 	return nil
 }
 
+// DeleteFromCache is used on write paths. Errors are only logged, because data is already changed in crm.
+func DeleteFromCache(c cache.Cache, keys ...string) {
+	for _, key := range keys {
+		if err := c.Delete(key); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString("can not delete " + key + " from cache: " + err.Error()))
+		}
+	}
+}
+
 func StoreInCache[T any](key string, value T, ttl time.Duration, c cache.Cache) error {
 	data, err := json.Marshal(value)
 	if err != nil {
@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/crewjam/saml"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"golang.org/x/oauth2"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSsoNotConfigured = errors.New("single sign-on is not configured for this account")
+
+var ErrSsoStateNotFound = errors.New("single sign-on request is not started or expired")
+
+var ErrSsoFailed = errors.New("identity provider response is not valid")
+
+var ErrSsoCodeNotFound = errors.New("single sign-on code is not valid or expired")
+
+const (
+	SsoOidc = "oidc"
+	SsoSaml = "saml"
+)
+
+const CacheSsoState = "sso_state_"
+const CacheSsoCode = "sso_code_"
+
+const defaultSsoTimeout = 10 * time.Minute
+const ssoCodeTimeout = time.Minute
+
+// ssoEmailAttributes are names of SAML attributes, where identity providers usually put email.
+var ssoEmailAttributes = []string{
+	"email",
+	"mail",
+	"emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+}
+
+// ssoState is kept in cache between redirect to identity provider and callback.
+type ssoState struct {
+	Account   string `json:"account"`
+	Nonce     string `json:"nonce"`
+	Verifier  string `json:"verifier"`
+	RequestId string `json:"request_id"`
+}
+
+// Sso signs in contacts with identity provider of their vtiger account. Every account has own OIDC or SAML provider
+// in sso.providers config. Contact is found by email, asserted by provider.
+// Tokens are not returned to browser in callback, it is redirected to frontend with one-time code, which is exchanged for tokens.
+type Sso struct {
+	users     UsersService
+	tokens    TokensService
+	cache     cache.Cache
+	config    config.Config
+	client    *http.Client
+	providers *sync.Map
+}
+
+func NewSsoService(users UsersService, tokens TokensService, cache cache.Cache, config config.Config) Sso {
+	return Sso{
+		users:     users,
+		tokens:    tokens,
+		cache:     cache,
+		config:    config,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		providers: &sync.Map{},
+	}
+}
+
+// Begin returns url of identity provider, where user should be redirected to sign in.
+func (s Sso) Begin(ctx context.Context, account string) (string, error) {
+	provider, err := s.provider(account)
+	if err != nil {
+		return "", err
+	}
+	key, err := randomSsoString()
+	if err != nil {
+		return "", e.Wrap("can not generate sso state", err)
+	}
+	state := ssoState{Account: account}
+
+	var location string
+	switch provider.Protocol {
+	case SsoOidc:
+		if state.Nonce, err = randomSsoString(); err != nil {
+			return "", e.Wrap("can not generate nonce", err)
+		}
+		if state.Verifier, err = randomSsoString(); err != nil {
+			return "", e.Wrap("can not generate code verifier", err)
+		}
+		oauth, _, err := s.oidcClient(ctx, account, provider)
+		if err != nil {
+			return "", err
+		}
+		challenge := sha256.Sum256([]byte(state.Verifier))
+		location = oauth.AuthCodeURL(key, oidc.Nonce(state.Nonce),
+			oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		)
+	case SsoSaml:
+		sp, err := s.serviceProvider(ctx, account, provider)
+		if err != nil {
+			return "", err
+		}
+		request, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+		if err != nil {
+			return "", e.Wrap("can not make saml authentication request", err)
+		}
+		redirect, err := request.Redirect(key, sp)
+		if err != nil {
+			return "", e.Wrap("can not make saml redirect", err)
+		}
+		state.RequestId = request.ID
+		location = redirect.String()
+	}
+
+	if err = s.storeState(key, state); err != nil {
+		return "", err
+	}
+	return location, nil
+}
+
+// FinishOidc exchanges authorization code for id token and signs in user with email from it.
+// It returns url of frontend with one-time code.
+func (s Sso) FinishOidc(ctx context.Context, account string, code string, stateKey string) (string, error) {
+	provider, err := s.provider(account)
+	if err != nil {
+		return "", err
+	}
+	if provider.Protocol != SsoOidc {
+		return "", e.Wrap("account uses "+provider.Protocol, ErrSsoNotConfigured)
+	}
+	state, err := s.takeState(account, stateKey)
+	if err != nil {
+		return "", err
+	}
+	oauth, verifier, err := s.oidcClient(ctx, account, provider)
+	if err != nil {
+		return "", err
+	}
+	clientCtx := oidc.ClientContext(ctx, s.client)
+	exchanged, err := oauth.Exchange(clientCtx, code, oauth2.SetAuthURLParam("code_verifier", state.Verifier))
+	if err != nil {
+		return "", e.Wrap("can not exchange authorization code: "+err.Error(), ErrSsoFailed)
+	}
+	rawIdToken, ok := exchanged.Extra("id_token").(string)
+	if !ok {
+		return "", e.Wrap("id token is missing", ErrSsoFailed)
+	}
+	idToken, err := verifier.Verify(clientCtx, rawIdToken)
+	if err != nil {
+		return "", e.Wrap(err.Error(), ErrSsoFailed)
+	}
+	if idToken.Nonce != state.Nonce {
+		return "", e.Wrap("nonce does not match", ErrSsoFailed)
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		return "", e.Wrap("can not read claims: "+err.Error(), ErrSsoFailed)
+	}
+	if claims.Email == "" {
+		return "", e.Wrap("email claim is missing", ErrSsoFailed)
+	}
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return "", e.Wrap("email is not verified by identity provider", ErrSsoFailed)
+	}
+	return s.signIn(ctx, account, claims.Email)
+}
+
+// FinishSaml validates signed response of identity provider, posted to assertion consumer service.
+// It returns url of frontend with one-time code.
+func (s Sso) FinishSaml(ctx context.Context, account string, samlResponse string, relayState string) (string, error) {
+	provider, err := s.provider(account)
+	if err != nil {
+		return "", err
+	}
+	if provider.Protocol != SsoSaml {
+		return "", e.Wrap("account uses "+provider.Protocol, ErrSsoNotConfigured)
+	}
+	state, err := s.takeState(account, relayState)
+	if err != nil {
+		return "", err
+	}
+	sp, err := s.serviceProvider(ctx, account, provider)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return "", e.Wrap("can not decode saml response", ErrSsoFailed)
+	}
+	assertion, err := sp.ParseXMLResponse(decoded, []string{state.RequestId})
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) && invalid.PrivateErr != nil {
+			err = invalid.PrivateErr
+		}
+		return "", e.Wrap(err.Error(), ErrSsoFailed)
+	}
+	email := samlEmail(assertion, provider.EmailAttribute)
+	if email == "" {
+		return "", e.Wrap("email is missing in assertion", ErrSsoFailed)
+	}
+	return s.signIn(ctx, account, email)
+}
+
+// Exchange returns tokens for one-time code from callback redirect. Every code is accepted only once.
+func (s Sso) Exchange(code string) (*domain.Token, error) {
+	var token domain.Token
+	if code == "" {
+		return nil, ErrSsoCodeNotFound
+	}
+	if err := GetFromCache[*domain.Token](CacheSsoCode+code, &token, s.cache); err != nil {
+		return nil, ErrSsoCodeNotFound
+	}
+	DeleteFromCache(s.cache, CacheSsoCode+code)
+	return &token, nil
+}
+
+// Metadata returns SAML metadata of portal for identity provider of account.
+func (s Sso) Metadata(ctx context.Context, account string) ([]byte, error) {
+	provider, err := s.provider(account)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Protocol != SsoSaml {
+		return nil, e.Wrap("account uses "+provider.Protocol, ErrSsoNotConfigured)
+	}
+	sp, err := s.serviceProvider(ctx, account, provider)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, e.Wrap("can not encode metadata", err)
+	}
+	return metadata, nil
+}
+
+func (s Sso) signIn(ctx context.Context, account string, email string) (string, error) {
+	user, err := s.users.FindOrProvision(ctx, account, strings.ToLower(email))
+	if err != nil {
+		return "", err
+	}
+	token, err := s.tokens.CreateAuthTokenForUser(ctx, *user)
+	if err != nil {
+		return "", err
+	}
+	code, err := randomSsoString()
+	if err != nil {
+		return "", e.Wrap("can not generate sso code", err)
+	}
+	if err = StoreInCache[*domain.Token](CacheSsoCode+code, token, ssoCodeTimeout, s.cache); err != nil {
+		return "", e.Wrap("can not store sso code", err)
+	}
+	redirect, err := url.Parse(s.redirectUrl())
+	if err != nil {
+		return "", e.Wrap("can not parse sso redirect url", err)
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	redirect.RawQuery = query.Encode()
+	return redirect.String(), nil
+}
+
+func (s Sso) provider(account string) (config.SsoProvider, error) {
+	provider, ok := s.config.Sso.Providers[account]
+	if !ok || (provider.Protocol != SsoOidc && provider.Protocol != SsoSaml) {
+		return provider, ErrSsoNotConfigured
+	}
+	return provider, nil
+}
+
+// redirectUrl is a page of frontend, which receives one-time code after sign in.
+func (s Sso) redirectUrl() string {
+	if s.config.Sso.RedirectUrl != "" {
+		return s.config.Sso.RedirectUrl
+	}
+	return strings.TrimRight(s.config.Domain, "/") + "/sso"
+}
+
+func (s Sso) callbackUrl(account string, endpoint string) string {
+	return strings.TrimRight(s.config.Sso.BaseUrl, "/") + "/sso/" + url.PathEscape(account) + "/" + endpoint
+}
+
+func (s Sso) oidcClient(ctx context.Context, account string, provider config.SsoProvider) (oauth2.Config, *oidc.IDTokenVerifier, error) {
+	discovered, err := s.discover(ctx, account, provider)
+	if err != nil {
+		return oauth2.Config{}, nil, err
+	}
+	scopes := provider.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	oauth := oauth2.Config{
+		ClientID:     provider.ClientId,
+		ClientSecret: provider.ClientSecret,
+		Endpoint:     discovered.Endpoint(),
+		RedirectURL:  s.callbackUrl(account, "callback"),
+		Scopes:       scopes,
+	}
+	return oauth, discovered.Verifier(&oidc.Config{ClientID: provider.ClientId}), nil
+}
+
+// discover loads openid configuration of provider once per account. Keys are refreshed by provider itself,
+// when token is signed with unknown key.
+func (s Sso) discover(ctx context.Context, account string, provider config.SsoProvider) (*oidc.Provider, error) {
+	key := account + " " + provider.Issuer
+	if cached, ok := s.providers.Load(key); ok {
+		return cached.(*oidc.Provider), nil
+	}
+	// provider keeps context for fetching keys, so it must not be cancelled with request
+	discovered, err := oidc.NewProvider(oidc.ClientContext(context.Background(), s.client), provider.Issuer)
+	if err != nil {
+		return nil, e.Wrap("can not discover openid provider "+provider.Issuer, err)
+	}
+	s.providers.Store(key, discovered)
+	return discovered, nil
+}
+
+func (s Sso) serviceProvider(ctx context.Context, account string, provider config.SsoProvider) (*saml.ServiceProvider, error) {
+	metadata := []byte(provider.Metadata)
+	if len(metadata) == 0 {
+		if provider.MetadataUrl == "" {
+			return nil, e.Wrap("metadata of identity provider is not set", ErrSsoNotConfigured)
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.MetadataUrl, nil)
+		if err != nil {
+			return nil, e.Wrap("can not create metadata request", err)
+		}
+		response, err := s.client.Do(request)
+		if err != nil {
+			return nil, e.Wrap("can not fetch metadata of identity provider", err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, e.Wrap("can not fetch metadata of identity provider, status "+response.Status, ErrSsoNotConfigured)
+		}
+		if metadata, err = io.ReadAll(response.Body); err != nil {
+			return nil, e.Wrap("can not read metadata of identity provider", err)
+		}
+	}
+	idp := &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(metadata, idp); err != nil {
+		return nil, e.Wrap("can not parse metadata of identity provider: "+err.Error(), ErrSsoNotConfigured)
+	}
+
+	acs, err := url.Parse(s.callbackUrl(account, "acs"))
+	if err != nil {
+		return nil, e.Wrap("can not build acs url", err)
+	}
+	metadataUrl, err := url.Parse(s.callbackUrl(account, "metadata"))
+	if err != nil {
+		return nil, e.Wrap("can not build metadata url", err)
+	}
+	return &saml.ServiceProvider{
+		EntityID:          provider.EntityId,
+		AcsURL:            *acs,
+		MetadataURL:       *metadataUrl,
+		IDPMetadata:       idp,
+		AuthnNameIDFormat: saml.EmailAddressNameIDFormat,
+	}, nil
+}
+
+func (s Sso) storeState(key string, state ssoState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return e.Wrap("can not encode sso state", err)
+	}
+	ttl := s.config.Sso.Timeout
+	if ttl <= 0 {
+		ttl = defaultSsoTimeout
+	}
+	if err = s.cache.Set(CacheSsoState+key, data, int64(ttl/time.Second)); err != nil {
+		return e.Wrap("can not store sso state", err)
+	}
+	return nil
+}
+
+// takeState returns started request and forgets it, so every response of identity provider is accepted only once.
+func (s Sso) takeState(account string, key string) (ssoState, error) {
+	var state ssoState
+	if key == "" {
+		return state, ErrSsoStateNotFound
+	}
+	if err := GetFromCache[*ssoState](CacheSsoState+key, &state, s.cache); err != nil {
+		return state, ErrSsoStateNotFound
+	}
+	DeleteFromCache(s.cache, CacheSsoState+key)
+	if state.Account != account {
+		return state, ErrSsoStateNotFound
+	}
+	return state, nil
+}
+
+func samlEmail(assertion *saml.Assertion, attribute string) string {
+	names := ssoEmailAttributes
+	if attribute != "" {
+		names = []string{attribute}
+	}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			for _, name := range names {
+				if (strings.EqualFold(attr.Name, name) || strings.EqualFold(attr.FriendlyName, name)) && len(attr.Values) > 0 {
+					return attr.Values[0].Value
+				}
+			}
+		}
+	}
+	if assertion.Subject != nil && assertion.Subject.NameID != nil && strings.Contains(assertion.Subject.NameID.Value, "@") {
+		return assertion.Subject.NameID.Value
+	}
+	return ""
+}
+
+func randomSsoString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -8,12 +8,15 @@ import (
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
 	"strconv"
 	"sync"
 )
 
 const CacheStatisticsTtl = 500
 
+const CacheStatistics = "stat-"
+
 type StatisticsService struct {
 	repository repository.StatisticsCrm
 	cache      cache.Cache
@@ -49,7 +52,7 @@ func (s StatisticsService) GetStatistics(ctx context.Context, userModel domain.U
 
 	statOperation.limitCh <- struct{}{}
 
-	err := GetFromCache[*domain.Statistics]("stat-"+userModel.Crmid, statOperation.stats, s.cache)
+	err := GetFromCache[*domain.Statistics](statisticsCacheKey(userModel), statOperation.stats, s.cache)
 	if err == nil {
 		return *statOperation.stats, nil
 	}
@@ -155,7 +158,7 @@ func (s StatisticsService) GetStatistics(ctx context.Context, userModel domain.U
 			return *statOperation.stats, fmt.Errorf("error calculating closed projects: %v", closedProjectsErr)
 		}
 
-		err = StoreInCache[*domain.Statistics]("stat-"+userModel.Crmid, statOperation.stats, CacheStatisticsTtl, s.cache)
+		err = StoreInCache[*domain.Statistics](statisticsCacheKey(userModel), statOperation.stats, CacheStatisticsTtl, s.cache)
 		if err != nil {
 			return *statOperation.stats, err
 		}
@@ -166,6 +169,17 @@ func (s StatisticsService) GetStatistics(ctx context.Context, userModel domain.U
 	}
 }
 
+// statisticsCacheKey groups statistics by account, so they can be purged for all users of account at once.
+func statisticsCacheKey(userModel domain.User) string {
+	return CacheStatistics + userModel.AccountId + "-" + userModel.Crmid
+}
+
+func DeleteStatisticsFromCache(c cache.Cache, accountId string) {
+	if err := c.DeleteByPrefix(CacheStatistics + accountId + "-"); err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not delete statistics from cache: " + err.Error()))
+	}
+}
+
 func (s StatisticsService) calcTotalTickets(ctx context.Context, userModel domain.User, op *operation) error {
 	defer op.wg.Done()
 	total, err := s.repository.CalcTicketTotal(ctx, userModel)
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/internal/utils"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"sync"
+	"time"
+)
+
+const defaultSyncInterval = 5 * time.Minute
+
+// SyncService copies records, changed in vtiger, to local mirror with help of vtiger sync operation.
+type SyncService struct {
+	mirror  repository.Mirror
+	crm     repository.Sync
+	modules ModulesService
+	config  config.Config
+	wg      *sync.WaitGroup
+	events  *pubsub.Hub
+}
+
+func NewSyncService(mirror repository.Mirror, crm repository.Sync, modules ModulesService, config config.Config, wg *sync.WaitGroup, events *pubsub.Hub) SyncService {
+	return SyncService{
+		mirror:  mirror,
+		crm:     crm,
+		modules: modules,
+		config:  config,
+		wg:      wg,
+		events:  events,
+	}
+}
+
+// Modules returns list of synced modules. Custom modules from business config are synced after default ones.
+func (s SyncService) Modules() []string {
+	modules := make([]string, 0, len(s.config.Sync.Modules)+len(s.config.Vtiger.Business.CustomModules))
+	exists := make(map[string]bool)
+	for _, module := range s.config.Sync.Modules {
+		if !exists[module] {
+			exists[module] = true
+			modules = append(modules, module)
+		}
+	}
+	for module := range s.config.Vtiger.Business.CustomModules {
+		if !exists[module] {
+			exists[module] = true
+			modules = append(modules, module)
+		}
+	}
+	return modules
+}
+
+func (s SyncService) SyncAll(ctx context.Context) error {
+	var lastErr error
+	for _, module := range s.Modules() {
+		if err := s.SyncModule(ctx, module); err != nil {
+			logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// SyncModule requests changes of module since last sync and stores them in mirror.
+func (s SyncService) SyncModule(ctx context.Context, module string) error {
+	scope, err := s.scope(ctx, module)
+	if err != nil {
+		return e.Wrap("can not get sync scope of "+module, err)
+	}
+	state, err := s.mirror.GetSyncState(ctx, module)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return e.Wrap("can not get sync state of "+module, err)
+	}
+	for {
+		result, err := s.crm.Sync(ctx, module, state.LastModifiedTime)
+		if err != nil {
+			return err
+		}
+		for _, data := range result.Updated {
+			record, err := repository.MirrorRecordFromData(ctx, module, scope, data, s.mirror)
+			if err != nil {
+				return e.Wrap("can not prepare mirror record of "+module, err)
+			}
+			if module == "HelpDesk" {
+				s.publishTicketStatus(ctx, record)
+			}
+			if err = s.mirror.Upsert(ctx, record); err != nil {
+				return e.Wrap("can not store mirror record "+record.Id, err)
+			}
+		}
+		if err = s.mirror.Delete(ctx, result.Deleted); err != nil {
+			return e.Wrap("can not delete mirror records of "+module, err)
+		}
+		moved := result.LastModifiedTime > state.LastModifiedTime
+		if moved {
+			state.LastModifiedTime = result.LastModifiedTime
+		}
+		if err = s.mirror.SaveSyncState(ctx, state); err != nil {
+			return e.Wrap("can not save sync state of "+module, err)
+		}
+		if !result.More || !moved {
+			return nil
+		}
+	}
+}
+
+// Start runs synchronization every configured interval until context is cancelled.
+func (s SyncService) Start(ctx context.Context) {
+	if !s.config.Sync.Enabled {
+		return
+	}
+	interval := s.config.Sync.Interval
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.SyncAll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s SyncService) scope(ctx context.Context, module string) (repository.MirrorScope, error) {
+	if scope, ok := repository.MirrorScopes[module]; ok {
+		return scope, nil
+	}
+	description, err := s.modules.Describe(ctx, module)
+	if err != nil {
+		return repository.MirrorScope{}, err
+	}
+	scope := repository.MirrorScope{}
+	if field := utils.FindFieldByRefers(description, "Accounts"); field != nil {
+		scope.AccountField = field.Name
+	}
+	if field := utils.FindFieldByRefers(description, "Contacts"); field != nil {
+		scope.ContactField = field.Name
+	}
+	return scope, nil
+}
+
+// publishTicketStatus compares status of ticket with mirrored one. Tickets seen for the first time are skipped,
+// so initial sync does not flood subscribers.
+func (s SyncService) publishTicketStatus(ctx context.Context, record domain.MirrorRecord) {
+	previous, err := s.mirror.GetById(ctx, record.Module, record.Id)
+	if err != nil {
+		return
+	}
+	status, _ := previous.Data["ticketstatus"].(string)
+	ticket, err := domain.ConvertMapToHelpDesk(record.Data)
+	if err != nil {
+		return
+	}
+	PublishTicketStatus(s.events, status, ticket)
+}
@@ -2,11 +2,16 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
 	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
 	"time"
 )
 
@@ -16,36 +21,83 @@ type TokensService struct {
 	emails   EmailServiceInterface
 	config   config.Config
 	company  Company
+	cache    cache.Cache
 }
 
 var ErrPasswordDoesNotMatch = errors.New("password does not match")
 
-func NewTokensService(repo repository.Tokens, userRepo repository.Users, emails EmailServiceInterface, config config.Config, company Company) TokensService {
+var ErrInvalidRefreshToken = errors.New("refresh token is invalid or expired")
+
+var ErrRefreshTokenReused = errors.New("refresh token was already used, session is revoked")
+
+var ErrInvalidMagicLink = errors.New("magic link is invalid, expired or already used")
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	defaultTouchInterval   = time.Minute
+	defaultMagicLinkTTL    = 15 * time.Minute
+)
+
+const CacheSessionTouch = "session_touch_"
+
+const maxUserAgentLength = 255
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required,len=26"`
+}
+
+type MagicLinkInput struct {
+	Token string `json:"token" binding:"required,len=26"`
+}
+
+func NewTokensService(repo repository.Tokens, userRepo repository.Users, emails EmailServiceInterface, config config.Config, company Company, cache cache.Cache) TokensService {
 	return TokensService{
 		repo:     repo,
 		userRepo: userRepo,
 		emails:   emails,
 		config:   config,
 		company:  company,
+		cache:    cache,
 	}
 }
 
 func (s TokensService) CreateAuthToken(ctx context.Context, login string, pass string) (*domain.Token, error) {
+	user, err := s.authenticate(ctx, login, pass)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateAuthTokenForUser(ctx, user)
+}
+
+// authenticate checks password of user. User is returned also, when password does not match.
+func (s TokensService) authenticate(ctx context.Context, login string, pass string) (domain.User, error) {
 	user, err := s.userRepo.GetByEmail(ctx, login)
 	if err != nil {
-		return nil, e.Wrap("can not find user by email", err)
+		return domain.User{}, e.Wrap("can not find user by email", err)
 	}
 	match := user.Password.Matches(pass)
 	if !match {
-		return nil, ErrPasswordDoesNotMatch
+		return user, ErrPasswordDoesNotMatch
 	}
-	token, err := s.repo.New(ctx, user.Id, 24*time.Hour*90, domain.ScopeAuthentication)
+	return user, nil
+}
+
+// CreateAuthTokenForUser starts new session of already authenticated user. Second factor is still required,
+// when user has it enabled.
+func (s TokensService) CreateAuthTokenForUser(ctx context.Context, user domain.User) (*domain.Token, error) {
+	family, err := newTokenFamily()
 	if err != nil {
-		return token, e.Wrap("can not create a token", err)
+		return nil, e.Wrap("can not create token family", err)
 	}
-	if user.Otp_enabled {
+	token, err := s.issuePair(ctx, user.Id, family)
+	if err != nil {
+		return token, err
+	}
+	if user.SecondFactorEnabled() {
 		err = s.userRepo.VerifyOrInvalidateOtp(ctx, user.Id, false)
 		token.OtpEnabled = true
+		token.Webauthn = user.Webauthn_enabled
 	}
 	return token, err
 }
@@ -81,3 +133,227 @@ func (s TokensService) SendPasswordResetToken(ctx context.Context, email string)
 	}
 	return s.emails.SendPasswordReset(emailData)
 }
+
+// SendMagicLink emails single-use login link to user. Only the latest link of user is valid.
+func (s TokensService) SendMagicLink(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return ErrUserIsNotActive
+	}
+	ttl := s.config.Tokens.MagicLinkTTL
+	if ttl <= 0 {
+		ttl = defaultMagicLinkTTL
+	}
+	if err = s.repo.DeleteAllForUser(ctx, domain.ScopeMagicLink, user.Id); err != nil {
+		return e.Wrap("can not delete previous magic links", err)
+	}
+	token, err := s.repo.New(ctx, user.Id, ttl, domain.ScopeMagicLink)
+	if err != nil {
+		return e.Wrap("can not create magic link token", err)
+	}
+	companyData, err := s.company.GetCompany(ctx)
+	if err != nil {
+		return e.Wrap("can not send email because company data not received", err)
+	}
+	return s.emails.SendMagicLink(MagicLinkData{
+		Name:    user.FirstName + " " + user.LastName,
+		Email:   user.Email,
+		Token:   token.Plaintext,
+		Valid:   token.Expiry,
+		Company: companyData.OrganizationName,
+		Support: s.config.Vtiger.Business.SupportEmail,
+		Domain:  s.config.Domain,
+		Subject: s.config.Email.Subjects.MagicLink,
+	})
+}
+
+// VerifyMagicLink exchanges token from magic link for new session. As with password login, user with enabled second
+// factor still has to pass it.
+func (s TokensService) VerifyMagicLink(ctx context.Context, plaintext string) (*domain.Token, error) {
+	link, err := s.repo.GetByPlaintext(ctx, domain.ScopeMagicLink, plaintext)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, e.Wrap("can not get magic link token", err)
+	}
+	if link.UsedAt != nil || link.Expiry.Before(time.Now()) {
+		return nil, ErrInvalidMagicLink
+	}
+	claimed, err := s.repo.MarkUsed(ctx, link.ID)
+	if err != nil {
+		return nil, e.Wrap("can not mark magic link as used", err)
+	}
+	if !claimed {
+		return nil, ErrInvalidMagicLink
+	}
+	user, err := s.userRepo.GetById(ctx, link.UserId)
+	if err != nil {
+		return nil, e.Wrap("can not find user of magic link", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserIsNotActive
+	}
+	return s.CreateAuthTokenForUser(ctx, user)
+}
+
+// Refresh exchanges refresh token for new access and refresh tokens. Every refresh token can be used once.
+// When used token comes again, it was stolen or leaked, so the whole session is revoked.
+func (s TokensService) Refresh(ctx context.Context, refreshToken string) (*domain.Token, error) {
+	current, err := s.repo.GetByPlaintext(ctx, domain.ScopeRefresh, refreshToken)
+	if errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, e.Wrap("can not get refresh token", err)
+	}
+	if current.UsedAt != nil {
+		return nil, s.revokeFamily(ctx, current)
+	}
+	if current.Expiry.Before(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+	claimed, err := s.repo.MarkUsed(ctx, current.ID)
+	if err != nil {
+		return nil, e.Wrap("can not mark refresh token as used", err)
+	}
+	if !claimed {
+		return nil, s.revokeFamily(ctx, current)
+	}
+
+	user, err := s.userRepo.GetById(ctx, current.UserId)
+	if err != nil {
+		return nil, e.Wrap("can not find user of refresh token", err)
+	}
+	if !user.IsActive {
+		return nil, ErrUserIsNotActive
+	}
+	token, err := s.issuePair(ctx, user.Id, current.Family)
+	if err != nil {
+		return token, err
+	}
+	token.OtpEnabled = user.SecondFactorEnabled()
+	token.Webauthn = user.Webauthn_enabled
+	return token, nil
+}
+
+// Logout deletes access token and all tokens of its session, so refresh token can not restore it.
+func (s TokensService) Logout(ctx context.Context, accessToken string) error {
+	current, err := s.repo.GetByPlaintext(ctx, domain.ScopeAuthentication, accessToken)
+	if err != nil {
+		return e.Wrap("can not get current token", err)
+	}
+	if current.Family == "" {
+		return s.repo.Delete(ctx, current.ID)
+	}
+	return s.repo.DeleteFamily(ctx, current.Family)
+}
+
+// Touch records, that session of access token was used. Database is updated not more often than once in
+// tokens.touchInterval for every token. Errors are only logged, because they should not break the request.
+func (s TokensService) Touch(ctx context.Context, accessToken string, userAgent string, ip string) {
+	interval := s.config.Tokens.TouchInterval
+	if interval <= 0 {
+		interval = defaultTouchInterval
+	}
+	hash := sha256.Sum256([]byte(accessToken))
+	key := CacheSessionTouch + hex.EncodeToString(hash[:])
+	if touched, err := s.cache.Get(key); err == nil && touched != nil {
+		return
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	if err := s.repo.Touch(ctx, accessToken, userAgent, ip); err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not update session usage: " + err.Error()))
+		return
+	}
+	if err := s.cache.Set(key, []byte{1}, int64(interval/time.Second)); err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString("can not store session usage in cache: " + err.Error()))
+	}
+}
+
+// GetSessions returns all active sessions of user and marks session of passed access token as current.
+func (s TokensService) GetSessions(ctx context.Context, user domain.User, accessToken string) ([]domain.Session, error) {
+	sessions, err := s.repo.GetSessions(ctx, user.Id)
+	if err != nil {
+		return nil, e.Wrap("can not get sessions of user", err)
+	}
+	current := s.currentFamily(ctx, accessToken)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == current
+	}
+	return sessions, nil
+}
+
+func (s TokensService) DeleteSession(ctx context.Context, user domain.User, id string) error {
+	err := s.repo.DeleteSession(ctx, user.Id, id)
+	if err != nil {
+		return e.Wrap("can not delete session "+id, err)
+	}
+	return nil
+}
+
+// DeleteOtherSessions signs user out on all devices, except the one, which makes the request.
+func (s TokensService) DeleteOtherSessions(ctx context.Context, user domain.User, accessToken string) error {
+	current := s.currentFamily(ctx, accessToken)
+	if current == "" {
+		return e.Wrap("can not find current session", repository.ErrRecordNotFound)
+	}
+	err := s.repo.DeleteOtherSessions(ctx, user.Id, current)
+	if err != nil {
+		return e.Wrap("can not delete other sessions", err)
+	}
+	return nil
+}
+
+func (s TokensService) currentFamily(ctx context.Context, accessToken string) string {
+	token, err := s.repo.GetByPlaintext(ctx, domain.ScopeAuthentication, accessToken)
+	if err != nil {
+		return ""
+	}
+	return token.Family
+}
+
+func (s TokensService) revokeFamily(ctx context.Context, token domain.Token) error {
+	if err := s.repo.DeleteFamily(ctx, token.Family); err != nil {
+		return e.Wrap("can not revoke reused token family", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s TokensService) issuePair(ctx context.Context, userId int64, family string) (*domain.Token, error) {
+	accessTTL := s.config.Tokens.AccessTTL
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenTTL
+	}
+	refreshTTL := s.config.Tokens.RefreshTTL
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenTTL
+	}
+	if err := s.repo.DeleteExpiredForUser(ctx, userId); err != nil {
+		return nil, e.Wrap("can not delete expired tokens", err)
+	}
+	token, err := s.repo.NewInFamily(ctx, userId, accessTTL, domain.ScopeAuthentication, family)
+	if err != nil {
+		return token, e.Wrap("can not create a token", err)
+	}
+	refresh, err := s.repo.NewInFamily(ctx, userId, refreshTTL, domain.ScopeRefresh, family)
+	if err != nil {
+		return token, e.Wrap("can not create a refresh token", err)
+	}
+	token.RefreshToken = refresh.Plaintext
+	token.RefreshExpiry = &refresh.Expiry
+	return token, nil
+}
+
+func newTokenFamily() (string, error) {
+	family := make([]byte, 16)
+	if _, err := rand.Read(family); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(family), nil
+}
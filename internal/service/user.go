@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"github.com/semelyanov86/vtiger-portal/internal/config"
 	"github.com/semelyanov86/vtiger-portal/internal/domain"
@@ -17,6 +19,8 @@ import (
 
 const CacheUsersTTL = 50000
 
+const CacheUsersAccount = "account-"
+
 var ErrUserNotFound = errors.New("user not found")
 
 var ErrUserIsNotActive = errors.New("user is not active")
@@ -101,23 +105,76 @@ func (s UsersService) SignUp(ctx context.Context, input UserSignUpInput, cfg *co
 			user = &u
 		}
 	}
-	err = FillVtigerContactWithAdditionalValues(user, input.Password)
+	if user == nil || user.Crmid == "" {
+		return user, e.Wrap("can not find user in vtiger", ErrUserNotFound)
+	}
+	if err = s.provision(ctx, user, input.Password, cfg); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+// FindOrProvision returns portal user of contact with email from account. Contact, which is not registered in portal yet,
+// gets its users row the same way as on sign up, but with random password. It is used by single sign-on.
+func (s UsersService) FindOrProvision(ctx context.Context, accountId string, email string) (*domain.User, error) {
+	existing, err := s.repo.GetByEmail(ctx, email)
+	if err == nil {
+		if existing.AccountId != accountId {
+			return nil, e.Wrap("user "+email+" belongs to other account", ErrUserNotFound)
+		}
+		if !existing.IsActive {
+			return nil, ErrUserIsNotActive
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, repository.ErrRecordNotFound) {
+		return nil, e.Wrap("can not get user by email", err)
+	}
+
+	contacts, err := s.crm.FindByEmail(ctx, email)
 	if err != nil {
-		return nil, e.Wrap("can not fill data with additional values", err)
+		return nil, e.Wrap("can not find current email in crm", err)
+	}
+	var user *domain.User
+	for i := range contacts {
+		if contacts[i].AccountId == accountId {
+			user = &contacts[i]
+			break
+		}
 	}
 	if user == nil || user.Crmid == "" {
-		return user, e.Wrap("can not find user in vtiger", ErrUserNotFound)
+		return nil, e.Wrap("can not find contact "+email+" in account "+accountId, ErrUserNotFound)
+	}
+	password := make([]byte, 24)
+	if _, err = rand.Read(password); err != nil {
+		return nil, e.Wrap("can not generate password", err)
+	}
+	if err = s.provision(ctx, user, base64.RawURLEncoding.EncodeToString(password), &s.config); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provision creates portal user for contact from crm and runs post registration jobs.
+func (s UsersService) provision(ctx context.Context, user *domain.User, password string, cfg *config.Config) error {
+	err := FillVtigerContactWithAdditionalValues(user, password)
+	if err != nil {
+		return e.Wrap("can not fill data with additional values", err)
 	}
 	if user.AccountId != "" {
 		account, err := s.account.GetAccountById(ctx, user.AccountId)
 		if err != nil {
-			return user, e.Wrap("can not get account info", err)
+			return e.Wrap("can not get account info", err)
 		}
 		user.AccountName = account.AccountName
 	}
+	user.Role, err = s.initialRole(ctx, user.AccountId)
+	if err != nil {
+		return e.Wrap("can not get role of new user", err)
+	}
 
 	if err := s.repo.Insert(ctx, user); err != nil {
-		return user, err
+		return err
 	}
 
 	if cfg.Vtiger.Business.ClearCode {
@@ -136,7 +193,7 @@ func (s UsersService) SignUp(ctx context.Context, input UserSignUpInput, cfg *co
 	if cfg.Email.SendWelcomeEmail {
 		companyData, err := s.company.GetCompany(ctx)
 		if err != nil {
-			return nil, e.Wrap("can not send email because company data not received", err)
+			return e.Wrap("can not send email because company data not received", err)
 		}
 		s.wg.Add(1)
 		go func() {
@@ -153,7 +210,7 @@ func (s UsersService) SignUp(ctx context.Context, input UserSignUpInput, cfg *co
 		}()
 	}
 
-	return user, nil
+	return nil
 }
 
 func (s UsersService) Update(ctx context.Context, id int64, updateData UserUpdateInput) (*domain.User, error) {
@@ -244,7 +301,11 @@ func (s UsersService) Update(ctx context.Context, id int64, updateData UserUpdat
 		return user, e.Wrap("can not update user", err)
 	}
 	_, err = s.crm.Update(ctx, user.Crmid, *user)
-	return user, err
+	if err != nil {
+		return user, err
+	}
+	DeleteFromCache(s.cache, user.Crmid, CacheUsersAccount+user.AccountId)
+	return user, nil
 }
 
 func (s UsersService) GetUserByToken(ctx context.Context, token string) (*domain.User, error) {
@@ -296,9 +357,30 @@ func (s UsersService) GetUserById(ctx context.Context, id int64) (*domain.User,
 	return &user, nil
 }
 
+// FindContactsFromAccount returns contacts of account from crm with their portal roles.
+// Role is empty for contacts, which are not registered in portal.
 func (s UsersService) FindContactsFromAccount(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.User, int, error) {
+	users, count, err := s.findContactsFromAccount(ctx, filter)
+	if err != nil {
+		return users, count, err
+	}
+	registered, err := s.repo.GetAllByAccountId(ctx, filter.Client)
+	if err != nil {
+		return users, count, e.Wrap("can not get roles of account "+filter.Client, err)
+	}
+	roles := make(map[string]string, len(registered))
+	for _, user := range registered {
+		roles[user.Crmid] = user.RoleOrDefault()
+	}
+	for i := range users {
+		users[i].Role = roles[users[i].Crmid]
+	}
+	return users, count, nil
+}
+
+func (s UsersService) findContactsFromAccount(ctx context.Context, filter vtiger.PaginationQueryFilter) ([]domain.User, int, error) {
 	users := make([]domain.User, 0)
-	err := GetFromCache[*[]domain.User]("account-"+filter.Client, &users, s.cache)
+	err := GetFromCache[*[]domain.User](CacheUsersAccount+filter.Client, &users, s.cache)
 	if err == nil {
 		return users, len(users), nil
 	}
@@ -320,7 +402,7 @@ func (s UsersService) FindContactsFromAccount(ctx context.Context, filter vtiger
 			}
 			users = append(users, user)
 		}
-		err = StoreInCache[*[]domain.User]("account-"+filter.Client, &users, CacheUsersTTL, s.cache)
+		err = StoreInCache[*[]domain.User](CacheUsersAccount+filter.Client, &users, CacheUsersTTL, s.cache)
 		if err != nil {
 			return users, len(users), err
 		}
@@ -404,7 +486,12 @@ func (s UsersService) GetUserSettings(ctx context.Context, id string) (map[strin
 }
 
 func (s UsersService) ChangeUserSetting(ctx context.Context, id string, field string, value bool) error {
-	return s.crm.ChangeSettingField(ctx, id, field, value)
+	err := s.crm.ChangeSettingField(ctx, id, field, value)
+	if err != nil {
+		return err
+	}
+	DeleteFromCache(s.cache, id)
+	return nil
 }
 
 func FillVtigerContactWithAdditionalValues(user *domain.User, password string) error {
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"io"
+	"strconv"
+	"time"
+)
+
+var ErrWebauthnNotConfigured = errors.New("passkeys are not configured on this portal")
+
+var ErrWebauthnSessionNotFound = errors.New("passkey ceremony is not started or expired")
+
+var ErrWebauthnFailed = errors.New("passkey verification failed")
+
+var ErrWebauthnNoCredentials = errors.New("user does not have registered passkeys")
+
+const (
+	WebauthnRegistration = "registration"
+	WebauthnLogin        = "login"
+)
+
+const CacheWebauthnSession = "webauthn_session_"
+
+const defaultWebauthnTimeout = 5 * time.Minute
+
+// Webauthn registers passkeys and hardware keys and checks them as second factor, alternative to OTP.
+type Webauthn struct {
+	repo   repository.WebauthnCredentials
+	users  repository.Users
+	cache  cache.Cache
+	config config.Config
+}
+
+func NewWebauthnService(repo repository.WebauthnCredentials, users repository.Users, cache cache.Cache, config config.Config) Webauthn {
+	return Webauthn{
+		repo:   repo,
+		users:  users,
+		cache:  cache,
+		config: config,
+	}
+}
+
+// WebauthnSessionKey is a cache key, where challenge of started ceremony is kept until it is finished.
+func WebauthnSessionKey(ceremony string, userId int64) string {
+	return CacheWebauthnSession + ceremony + "_" + strconv.FormatInt(userId, 10)
+}
+
+func (w Webauthn) BeginRegistration(ctx context.Context, user domain.User) (*protocol.CredentialCreation, error) {
+	rp, err := w.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := w.owner(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(owner.credentials))
+	for _, credential := range owner.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+	creation, session, err := rp.BeginRegistration(owner, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, e.Wrap("can not start passkey registration", err)
+	}
+	if err = w.storeSession(WebauthnRegistration, user.Id, session); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// FinishRegistration verifies attestation of new credential and stores it. Second factor becomes required for user and
+// current session is treated as verified, as it is done when OTP is enabled.
+func (w Webauthn) FinishRegistration(ctx context.Context, user domain.User, name string, body io.Reader) (domain.WebauthnCredential, error) {
+	rp, err := w.relyingParty()
+	if err != nil {
+		return domain.WebauthnCredential{}, err
+	}
+	session, err := w.takeSession(WebauthnRegistration, user.Id)
+	if err != nil {
+		return domain.WebauthnCredential{}, err
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		return domain.WebauthnCredential{}, e.Wrap(describeWebauthnError(err), ErrWebauthnFailed)
+	}
+	owner, err := w.owner(ctx, user)
+	if err != nil {
+		return domain.WebauthnCredential{}, err
+	}
+	created, err := rp.CreateCredential(owner, session, parsed)
+	if err != nil {
+		return domain.WebauthnCredential{}, e.Wrap(describeWebauthnError(err), ErrWebauthnFailed)
+	}
+
+	if name == "" {
+		name = "Passkey " + strconv.Itoa(len(owner.credentials)+1)
+	}
+	credential := domain.WebauthnCredential{
+		UserId:          user.Id,
+		Name:            name,
+		CredentialId:    created.ID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		Transports:      make([]string, 0, len(created.Transport)),
+		Aaguid:          created.Authenticator.AAGUID,
+		SignCount:       created.Authenticator.SignCount,
+	}
+	for _, transport := range created.Transport {
+		credential.Transports = append(credential.Transports, string(transport))
+	}
+	if err = w.repo.Insert(ctx, &credential); err != nil {
+		return credential, e.Wrap("can not store passkey", err)
+	}
+	if !user.Webauthn_enabled {
+		if err = w.users.SetWebauthnEnabled(ctx, user.Id, true); err != nil {
+			return credential, e.Wrap("can not enable passkeys for user", err)
+		}
+		if err = w.users.VerifyOrInvalidateOtp(ctx, user.Id, true); err != nil {
+			return credential, e.Wrap("can not verify user", err)
+		}
+	}
+	DeleteFromCache(w.cache, user.Crmid)
+	return credential, nil
+}
+
+func (w Webauthn) BeginLogin(ctx context.Context, user domain.User) (*protocol.CredentialAssertion, error) {
+	rp, err := w.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	owner, err := w.owner(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if len(owner.credentials) == 0 {
+		return nil, ErrWebauthnNoCredentials
+	}
+	assertion, session, err := rp.BeginLogin(owner)
+	if err != nil {
+		return nil, e.Wrap("can not start passkey login", err)
+	}
+	if err = w.storeSession(WebauthnLogin, user.Id, session); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishLogin verifies assertion of authenticator and marks user as passed second factor.
+func (w Webauthn) FinishLogin(ctx context.Context, user domain.User, body io.Reader) (domain.User, error) {
+	rp, err := w.relyingParty()
+	if err != nil {
+		return user, err
+	}
+	session, err := w.takeSession(WebauthnLogin, user.Id)
+	if err != nil {
+		return user, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		return user, e.Wrap(describeWebauthnError(err), ErrWebauthnFailed)
+	}
+	owner, err := w.owner(ctx, user)
+	if err != nil {
+		return user, err
+	}
+	validated, err := rp.ValidateLogin(owner, session, parsed)
+	if err != nil {
+		return user, e.Wrap(describeWebauthnError(err), ErrWebauthnFailed)
+	}
+	for _, credential := range owner.credentials {
+		if !bytes.Equal(credential.CredentialId, validated.ID) {
+			continue
+		}
+		err = w.repo.UpdateUsage(ctx, credential.Id, validated.Authenticator.SignCount, validated.Authenticator.CloneWarning)
+		if err != nil {
+			return user, e.Wrap("can not update passkey usage", err)
+		}
+	}
+	if validated.Authenticator.CloneWarning {
+		return user, e.Wrap("signature counter of authenticator went back, it may be cloned", ErrWebauthnFailed)
+	}
+	if err = w.users.VerifyOrInvalidateOtp(ctx, user.Id, true); err != nil {
+		return user, e.Wrap("can not verify user", err)
+	}
+	user.Otp_verified = true
+	StoreInCache[*domain.User](user.Crmid, &user, CacheUsersTTL, w.cache)
+	return user, nil
+}
+
+func (w Webauthn) GetCredentials(ctx context.Context, user domain.User) ([]domain.WebauthnCredential, error) {
+	credentials, err := w.repo.GetAllByUser(ctx, user.Id)
+	if err != nil {
+		return nil, e.Wrap("can not get passkeys", err)
+	}
+	return credentials, nil
+}
+
+// DeleteCredential removes passkey. When it was the last one, passkeys stop being required as second factor.
+func (w Webauthn) DeleteCredential(ctx context.Context, user domain.User, id int64) error {
+	if err := w.repo.Delete(ctx, user.Id, id); err != nil {
+		return e.Wrap("can not delete passkey", err)
+	}
+	credentials, err := w.repo.GetAllByUser(ctx, user.Id)
+	if err != nil {
+		return e.Wrap("can not get passkeys", err)
+	}
+	if len(credentials) == 0 {
+		if err = w.users.SetWebauthnEnabled(ctx, user.Id, false); err != nil {
+			return e.Wrap("can not disable passkeys for user", err)
+		}
+	}
+	DeleteFromCache(w.cache, user.Crmid)
+	return nil
+}
+
+func (w Webauthn) relyingParty() (*webauthn.WebAuthn, error) {
+	cfg := w.config.Webauthn
+	if cfg.RPID == "" || len(cfg.RPOrigins) == 0 {
+		return nil, ErrWebauthnNotConfigured
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebauthnTimeout
+	}
+	timeouts := webauthn.TimeoutConfig{Enforce: true, Timeout: timeout, TimeoutUVD: timeout}
+	rp, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeouts, Registration: timeouts},
+	})
+	if err != nil {
+		return nil, e.Wrap(err.Error(), ErrWebauthnNotConfigured)
+	}
+	return rp, nil
+}
+
+func (w Webauthn) owner(ctx context.Context, user domain.User) (webauthnUser, error) {
+	credentials, err := w.repo.GetAllByUser(ctx, user.Id)
+	if err != nil {
+		return webauthnUser{}, e.Wrap("can not get passkeys", err)
+	}
+	return webauthnUser{user: user, credentials: credentials}, nil
+}
+
+func (w Webauthn) storeSession(ceremony string, userId int64, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return e.Wrap("can not encode passkey session", err)
+	}
+	ttl := w.config.Webauthn.Timeout
+	if ttl <= 0 {
+		ttl = defaultWebauthnTimeout
+	}
+	if err = w.cache.Set(WebauthnSessionKey(ceremony, userId), data, int64(ttl/time.Second)); err != nil {
+		return e.Wrap("can not store passkey session", err)
+	}
+	return nil
+}
+
+// takeSession returns started ceremony and forgets it, so every challenge is answered only once.
+func (w Webauthn) takeSession(ceremony string, userId int64) (webauthn.SessionData, error) {
+	var session webauthn.SessionData
+	key := WebauthnSessionKey(ceremony, userId)
+	if err := GetFromCache[*webauthn.SessionData](key, &session, w.cache); err != nil {
+		return session, ErrWebauthnSessionNotFound
+	}
+	DeleteFromCache(w.cache, key)
+	if !session.Expires.IsZero() && session.Expires.Before(time.Now()) {
+		return session, ErrWebauthnSessionNotFound
+	}
+	return session, nil
+}
+
+func describeWebauthnError(err error) string {
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) && protocolErr.Details != "" {
+		return protocolErr.Details
+	}
+	return err.Error()
+}
+
+// webauthnUser adapts portal user to library interface. User handle is id of user in portal database.
+type webauthnUser struct {
+	user        domain.User
+	credentials []domain.WebauthnCredential
+}
+
+func (u webauthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(u.user.Id, 10))
+}
+
+func (u webauthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u webauthnUser) WebAuthnDisplayName() string {
+	return u.user.FirstName + " " + u.user.LastName
+}
+
+func (u webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, credential := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(credential.Transports))
+		for _, transport := range credential.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credential.CredentialId,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.Aaguid,
+				SignCount: credential.SignCount,
+			},
+		})
+	}
+	return credentials
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/internal/config"
+	"github.com/semelyanov86/vtiger-portal/internal/domain"
+	"github.com/semelyanov86/vtiger-portal/internal/repository"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/logger"
+	"github.com/semelyanov86/vtiger-portal/pkg/pubsub"
+	"github.com/semelyanov86/vtiger-portal/pkg/webhook"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWebhookAttempts     = 8
+	defaultWebhookBackoff      = 30 * time.Second
+	defaultWebhookMaxBackoff   = time.Hour
+	defaultWebhookPollInterval = 5 * time.Second
+	defaultWebhookBatchSize    = 50
+	webhookDeliveriesLimit     = 100
+)
+
+// WebhookEvents are events, which customers can subscribe to.
+var WebhookEvents = []string{EventTicketCreated, EventTicketUpdated, EventComment, EventPaymentSucceeded, EventPaymentRefunded, EventDocumentUploaded}
+
+type WebhookInput struct {
+	Url      string   `json:"url" binding:"required"`
+	Events   []string `json:"events" binding:"required"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// WebhookPayload is body, which is sent to customer url.
+type WebhookPayload struct {
+	Id        int64     `json:"id"`
+	Type      string    `json:"type"`
+	AccountId string    `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Webhooks struct {
+	repository repository.Webhooks
+	config     config.Config
+	events     *pubsub.Hub
+	wg         *sync.WaitGroup
+	sender     *webhook.Sender
+}
+
+func NewWebhooksService(repository repository.Webhooks, config config.Config, events *pubsub.Hub, wg *sync.WaitGroup) Webhooks {
+	return Webhooks{
+		repository: repository,
+		config:     config,
+		events:     events,
+		wg:         wg,
+		sender:     webhook.NewSender(config.Webhooks.Timeout, config.Webhooks.AllowPrivateNetworks),
+	}
+}
+
+func (w Webhooks) GetAll(ctx context.Context, user domain.User) ([]domain.Webhook, error) {
+	if err := CheckPermission(user, domain.ModuleWebhooks, domain.PermissionRead); err != nil {
+		return nil, err
+	}
+	webhooks, err := w.repository.GetAllByAccountId(ctx, user.AccountId)
+	if err != nil {
+		return webhooks, e.Wrap("can not get webhooks of account "+user.AccountId, err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (w Webhooks) Get(ctx context.Context, id int64, user domain.User) (domain.Webhook, error) {
+	if err := CheckPermission(user, domain.ModuleWebhooks, domain.PermissionRead); err != nil {
+		return domain.Webhook{}, err
+	}
+	hook, err := w.repository.GetById(ctx, id, user.AccountId)
+	hook.Secret = ""
+	return hook, err
+}
+
+// Create stores webhook with new secret. Secret is returned only once, customer uses it to check signatures.
+func (w Webhooks) Create(ctx context.Context, input WebhookInput, user domain.User) (domain.Webhook, error) {
+	if err := CheckPermission(user, domain.ModuleWebhooks, domain.PermissionWrite); err != nil {
+		return domain.Webhook{}, err
+	}
+	if err := validateWebhookInput(input); err != nil {
+		return domain.Webhook{}, err
+	}
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return domain.Webhook{}, e.Wrap("can not generate webhook secret", err)
+	}
+	hook := domain.Webhook{
+		AccountId: user.AccountId,
+		UserId:    user.Crmid,
+		Url:       input.Url,
+		Secret:    secret,
+		Events:    input.Events,
+		IsActive:  input.IsActive == nil || *input.IsActive,
+	}
+	err = w.repository.Insert(ctx, &hook)
+	return hook, err
+}
+
+func (w Webhooks) Update(ctx context.Context, id int64, input WebhookInput, user domain.User) (domain.Webhook, error) {
+	if err := CheckPermission(user, domain.ModuleWebhooks, domain.PermissionWrite); err != nil {
+		return domain.Webhook{}, err
+	}
+	if err := validateWebhookInput(input); err != nil {
+		return domain.Webhook{}, err
+	}
+	hook, err := w.repository.GetById(ctx, id, user.AccountId)
+	if err != nil {
+		return hook, err
+	}
+	hook.Url = input.Url
+	hook.Events = input.Events
+	if input.IsActive != nil {
+		hook.IsActive = *input.IsActive
+	}
+	err = w.repository.Update(ctx, &hook)
+	hook.Secret = ""
+	return hook, err
+}
+
+func (w Webhooks) Delete(ctx context.Context, id int64, user domain.User) error {
+	if err := CheckPermission(user, domain.ModuleWebhooks, domain.PermissionWrite); err != nil {
+		return err
+	}
+	return w.repository.Delete(ctx, id, user.AccountId)
+}
+
+// GetDeliveries returns log of last deliveries of webhook.
+func (w Webhooks) GetDeliveries(ctx context.Context, id int64, user domain.User) ([]domain.WebhookDelivery, error) {
+	if err := CheckPermission(user, domain.ModuleWebhooks, domain.PermissionRead); err != nil {
+		return nil, err
+	}
+	if _, err := w.repository.GetById(ctx, id, user.AccountId); err != nil {
+		return nil, err
+	}
+	return w.repository.GetDeliveries(ctx, id, webhookDeliveriesLimit)
+}
+
+// Dispatch queues delivery of event to every webhook of account, which is subscribed to it.
+func (w Webhooks) Dispatch(ctx context.Context, event pubsub.Event) error {
+	if event.AccountId == "" || !isWebhookEvent(event.Type) {
+		return nil
+	}
+	hooks, err := w.repository.GetActiveForEvent(ctx, event.AccountId, event.Type)
+	if err != nil {
+		return e.Wrap("can not get webhooks for event "+event.Type, err)
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(WebhookPayload{Id: event.Id, Type: event.Type, AccountId: event.AccountId, CreatedAt: time.Now(), Data: event.Data})
+	if err != nil {
+		return e.Wrap("can not encode webhook payload", err)
+	}
+	now := time.Now()
+	for _, hook := range hooks {
+		delivery := domain.WebhookDelivery{
+			WebhookId:     hook.Id,
+			Event:         event.Type,
+			Payload:       payload,
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err = w.repository.InsertDelivery(ctx, &delivery); err != nil {
+			return e.Wrap("can not queue delivery for webhook "+strconv.FormatInt(hook.Id, 10), err)
+		}
+	}
+	return nil
+}
+
+// Start listens for portal events and sends queued deliveries until context is cancelled.
+func (w Webhooks) Start(ctx context.Context) {
+	if !w.config.Webhooks.Enabled {
+		return
+	}
+	w.wg.Add(2)
+	go func() {
+		defer w.wg.Done()
+		w.listen(ctx)
+	}()
+	go func() {
+		defer w.wg.Done()
+		interval := w.config.Webhooks.PollInterval
+		if interval <= 0 {
+			interval = defaultWebhookPollInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.DeliverDue(ctx); err != nil {
+					logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+// listen dispatches events from hub. When hub drops subscription, it subscribes again and receives missed events.
+func (w Webhooks) listen(ctx context.Context) {
+	var lastId int64
+	for {
+		subscription := w.events.SubscribeAll(lastId)
+		for _, event := range subscription.Missed() {
+			lastId = w.dispatch(ctx, event)
+		}
+	receive:
+		for {
+			select {
+			case <-ctx.Done():
+				subscription.Close()
+				return
+			case event, ok := <-subscription.C:
+				if !ok {
+					break receive
+				}
+				lastId = w.dispatch(ctx, event)
+			}
+		}
+	}
+}
+
+func (w Webhooks) dispatch(ctx context.Context, event pubsub.Event) int64 {
+	if err := w.Dispatch(ctx, event); err != nil {
+		logger.Error(logger.GenerateErrorMessageFromString(err.Error()))
+	}
+	return event.Id
+}
+
+// DeliverDue sends deliveries, which are waiting for next attempt.
+func (w Webhooks) DeliverDue(ctx context.Context) error {
+	batch := w.config.Webhooks.BatchSize
+	if batch < 1 {
+		batch = defaultWebhookBatchSize
+	}
+	now := time.Now()
+	deliveries, err := w.repository.GetDueDeliveries(ctx, now, batch)
+	if err != nil {
+		return e.Wrap("can not get due webhook deliveries", err)
+	}
+	for _, delivery := range deliveries {
+		// delivery is locked for time of request, so other instance does not send it twice
+		claimed, err := w.repository.ClaimDelivery(ctx, delivery.Id, now, now.Add(2*w.timeout()))
+		if err != nil {
+			return e.Wrap("can not claim webhook delivery", err)
+		}
+		if !claimed {
+			continue
+		}
+		w.deliver(ctx, &delivery)
+		if err = w.repository.UpdateDelivery(ctx, &delivery); err != nil {
+			return e.Wrap("can not update webhook delivery", err)
+		}
+	}
+	return nil
+}
+
+func (w Webhooks) deliver(ctx context.Context, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+	headers := map[string]string{
+		"X-Portal-Event":    delivery.Event,
+		"X-Portal-Delivery": strconv.FormatInt(delivery.Id, 10),
+	}
+	code, err := w.sender.Send(ctx, delivery.Webhook.Url, delivery.Webhook.Secret, headers, delivery.Payload)
+	delivery.ResponseCode = code
+	now := time.Now()
+	if err == nil {
+		delivery.Status = domain.WebhookDeliverySucceeded
+		delivery.Error = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		return
+	}
+	delivery.Error = err.Error()
+	if delivery.Attempts >= w.maxAttempts() {
+		delivery.Status = domain.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		return
+	}
+	next := now.Add(w.backoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+}
+
+// backoff returns delay before next attempt: retryBackoff, 2*retryBackoff, 4*retryBackoff and so on.
+func (w Webhooks) backoff(attempts int) time.Duration {
+	base := w.config.Webhooks.RetryBackoff
+	if base <= 0 {
+		base = defaultWebhookBackoff
+	}
+	maxBackoff := w.config.Webhooks.MaxRetryBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultWebhookMaxBackoff
+	}
+	delay := base << (attempts - 1)
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+func (w Webhooks) maxAttempts() int {
+	if w.config.Webhooks.MaxAttempts < 1 {
+		return defaultWebhookAttempts
+	}
+	return w.config.Webhooks.MaxAttempts
+}
+
+func (w Webhooks) timeout() time.Duration {
+	if w.config.Webhooks.Timeout <= 0 {
+		return webhook.DefaultTimeout
+	}
+	return w.config.Webhooks.Timeout
+}
+
+var ErrWrongWebhookUrl = errors.New("webhook url should be absolute http or https url")
+var ErrWrongWebhookEvent = errors.New("webhook event is not supported")
+
+func validateWebhookInput(input WebhookInput) error {
+	parsed, err := url.Parse(input.Url)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return e.Wrap(ErrWrongWebhookUrl.Error(), ErrValidation)
+	}
+	if len(input.Events) == 0 {
+		return e.Wrap(ErrWrongWebhookEvent.Error(), ErrValidation)
+	}
+	for _, event := range input.Events {
+		if !isWebhookEvent(event) {
+			return e.Wrap(ErrWrongWebhookEvent.Error()+": "+event, ErrValidation)
+		}
+	}
+	return nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, allowed := range WebhookEvents {
+		if allowed == event {
+			return true
+		}
+	}
+	return false
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
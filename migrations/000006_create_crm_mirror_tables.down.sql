@@ -0,0 +1,2 @@
+DROP TABLE crm_sync_state;
+DROP TABLE crm_records;
\ No newline at end of file
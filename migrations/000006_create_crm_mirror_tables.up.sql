@@ -0,0 +1,18 @@
+CREATE TABLE crm_records (
+                             id VARCHAR(50) PRIMARY KEY NOT NULL,
+                             module VARCHAR(50) NOT NULL,
+                             account_id VARCHAR(50) NOT NULL DEFAULT '',
+                             contact_id VARCHAR(50) NOT NULL DEFAULT '',
+                             parent_id VARCHAR(50) NOT NULL DEFAULT '',
+                             data JSON NOT NULL,
+                             modified_at TIMESTAMP NULL,
+                             synced_at TIMESTAMP NOT NULL,
+                             INDEX crm_records_account (module, account_id),
+                             INDEX crm_records_contact (module, contact_id),
+                             INDEX crm_records_parent (module, parent_id)
+);
+CREATE TABLE crm_sync_state (
+                                module VARCHAR(50) PRIMARY KEY NOT NULL,
+                                last_modified_time BIGINT NOT NULL DEFAULT 0,
+                                synced_at TIMESTAMP NOT NULL
+);
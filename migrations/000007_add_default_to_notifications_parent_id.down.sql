@@ -0,0 +1 @@
+ALTER TABLE notifications MODIFY parent_id VARCHAR(50) NOT NULL;
@@ -0,0 +1,30 @@
+CREATE TABLE webhooks (
+                          id INT AUTO_INCREMENT PRIMARY KEY,
+                          account_id VARCHAR(15) NOT NULL,
+                          user_id VARCHAR(15) NOT NULL,
+                          url VARCHAR(2048) NOT NULL,
+                          secret VARCHAR(64) NOT NULL,
+                          events VARCHAR(255) NOT NULL,
+                          is_active TINYINT(1) NOT NULL DEFAULT 1,
+                          created_at TIMESTAMP NOT NULL,
+                          updated_at TIMESTAMP NOT NULL,
+                          INDEX webhooks_account_id_idx (account_id)
+);
+
+CREATE TABLE webhook_deliveries (
+                                    id INT AUTO_INCREMENT PRIMARY KEY,
+                                    webhook_id INT NOT NULL,
+                                    event VARCHAR(50) NOT NULL,
+                                    payload JSON NOT NULL,
+                                    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+                                    attempts INT NOT NULL DEFAULT 0,
+                                    response_code INT NOT NULL DEFAULT 0,
+                                    error TEXT NULL,
+                                    next_attempt_at TIMESTAMP NULL,
+                                    delivered_at TIMESTAMP NULL,
+                                    created_at TIMESTAMP NOT NULL,
+                                    updated_at TIMESTAMP NOT NULL,
+                                    INDEX webhook_deliveries_webhook_id_idx (webhook_id, created_at),
+                                    INDEX webhook_deliveries_due_idx (status, next_attempt_at),
+                                    CONSTRAINT webhook_deliveries_webhook_id_fk FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+-- CREATE FIELD "role" -----------------------------------------
+ALTER TABLE `users` ADD COLUMN `role` VarChar( 20 ) NOT NULL DEFAULT 'member';
+-- -------------------------------------------------------------
+
+-- first registered contact of every account becomes its owner
+UPDATE `users` INNER JOIN (SELECT MIN(`id`) AS `id` FROM `users` GROUP BY `account_id`) AS `first_users` ON `users`.`id` = `first_users`.`id`
+SET `users`.`role` = 'owner';
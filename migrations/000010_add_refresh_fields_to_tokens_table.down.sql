@@ -0,0 +1,4 @@
+DROP INDEX `tokens_family_idx` ON `tokens`;
+DROP INDEX `tokens_hash_idx` ON `tokens`;
+ALTER TABLE `tokens` DROP COLUMN `used_at`;
+ALTER TABLE `tokens` DROP COLUMN `family`;
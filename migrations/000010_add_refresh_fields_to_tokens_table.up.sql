@@ -0,0 +1,10 @@
+-- CREATE FIELD "family" ---------------------------------------
+ALTER TABLE `tokens` ADD COLUMN `family` VarChar( 64 ) NOT NULL DEFAULT '' COMMENT 'Tokens of one login session share family';
+-- -------------------------------------------------------------
+
+-- CREATE FIELD "used_at" --------------------------------------
+ALTER TABLE `tokens` ADD COLUMN `used_at` DATETIME NULL COMMENT 'When refresh token was exchanged';
+-- -------------------------------------------------------------
+
+CREATE INDEX `tokens_hash_idx` ON `tokens` (`hash`);
+CREATE INDEX `tokens_family_idx` ON `tokens` (`family`);
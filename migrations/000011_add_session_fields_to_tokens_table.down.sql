@@ -0,0 +1,5 @@
+DROP INDEX `tokens_user_idx` ON `tokens`;
+ALTER TABLE `tokens` DROP COLUMN `ip`;
+ALTER TABLE `tokens` DROP COLUMN `user_agent`;
+ALTER TABLE `tokens` DROP COLUMN `last_used_at`;
+ALTER TABLE `tokens` DROP COLUMN `created_at`;
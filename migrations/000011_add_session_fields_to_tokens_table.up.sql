@@ -0,0 +1,20 @@
+-- CREATE FIELD "created_at" -----------------------------------
+ALTER TABLE `tokens` ADD COLUMN `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;
+-- -------------------------------------------------------------
+
+-- CREATE FIELD "last_used_at" ---------------------------------
+ALTER TABLE `tokens` ADD COLUMN `last_used_at` DATETIME NULL COMMENT 'Last request with this session, updated not more often than once in tokens.touchInterval';
+-- -------------------------------------------------------------
+
+-- CREATE FIELD "user_agent" -----------------------------------
+ALTER TABLE `tokens` ADD COLUMN `user_agent` VarChar( 255 ) NOT NULL DEFAULT '';
+-- -------------------------------------------------------------
+
+-- CREATE FIELD "ip" -------------------------------------------
+ALTER TABLE `tokens` ADD COLUMN `ip` VarChar( 45 ) NOT NULL DEFAULT '';
+-- -------------------------------------------------------------
+
+-- tokens, issued before refresh tokens, become sessions of their own
+UPDATE `tokens` SET `family` = CONCAT('legacy', `id`) WHERE `family` = '' AND `scope` = 'authentication';
+
+CREATE INDEX `tokens_user_idx` ON `tokens` (`user_id`, `scope`);
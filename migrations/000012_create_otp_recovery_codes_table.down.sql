@@ -0,0 +1,3 @@
+ALTER TABLE `users` DROP COLUMN `otp_locked_until`;
+ALTER TABLE `users` DROP COLUMN `otp_failed_attempts`;
+DROP TABLE IF EXISTS `otp_recovery_codes`;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS `otp_recovery_codes`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`    BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `hash`       VARCHAR(64)     NOT NULL COMMENT 'sha-256 of recovery code',
+    `used_at`    DATETIME        NULL,
+    `created_at` DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX `otp_recovery_codes_user_idx` ON `otp_recovery_codes` (`user_id`, `hash`);
+
+-- CREATE FIELD "otp_failed_attempts" --------------------------
+ALTER TABLE `users` ADD COLUMN `otp_failed_attempts` INT NOT NULL DEFAULT 0;
+-- -------------------------------------------------------------
+
+-- CREATE FIELD "otp_locked_until" -----------------------------
+ALTER TABLE `users` ADD COLUMN `otp_locked_until` DATETIME NULL COMMENT 'OTP can not be checked until this time after too many failed attempts';
+-- -------------------------------------------------------------
@@ -0,0 +1,2 @@
+ALTER TABLE `users` DROP COLUMN `webauthn_enabled`;
+DROP TABLE IF EXISTS `webauthn_credentials`;
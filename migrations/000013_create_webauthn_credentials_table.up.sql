@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS `webauthn_credentials`
+(
+    `id`               BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`          BIGINT          NOT NULL REFERENCES users ON DELETE CASCADE,
+    `name`             VARCHAR(100)    NOT NULL DEFAULT '',
+    `credential_id`    VARBINARY(255)  NOT NULL,
+    `public_key`       BLOB            NOT NULL COMMENT 'COSE encoded public key',
+    `attestation_type` VARCHAR(32)     NOT NULL DEFAULT '',
+    `transports`       VARCHAR(255)    NOT NULL DEFAULT '',
+    `aaguid`           VARBINARY(16)   NULL,
+    `sign_count`       INT UNSIGNED    NOT NULL DEFAULT 0,
+    `clone_warning`    TINYINT(1)      NOT NULL DEFAULT 0,
+    `created_at`       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_used_at`     DATETIME        NULL,
+    CONSTRAINT `webauthn_credentials_credential_id_unique` UNIQUE (`credential_id`)
+);
+
+CREATE INDEX `webauthn_credentials_user_idx` ON `webauthn_credentials` (`user_id`);
+
+-- CREATE FIELD "webauthn_enabled" -----------------------------
+ALTER TABLE `users` ADD COLUMN `webauthn_enabled` TINYINT(1) NOT NULL DEFAULT 0 COMMENT 'User has at least one passkey';
+-- -------------------------------------------------------------
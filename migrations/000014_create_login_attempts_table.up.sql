@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS `login_attempts`
+(
+    `id`              BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `key`             VARCHAR(255)    NOT NULL COMMENT 'email:<email> or ip:<address>',
+    `failed_attempts` INT             NOT NULL DEFAULT 0,
+    `last_failed_at`  DATETIME        NULL,
+    `locked_until`    DATETIME        NULL COMMENT 'sign in is blocked until this time after too many failed attempts',
+    `created_at`      DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX `login_attempts_key_idx` ON `login_attempts` (`key`);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS `audit_log`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `user_id`    BIGINT          NOT NULL,
+    `user_crmid` VARCHAR(50)     NOT NULL,
+    `user_name`  VARCHAR(255)    NOT NULL DEFAULT '',
+    `account_id` VARCHAR(50)     NOT NULL,
+    `ip`         VARCHAR(45)     NOT NULL DEFAULT '',
+    `action`     VARCHAR(100)    NOT NULL COMMENT 'ticket.update, comment.create and so on',
+    `module`     VARCHAR(100)    NOT NULL,
+    `entity_id`  VARCHAR(50)     NOT NULL DEFAULT '',
+    `changes`    JSON            NULL COMMENT 'changed fields with old and new values',
+    `created_at` DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX `audit_log_account_idx` ON `audit_log` (`account_id`, `created_at`);
+CREATE INDEX `audit_log_entity_idx` ON `audit_log` (`account_id`, `module`, `entity_id`);
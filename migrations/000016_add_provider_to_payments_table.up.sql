@@ -0,0 +1,3 @@
+-- CREATE FIELD "provider" -------------------------------------
+ALTER TABLE `payments` ADD COLUMN `provider` VarChar( 32 ) NOT NULL DEFAULT 'stripe';
+-- -------------------------------------------------------------
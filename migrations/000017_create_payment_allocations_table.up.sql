@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS `payment_allocations`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `payment_id` INT            NOT NULL,
+    `parent_id`  VARCHAR(15)    NOT NULL COMMENT 'id of invoice or sales order in crm',
+    `module`     VARCHAR(50)    NOT NULL,
+    `amount`     DECIMAL(10, 2) NOT NULL,
+    `created_at` TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX `payment_allocations_payment_idx` ON `payment_allocations` (`payment_id`);
+CREATE INDEX `payment_allocations_parent_idx` ON `payment_allocations` (`parent_id`);
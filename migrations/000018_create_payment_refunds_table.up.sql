@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS `payment_refunds`
+(
+    `id`         BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `payment_id` INT            NOT NULL,
+    `refund_id`  VARCHAR(255)   NOT NULL COMMENT 'id of refund at payment provider',
+    `amount`     DECIMAL(10, 2) NOT NULL,
+    `currency`   VARCHAR(3)     NOT NULL,
+    `status`     VARCHAR(50)    NOT NULL,
+    `created_at` TIMESTAMP      NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX `payment_refunds_refund_idx` ON `payment_refunds` (`refund_id`);
+CREATE INDEX `payment_refunds_payment_idx` ON `payment_refunds` (`payment_id`);
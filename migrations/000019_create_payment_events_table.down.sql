@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `payment_events`;
+ALTER TABLE `payments` DROP COLUMN `settled_at`;
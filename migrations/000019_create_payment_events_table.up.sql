@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS `payment_events`
+(
+    `id`              BIGINT UNSIGNED PRIMARY KEY NOT NULL AUTO_INCREMENT,
+    `provider`        VARCHAR(32)  NOT NULL,
+    `event_id`        VARCHAR(255) NOT NULL COMMENT 'id of event at payment provider',
+    `type`            VARCHAR(50)  NOT NULL,
+    `intent_id`       VARCHAR(255) NOT NULL DEFAULT '',
+    `payment_id`      INT          NOT NULL DEFAULT 0,
+    `refund_id`       VARCHAR(255) NOT NULL DEFAULT '',
+    `status`          VARCHAR(20)  NOT NULL DEFAULT 'received',
+    `attempts`        INT          NOT NULL DEFAULT 0,
+    `error`           TEXT         NULL,
+    `revised`         TEXT         NULL COMMENT 'ids of invoices and sales orders, which are already revised in crm',
+    `next_attempt_at` TIMESTAMP    NULL,
+    `occurred_at`     TIMESTAMP    NULL,
+    `processed_at`    TIMESTAMP    NULL,
+    `created_at`      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX `payment_events_event_idx` ON `payment_events` (`provider`, `event_id`);
+CREATE INDEX `payment_events_payment_idx` ON `payment_events` (`payment_id`, `occurred_at`);
+CREATE INDEX `payment_events_due_idx` ON `payment_events` (`status`, `next_attempt_at`);
+
+-- CREATE FIELD "settled_at" -----------------------------------
+ALTER TABLE `payments` ADD COLUMN `settled_at` TIMESTAMP NULL COMMENT 'invoices and sales orders of payment are revised in crm';
+UPDATE `payments` SET `settled_at` = `updated_at` WHERE `status` = 1;
+-- -------------------------------------------------------------
@@ -0,0 +1,2 @@
+ALTER TABLE `payment_allocations`
+    DROP COLUMN `revised_at`;
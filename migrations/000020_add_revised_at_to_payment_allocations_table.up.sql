@@ -0,0 +1,7 @@
+ALTER TABLE `payment_allocations`
+    ADD COLUMN `revised_at` TIMESTAMP NULL DEFAULT NULL COMMENT 'time, when allocation was revised in crm';
+
+UPDATE `payment_allocations`
+    INNER JOIN `payments` ON `payments`.`id` = `payment_allocations`.`payment_id`
+SET `payment_allocations`.`revised_at` = `payments`.`settled_at`
+WHERE `payments`.`settled_at` IS NOT NULL;
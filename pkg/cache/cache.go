@@ -3,4 +3,9 @@ package cache
 type Cache interface {
 	Set(key string, value []byte, ttl int64) error
 	Get(key string) ([]byte, error)
+	// Add stores value only when key is missing and reports whether it was stored.
+	Add(key string, value []byte, ttl int64) (bool, error)
+	Delete(key string) error
+	DeleteByPrefix(prefix string) error
+	Flush() error
 }
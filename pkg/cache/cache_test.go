@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache_Delete(t *testing.T) {
+	fileCache, err := NewFileCache(t.TempDir())
+	assert.NoError(t, err)
+
+	drivers := []struct {
+		name  string
+		cache Cache
+	}{
+		{name: "memory", cache: NewMemoryCache()},
+		{name: "file", cache: fileCache},
+	}
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			c := driver.cache
+			for _, key := range []string{"documents-17x1", "documents-17x2", "stat-11x1-12x1", "17x10"} {
+				assert.NoError(t, c.Set(key, []byte("1"), 100))
+			}
+
+			assert.NoError(t, c.Delete("17x10"))
+			assert.NoError(t, c.Delete("unknown"))
+			_, err := c.Get("17x10")
+			assert.Error(t, err)
+
+			assert.NoError(t, c.DeleteByPrefix("documents-"))
+			_, err = c.Get("documents-17x1")
+			assert.Error(t, err)
+			_, err = c.Get("documents-17x2")
+			assert.Error(t, err)
+			value, err := c.Get("stat-11x1-12x1")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("1"), value)
+
+			assert.NoError(t, c.Flush())
+			_, err = c.Get("stat-11x1-12x1")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCache_Add(t *testing.T) {
+	fileCache, err := NewFileCache(t.TempDir())
+	assert.NoError(t, err)
+
+	drivers := []struct {
+		name  string
+		cache Cache
+	}{
+		{name: "memory", cache: NewMemoryCache()},
+		{name: "file", cache: fileCache},
+	}
+
+	for _, driver := range drivers {
+		t.Run(driver.name, func(t *testing.T) {
+			c := driver.cache
+			added, err := c.Add("lock", []byte("1"), 100)
+			assert.NoError(t, err)
+			assert.True(t, added)
+
+			added, err = c.Add("lock", []byte("2"), 100)
+			assert.NoError(t, err)
+			assert.False(t, added)
+			value, err := c.Get("lock")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("1"), value)
+
+			assert.NoError(t, c.Delete("lock"))
+			added, err = c.Add("lock", []byte("3"), 100)
+			assert.NoError(t, err)
+			assert.True(t, added)
+		})
+	}
+}
+
+func TestMemoryCache_GetMissingKey(t *testing.T) {
+	c := NewMemoryCache()
+	assert.NoError(t, c.Set("key", []byte("value"), 100))
+	assert.NoError(t, c.Delete("key"))
+
+	_, err := c.Get("key")
+	assert.True(t, errors.Is(err, ErrItemNotFound))
+}
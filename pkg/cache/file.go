@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -12,9 +13,9 @@ type FileCache struct {
 }
 
 func NewFileCache(cacheDir string) (*FileCache, error) {
-	// Use the OS temp folder if cacheDir is not provided
+	// Use separate folder inside OS temp folder if cacheDir is not provided, so Flush does not touch other files
 	if cacheDir == "" {
-		cacheDir = os.TempDir()
+		cacheDir = filepath.Join(os.TempDir(), "portal-cache")
 	}
 
 	// Create the cache directory if it doesn't exist
@@ -45,6 +46,39 @@ func (fc *FileCache) Set(key string, value []byte, ttl int64) error {
 	return nil
 }
 
+// Add creates cache file exclusively. File left by stopped process is replaced, when it is older than ttl.
+func (fc *FileCache) Add(key string, value []byte, ttl int64) (bool, error) {
+	cacheFilePath := filepath.Join(fc.cacheDir, key)
+
+	if info, err := os.Stat(cacheFilePath); err == nil && time.Since(info.ModTime()) > time.Duration(ttl)*time.Second {
+		if err = fc.Delete(key); err != nil {
+			return false, err
+		}
+	}
+	file, err := os.OpenFile(cacheFilePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create cache file: %w", err)
+	}
+	_, err = file.Write(value)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to write cache file: %w", err)
+	}
+
+	if ttl > 0 {
+		time.AfterFunc(time.Duration(ttl)*time.Second, func() {
+			os.Remove(cacheFilePath)
+		})
+	}
+
+	return true, nil
+}
+
 func (fc *FileCache) Get(key string) ([]byte, error) {
 	cacheFilePath := filepath.Join(fc.cacheDir, key)
 
@@ -59,3 +93,31 @@ func (fc *FileCache) Get(key string) ([]byte, error) {
 
 	return value, nil
 }
+
+func (fc *FileCache) Delete(key string) error {
+	err := os.Remove(filepath.Join(fc.cacheDir, key))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache file: %w", err)
+	}
+	return nil
+}
+
+func (fc *FileCache) DeleteByPrefix(prefix string) error {
+	entries, err := os.ReadDir(fc.cacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if err := fc.Delete(entry.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fc *FileCache) Flush() error {
+	return fc.DeleteByPrefix("")
+}
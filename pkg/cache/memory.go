@@ -2,6 +2,7 @@ package cache
 
 import (
 	"errors"
+	"strings"
 	"sync"
 	"time"
 )
@@ -53,6 +54,22 @@ func (c *MemoryCache) Set(key string, value []byte, ttl int64) error {
 	return nil
 }
 
+func (c *MemoryCache) Add(key string, value []byte, ttl int64) (bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	now := time.Now().Unix()
+	if existing, ok := c.cache[key]; ok && now-existing.createdAt <= existing.ttl {
+		return false, nil
+	}
+	c.cache[key] = &item{
+		value:     value,
+		createdAt: now,
+		ttl:       ttl,
+	}
+
+	return true, nil
+}
+
 func (c *MemoryCache) Get(key string) ([]byte, error) {
 	c.RLock()
 	item, ex := c.cache[key]
@@ -64,3 +81,31 @@ func (c *MemoryCache) Get(key string) ([]byte, error) {
 
 	return item.value, nil
 }
+
+func (c *MemoryCache) Delete(key string) error {
+	c.Lock()
+	delete(c.cache, key)
+	c.Unlock()
+
+	return nil
+}
+
+func (c *MemoryCache) DeleteByPrefix(prefix string) error {
+	c.Lock()
+	for k := range c.cache {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.cache, k)
+		}
+	}
+	c.Unlock()
+
+	return nil
+}
+
+func (c *MemoryCache) Flush() error {
+	c.Lock()
+	c.cache = make(map[string]*item)
+	c.Unlock()
+
+	return nil
+}
@@ -0,0 +1,313 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrRedisResponse = errors.New("cache: unexpected redis response")
+
+type RedisOptions struct {
+	Addr     string
+	Password string
+	Db       int
+	Prefix   string
+	PoolSize int
+	Timeout  time.Duration
+}
+
+// RedisCache stores values in redis, so they survive restarts and are shared between portal instances.
+type RedisCache struct {
+	options RedisOptions
+	pool    chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "cache: redis error " + string(e)
+}
+
+func NewRedisCache(options RedisOptions) (*RedisCache, error) {
+	if options.Addr == "" {
+		options.Addr = "127.0.0.1:6379"
+	}
+	if options.PoolSize < 1 {
+		options.PoolSize = 10
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 3 * time.Second
+	}
+	c := &RedisCache{
+		options: options,
+		pool:    make(chan *redisConn, options.PoolSize),
+	}
+
+	// Check connection on start, so misconfiguration is visible immediately
+	reply, err := c.do("PING")
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	if reply != "PONG" {
+		return nil, ErrRedisResponse
+	}
+	return c, nil
+}
+
+// Set stores value with ttl in seconds. Non-positive ttl keeps value only for a second, as MemoryCache does.
+func (c *RedisCache) Set(key string, value []byte, ttl int64) error {
+	if ttl < 1 {
+		ttl = 1
+	}
+	_, err := c.do("SET", c.options.Prefix+key, string(value), "EX", strconv.FormatInt(ttl, 10))
+	return err
+}
+
+// Add uses SET with NX, so only one of portal instances stores the key.
+func (c *RedisCache) Add(key string, value []byte, ttl int64) (bool, error) {
+	if ttl < 1 {
+		ttl = 1
+	}
+	reply, err := c.do("SET", c.options.Prefix+key, string(value), "EX", strconv.FormatInt(ttl, 10), "NX")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (c *RedisCache) Get(key string) ([]byte, error) {
+	reply, err := c.do("GET", c.options.Prefix+key)
+	if err != nil {
+		return []byte{}, err
+	}
+	if reply == nil {
+		return []byte{}, ErrItemNotFound
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return []byte{}, ErrRedisResponse
+	}
+	return value, nil
+}
+
+func (c *RedisCache) Delete(key string) error {
+	_, err := c.do("DEL", c.options.Prefix+key)
+	return err
+}
+
+// DeleteByPrefix iterates over keys with SCAN instead of KEYS, so redis is not blocked on large databases.
+func (c *RedisCache) DeleteByPrefix(prefix string) error {
+	pattern := escapeRedisPattern(c.options.Prefix+prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return ErrRedisResponse
+		}
+		next, ok := items[0].([]byte)
+		if !ok {
+			return ErrRedisResponse
+		}
+		keys, _ := items[1].([]any)
+		if len(keys) > 0 {
+			args := make([]string, 0, len(keys)+1)
+			args = append(args, "DEL")
+			for _, key := range keys {
+				value, ok := key.([]byte)
+				if !ok {
+					return ErrRedisResponse
+				}
+				args = append(args, string(value))
+			}
+			if _, err = c.do(args...); err != nil {
+				return err
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// Flush removes only keys of the portal, when prefix is configured. Otherwise, whole database is cleared.
+func (c *RedisCache) Flush() error {
+	if c.options.Prefix != "" {
+		return c.DeleteByPrefix("")
+	}
+	_, err := c.do("FLUSHDB")
+	return err
+}
+
+func (c *RedisCache) Close() error {
+	for {
+		select {
+		case conn := <-c.pool:
+			conn.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *RedisCache) do(args ...string) (any, error) {
+	conn, err := c.getConn()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(c.options.Timeout, args...)
+	if err != nil {
+		var redisErr redisError
+		if !errors.As(err, &redisErr) {
+			// Connection is in unknown state after network errors, so it is not reused
+			conn.conn.Close()
+			return nil, err
+		}
+	}
+	c.putConn(conn)
+	return reply, err
+}
+
+func (c *RedisCache) getConn() (*redisConn, error) {
+	select {
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+		return c.dial()
+	}
+}
+
+func (c *RedisCache) putConn(conn *redisConn) {
+	select {
+	case c.pool <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (c *RedisCache) dial() (*redisConn, error) {
+	netConn, err := net.DialTimeout("tcp", c.options.Addr, c.options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if c.options.Password != "" {
+		if _, err = conn.do(c.options.Timeout, "AUTH", c.options.Password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if c.options.Db != 0 {
+		if _, err = conn.do(c.options.Timeout, "SELECT", strconv.Itoa(c.options.Db)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *redisConn) do(timeout time.Duration, args ...string) (any, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := c.conn.Write(encodeRedisCommand(args)); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.reader)
+}
+
+func escapeRedisPattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(value)
+}
+
+func encodeRedisCommand(args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// readRedisReply parses RESP reply: strings are returned as string, bulk strings as []byte,
+// integers as int64, arrays as []any and null values as nil.
+func readRedisReply(reader *bufio.Reader) (any, error) {
+	line, err := readRedisLine(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, ErrRedisResponse
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		value := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	case '*':
+		size, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]any, 0, size)
+		for i := 0; i < size; i++ {
+			item, err := readRedisReply(reader)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, ErrRedisResponse
+}
+
+func readRedisLine(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, ErrRedisResponse
+	}
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,305 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRedisEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// fakeRedis is an in-process RESP server which supports commands used by RedisCache.
+type fakeRedis struct {
+	sync.Mutex
+	listener net.Listener
+	data     map[string]fakeRedisEntry
+	password string
+	now      time.Time
+	commands [][]string
+	// scanPositions keeps the last returned key for every issued cursor, zero cursor is reserved
+	scanPositions []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{listener: listener, data: make(map[string]fakeRedisEntry), password: password, now: time.Now(), scanPositions: []string{""}}
+	go server.serve()
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	return server
+}
+
+func (s *fakeRedis) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeRedis) advance(d time.Duration) {
+	s.Lock()
+	s.now = s.now.Add(d)
+	s.Unlock()
+}
+
+func (s *fakeRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authorized := s.password == ""
+	for {
+		reply, err := readRedisReply(reader)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, 0, len(items))
+		for _, item := range items {
+			value, _ := item.([]byte)
+			args = append(args, string(value))
+		}
+		if len(args) == 0 {
+			return
+		}
+		command := strings.ToUpper(args[0])
+		if command == "AUTH" {
+			authorized = len(args) == 2 && args[1] == s.password
+		}
+		if !authorized && command != "AUTH" {
+			conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		conn.Write(s.execute(command, args))
+	}
+}
+
+func (s *fakeRedis) execute(command string, args []string) []byte {
+	s.Lock()
+	defer s.Unlock()
+	s.commands = append(s.commands, args)
+	switch command {
+	case "PING":
+		return []byte("+PONG\r\n")
+	case "AUTH":
+		if args[1] != s.password {
+			return []byte("-WRONGPASS invalid password\r\n")
+		}
+		return []byte("+OK\r\n")
+	case "SELECT":
+		return []byte("+OK\r\n")
+	case "SET":
+		entry := fakeRedisEntry{value: args[2]}
+		if len(args) == 6 && strings.ToUpper(args[5]) == "NX" {
+			existing, ok := s.data[args[1]]
+			if ok && (existing.expiresAt.IsZero() || s.now.Before(existing.expiresAt)) {
+				return []byte("$-1\r\n")
+			}
+		}
+		if len(args) >= 5 && strings.ToUpper(args[3]) == "EX" {
+			seconds, _ := strconv.Atoi(args[4])
+			entry.expiresAt = s.now.Add(time.Duration(seconds) * time.Second)
+		}
+		s.data[args[1]] = entry
+		return []byte("+OK\r\n")
+	case "GET":
+		entry, ok := s.data[args[1]]
+		if !ok || (!entry.expiresAt.IsZero() && !s.now.Before(entry.expiresAt)) {
+			return []byte("$-1\r\n")
+		}
+		return []byte("$" + strconv.Itoa(len(entry.value)) + "\r\n" + entry.value + "\r\n")
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.data[key]; ok {
+				delete(s.data, key)
+				deleted++
+			}
+		}
+		return []byte(":" + strconv.Itoa(deleted) + "\r\n")
+	case "FLUSHDB":
+		s.data = make(map[string]fakeRedisEntry)
+		return []byte("+OK\r\n")
+	case "SCAN":
+		return s.scan(args)
+	}
+	return []byte("-ERR unknown command '" + command + "'\r\n")
+}
+
+// scan returns two keys per call, so clients have to follow the cursor. As in redis, keys deleted
+// during iteration do not cause other keys to be skipped.
+func (s *fakeRedis) scan(args []string) []byte {
+	cursor, _ := strconv.Atoi(args[1])
+	prefix := ""
+	for i := 2; i+1 < len(args); i += 2 {
+		if strings.ToUpper(args[i]) == "MATCH" {
+			prefix = strings.NewReplacer(`\*`, "*", `\?`, "?", `\[`, "[", `\]`, "]", `\\`, `\`).Replace(strings.TrimSuffix(args[i+1], "*"))
+		}
+	}
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		if cursor == 0 || key > s.scanPositions[cursor] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	next := 0
+	if len(keys) > 2 {
+		keys = keys[:2]
+		s.scanPositions = append(s.scanPositions, keys[1])
+		next = len(s.scanPositions) - 1
+	}
+	matched := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			matched = append(matched, key)
+		}
+	}
+
+	nextCursor := strconv.Itoa(next)
+	reply := "*2\r\n$" + strconv.Itoa(len(nextCursor)) + "\r\n" + nextCursor + "\r\n*" + strconv.Itoa(len(matched)) + "\r\n"
+	for _, key := range matched {
+		reply += "$" + strconv.Itoa(len(key)) + "\r\n" + key + "\r\n"
+	}
+	return []byte(reply)
+}
+
+func (s *fakeRedis) keys() []string {
+	s.Lock()
+	defer s.Unlock()
+	keys := make([]string, 0, len(s.data))
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestRedisCache_SetAndGet(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c, err := NewRedisCache(RedisOptions{Addr: server.addr(), Prefix: "portal:"})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	value := []byte("{\"sessionName\":\"abc\r\n\"}")
+	assert.NoError(t, c.Set("portal_vtiger_token", value, 500))
+
+	result, err := c.Get("portal_vtiger_token")
+	assert.NoError(t, err)
+	assert.Equal(t, value, result)
+
+	server.Lock()
+	_, ok := server.data["portal:portal_vtiger_token"]
+	server.Unlock()
+	assert.True(t, ok, "key should be stored with prefix")
+}
+
+func TestRedisCache_GetMissingKey(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c, err := NewRedisCache(RedisOptions{Addr: server.addr()})
+	assert.NoError(t, err)
+
+	_, err = c.Get("unknown")
+	assert.True(t, errors.Is(err, ErrItemNotFound))
+}
+
+func TestRedisCache_TtlExpiration(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c, err := NewRedisCache(RedisOptions{Addr: server.addr()})
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Set("stat-12x11", []byte("1"), 500))
+	assert.NoError(t, c.Set("removed", []byte("1"), 0))
+
+	server.advance(time.Second)
+	_, err = c.Get("removed")
+	assert.True(t, errors.Is(err, ErrItemNotFound), "value with zero ttl should expire as in memory cache")
+	_, err = c.Get("stat-12x11")
+	assert.NoError(t, err)
+
+	server.advance(500 * time.Second)
+	_, err = c.Get("stat-12x11")
+	assert.True(t, errors.Is(err, ErrItemNotFound))
+}
+
+func TestRedisCache_Add(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c, err := NewRedisCache(RedisOptions{Addr: server.addr(), Prefix: "portal:"})
+	assert.NoError(t, err)
+
+	added, err := c.Add("notifications_import", []byte("1"), 60)
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	added, err = c.Add("notifications_import", []byte("2"), 60)
+	assert.NoError(t, err)
+	assert.False(t, added, "existing key should not be replaced")
+
+	server.advance(60 * time.Second)
+	added, err = c.Add("notifications_import", []byte("3"), 60)
+	assert.NoError(t, err)
+	assert.True(t, added, "expired key should be replaced")
+}
+
+func TestRedisCache_Auth(t *testing.T) {
+	server := newFakeRedis(t, "secret")
+
+	_, err := NewRedisCache(RedisOptions{Addr: server.addr(), Password: "wrong"})
+	assert.Error(t, err)
+
+	c, err := NewRedisCache(RedisOptions{Addr: server.addr(), Password: "secret", Db: 2})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Set("key", []byte("value"), 10))
+	result, err := c.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), result)
+}
+
+func TestRedisCache_Delete(t *testing.T) {
+	server := newFakeRedis(t, "")
+	c, err := NewRedisCache(RedisOptions{Addr: server.addr(), Prefix: "portal:"})
+	assert.NoError(t, err)
+
+	for _, key := range []string{"documents-17x1", "documents-17x2", "documents-17x3", "stat-11x1-12x1", "stat-11x1-12x2", "17x10"} {
+		assert.NoError(t, c.Set(key, []byte("1"), 100))
+	}
+	server.Lock()
+	server.data["other:documents-17x4"] = fakeRedisEntry{value: "1"}
+	server.Unlock()
+
+	assert.NoError(t, c.Delete("17x10"))
+	assert.NoError(t, c.Delete("unknown"))
+	_, err = c.Get("17x10")
+	assert.True(t, errors.Is(err, ErrItemNotFound))
+
+	assert.NoError(t, c.DeleteByPrefix("documents-"))
+	assert.Equal(t, []string{"other:documents-17x4", "portal:stat-11x1-12x1", "portal:stat-11x1-12x2"}, server.keys())
+
+	assert.NoError(t, c.Flush())
+	assert.Equal(t, []string{"other:documents-17x4"}, server.keys(), "flush should not remove keys of other applications")
+}
+
+func TestEscapeRedisPattern(t *testing.T) {
+	assert.Equal(t, `portal:a\*b\?c\[d\]\\`, escapeRedisPattern(`portal:a*b?c[d]\`))
+}
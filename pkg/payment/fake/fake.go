@@ -0,0 +1,139 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrNotRefundable = errors.New("only succeeded intent can be refunded")
+
+// Provider keeps intents in memory. It is used in tests and local development instead of real payment gateway.
+// Webhook payload is json of payment.Event.
+type Provider struct {
+	mu      sync.Mutex
+	name    string
+	seq     int
+	intents map[string]payment.Intent
+	refunds []payment.Refund
+}
+
+func NewProvider(name string) *Provider {
+	if name == "" {
+		name = "fake"
+	}
+	return &Provider{
+		name:    name,
+		intents: make(map[string]payment.Intent),
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+func (p *Provider) CreateIntent(ctx context.Context, input payment.IntentInput) (payment.Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	id := p.name + "_" + strconv.Itoa(p.seq)
+	intent := payment.Intent{
+		Id:           id,
+		Amount:       input.Amount,
+		Currency:     strings.ToLower(input.Currency),
+		Status:       payment.StatusRequiresPaymentMethod,
+		ClientSecret: id + "_secret",
+	}
+	if input.ReturnUrl != "" {
+		intent.ConfirmationUrl = "https://" + p.name + ".local/confirm/" + id
+	}
+	p.intents[id] = intent
+	return intent, nil
+}
+
+func (p *Provider) GetIntent(ctx context.Context, id string) (payment.Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[id]
+	if !ok {
+		return intent, e.Wrap("fake intent "+id, payment.ErrIntentNotFound)
+	}
+	return intent, nil
+}
+
+func (p *Provider) Refund(ctx context.Context, intentId string, amount int64) (payment.Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[intentId]
+	if !ok {
+		return payment.Refund{}, e.Wrap("fake intent "+intentId, payment.ErrIntentNotFound)
+	}
+	if intent.Status != payment.StatusSucceeded {
+		return payment.Refund{}, ErrNotRefundable
+	}
+	if amount <= 0 {
+		amount = intent.Amount - intent.AmountRefunded
+	}
+	intent.AmountRefunded += amount
+	p.intents[intentId] = intent
+	refund := payment.Refund{
+		Id:       "re_" + intentId + "_" + strconv.Itoa(len(p.refunds)+1),
+		IntentId: intentId,
+		Amount:   amount,
+		Currency: intent.Currency,
+		Status:   payment.StatusSucceeded,
+	}
+	p.refunds = append(p.refunds, refund)
+	return refund, nil
+}
+
+func (p *Provider) Cancel(ctx context.Context, id string) (payment.Intent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent, ok := p.intents[id]
+	if !ok {
+		return intent, e.Wrap("fake intent "+id, payment.ErrIntentNotFound)
+	}
+	if intent.Status == payment.StatusSucceeded || intent.Status == payment.StatusCanceled {
+		return intent, e.Wrap("fake intent "+id+" is "+intent.Status, payment.ErrNotCancelable)
+	}
+	intent.Status = payment.StatusCanceled
+	p.intents[id] = intent
+	return intent, nil
+}
+
+func (p *Provider) ParseWebhook(ctx context.Context, payload []byte, header http.Header) (payment.Event, error) {
+	var event payment.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, e.Wrap(err.Error(), payment.ErrInvalidSignature)
+	}
+	return event, nil
+}
+
+// Put stores intent as it is, so tests can prepare any state of provider.
+func (p *Provider) Put(intent payment.Intent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intents[intent.Id] = intent
+}
+
+// SetStatus changes status of intent, like customer or bank would do it.
+func (p *Provider) SetStatus(id string, status string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	intent := p.intents[id]
+	intent.Status = status
+	p.intents[id] = intent
+}
+
+func (p *Provider) Refunds() []payment.Refund {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]payment.Refund(nil), p.refunds...)
+}
@@ -0,0 +1,81 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Statuses of payment intent. Providers map own statuses to these ones.
+const (
+	StatusRequiresPaymentMethod = "requires_payment_method"
+	StatusRequiresConfirmation  = "requires_confirmation"
+	StatusRequiresAction        = "requires_action"
+	StatusRequiresCapture       = "requires_capture"
+	StatusProcessing            = "processing"
+	StatusSucceeded             = "succeeded"
+	StatusCanceled              = "canceled"
+)
+
+// Types of events, parsed from provider webhooks.
+const (
+	EventIntentUpdated = "intent.updated"
+	EventRefunded      = "intent.refunded"
+)
+
+var ErrInvalidSignature = errors.New("webhook signature is not valid")
+
+var ErrIntentNotFound = errors.New("payment intent not found")
+
+var ErrNotCancelable = errors.New("payment intent can not be canceled in its status")
+
+// IntentInput describes payment, which should be created at provider. Amount is in minor units of currency.
+type IntentInput struct {
+	Amount            int64
+	Currency          string
+	PaymentMethodType string
+	Description       string
+	ReturnUrl         string
+	Metadata          map[string]string
+	IdempotencyKey    string
+}
+
+// Intent is a payment at provider. Card payments are finished by client with ClientSecret, redirect payments are
+// finished on page of provider at ConfirmationUrl.
+type Intent struct {
+	Id              string
+	Amount          int64
+	AmountRefunded  int64
+	Currency        string
+	Status          string
+	ClientSecret    string
+	ConfirmationUrl string
+}
+
+type Refund struct {
+	Id       string
+	IntentId string
+	Amount   int64
+	Currency string
+	Status   string
+}
+
+// Event is a webhook notification of provider. Intent keeps state of intent at the moment of event, Refunds are set for
+// EventRefunded from the oldest one. Event can contain refunds, which were already sent by previous events.
+type Event struct {
+	Id      string
+	Type    string
+	Created time.Time
+	Intent  Intent
+	Refunds []Refund
+}
+
+type Provider interface {
+	Name() string
+	CreateIntent(ctx context.Context, input IntentInput) (Intent, error)
+	GetIntent(ctx context.Context, id string) (Intent, error)
+	Refund(ctx context.Context, intentId string, amount int64) (Refund, error)
+	Cancel(ctx context.Context, id string) (Intent, error)
+	ParseWebhook(ctx context.Context, payload []byte, header http.Header) (Event, error)
+}
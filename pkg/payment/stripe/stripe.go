@@ -0,0 +1,213 @@
+package stripe
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	stripeapi "github.com/stripe/stripe-go/v72"
+	"github.com/stripe/stripe-go/v72/client"
+	"github.com/stripe/stripe-go/v72/webhook"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const Name = "stripe"
+
+const SignatureHeader = "Stripe-Signature"
+
+// Provider accepts payments with stripe payment intents. Client finishes payment with client secret and stripe.js.
+type Provider struct {
+	api           *client.API
+	webhookSecret string
+}
+
+func NewProvider(key string, webhookSecret string, domain string) *Provider {
+	// For sample support and debugging, not required for production:
+	stripeapi.SetAppInfo(&stripeapi.AppInfo{
+		Name:    "customer-portal",
+		Version: runtime.Version(),
+		URL:     domain,
+	})
+	api := &client.API{}
+	api.Init(key, nil)
+	return &Provider{
+		api:           api,
+		webhookSecret: webhookSecret,
+	}
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+func (p *Provider) CreateIntent(ctx context.Context, input payment.IntentInput) (payment.Intent, error) {
+	var paymentMethodTypes []*string
+	if input.PaymentMethodType == "link" {
+		paymentMethodTypes = append(paymentMethodTypes, stripeapi.String("link"), stripeapi.String("card"))
+	} else {
+		paymentMethodTypes = append(paymentMethodTypes, stripeapi.String(input.PaymentMethodType))
+	}
+
+	params := &stripeapi.PaymentIntentParams{
+		Amount:             stripeapi.Int64(input.Amount),
+		Currency:           stripeapi.String(strings.ToLower(input.Currency)),
+		PaymentMethodTypes: paymentMethodTypes,
+	}
+	if input.Description != "" {
+		params.Description = stripeapi.String(input.Description)
+	}
+	// If this is for an ACSS payment, we add payment_method_options to create
+	// the Mandate.
+	if input.PaymentMethodType == "acss_debit" {
+		params.PaymentMethodOptions = &stripeapi.PaymentIntentPaymentMethodOptionsParams{
+			ACSSDebit: &stripeapi.PaymentIntentPaymentMethodOptionsACSSDebitParams{
+				MandateOptions: &stripeapi.PaymentIntentPaymentMethodOptionsACSSDebitMandateOptionsParams{
+					PaymentSchedule: stripeapi.String("sporadic"),
+					TransactionType: stripeapi.String("personal"),
+				},
+			},
+		}
+	}
+	for key, value := range input.Metadata {
+		params.AddMetadata(key, value)
+	}
+	if input.IdempotencyKey != "" {
+		params.SetIdempotencyKey(input.IdempotencyKey)
+	}
+	params.Context = ctx
+
+	intent, err := p.api.PaymentIntents.New(params)
+	if err != nil {
+		return payment.Intent{}, e.Wrap("can not create stripe payment intent", err)
+	}
+	return convertIntent(intent), nil
+}
+
+func (p *Provider) GetIntent(ctx context.Context, id string) (payment.Intent, error) {
+	params := &stripeapi.PaymentIntentParams{}
+	params.Context = ctx
+	intent, err := p.api.PaymentIntents.Get(id, params)
+	if err != nil {
+		if stripeErr, ok := err.(*stripeapi.Error); ok && stripeErr.HTTPStatusCode == http.StatusNotFound {
+			return payment.Intent{}, e.Wrap("stripe payment intent "+id, payment.ErrIntentNotFound)
+		}
+		return payment.Intent{}, e.Wrap("can not retrieve stripe payment intent "+id, err)
+	}
+	return convertIntent(intent), nil
+}
+
+// Refund returns amount of succeeded intent to customer. Whole rest of intent is refunded, when amount is 0.
+func (p *Provider) Refund(ctx context.Context, intentId string, amount int64) (payment.Refund, error) {
+	params := &stripeapi.RefundParams{PaymentIntent: stripeapi.String(intentId)}
+	if amount > 0 {
+		params.Amount = stripeapi.Int64(amount)
+	}
+	params.Context = ctx
+	refund, err := p.api.Refunds.New(params)
+	if err != nil {
+		return payment.Refund{}, e.Wrap("can not refund stripe payment intent "+intentId, err)
+	}
+	return payment.Refund{
+		Id:       refund.ID,
+		IntentId: intentId,
+		Amount:   refund.Amount,
+		Currency: string(refund.Currency),
+		Status:   string(refund.Status),
+	}, nil
+}
+
+func (p *Provider) Cancel(ctx context.Context, id string) (payment.Intent, error) {
+	params := &stripeapi.PaymentIntentCancelParams{CancellationReason: stripeapi.String("requested_by_customer")}
+	params.Context = ctx
+	intent, err := p.api.PaymentIntents.Cancel(id, params)
+	if err != nil {
+		if stripeErr, ok := err.(*stripeapi.Error); ok && stripeErr.Code == stripeapi.ErrorCodePaymentIntentUnexpectedState {
+			return payment.Intent{}, e.Wrap("stripe payment intent "+id, payment.ErrNotCancelable)
+		}
+		return payment.Intent{}, e.Wrap("can not cancel stripe payment intent "+id, err)
+	}
+	return convertIntent(intent), nil
+}
+
+// ParseWebhook checks signature of stripe event and converts payment intent and charge.refunded events. Other events
+// are returned with their stripe type and empty intent.
+func (p *Provider) ParseWebhook(ctx context.Context, payload []byte, header http.Header) (payment.Event, error) {
+	event, err := webhook.ConstructEvent(payload, header.Get(SignatureHeader), p.webhookSecret)
+	if err != nil {
+		return payment.Event{}, e.Wrap(err.Error(), payment.ErrInvalidSignature)
+	}
+	result := payment.Event{Id: event.ID, Type: event.Type, Created: time.Unix(event.Created, 0)}
+	if event.Type == "charge.refunded" {
+		var charge stripeapi.Charge
+		if err = json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			return result, e.Wrap("can not parse charge of event "+event.ID, err)
+		}
+		// refunds are not included in charge since api version 2022-11-15, older versions include only the first page
+		if charge.Refunds == nil || len(charge.Refunds.Data) == 0 || charge.Refunds.HasMore {
+			params := &stripeapi.RefundListParams{Charge: stripeapi.String(charge.ID)}
+			params.Context = ctx
+			refunds := p.api.Refunds.List(params)
+			list := &stripeapi.RefundList{}
+			for refunds.Next() {
+				list.Data = append(list.Data, refunds.Refund())
+			}
+			if err = refunds.Err(); err != nil {
+				return result, e.Wrap("can not list refunds of charge "+charge.ID, err)
+			}
+			charge.Refunds = list
+		}
+		return convertRefundedCharge(result, &charge), nil
+	}
+	if !strings.HasPrefix(event.Type, "payment_intent.") {
+		return result, nil
+	}
+	var intent stripeapi.PaymentIntent
+	if err = json.Unmarshal(event.Data.Raw, &intent); err != nil {
+		return result, e.Wrap("can not parse payment intent of event "+event.ID, err)
+	}
+	result.Type = payment.EventIntentUpdated
+	result.Intent = convertIntent(&intent)
+	return result, nil
+}
+
+// convertRefundedCharge fills event with intent of charge and all its refunds. Stripe lists refunds of charge from the
+// newest one, so they are reversed.
+func convertRefundedCharge(event payment.Event, charge *stripeapi.Charge) payment.Event {
+	event.Type = payment.EventRefunded
+	event.Intent = payment.Intent{
+		Amount:         charge.Amount,
+		AmountRefunded: charge.AmountRefunded,
+		Currency:       string(charge.Currency),
+		Status:         payment.StatusSucceeded,
+	}
+	if charge.PaymentIntent != nil {
+		event.Intent.Id = charge.PaymentIntent.ID
+	}
+	if charge.Refunds == nil {
+		return event
+	}
+	for i := len(charge.Refunds.Data) - 1; i >= 0; i-- {
+		refund := charge.Refunds.Data[i]
+		event.Refunds = append(event.Refunds, payment.Refund{
+			Id:       refund.ID,
+			IntentId: event.Intent.Id,
+			Amount:   refund.Amount,
+			Currency: string(refund.Currency),
+			Status:   string(refund.Status),
+		})
+	}
+	return event
+}
+
+func convertIntent(intent *stripeapi.PaymentIntent) payment.Intent {
+	return payment.Intent{
+		Id:           intent.ID,
+		Amount:       intent.Amount,
+		Currency:     intent.Currency,
+		Status:       string(intent.Status),
+		ClientSecret: intent.ClientSecret,
+	}
+}
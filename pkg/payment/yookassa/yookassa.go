@@ -0,0 +1,290 @@
+package yookassa
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const Name = "yookassa"
+
+const DefaultUrl = "https://api.yookassa.ru/v3"
+
+const DefaultTimeout = 10 * time.Second
+
+var ErrUnexpectedStatus = errors.New("yookassa responded with unexpected status")
+
+// Provider accepts payments with redirect flow: customer is sent to ConfirmationUrl of intent and returns to
+// return url after payment. Notifications of yookassa are not signed, so state of payment is always fetched from api.
+type Provider struct {
+	client    *http.Client
+	url       string
+	shopId    string
+	secretKey string
+}
+
+func NewProvider(shopId string, secretKey string, url string, timeout time.Duration) *Provider {
+	if url == "" {
+		url = DefaultUrl
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Provider{
+		client:    &http.Client{Timeout: timeout},
+		url:       strings.TrimSuffix(url, "/"),
+		shopId:    shopId,
+		secretKey: secretKey,
+	}
+}
+
+type amount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+type confirmation struct {
+	Type            string `json:"type"`
+	ReturnUrl       string `json:"return_url,omitempty"`
+	ConfirmationUrl string `json:"confirmation_url,omitempty"`
+}
+
+type paymentObject struct {
+	Id             string        `json:"id"`
+	Status         string        `json:"status"`
+	Amount         amount        `json:"amount"`
+	RefundedAmount *amount       `json:"refunded_amount,omitempty"`
+	Confirmation   *confirmation `json:"confirmation,omitempty"`
+}
+
+type refundObject struct {
+	Id        string `json:"id"`
+	PaymentId string `json:"payment_id"`
+	Status    string `json:"status"`
+	Amount    amount `json:"amount"`
+}
+
+type notification struct {
+	Type   string          `json:"type"`
+	Event  string          `json:"event"`
+	Object json.RawMessage `json:"object"`
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+func (p *Provider) CreateIntent(ctx context.Context, input payment.IntentInput) (payment.Intent, error) {
+	body := map[string]any{
+		"amount":       amount{Value: formatAmount(input.Amount), Currency: strings.ToUpper(input.Currency)},
+		"capture":      true,
+		"confirmation": confirmation{Type: "redirect", ReturnUrl: input.ReturnUrl},
+		"description":  input.Description,
+		"metadata":     input.Metadata,
+	}
+	var created paymentObject
+	if err := p.call(ctx, http.MethodPost, "/payments", input.IdempotencyKey, body, &created); err != nil {
+		return payment.Intent{}, e.Wrap("can not create yookassa payment", err)
+	}
+	return convertPayment(created), nil
+}
+
+func (p *Provider) GetIntent(ctx context.Context, id string) (payment.Intent, error) {
+	var found paymentObject
+	if err := p.call(ctx, http.MethodGet, "/payments/"+id, "", nil, &found); err != nil {
+		return payment.Intent{}, e.Wrap("can not retrieve yookassa payment "+id, err)
+	}
+	return convertPayment(found), nil
+}
+
+// Refund returns amount of succeeded payment to customer. Whole rest of payment is refunded, when amount is 0.
+func (p *Provider) Refund(ctx context.Context, intentId string, value int64) (payment.Refund, error) {
+	intent, err := p.GetIntent(ctx, intentId)
+	if err != nil {
+		return payment.Refund{}, err
+	}
+	if value <= 0 {
+		value = intent.Amount - intent.AmountRefunded
+	}
+	body := map[string]any{
+		"payment_id": intentId,
+		"amount":     amount{Value: formatAmount(value), Currency: strings.ToUpper(intent.Currency)},
+	}
+	var refund refundObject
+	if err = p.call(ctx, http.MethodPost, "/refunds", "", body, &refund); err != nil {
+		return payment.Refund{}, e.Wrap("can not refund yookassa payment "+intentId, err)
+	}
+	return convertRefund(refund), nil
+}
+
+// Cancel cancels payment, which waits for capture. Yookassa does not cancel pending payments, they expire on their own.
+func (p *Provider) Cancel(ctx context.Context, id string) (payment.Intent, error) {
+	intent, err := p.GetIntent(ctx, id)
+	if err != nil {
+		return intent, err
+	}
+	if intent.Status != payment.StatusRequiresCapture {
+		return intent, e.Wrap("yookassa payment "+id+" is "+intent.Status, payment.ErrNotCancelable)
+	}
+	var canceled paymentObject
+	if err = p.call(ctx, http.MethodPost, "/payments/"+id+"/cancel", "", map[string]any{}, &canceled); err != nil {
+		return intent, e.Wrap("can not cancel yookassa payment "+id, err)
+	}
+	return convertPayment(canceled), nil
+}
+
+// ParseWebhook reads notification and fetches its payment or refund from api, so forged notification can not change
+// status.
+func (p *Provider) ParseWebhook(ctx context.Context, payload []byte, header http.Header) (payment.Event, error) {
+	var event notification
+	if err := json.Unmarshal(payload, &event); err != nil || event.Type != "notification" {
+		return payment.Event{}, e.Wrap("can not parse yookassa notification", payment.ErrInvalidSignature)
+	}
+	result := payment.Event{Type: event.Event, Created: time.Now()}
+	var paymentId string
+	switch {
+	case strings.HasPrefix(event.Event, "payment."):
+		var object paymentObject
+		if err := json.Unmarshal(event.Object, &object); err != nil {
+			return result, e.Wrap("can not parse payment of yookassa notification", err)
+		}
+		paymentId = object.Id
+		result.Id = event.Event + ":" + object.Id
+		result.Type = payment.EventIntentUpdated
+	case event.Event == "refund.succeeded":
+		var object refundObject
+		if err := json.Unmarshal(event.Object, &object); err != nil {
+			return result, e.Wrap("can not parse refund of yookassa notification", err)
+		}
+		var found refundObject
+		if err := p.call(ctx, http.MethodGet, "/refunds/"+object.Id, "", nil, &found); err != nil {
+			return result, e.Wrap("can not retrieve yookassa refund "+object.Id, err)
+		}
+		refund := convertRefund(found)
+		paymentId = refund.IntentId
+		result.Id = event.Event + ":" + refund.Id
+		result.Type = payment.EventRefunded
+		result.Refunds = []payment.Refund{refund}
+	default:
+		return result, nil
+	}
+	intent, err := p.GetIntent(ctx, paymentId)
+	if err != nil {
+		return result, err
+	}
+	result.Intent = intent
+	return result, nil
+}
+
+func (p *Provider) call(ctx context.Context, method string, path string, idempotencyKey string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.shopId, p.secretKey)
+	req.Header.Set("Content-Type", "application/json")
+	if method == http.MethodPost {
+		if idempotencyKey == "" {
+			idempotencyKey = newIdempotencyKey()
+		}
+		req.Header.Set("Idempotence-Key", idempotencyKey)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return payment.ErrIntentNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Description string `json:"description"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return e.Wrap(strconv.Itoa(resp.StatusCode)+" "+apiErr.Description, ErrUnexpectedStatus)
+	}
+	return json.Unmarshal(data, result)
+}
+
+func convertPayment(object paymentObject) payment.Intent {
+	intent := payment.Intent{
+		Id:       object.Id,
+		Amount:   parseAmount(object.Amount.Value),
+		Currency: strings.ToLower(object.Amount.Currency),
+		Status:   convertStatus(object.Status),
+	}
+	if object.RefundedAmount != nil {
+		intent.AmountRefunded = parseAmount(object.RefundedAmount.Value)
+	}
+	if object.Confirmation != nil {
+		intent.ConfirmationUrl = object.Confirmation.ConfirmationUrl
+	}
+	return intent
+}
+
+func convertRefund(object refundObject) payment.Refund {
+	return payment.Refund{
+		Id:       object.Id,
+		IntentId: object.PaymentId,
+		Amount:   parseAmount(object.Amount.Value),
+		Currency: strings.ToLower(object.Amount.Currency),
+		Status:   object.Status,
+	}
+}
+
+func convertStatus(status string) string {
+	switch status {
+	case "pending":
+		return payment.StatusRequiresAction
+	case "waiting_for_capture":
+		return payment.StatusRequiresCapture
+	case "succeeded":
+		return payment.StatusSucceeded
+	case "canceled":
+		return payment.StatusCanceled
+	}
+	return payment.StatusProcessing
+}
+
+// formatAmount converts minor units to decimal string, which is expected by yookassa.
+func formatAmount(value int64) string {
+	return strconv.FormatFloat(float64(value)/100, 'f', 2, 64)
+}
+
+func parseAmount(value string) int64 {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(parsed * 100))
+}
+
+func newIdempotencyKey() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
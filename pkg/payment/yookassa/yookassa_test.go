@@ -0,0 +1,113 @@
+package yookassa
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/semelyanov86/vtiger-portal/pkg/payment"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvider_CreateIntent(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		assert.Equal(t, "shop", user)
+		assert.Equal(t, "secret", password)
+		assert.Equal(t, "/payments", r.URL.Path)
+		assert.Equal(t, "key-1", r.Header.Get("Idempotence-Key"))
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"id":"2c8a","status":"pending","amount":{"value":"1500.50","currency":"RUB"},"confirmation":{"type":"redirect","confirmation_url":"https://yoomoney.ru/checkout/2c8a"}}`))
+	}))
+	defer server.Close()
+
+	intent, err := NewProvider("shop", "secret", server.URL, time.Second).CreateIntent(context.Background(), payment.IntentInput{
+		Amount:         150050,
+		Currency:       "rub",
+		ReturnUrl:      "https://portal.example.com/payments",
+		IdempotencyKey: "key-1",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, payment.Intent{Id: "2c8a", Amount: 150050, Currency: "rub", Status: payment.StatusRequiresAction, ConfirmationUrl: "https://yoomoney.ru/checkout/2c8a"}, intent)
+	assert.Equal(t, map[string]any{"value": "1500.50", "currency": "RUB"}, body["amount"])
+	assert.Equal(t, map[string]any{"type": "redirect", "return_url": "https://portal.example.com/payments"}, body["confirmation"])
+}
+
+func TestProvider_ParseWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/payments/2c8a" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"2c8a","status":"canceled","amount":{"value":"10.00","currency":"RUB"}}`))
+	}))
+	defer server.Close()
+	provider := NewProvider("shop", "secret", server.URL, time.Second)
+
+	// status of notification is ignored, it is taken from api
+	event, err := provider.ParseWebhook(context.Background(), []byte(`{"type":"notification","event":"payment.succeeded","object":{"id":"2c8a","status":"succeeded"}}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, payment.EventIntentUpdated, event.Type)
+	assert.Equal(t, "payment.succeeded:2c8a", event.Id)
+	assert.Equal(t, payment.StatusCanceled, event.Intent.Status)
+
+	_, err = provider.ParseWebhook(context.Background(), []byte(`{"type":"notification","event":"payment.succeeded","object":{"id":"forged"}}`), nil)
+	assert.True(t, errors.Is(err, payment.ErrIntentNotFound), err)
+
+	_, err = provider.ParseWebhook(context.Background(), []byte(`{}`), nil)
+	assert.True(t, errors.Is(err, payment.ErrInvalidSignature), err)
+}
+
+func TestProvider_ParseRefundWebhook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/refunds/rf1":
+			_, _ = w.Write([]byte(`{"id":"rf1","payment_id":"2c8a","status":"succeeded","amount":{"value":"5.50","currency":"RUB"}}`))
+		case "/payments/2c8a":
+			_, _ = w.Write([]byte(`{"id":"2c8a","status":"succeeded","amount":{"value":"10.00","currency":"RUB"},"refunded_amount":{"value":"5.50","currency":"RUB"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	event, err := NewProvider("shop", "secret", server.URL, time.Second).ParseWebhook(context.Background(), []byte(`{"type":"notification","event":"refund.succeeded","object":{"id":"rf1","amount":{"value":"10000.00","currency":"RUB"}}}`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, payment.EventRefunded, event.Type)
+	assert.Equal(t, "refund.succeeded:rf1", event.Id)
+	assert.Equal(t, []payment.Refund{{Id: "rf1", IntentId: "2c8a", Amount: 550, Currency: "rub", Status: payment.StatusSucceeded}}, event.Refunds)
+	assert.Equal(t, int64(550), event.Intent.AmountRefunded)
+}
+
+func TestProvider_Cancel(t *testing.T) {
+	var canceled bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/payments/2c8a/cancel" && r.Method == http.MethodPost:
+			canceled = true
+			_, _ = w.Write([]byte(`{"id":"2c8a","status":"canceled","amount":{"value":"10.00","currency":"RUB"}}`))
+		case r.URL.Path == "/payments/2c8a":
+			_, _ = w.Write([]byte(`{"id":"2c8a","status":"waiting_for_capture","amount":{"value":"10.00","currency":"RUB"}}`))
+		case r.URL.Path == "/payments/3d9b":
+			_, _ = w.Write([]byte(`{"id":"3d9b","status":"pending","amount":{"value":"10.00","currency":"RUB"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	provider := NewProvider("shop", "secret", server.URL, time.Second)
+
+	intent, err := provider.Cancel(context.Background(), "2c8a")
+	assert.NoError(t, err)
+	assert.True(t, canceled)
+	assert.Equal(t, payment.StatusCanceled, intent.Status)
+
+	_, err = provider.Cancel(context.Background(), "3d9b")
+	assert.True(t, errors.Is(err, payment.ErrNotCancelable), err)
+}
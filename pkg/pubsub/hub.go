@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+const DefaultHistorySize = 1000
+const DefaultBufferSize = 32
+
+// Event is delivered to subscribers of the same user or, when UserId is empty, to all subscribers of account.
+type Event struct {
+	Id        int64
+	Type      string
+	AccountId string
+	UserId    string
+	Data      any
+}
+
+// Hub is in-process publisher. It keeps history of last events, so reconnected subscribers can receive
+// events they missed.
+type Hub struct {
+	mu          sync.Mutex
+	lastId      int64
+	history     []Event
+	historySize int
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	C         <-chan Event
+	events    chan Event
+	accountId string
+	userId    string
+	all       bool
+	missed    []Event
+	hub       *Hub
+	closed    bool
+}
+
+func NewHub(historySize int, bufferSize int) *Hub {
+	if historySize < 1 {
+		historySize = DefaultHistorySize
+	}
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+	return &Hub{
+		// ids continue to grow after restart, so old Last-Event-ID does not hide new events
+		lastId:      time.Now().UnixMilli(),
+		history:     make([]Event, 0, historySize),
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns id to event and sends it to all matching subscribers. Subscribers, which can not keep up,
+// are closed and have to reconnect with last received id. Publishing to nil hub does nothing.
+func (h *Hub) Publish(event Event) Event {
+	if h == nil {
+		return event
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastId++
+	event.Id = h.lastId
+	if len(h.history) == h.historySize {
+		copy(h.history, h.history[1:])
+		h.history = h.history[:len(h.history)-1]
+	}
+	h.history = append(h.history, event)
+
+	for sub := range h.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe returns subscription for user of account. When lastEventId is greater than zero,
+// events after it are available in Missed.
+func (h *Hub) Subscribe(accountId string, userId string, lastEventId int64) *Subscription {
+	return h.subscribe(&Subscription{accountId: accountId, userId: userId}, lastEventId)
+}
+
+// SubscribeAll returns subscription to events of all accounts, it is used by internal consumers.
+func (h *Hub) SubscribeAll(lastEventId int64) *Subscription {
+	return h.subscribe(&Subscription{all: true}, lastEventId)
+}
+
+func (h *Hub) subscribe(sub *Subscription, lastEventId int64) *Subscription {
+	sub.events = make(chan Event, h.bufferSize)
+	sub.C = sub.events
+	sub.hub = h
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if lastEventId > 0 {
+		for _, event := range h.history {
+			if event.Id > lastEventId && sub.matches(event) {
+				sub.missed = append(sub.missed, event)
+			}
+		}
+	}
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *Hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+// Missed returns events published after last event id passed to Subscribe.
+func (s *Subscription) Missed() []Event {
+	return s.missed
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (s *Subscription) matches(event Event) bool {
+	if s.all {
+		return true
+	}
+	if event.UserId != "" {
+		return event.UserId == s.userId
+	}
+	return event.AccountId != "" && event.AccountId == s.accountId
+}
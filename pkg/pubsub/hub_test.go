@@ -0,0 +1,74 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_PublishToMatchingSubscribers(t *testing.T) {
+	hub := NewHub(10, 10)
+	user := hub.Subscribe("11x1", "12x1", 0)
+	colleague := hub.Subscribe("11x1", "12x2", 0)
+	stranger := hub.Subscribe("11x2", "12x3", 0)
+	all := hub.SubscribeAll(0)
+	defer all.Close()
+	defer user.Close()
+	defer colleague.Close()
+	defer stranger.Close()
+
+	personal := hub.Publish(Event{Type: "notification", AccountId: "11x1", UserId: "12x1"})
+	common := hub.Publish(Event{Type: "ticket", AccountId: "11x1"})
+
+	assert.Equal(t, personal.Id+1, common.Id)
+	assert.Equal(t, personal, <-user.C)
+	assert.Equal(t, common, <-user.C)
+	assert.Equal(t, common, <-colleague.C)
+	assert.Len(t, colleague.C, 0)
+	assert.Len(t, stranger.C, 0)
+	assert.Equal(t, personal, <-all.C)
+	assert.Equal(t, common, <-all.C)
+}
+
+func TestHub_Missed(t *testing.T) {
+	hub := NewHub(3, 10)
+	events := make([]Event, 0)
+	for i := 0; i < 5; i++ {
+		events = append(events, hub.Publish(Event{Type: "ticket", AccountId: "11x1"}))
+	}
+	hub.Publish(Event{Type: "ticket", AccountId: "11x2"})
+
+	tests := []struct {
+		name   string
+		lastId int64
+		missed []Event
+	}{
+		{name: "new subscription", lastId: 0, missed: nil},
+		{name: "reconnect", lastId: events[3].Id, missed: events[4:]},
+		{name: "only history is replayed", lastId: events[0].Id, missed: events[3:]},
+		{name: "nothing missed", lastId: events[4].Id, missed: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := hub.Subscribe("11x1", "12x1", tt.lastId)
+			defer sub.Close()
+
+			assert.Equal(t, tt.missed, sub.Missed())
+		})
+	}
+}
+
+func TestHub_SlowSubscriberIsClosed(t *testing.T) {
+	hub := NewHub(10, 1)
+	sub := hub.Subscribe("11x1", "12x1", 0)
+
+	hub.Publish(Event{AccountId: "11x1"})
+	hub.Publish(Event{AccountId: "11x1"})
+
+	_, ok := <-sub.C
+	assert.True(t, ok)
+	_, ok = <-sub.C
+	assert.False(t, ok)
+	assert.Equal(t, 0, hub.Subscribers())
+	sub.Close()
+}
@@ -10,9 +10,14 @@ type Connector interface {
 	Lookup(ctx context.Context, dataType, value, module string, columns []string) (*VtigerResponse[[]map[string]any], error)
 	AddRelated(ctx context.Context, source string, related string, label string) (*VtigerResponse[[]map[string]any], error)
 	Query(ctx context.Context, query string) (*VtigerResponse[[]map[string]any], error)
+	Select(ctx context.Context, query *QueryBuilder) ([]map[string]any, error)
+	SelectCount(ctx context.Context, query *QueryBuilder) (int, error)
+	QueryAll(ctx context.Context, query *QueryBuilder) *RecordIterator
+	SelectAll(ctx context.Context, query *QueryBuilder) ([]map[string]any, error)
 	RetrieveRelated(ctx context.Context, id string, module string) (*VtigerResponse[[]map[string]any], error)
 	Retrieve(ctx context.Context, id string) (*VtigerResponse[map[string]any], error)
 	Describe(ctx context.Context, element string) (*VtigerResponse[Module], error)
+	Sync(ctx context.Context, module string, modifiedTime int64) (*VtigerResponse[SyncResult], error)
 	Delete(ctx context.Context, element string) error
 	RetrieveFiles(ctx context.Context, id string) (*VtigerResponse[[]File], error)
 	Update(ctx context.Context, data map[string]any) (*VtigerResponse[map[string]any], error)
@@ -2,7 +2,9 @@ package vtiger
 
 import (
 	"context"
+	"fmt"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -27,42 +29,162 @@ type QueryFieldsProps struct {
 }
 
 func (c VtigerConnector) GetAll(ctx context.Context, filter PaginationQueryFilter, fields QueryFieldsProps) ([]map[string]any, error) {
-	// Calculate the offset for the given page number and page size
-	offset := (filter.Page - 1) * filter.PageSize
-	query := "SELECT * FROM " + fields.TableName + " WHERE "
 	sort := filter.Sort
 	if sort == "" {
 		sort = fields.DefaultSort
 	}
-	if filter.Search != "" {
-		for _, field := range fields.SearchFields {
-			query += " " + field + " LIKE '%" + filter.Search + "%' OR"
-		}
-		query = strings.Trim(query, "OR")
-	} else {
-		if fields.AccountField != "" {
-			query += fields.AccountField + " = " + filter.Client + " "
+	query := NewQuery(fields.TableName).OrderBy(sort)
+
+	owners := make([]Condition, 0, 2)
+	if fields.AccountField != "" {
+		owners = append(owners, Eq(fields.AccountField, filter.Client))
+	}
+	if fields.ClientField != "" {
+		owners = append(owners, Eq(fields.ClientField, filter.Contact))
+	}
+	if filter.Search == "" || len(fields.SearchFields) == 0 {
+		if len(owners) > 0 {
+			query.Where(Or(owners...))
 		}
-		if fields.ClientField != "" {
-			query += "OR " + fields.ClientField + " = " + filter.Contact + " "
+		return c.Select(ctx, query.Page(filter.Page, filter.PageSize))
+	}
+
+	searches := make([]Condition, 0, len(fields.SearchFields))
+	for _, field := range fields.SearchFields {
+		searches = append(searches, Contains(field, filter.Search))
+	}
+	if len(owners) == 0 {
+		return c.Select(ctx, query.Where(Or(searches...)).Page(filter.Page, filter.PageSize))
+	}
+	return c.selectOwnedPage(ctx, query, owners, searches, filter)
+}
+
+// selectOwnedPage returns page of search results, which belong to client or contact of filter. VTQL can not combine
+// OR of owners with OR of search fields, so every owner is searched by every field in own query. Each query reads
+// only rows up to the end of page, the rest of them can not get to the page.
+func (c VtigerConnector) selectOwnedPage(ctx context.Context, query *QueryBuilder, owners []Condition, searches []Condition, filter PaginationQueryFilter) ([]map[string]any, error) {
+	offset := (filter.Page - 1) * filter.PageSize
+	if offset < 0 {
+		offset = 0
+	}
+	branches := make([][]Condition, 0, len(owners)*len(searches))
+	for _, owner := range owners {
+		for _, search := range searches {
+			branches = append(branches, []Condition{owner, search})
 		}
 	}
-	query += GenerateOrderByClause(sort)
-	query += " LIMIT " + strconv.Itoa(offset) + ", " + strconv.Itoa(filter.PageSize) + ";"
-	result, err := c.Query(ctx, query)
+	records, err := c.SelectUnion(ctx, query, offset+filter.PageSize, branches...)
 	if err != nil {
-		return nil, e.Wrap("can not execute query "+query+", got error", err)
+		return nil, err
 	}
-	return result.Result, nil
+	if offset >= len(records) {
+		return make([]map[string]any, 0), nil
+	}
+	return records[offset:], nil
+}
+
+// SelectUnion returns records, which match conditions of any branch, conditions of one branch are joined with AND. It
+// replaces OR of grouped conditions, which VTQL does not support. Records are sorted by order of query, limit is
+// applied to every branch and to the result, 0 means all records.
+func (c VtigerConnector) SelectUnion(ctx context.Context, query *QueryBuilder, limit int, branches ...[]Condition) ([]map[string]any, error) {
+	if len(query.order) == 0 {
+		query = query.Clone().OrderBy("id")
+	}
+	seen := make(map[any]bool)
+	records := make([]map[string]any, 0)
+	for _, branch := range branches {
+		it := c.QueryAll(ctx, query.Clone().Where(branch...))
+		read := 0
+		for (limit <= 0 || read < limit) && it.Next() {
+			record := it.Record()
+			read++
+			if seen[record["id"]] {
+				continue
+			}
+			seen[record["id"]] = true
+			records = append(records, record)
+		}
+		it.Close()
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, order := range query.order {
+			compared := compareValues(records[i][order.field], records[j][order.field])
+			if compared == 0 {
+				continue
+			}
+			return (compared < 0) != order.desc
+		}
+		return false
+	})
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
+
+// compareValues compares values of records as numbers, when both are numbers or ids of crm, otherwise as strings.
+func compareValues(a any, b any) int {
+	left, right := fmt.Sprint(a), fmt.Sprint(b)
+	leftNumber, leftErr := strconv.ParseFloat(crmidNumber(left), 64)
+	rightNumber, rightErr := strconv.ParseFloat(crmidNumber(right), 64)
+	if leftErr == nil && rightErr == nil {
+		switch {
+		case leftNumber < rightNumber:
+			return -1
+		case leftNumber > rightNumber:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(left, right)
+}
+
+// crmidNumber returns number of record from id like 17x25, other values are returned as is.
+func crmidNumber(value string) string {
+	module, number, found := strings.Cut(value, "x")
+	if !found {
+		return value
+	}
+	if _, err := strconv.Atoi(module); err != nil {
+		return value
+	}
+	return number
 }
 
 func (c VtigerConnector) GetByWhereClause(ctx context.Context, filter PaginationQueryFilter, field string, value string, table string) ([]map[string]any, error) {
-	// Calculate the offset for the given page number and page size
-	offset := (filter.Page - 1) * filter.PageSize
-	query := "SELECT * FROM " + table + " WHERE " + field + " = '" + value + "' LIMIT " + strconv.Itoa(offset) + ", " + strconv.Itoa(filter.PageSize) + ";"
-	result, err := c.Query(ctx, query)
+	query := NewQuery(table).Where(Eq(field, value)).Page(filter.Page, filter.PageSize)
+	return c.Select(ctx, query)
+}
+
+func (c VtigerConnector) Select(ctx context.Context, query *QueryBuilder) ([]map[string]any, error) {
+	sql, err := query.Build()
+	if err != nil {
+		return nil, e.Wrap("can not build query", err)
+	}
+	result, err := c.Query(ctx, sql)
 	if err != nil {
-		return nil, e.Wrap("can not execute query "+query+", got error: ", err)
+		return nil, e.Wrap("can not execute query "+sql+", got error", err)
 	}
 	return result.Result, nil
 }
+
+// QueryAll iterates over all records of query, limit of the query is replaced with pages of configured size.
+func (c VtigerConnector) QueryAll(ctx context.Context, query *QueryBuilder) *RecordIterator {
+	return newRecordIterator(ctx, query, c.connection.PageSize, c.connection.QueryConcurrency, c.Select)
+}
+
+// SelectAll returns all records of query, without truncating them to 100 rows.
+func (c VtigerConnector) SelectAll(ctx context.Context, query *QueryBuilder) ([]map[string]any, error) {
+	return c.QueryAll(ctx, query).Collect()
+}
+
+func (c VtigerConnector) SelectCount(ctx context.Context, query *QueryBuilder) (int, error) {
+	sql, err := query.Count().Build()
+	if err != nil {
+		return 0, e.Wrap("can not build count query", err)
+	}
+	return c.ExecuteCount(ctx, sql)
+}
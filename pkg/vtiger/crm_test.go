@@ -0,0 +1,157 @@
+package vtiger
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/semelyanov86/vtiger-portal/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingFetcher struct {
+	queries []string
+	result  string
+	// results are returned instead of result for matching queries
+	results map[string]string
+}
+
+func (f *recordingFetcher) FetchBytes(ctx context.Context, postfix string) ([]byte, error) {
+	values, err := url.ParseQuery(postfix)
+	if err != nil {
+		return nil, err
+	}
+	switch values.Get("operation") {
+	case "getchallenge":
+		return []byte(`{"success":true,"result":{"token":"token","serverTime":1,"expireTime":1}}`), nil
+	case "query":
+		f.queries = append(f.queries, values.Get("query"))
+		if result, ok := f.results[values.Get("query")]; ok {
+			return []byte(`{"success":true,"result":` + result + `}`), nil
+		}
+		return []byte(`{"success":true,"result":` + f.result + `}`), nil
+	case "sync":
+		f.queries = append(f.queries, values.Get("elementType")+":"+values.Get("modifiedTime"))
+		return []byte(`{"success":true,"result":` + f.result + `}`), nil
+	}
+	return []byte(`{"success":false,"error":{"code":"UNKNOWN","message":"unknown operation"}}`), nil
+}
+
+func (f *recordingFetcher) SendData(ctx context.Context, data RequestData) ([]byte, error) {
+	return []byte(`{"success":true,"result":{"sessionName":"session","userId":"19x1"}}`), nil
+}
+
+func newRecordingConnector(result string) (VtigerConnector, *recordingFetcher) {
+	fetcher := &recordingFetcher{result: result}
+	config := VtigerConnectionConfig{PersistenceConnection: true, MaxRetries: 3}
+	return NewVtigerConnector(cache.NewMemoryCache(), config, fetcher), fetcher
+}
+
+func TestVtigerConnector_GetAllEscapesSearch(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`[]`)
+
+	_, err := connector.GetAll(context.Background(), PaginationQueryFilter{
+		Page:     1,
+		PageSize: 20,
+		Client:   "11x1",
+		Search:   "' OR ticket_title LIKE '",
+	}, QueryFieldsProps{
+		DefaultSort:  "-ticket_no",
+		SearchFields: []string{"ticket_title", "ticket_no"},
+		AccountField: "parent_id",
+		TableName:    "HelpDesk",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticket_title LIKE '%\' OR ticket\\_title LIKE \'%' ORDER BY ticket_no DESC LIMIT 0, 100;`,
+		`SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticket_no LIKE '%\' OR ticket\\_title LIKE \'%' ORDER BY ticket_no DESC LIMIT 0, 100;`,
+	}, fetcher.queries)
+}
+
+func TestVtigerConnector_GetAllFiltersSearchByOwner(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`[]`)
+	fetcher.results = map[string]string{
+		`SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticket_title LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`:  `[{"id":"17x1","ticket_no":"TT1"},{"id":"17x4","ticket_no":"TT4"}]`,
+		`SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticket_no LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`:     `[{"id":"17x4","ticket_no":"TT4"}]`,
+		`SELECT * FROM HelpDesk WHERE contact_id = '12x5' AND ticket_title LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`: `[{"id":"17x3","ticket_no":"TT3"},{"id":"17x4","ticket_no":"TT4"}]`,
+	}
+
+	result, err := connector.GetAll(context.Background(), PaginationQueryFilter{
+		Page:     2,
+		PageSize: 2,
+		Client:   "11x1",
+		Contact:  "12x5",
+		Search:   "printer",
+	}, QueryFieldsProps{
+		DefaultSort:  "ticket_no",
+		SearchFields: []string{"ticket_title", "ticket_no"},
+		AccountField: "parent_id",
+		ClientField:  "contact_id",
+		TableName:    "HelpDesk",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": "17x4", "ticket_no": "TT4"}}, result)
+	assert.Equal(t, []string{
+		`SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticket_title LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`,
+		`SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticket_no LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`,
+		`SELECT * FROM HelpDesk WHERE contact_id = '12x5' AND ticket_title LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`,
+		`SELECT * FROM HelpDesk WHERE contact_id = '12x5' AND ticket_no LIKE '%printer%' ORDER BY ticket_no ASC LIMIT 0, 100;`,
+	}, fetcher.queries)
+}
+
+func TestVtigerConnector_SelectUnion(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`[]`)
+	fetcher.results = map[string]string{
+		`SELECT id FROM Project WHERE linktoaccountscontacts = '11x1' AND projectname LIKE '%site%' ORDER BY id ASC LIMIT 0, 100;`: `[{"id":"31x9"},{"id":"31x12"}]`,
+		`SELECT id FROM Project WHERE linktoaccountscontacts = '12x5' AND projectname LIKE '%site%' ORDER BY id ASC LIMIT 0, 100;`: `[{"id":"31x10"},{"id":"31x12"}]`,
+	}
+
+	result, err := connector.SelectUnion(context.Background(), NewQuery("Project").Select("id"), 0,
+		[]Condition{Eq("linktoaccountscontacts", "11x1"), Contains("projectname", "site")},
+		[]Condition{Eq("linktoaccountscontacts", "12x5"), Contains("projectname", "site")},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"id": "31x9"}, {"id": "31x10"}, {"id": "31x12"}}, result)
+}
+
+func TestVtigerConnector_GetAllRejectsWrongSort(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`[]`)
+
+	_, err := connector.GetAll(context.Background(), PaginationQueryFilter{
+		Page:     1,
+		PageSize: 20,
+		Client:   "11x1",
+		Sort:     "id; DELETE",
+	}, QueryFieldsProps{AccountField: "parent_id", TableName: "HelpDesk"})
+
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+	assert.Empty(t, fetcher.queries)
+}
+
+func TestVtigerConnector_CountEscapesFilters(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`[{"count":"7"}]`)
+
+	count, err := connector.Count(context.Background(), "Faq", map[string]string{"faqstatus": "Published' OR '1'='1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, []string{`SELECT COUNT(*) FROM Faq WHERE faqstatus = 'Published\' OR \'1\'=\'1';`}, fetcher.queries)
+}
+
+func TestVtigerConnector_Sync(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`{"updated":[{"id":"17x1","parent_id":"11x1"}],"deleted":["17x2"],"lastModifiedTime":1700000000,"more":true}`)
+
+	result, err := connector.Sync(context.Background(), "HelpDesk", 1600000000)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HelpDesk:1600000000"}, fetcher.queries)
+	assert.Equal(t, SyncResult{
+		Updated:          []map[string]any{{"id": "17x1", "parent_id": "11x1"}},
+		Deleted:          []string{"17x2"},
+		LastModifiedTime: 1700000000,
+		More:             true,
+	}, result.Result)
+}
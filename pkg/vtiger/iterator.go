@@ -0,0 +1,144 @@
+package vtiger
+
+import (
+	"context"
+	"sync"
+)
+
+// MaxPageSize is the maximum amount of rows which vtiger returns for one query.
+const MaxPageSize = 100
+
+const DefaultQueryConcurrency = 1
+
+type pageFetcher func(ctx context.Context, query *QueryBuilder) ([]map[string]any, error)
+
+type pageResult struct {
+	records []map[string]any
+	err     error
+}
+
+// RecordIterator streams records of query, requesting next pages with LIMIT offsets until vtiger returns incomplete page.
+//
+//	it := connector.QueryAll(ctx, query)
+//	defer it.Close()
+//	for it.Next() {
+//		record := it.Record()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type RecordIterator struct {
+	pages   chan pageResult
+	cancel  context.CancelFunc
+	done    sync.WaitGroup
+	current []map[string]any
+	record  map[string]any
+	err     error
+	// stopErr is an error of context, which has stopped fetching. It is set before pages are closed.
+	stopErr error
+}
+
+func newRecordIterator(ctx context.Context, query *QueryBuilder, pageSize int, concurrency int, fetch pageFetcher) *RecordIterator {
+	if pageSize < 1 || pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	if concurrency < 1 {
+		concurrency = DefaultQueryConcurrency
+	}
+	// pages are requested in parallel, so without stable order they could overlap or skip rows
+	if len(query.order) == 0 {
+		query = query.Clone().OrderBy("id")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	it := &RecordIterator{
+		pages:  make(chan pageResult, concurrency),
+		cancel: cancel,
+	}
+	it.done.Add(1)
+	go it.run(ctx, query, pageSize, concurrency, fetch)
+	return it
+}
+
+// run requests pages in batches of concurrency size and sends them to channel in order of offsets.
+func (it *RecordIterator) run(ctx context.Context, query *QueryBuilder, pageSize int, concurrency int, fetch pageFetcher) {
+	defer it.done.Done()
+	defer close(it.pages)
+
+	offset := 0
+	for {
+		if ctx.Err() != nil {
+			it.stopErr = ctx.Err()
+			return
+		}
+		results := make([]pageResult, concurrency)
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				page := query.Clone().Limit(offset+i*pageSize, pageSize)
+				records, err := fetch(ctx, page)
+				results[i] = pageResult{records: records, err: err}
+			}(i)
+		}
+		wg.Wait()
+
+		for _, result := range results {
+			select {
+			case it.pages <- result:
+			case <-ctx.Done():
+				it.stopErr = ctx.Err()
+				return
+			}
+			if result.err != nil || len(result.records) < pageSize {
+				return
+			}
+		}
+		offset += concurrency * pageSize
+	}
+}
+
+func (it *RecordIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for len(it.current) == 0 {
+		page, ok := <-it.pages
+		if !ok {
+			// records are not complete, when fetching was stopped by context
+			it.err = it.stopErr
+			return false
+		}
+		if page.err != nil {
+			it.err = page.err
+			return false
+		}
+		it.current = page.records
+	}
+	it.record = it.current[0]
+	it.current = it.current[1:]
+	return true
+}
+
+func (it *RecordIterator) Record() map[string]any {
+	return it.record
+}
+
+func (it *RecordIterator) Err() error {
+	return it.err
+}
+
+// Close stops fetching of next pages. It is safe to call Close several times.
+func (it *RecordIterator) Close() {
+	it.cancel()
+	it.done.Wait()
+}
+
+// Collect reads all remaining records of iterator and closes it.
+func (it *RecordIterator) Collect() ([]map[string]any, error) {
+	defer it.Close()
+	result := make([]map[string]any, 0)
+	for it.Next() {
+		result = append(result, it.Record())
+	}
+	return result, it.Err()
+}
@@ -0,0 +1,127 @@
+package vtiger
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pagedFetcher struct {
+	sync.Mutex
+	total   int
+	failAt  int
+	queries []string
+}
+
+func (f *pagedFetcher) fetch(ctx context.Context, query *QueryBuilder) ([]map[string]any, error) {
+	sql, err := query.Build()
+	if err != nil {
+		return nil, err
+	}
+	f.Lock()
+	f.queries = append(f.queries, sql)
+	f.Unlock()
+	if f.failAt > 0 && query.offset >= f.failAt {
+		return nil, errors.New("connection refused")
+	}
+	records := make([]map[string]any, 0, query.limit)
+	for i := query.offset; i < query.offset+query.limit && i < f.total; i++ {
+		records = append(records, map[string]any{"id": "17x" + strconv.Itoa(i)})
+	}
+	return records, nil
+}
+
+func TestRecordIterator_Pages(t *testing.T) {
+	tests := []struct {
+		name        string
+		total       int
+		pageSize    int
+		concurrency int
+		queries     int
+	}{
+		{name: "empty result", total: 0, pageSize: 100, concurrency: 1, queries: 1},
+		{name: "one incomplete page", total: 20, pageSize: 100, concurrency: 1, queries: 1},
+		{name: "several pages", total: 250, pageSize: 100, concurrency: 1, queries: 3},
+		{name: "exact amount of pages", total: 200, pageSize: 100, concurrency: 1, queries: 3},
+		{name: "concurrent requests", total: 250, pageSize: 50, concurrency: 3, queries: 6},
+		{name: "page size is limited by vtiger", total: 150, pageSize: 500, concurrency: 1, queries: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &pagedFetcher{total: tt.total}
+			query := NewQuery("HelpDesk").Where(Eq("parent_id", "11x1"))
+
+			records, err := newRecordIterator(context.Background(), query, tt.pageSize, tt.concurrency, fetcher.fetch).Collect()
+
+			assert.NoError(t, err)
+			assert.Len(t, records, tt.total)
+			for i, record := range records {
+				assert.Equal(t, "17x"+strconv.Itoa(i), record["id"])
+			}
+			assert.Len(t, fetcher.queries, tt.queries)
+			assert.Equal(t, 0, query.limit, "original query should not be changed")
+		})
+	}
+}
+
+func TestRecordIterator_Error(t *testing.T) {
+	fetcher := &pagedFetcher{total: 500, failAt: 200}
+	it := newRecordIterator(context.Background(), NewQuery("Invoice"), 100, 1, fetcher.fetch)
+	defer it.Close()
+
+	count := 0
+	for it.Next() {
+		count++
+	}
+	assert.Equal(t, 200, count)
+	assert.EqualError(t, it.Err(), "connection refused")
+	assert.False(t, it.Next())
+}
+
+func TestRecordIterator_Close(t *testing.T) {
+	fetcher := &pagedFetcher{total: 100000}
+	it := newRecordIterator(context.Background(), NewQuery("Invoice"), 100, 2, fetcher.fetch)
+
+	assert.True(t, it.Next())
+	it.Close()
+	it.Close()
+
+	fetcher.Lock()
+	defer fetcher.Unlock()
+	assert.Less(t, len(fetcher.queries), 10)
+}
+
+func TestRecordIterator_Cancel(t *testing.T) {
+	fetcher := &pagedFetcher{total: 100000}
+	ctx, cancel := context.WithCancel(context.Background())
+	it := newRecordIterator(ctx, NewQuery("Invoice"), 100, 1, fetcher.fetch)
+	defer it.Close()
+
+	assert.True(t, it.Next())
+	cancel()
+	for it.Next() {
+	}
+
+	assert.ErrorIs(t, it.Err(), context.Canceled)
+}
+
+func TestVtigerConnector_QueryAll(t *testing.T) {
+	connector, fetcher := newRecordingConnector(`[{"id":"17x1"},{"id":"17x2"}]`)
+	connector.connection.PageSize = 2
+
+	it := connector.QueryAll(context.Background(), NewQuery("ProjectTask").Where(Eq("projectid", "31x1")))
+	count := 0
+	for count < 5 && it.Next() {
+		count++
+	}
+	it.Close()
+
+	assert.NoError(t, it.Err())
+	assert.Equal(t, `SELECT * FROM ProjectTask WHERE projectid = '31x1' ORDER BY id ASC LIMIT 0, 2;`, fetcher.queries[0])
+	assert.Equal(t, `SELECT * FROM ProjectTask WHERE projectid = '31x1' ORDER BY id ASC LIMIT 2, 2;`, fetcher.queries[1])
+}
@@ -53,6 +53,31 @@ func (m MockedConnector) Query(ctx context.Context, query string) (*VtigerRespon
 	return &VtigerResponse[[]map[string]any]{Result: result}, nil
 }
 
+func (m MockedConnector) Select(ctx context.Context, query *QueryBuilder) ([]map[string]any, error) {
+	result := make([]map[string]any, 2)
+	result[0] = MockedEntity.ConvertToMap()
+	result[1] = MockedEntity.ConvertToMap()
+	return result, nil
+}
+
+func (m MockedConnector) QueryAll(ctx context.Context, query *QueryBuilder) *RecordIterator {
+	return newRecordIterator(ctx, query, MaxPageSize, DefaultQueryConcurrency, m.Select)
+}
+
+func (m MockedConnector) SelectAll(ctx context.Context, query *QueryBuilder) ([]map[string]any, error) {
+	return m.QueryAll(ctx, query).Collect()
+}
+
+func (m MockedConnector) SelectCount(ctx context.Context, query *QueryBuilder) (int, error) {
+	return 2, nil
+}
+
+func (m MockedConnector) Sync(ctx context.Context, module string, modifiedTime int64) (*VtigerResponse[SyncResult], error) {
+	updated := make([]map[string]any, 1)
+	updated[0] = MockedEntity.ConvertToMap()
+	return &VtigerResponse[SyncResult]{Result: SyncResult{Updated: updated, LastModifiedTime: modifiedTime + 1}}, nil
+}
+
 func (m MockedConnector) RetrieveRelated(ctx context.Context, id string, module string) (*VtigerResponse[[]map[string]any], error) {
 	result := make([]map[string]any, 2)
 	result[0] = MockedEntity.ConvertToMap()
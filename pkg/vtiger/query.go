@@ -0,0 +1,330 @@
+package vtiger
+
+import (
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidIdentifier = errors.New("invalid identifier in query")
+
+var ErrEmptyCondition = errors.New("empty condition in query")
+
+// ErrNestedCondition is returned for AND and OR in one WHERE clause, VTQL does not support parentheses.
+var ErrNestedCondition = errors.New("vtql does not support grouped conditions")
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Condition is a part of WHERE clause, it is rendered only by QueryBuilder.
+type Condition interface {
+	render(sb *strings.Builder) error
+}
+
+type comparison struct {
+	field    string
+	operator string
+	value    any
+}
+
+type inCondition struct {
+	field  string
+	values []any
+}
+
+type group struct {
+	glue       string
+	conditions []Condition
+}
+
+type orderField struct {
+	field string
+	desc  bool
+}
+
+// QueryBuilder generates VTQL queries with escaped values instead of concatenating user input.
+type QueryBuilder struct {
+	module string
+	fields []string
+	count  bool
+	where  []Condition
+	order  []orderField
+	offset int
+	limit  int
+}
+
+func NewQuery(module string) *QueryBuilder {
+	return &QueryBuilder{module: module}
+}
+
+func (q *QueryBuilder) Select(fields ...string) *QueryBuilder {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+func (q *QueryBuilder) Count() *QueryBuilder {
+	q.count = true
+	return q
+}
+
+// Where adds conditions joined with AND to the query.
+func (q *QueryBuilder) Where(conditions ...Condition) *QueryBuilder {
+	for _, condition := range conditions {
+		if condition != nil {
+			q.where = append(q.where, condition)
+		}
+	}
+	return q
+}
+
+// OrderBy accepts sort string in the format used by api, for example "-ticket_no,title".
+func (q *QueryBuilder) OrderBy(sort string) *QueryBuilder {
+	if sort == "" {
+		return q
+	}
+	for _, field := range strings.Split(sort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.HasPrefix(field, "-") {
+			q.order = append(q.order, orderField{field: strings.TrimPrefix(field, "-"), desc: true})
+		} else {
+			q.order = append(q.order, orderField{field: field})
+		}
+	}
+	return q
+}
+
+func (q *QueryBuilder) Limit(offset int, limit int) *QueryBuilder {
+	if offset < 0 {
+		offset = 0
+	}
+	q.offset = offset
+	q.limit = limit
+	return q
+}
+
+// Clone copies builder, so it can be changed without affecting the original query.
+func (q *QueryBuilder) Clone() *QueryBuilder {
+	clone := *q
+	clone.fields = append([]string(nil), q.fields...)
+	clone.where = append([]Condition(nil), q.where...)
+	clone.order = append([]orderField(nil), q.order...)
+	return &clone
+}
+
+// Page sets limit clause from page number, which starts from 1.
+func (q *QueryBuilder) Page(page int, size int) *QueryBuilder {
+	return q.Limit((page-1)*size, size)
+}
+
+func (q *QueryBuilder) Build() (string, error) {
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	switch {
+	case q.count:
+		sb.WriteString("COUNT(*)")
+	case len(q.fields) == 0:
+		sb.WriteString("*")
+	default:
+		for i, field := range q.fields {
+			if err := validateIdentifier(field); err != nil {
+				return "", err
+			}
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(field)
+		}
+	}
+	if err := validateIdentifier(q.module); err != nil {
+		return "", err
+	}
+	sb.WriteString(" FROM ")
+	sb.WriteString(q.module)
+
+	if len(q.where) > 0 {
+		sb.WriteString(" WHERE ")
+		if err := And(q.where...).render(&sb); err != nil {
+			return "", err
+		}
+	}
+
+	if len(q.order) > 0 {
+		sb.WriteString(" ORDER BY ")
+		for i, order := range q.order {
+			if err := validateIdentifier(order.field); err != nil {
+				return "", err
+			}
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(order.field)
+			if order.desc {
+				sb.WriteString(" DESC")
+			} else {
+				sb.WriteString(" ASC")
+			}
+		}
+	}
+
+	if q.limit > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.Itoa(q.offset))
+		sb.WriteString(", ")
+		sb.WriteString(strconv.Itoa(q.limit))
+	}
+	sb.WriteString(";")
+	return sb.String(), nil
+}
+
+func Eq(field string, value any) Condition {
+	return comparison{field: field, operator: "=", value: value}
+}
+
+func NotEq(field string, value any) Condition {
+	return comparison{field: field, operator: "!=", value: value}
+}
+
+func Gt(field string, value any) Condition {
+	return comparison{field: field, operator: ">", value: value}
+}
+
+func Lt(field string, value any) Condition {
+	return comparison{field: field, operator: "<", value: value}
+}
+
+// Like uses pattern as is, so wildcards passed in pattern keep their meaning.
+func Like(field string, pattern string) Condition {
+	return comparison{field: field, operator: "LIKE", value: pattern}
+}
+
+// Contains searches for substring, wildcards inside value are escaped.
+func Contains(field string, value string) Condition {
+	return Like(field, "%"+EscapeLike(value)+"%")
+}
+
+func In(field string, values ...any) Condition {
+	return inCondition{field: field, values: values}
+}
+
+func InStrings(field string, values ...string) Condition {
+	converted := make([]any, 0, len(values))
+	for _, value := range values {
+		converted = append(converted, value)
+	}
+	return In(field, converted...)
+}
+
+func And(conditions ...Condition) Condition {
+	return group{glue: " AND ", conditions: conditions}
+}
+
+func Or(conditions ...Condition) Condition {
+	return group{glue: " OR ", conditions: conditions}
+}
+
+func (c comparison) render(sb *strings.Builder) error {
+	if err := validateIdentifier(c.field); err != nil {
+		return err
+	}
+	sb.WriteString(c.field)
+	sb.WriteString(" ")
+	sb.WriteString(c.operator)
+	sb.WriteString(" ")
+	sb.WriteString(QuoteValue(c.value))
+	return nil
+}
+
+func (c inCondition) render(sb *strings.Builder) error {
+	if err := validateIdentifier(c.field); err != nil {
+		return err
+	}
+	if len(c.values) == 0 {
+		return e.Wrap("IN condition for field "+c.field, ErrEmptyCondition)
+	}
+	sb.WriteString(c.field)
+	sb.WriteString(" IN (")
+	for i, value := range c.values {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(QuoteValue(value))
+	}
+	sb.WriteString(")")
+	return nil
+}
+
+func (g group) render(sb *strings.Builder) error {
+	conditions := make([]Condition, 0, len(g.conditions))
+	for _, condition := range g.conditions {
+		if condition != nil {
+			conditions = append(conditions, condition)
+		}
+	}
+	if len(conditions) == 0 {
+		return ErrEmptyCondition
+	}
+	for i, condition := range conditions {
+		if i > 0 {
+			sb.WriteString(g.glue)
+		}
+		// group with the same glue is rendered inline, other groups would need parentheses
+		nested, isGroup := condition.(group)
+		if isGroup && nested.glue != g.glue && len(conditions) > 1 && len(nested.conditions) > 1 {
+			return e.Wrap("OR inside AND", ErrNestedCondition)
+		}
+		if err := condition.render(sb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QuoteValue converts value to VTQL literal. Strings are always quoted and escaped.
+func QuoteValue(value any) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case string:
+		return "'" + escapeString(v) + "'"
+	case nil:
+		return "''"
+	default:
+		return "''"
+	}
+}
+
+// EscapeLike escapes wildcard symbols, so they are searched literally in LIKE condition.
+func EscapeLike(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(value)
+}
+
+func escapeString(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", "", "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+	return replacer.Replace(value)
+}
+
+// IsValidIdentifier checks, that value can be used as field or module name without escaping.
+func IsValidIdentifier(identifier string) bool {
+	return identifierPattern.MatchString(identifier)
+}
+
+func validateIdentifier(identifier string) error {
+	if !identifierPattern.MatchString(identifier) {
+		return e.Wrap("identifier "+strconv.Quote(identifier), ErrInvalidIdentifier)
+	}
+	return nil
+}
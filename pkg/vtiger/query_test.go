@@ -0,0 +1,130 @@
+package vtiger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    *QueryBuilder
+		expected string
+	}{
+		{
+			name:     "Select all",
+			query:    NewQuery("HelpDesk"),
+			expected: "SELECT * FROM HelpDesk;",
+		},
+		{
+			name:     "Select fields with condition",
+			query:    NewQuery("Faq").Select("id", "question").Where(Eq("faqstatus", "Published")),
+			expected: "SELECT id, question FROM Faq WHERE faqstatus = 'Published';",
+		},
+		{
+			name:     "Count with numeric value",
+			query:    NewQuery("Products").Count().Where(Eq("discontinued", 1)),
+			expected: "SELECT COUNT(*) FROM Products WHERE discontinued = 1;",
+		},
+		{
+			name:     "Or without other conditions",
+			query:    NewQuery("HelpDesk").Where(Or(Contains("ticket_title", "printer"), Contains("ticket_no", "printer"))),
+			expected: "SELECT * FROM HelpDesk WHERE ticket_title LIKE '%printer%' OR ticket_no LIKE '%printer%';",
+		},
+		{
+			name:     "And groups are flattened",
+			query:    NewQuery("HelpDesk").Where(Eq("parent_id", "11x1"), And(Eq("ticketstatus", "Open"), Eq("ticketpriorities", "High"))),
+			expected: "SELECT * FROM HelpDesk WHERE parent_id = '11x1' AND ticketstatus = 'Open' AND ticketpriorities = 'High';",
+		},
+		{
+			name:     "In condition",
+			query:    NewQuery("Invoice").Select("hdnGrandTotal").Where(InStrings("invoicestatus", "Created", "Sent")),
+			expected: "SELECT hdnGrandTotal FROM Invoice WHERE invoicestatus IN ('Created', 'Sent');",
+		},
+		{
+			name:     "Order and pagination",
+			query:    NewQuery("Invoice").OrderBy("-invoice_no,subject").Page(3, 20),
+			expected: "SELECT * FROM Invoice ORDER BY invoice_no DESC, subject ASC LIMIT 40, 20;",
+		},
+		{
+			name:     "Quote is escaped",
+			query:    NewQuery("Contacts").Where(Eq("email", "x' OR '1'='1")),
+			expected: `SELECT * FROM Contacts WHERE email = 'x\' OR \'1\'=\'1';`,
+		},
+		{
+			name:     "Backslash can not close a string",
+			query:    NewQuery("Contacts").Where(Eq("email", `x\' OR 1=1 --`)),
+			expected: `SELECT * FROM Contacts WHERE email = 'x\\\' OR 1=1 --';`,
+		},
+		{
+			name:     "Wildcards in search are literal",
+			query:    NewQuery("Faq").Where(Contains("question", "100%_done")),
+			expected: `SELECT * FROM Faq WHERE question LIKE '%100\\%\\_done%';`,
+		},
+		{
+			name:     "Statement terminator stays inside literal",
+			query:    NewQuery("Faq").Where(Contains("question", "a'; DELETE FROM Faq;")),
+			expected: `SELECT * FROM Faq WHERE question LIKE '%a\'; DELETE FROM Faq;%';`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.query.Build()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+func TestQueryBuilder_BuildRejectsInvalidIdentifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *QueryBuilder
+	}{
+		{
+			name:  "Module name",
+			query: NewQuery("HelpDesk WHERE 1=1"),
+		},
+		{
+			name:  "Selected field",
+			query: NewQuery("HelpDesk").Select("id, (SELECT 1)"),
+		},
+		{
+			name:  "Condition field",
+			query: NewQuery("HelpDesk").Where(Eq("parent_id = '' OR 1", "11x1")),
+		},
+		{
+			name:  "Sort field",
+			query: NewQuery("HelpDesk").OrderBy("-ticket_no;DROP"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.query.Build()
+			assert.True(t, errors.Is(err, ErrInvalidIdentifier))
+		})
+	}
+}
+
+func TestQueryBuilder_BuildRejectsEmptyConditions(t *testing.T) {
+	_, err := NewQuery("HelpDesk").Where(Or()).Build()
+	assert.True(t, errors.Is(err, ErrEmptyCondition))
+
+	_, err = NewQuery("HelpDesk").Where(In("ticketstatus")).Build()
+	assert.True(t, errors.Is(err, ErrEmptyCondition))
+}
+
+func TestQueryBuilder_BuildRejectsGroupedConditions(t *testing.T) {
+	_, err := NewQuery("HelpDesk").Where(
+		Eq("parent_id", "11x1"),
+		Or(Contains("ticket_title", "printer"), Contains("ticket_no", "printer")),
+	).Build()
+	assert.True(t, errors.Is(err, ErrNestedCondition))
+
+	_, err = NewQuery("HelpDesk").Where(Or(Eq("parent_id", "11x1"), And(Eq("ticketstatus", "Open"), Eq("ticketpriorities", "High")))).Build()
+	assert.True(t, errors.Is(err, ErrNestedCondition))
+}
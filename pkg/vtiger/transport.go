@@ -0,0 +1,283 @@
+package vtiger
+
+import (
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("vtiger is unavailable, circuit breaker is open")
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// idempotentOperations are retried on network errors and 5xx responses, other operations could change data twice.
+var idempotentOperations = map[string]bool{
+	"query":    true,
+	"retrieve": true,
+	"describe": true,
+	"sync":     true,
+}
+
+type TransportConfig struct {
+	Timeout           time.Duration            `yaml:"timeout"`
+	OperationTimeouts map[string]time.Duration `yaml:"operationTimeouts"`
+	MaxIdleConns      int                      `yaml:"maxIdleConns"`
+	MaxConnsPerHost   int                      `yaml:"maxConnsPerHost"`
+	IdleConnTimeout   time.Duration            `yaml:"idleConnTimeout"`
+	Retries           int                      `yaml:"retries"`
+	RetryBackoff      time.Duration            `yaml:"retryBackoff"`
+	MaxRetryBackoff   time.Duration            `yaml:"maxRetryBackoff"`
+	BreakerThreshold  int                      `yaml:"breakerThreshold"`
+	BreakerCooldown   time.Duration            `yaml:"breakerCooldown"`
+}
+
+// Transport sends requests to vtiger with a pooled http client, retries idempotent operations
+// and stops sending requests, when vtiger fails too often.
+type Transport struct {
+	client  *http.Client
+	config  TransportConfig
+	breaker *CircuitBreaker
+}
+
+var transports = struct {
+	sync.Mutex
+	items map[string]*Transport
+}{items: make(map[string]*Transport)}
+
+// SharedTransport returns transport for vtiger url, so all connectors share connection pool and circuit breaker.
+func SharedTransport(config VtigerConnectionConfig) *Transport {
+	transports.Lock()
+	defer transports.Unlock()
+	transport, ok := transports.items[config.Url]
+	if !ok {
+		transport = NewTransport(config.Transport)
+		transports.items[config.Url] = transport
+	}
+	return transport
+}
+
+func NewTransport(config TransportConfig) *Transport {
+	if config.Timeout <= 0 {
+		config.Timeout = TimeoutInSec * time.Second
+	}
+	if config.MaxIdleConns < 1 {
+		config.MaxIdleConns = 20
+	}
+	if config.IdleConnTimeout <= 0 {
+		config.IdleConnTimeout = 90 * time.Second
+	}
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 200 * time.Millisecond
+	}
+	if config.MaxRetryBackoff <= 0 {
+		config.MaxRetryBackoff = 5 * time.Second
+	}
+	if config.BreakerThreshold < 1 {
+		config.BreakerThreshold = 5
+	}
+	if config.BreakerCooldown <= 0 {
+		config.BreakerCooldown = 30 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: config.Timeout, KeepAlive: 30 * time.Second}
+	return &Transport{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				DialContext:         dialer.DialContext,
+				MaxIdleConns:        config.MaxIdleConns,
+				MaxIdleConnsPerHost: config.MaxIdleConns,
+				MaxConnsPerHost:     config.MaxConnsPerHost,
+				IdleConnTimeout:     config.IdleConnTimeout,
+				TLSHandshakeTimeout: config.Timeout,
+			},
+		},
+		config:  config,
+		breaker: NewCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown),
+	}
+}
+
+func (t *Transport) Breaker() *CircuitBreaker {
+	return t.breaker
+}
+
+// Do executes request created by newRequest. Request is created again for every attempt, because body can be read only once.
+func (t *Transport) Do(ctx context.Context, operation string, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	attempts := 1
+	if idempotentOperations[operation] {
+		attempts += t.config.Retries
+	}
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := t.wait(ctx, attempt); err != nil {
+				return []byte{}, err
+			}
+		}
+		if !t.breaker.Allow() {
+			return []byte{}, ErrCircuitOpen
+		}
+		body, retryable, err := t.send(ctx, operation, newRequest)
+		if err == nil {
+			t.breaker.Success()
+			return body, nil
+		}
+		if !retryable {
+			// vtiger answered, so it is available
+			t.breaker.Success()
+			return []byte{}, err
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			// request was cancelled by caller, it says nothing about vtiger state
+			t.breaker.Release()
+			break
+		}
+		t.breaker.Failure()
+	}
+	return []byte{}, lastErr
+}
+
+func (t *Transport) send(ctx context.Context, operation string, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout(operation))
+	defer cancel()
+
+	req, err := newRequest(ctx)
+	if err != nil {
+		return []byte{}, false, err
+	}
+	req.Header.Set("User-Agent", "Go-Portal")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, err := t.client.Do(req)
+	if err != nil {
+		return []byte{}, true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, res.Body)
+		return []byte{}, res.StatusCode >= http.StatusInternalServerError, e.Wrap("status code: "+strconv.Itoa(res.StatusCode), ErrWrongStatusCode)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return []byte{}, true, err
+	}
+	return body, false, nil
+}
+
+func (t *Transport) timeout(operation string) time.Duration {
+	if timeout, ok := t.config.OperationTimeouts[operation]; ok && timeout > 0 {
+		return timeout
+	}
+	return t.config.Timeout
+}
+
+// wait sleeps with exponential backoff before next attempt: backoff, 2*backoff, 4*backoff and so on.
+func (t *Transport) wait(ctx context.Context, attempt int) error {
+	delay := t.config.RetryBackoff << (attempt - 1)
+	if delay > t.config.MaxRetryBackoff || delay <= 0 {
+		delay = t.config.MaxRetryBackoff
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// CircuitBreaker opens after threshold of consecutive failures. After cooldown one request is allowed
+// to check, if vtiger is available again.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+type BreakerState struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: BreakerClosed, now: time.Now}
+}
+
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Release allows next probe request, when current one was cancelled without result.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := BreakerState{State: b.state, Failures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		state.OpenedAt = &openedAt
+	}
+	return state
+}
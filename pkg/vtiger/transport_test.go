@@ -0,0 +1,164 @@
+package vtiger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTransport(config TransportConfig) *Transport {
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = time.Millisecond
+	}
+	return NewTransport(config)
+}
+
+func TestWebRequests_RetriesIdempotentOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		send     func(w WebRequests) error
+		requests int32
+	}{
+		{
+			name:   "query is retried on server error",
+			status: http.StatusBadGateway,
+			send: func(w WebRequests) error {
+				_, err := w.FetchBytes(context.Background(), "operation=query&sessionName=1&query=SELECT")
+				return err
+			},
+			requests: 3,
+		},
+		{
+			name:   "create is not retried",
+			status: http.StatusBadGateway,
+			send: func(w WebRequests) error {
+				_, err := w.SendObject(context.Background(), "create", "1", "HelpDesk", map[string]any{"ticket_title": "test"})
+				return err
+			},
+			requests: 1,
+		},
+		{
+			name:   "client errors are not retried",
+			status: http.StatusNotFound,
+			send: func(w WebRequests) error {
+				_, err := w.FetchBytes(context.Background(), "operation=retrieve&sessionName=1&id=17x1")
+				return err
+			},
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			webRequest := WebRequests{config: VtigerConnectionConfig{Url: server.URL}, transport: newTestTransport(TransportConfig{Retries: 2})}
+			err := tt.send(webRequest)
+
+			assert.True(t, errors.Is(err, ErrWrongStatusCode))
+			assert.Equal(t, tt.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestWebRequests_RetrySucceeds(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"success":true,"result":[]}`))
+	}))
+	defer server.Close()
+
+	webRequest := WebRequests{config: VtigerConnectionConfig{Url: server.URL}, transport: newTestTransport(TransportConfig{Retries: 2})}
+	body, err := webRequest.FetchBytes(context.Background(), "operation=describe&sessionName=1&elementType=HelpDesk")
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"success":true,"result":[]}`, string(body))
+	assert.Equal(t, BreakerClosed, webRequest.transport.Breaker().State().State)
+}
+
+func TestWebRequests_OperationTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	webRequest := WebRequests{config: VtigerConnectionConfig{Url: server.URL}, transport: newTestTransport(TransportConfig{
+		Timeout:           time.Second,
+		OperationTimeouts: map[string]time.Duration{"query": 20 * time.Millisecond},
+	})}
+
+	start := time.Now()
+	_, err := webRequest.FetchBytes(context.Background(), "operation=query&sessionName=1")
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestTransport_CircuitBreakerFailsFast(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webRequest := WebRequests{config: VtigerConnectionConfig{Url: server.URL}, transport: newTestTransport(TransportConfig{
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	})}
+
+	for i := 0; i < 2; i++ {
+		_, err := webRequest.SendData(context.Background(), RequestData{FormParams: FormParamsData{Operation: "login"}})
+		assert.True(t, errors.Is(err, ErrWrongStatusCode))
+	}
+	_, err := webRequest.SendData(context.Background(), RequestData{FormParams: FormParamsData{Operation: "login"}})
+
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	state := webRequest.transport.Breaker().State()
+	assert.Equal(t, BreakerOpen, state.State)
+	assert.NotNil(t, state.OpenedAt)
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	breaker.Failure()
+	assert.False(t, breaker.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow(), "one probe request is allowed after cooldown")
+	assert.False(t, breaker.Allow(), "other requests wait for result of probe")
+	assert.Equal(t, BreakerHalfOpen, breaker.State().State)
+
+	breaker.Failure()
+	assert.Equal(t, BreakerOpen, breaker.State().State)
+	assert.False(t, breaker.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.Allow())
+	breaker.Success()
+	assert.Equal(t, BreakerState{State: BreakerClosed}, breaker.State())
+	assert.True(t, breaker.Allow())
+}
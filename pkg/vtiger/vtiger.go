@@ -9,6 +9,7 @@ import (
 	"github.com/semelyanov86/vtiger-portal/pkg/cache"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -40,11 +41,14 @@ type VtigerConnector struct {
 var sessionIdMutex = sync.Mutex{}
 
 type VtigerConnectionConfig struct {
-	Url                   string `yaml:"url"`
-	Login                 string `yaml:"login"`
-	Password              string `yaml:"password"`
-	PersistenceConnection bool   `yaml:"persistenceConnection"`
-	MaxRetries            int    `yaml:"maxRetries"`
+	Url                   string          `yaml:"url"`
+	Login                 string          `yaml:"login"`
+	Password              string          `yaml:"password"`
+	PersistenceConnection bool            `yaml:"persistenceConnection"`
+	MaxRetries            int             `yaml:"maxRetries"`
+	PageSize              int             `yaml:"pageSize"`
+	QueryConcurrency      int             `yaml:"queryConcurrency"`
+	Transport             TransportConfig `yaml:"transport"`
 }
 
 type SessionData struct {
@@ -57,7 +61,15 @@ type SessionData struct {
 }
 
 type ResultData interface {
-	SessionData | Module | map[string]any | []map[string]any | []File
+	SessionData | Module | map[string]any | []map[string]any | []File | SyncResult
+}
+
+// SyncResult is a response of sync operation: records changed after requested modified time.
+type SyncResult struct {
+	Updated          []map[string]any `json:"updated"`
+	Deleted          []string         `json:"deleted"`
+	LastModifiedTime int64            `json:"lastModifiedTime"`
+	More             bool             `json:"more"`
 }
 
 type ErrorData struct {
@@ -237,6 +249,32 @@ func (c VtigerConnector) Retrieve(ctx context.Context, id string) (*VtigerRespon
 	return processVtigerResponse[map[string]any](resp, vtigerResponse)
 }
 
+// Sync returns records of module, which were changed or deleted after modifiedTime (unix timestamp).
+func (c VtigerConnector) Sync(ctx context.Context, module string, modifiedTime int64) (*VtigerResponse[SyncResult], error) {
+	sessionID, err := c.sessionId()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.fetcher.FetchBytes(ctx, url.Values{
+		"operation":    {"sync"},
+		"sessionName":  {sessionID},
+		"modifiedTime": {strconv.FormatInt(modifiedTime, 10)},
+		"elementType":  {module},
+	}.Encode())
+	if err != nil {
+		return nil, e.Wrap("code 7", err)
+	}
+
+	err = c.close(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	vtigerResponse := &VtigerResponse[SyncResult]{}
+
+	return processVtigerResponse[SyncResult](resp, vtigerResponse)
+}
+
 func (c VtigerConnector) Describe(ctx context.Context, element string) (*VtigerResponse[Module], error) {
 	sessionID, err := c.sessionId()
 	if err != nil {
@@ -380,14 +418,21 @@ func (c VtigerConnector) Create(ctx context.Context, element string, data map[st
 }
 
 func (c VtigerConnector) Count(ctx context.Context, module string, filters map[string]string) (int, error) {
-	query := "SELECT COUNT(*) FROM " + module + " WHERE "
-	for field, value := range filters {
-		field = strings.TrimPrefix(field, "_")
-		query += field + " = '" + value + "' OR "
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	conditions := make([]Condition, 0, len(fields))
+	for _, field := range fields {
+		conditions = append(conditions, Eq(strings.TrimPrefix(field, "_"), filters[field]))
+	}
+	query := NewQuery(module)
+	if len(conditions) > 0 {
+		query.Where(Or(conditions...))
 	}
-	query = strings.TrimSuffix(query, " OR ")
-	query += ";"
-	return c.ExecuteCount(ctx, query)
+	return c.SelectCount(ctx, query)
 }
 
 func (c VtigerConnector) ExecuteCount(ctx context.Context, query string) (int, error) {
@@ -541,7 +586,7 @@ func (c VtigerConnector) login(session SessionData) (SessionData, error) {
 		loginResult.Result.ServerTime = session.ServerTime
 		responseData = loginResult
 		if err != nil {
-			c.cache.Set(TokenKey, []byte{}, 0)
+			c.cache.Delete(TokenKey)
 			return sessionData, e.Wrap("wrong response received from vtiger during login", err)
 		}
 		tryCounter++
@@ -568,7 +613,7 @@ func (c VtigerConnector) login(session SessionData) (SessionData, error) {
 	}
 
 	if responseData.Error.Code == "INVALID_USER_CREDENTIALS" || responseData.Error.Code == "INVALID_SESSIONID" {
-		c.cache.Set(TokenKey, []byte{}, 0)
+		c.cache.Delete(TokenKey)
 		return sessionData, ErrWrongCredentials
 	}
 
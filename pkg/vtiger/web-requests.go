@@ -5,15 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/semelyanov86/vtiger-portal/pkg/e"
-	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 )
 
 type WebRequests struct {
-	config VtigerConnectionConfig
+	config    VtigerConnectionConfig
+	transport *Transport
 }
 
 type CrmFetcher interface {
@@ -24,33 +23,14 @@ type CrmFetcher interface {
 var ErrWrongStatusCode = errors.New("wrong status code")
 
 func NewWebRequest(config VtigerConnectionConfig) WebRequests {
-	return WebRequests{config: config}
+	return WebRequests{config: config, transport: SharedTransport(config)}
 }
 
 func (w WebRequests) FetchBytes(ctx context.Context, postfix string) ([]byte, error) {
 	url := w.config.Url + "?" + postfix
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-
-	if err != nil {
-		return []byte{}, err
-	}
-	req.Header.Set("User-Agent", "Go-Portal")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return []byte{}, err
-	}
-	if res.StatusCode != http.StatusOK {
-		return []byte{}, e.Wrap("status code: "+strconv.Itoa(res.StatusCode), ErrWrongStatusCode)
-	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return []byte{}, err
-	}
-
-	return body, nil
+	return w.transport.Do(ctx, operationFromQuery(postfix), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	})
 }
 
 func (w WebRequests) SendData(ctx context.Context, data RequestData) ([]byte, error) {
@@ -60,29 +40,7 @@ func (w WebRequests) SendData(ctx context.Context, data RequestData) ([]byte, er
 		"accessKey":   {data.FormParams.AccessKey},
 		"sessionName": {data.FormParams.SessionName},
 	}
-	reqBody := strings.NewReader(form.Encode())
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.Url, reqBody)
-
-	if err != nil {
-		return []byte{}, err
-	}
-	req.Header.Set("User-Agent", "Go-Portal")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return []byte{}, err
-	}
-	if res.StatusCode != http.StatusOK {
-		return []byte{}, e.Wrap("status code: "+strconv.Itoa(res.StatusCode), ErrWrongStatusCode)
-	}
-	defer res.Body.Close()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return []byte{}, err
-	}
-
-	return body, nil
+	return w.post(ctx, data.FormParams.Operation, form)
 }
 
 func (w WebRequests) SendObject(ctx context.Context, operation string, session string, elementType string, data map[string]any) ([]byte, error) {
@@ -98,28 +56,24 @@ func (w WebRequests) SendObject(ctx context.Context, operation string, session s
 		"elementType": {elementType},
 	}
 
-	reqBody := strings.NewReader(form.Encode())
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.Url, reqBody)
-
-	if err != nil {
-		return []byte{}, e.Wrap("can not generate request", err)
-	}
-	req.Header.Set("User-Agent", "Go-Portal")
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	res, err := http.DefaultClient.Do(req)
+	body, err := w.post(ctx, operation, form)
 	if err != nil {
-		return []byte{}, err
-	}
-	if res.StatusCode != http.StatusOK {
-		return []byte{}, e.Wrap("status code: "+strconv.Itoa(res.StatusCode), ErrWrongStatusCode)
+		return body, e.Wrap("can not send "+operation+" request", err)
 	}
-	defer res.Body.Close()
+	return body, nil
+}
 
-	body, err := io.ReadAll(res.Body)
+func (w WebRequests) post(ctx context.Context, operation string, form url.Values) ([]byte, error) {
+	encoded := form.Encode()
+	return w.transport.Do(ctx, operation, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, w.config.Url, strings.NewReader(encoded))
+	})
+}
+
+func operationFromQuery(postfix string) string {
+	values, err := url.ParseQuery(postfix)
 	if err != nil {
-		return []byte{}, err
+		return ""
 	}
-
-	return body, nil
+	return values.Get("operation")
 }
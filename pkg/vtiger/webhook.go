@@ -0,0 +1,43 @@
+package vtiger
+
+import (
+	"encoding/json"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"github.com/semelyanov86/vtiger-portal/pkg/webhook"
+	"time"
+)
+
+const WebhookSignatureHeader = "X-Vtiger-Signature"
+
+var ErrWebhookNoSecret = webhook.ErrNoSecret
+var ErrWebhookInvalidSignature = webhook.ErrInvalidSignature
+
+// WebhookEvent is sent by vtiger workflow, when record is changed.
+type WebhookEvent struct {
+	Module         string         `json:"module"`
+	Id             string         `json:"id"`
+	Label          string         `json:"label"`
+	AccountId      string         `json:"account_id"`
+	AssignedUserId string         `json:"assigned_user_id"`
+	Changed        map[string]any `json:"changed"`
+	// Delivery identifies signed request, so replayed request can be rejected.
+	Delivery string `json:"-"`
+}
+
+// SignWebhookPayload returns value of signature header, which vtiger workflow should send.
+func SignWebhookPayload(payload []byte, secret string, timestamp time.Time) string {
+	return webhook.Sign(payload, secret, timestamp)
+}
+
+// ConstructWebhookEvent checks signature header and parses payload.
+func ConstructWebhookEvent(payload []byte, header string, secret string, tolerance time.Duration) (WebhookEvent, error) {
+	var event WebhookEvent
+	if err := webhook.Verify(payload, header, secret, tolerance, time.Now()); err != nil {
+		return event, err
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, e.Wrap("can not parse webhook payload", err)
+	}
+	event.Delivery = webhook.DeliveryId(payload, header)
+	return event, nil
+}
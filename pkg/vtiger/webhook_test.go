@@ -0,0 +1,33 @@
+package vtiger
+
+import (
+	"github.com/semelyanov86/vtiger-portal/pkg/webhook"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructWebhookEvent(t *testing.T) {
+	payload := []byte(`{"module":"HelpDesk","id":"17x1","account_id":"11x1","changed":{"ticketstatus":"Closed"}}`)
+
+	header := SignWebhookPayload(payload, "secret", time.Now())
+	event, err := ConstructWebhookEvent(payload, header, "secret", time.Minute)
+
+	assert.NoError(t, err)
+	assert.Equal(t, WebhookEvent{
+		Module:    "HelpDesk",
+		Id:        "17x1",
+		AccountId: "11x1",
+		Changed:   map[string]any{"ticketstatus": "Closed"},
+		Delivery:  webhook.DeliveryId(payload, header),
+	}, event)
+
+	// extra signature does not change delivery
+	replayed, err := ConstructWebhookEvent(payload, header+",v1=00", "secret", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, event.Delivery, replayed.Delivery)
+
+	_, err = ConstructWebhookEvent(payload, SignWebhookPayload(payload, "other", time.Now()), "secret", time.Minute)
+	assert.ErrorIs(t, err, ErrWebhookInvalidSignature)
+}
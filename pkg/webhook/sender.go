@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/semelyanov86/vtiger-portal/pkg/e"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const SignatureHeader = "X-Portal-Signature"
+const DefaultTimeout = 10 * time.Second
+
+var ErrUnexpectedStatus = errors.New("webhook receiver responded with unexpected status")
+var ErrPrivateAddress = errors.New("webhook url resolves to private address")
+
+// Sender posts signed payloads to customer urls. By default it refuses to connect to loopback and private networks,
+// so portal can not be used to reach internal services.
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(timeout time.Duration, allowPrivateNetworks bool) *Sender {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = denyPrivateAddresses
+	}
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				// proxy would dial private address instead of us, so the check in dialer would be bypassed
+				Proxy:               nil,
+				DialContext:         dialer.DialContext,
+				MaxIdleConns:        20,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: timeout,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send posts payload with signature header and returns status code of response. Any status except 2xx is an error.
+func (s *Sender) Send(ctx context.Context, url string, secret string, headers map[string]string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Go-Portal-Webhook")
+	req.Header.Set(SignatureHeader, Sign(payload, secret, time.Now()))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, e.Wrap("status code: "+strconv.Itoa(res.StatusCode), ErrUnexpectedStatus)
+	}
+	return res.StatusCode, nil
+}
+
+// deniedNetworks are not private by RFC 1918, but they can still be routed to internal hosts: "this" network, shared
+// address space of carrier NAT, benchmarking network and NAT64 prefix, which embeds IPv4 address.
+var deniedNetworks = parseNetworks("0.0.0.0/8", "100.64.0.0/10", "198.18.0.0/15", "64:ff9b::/96", "64:ff9b:1::/48")
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func denyPrivateAddresses(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return e.Wrap(host, ErrPrivateAddress)
+	}
+	for _, denied := range deniedNetworks {
+		if denied.Contains(ip) {
+			return e.Wrap(host, ErrPrivateAddress)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSender_Send(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	payload := []byte(`{"type":"ticket.created"}`)
+	code, err := NewSender(time.Second, true).Send(context.Background(), server.URL, "secret", map[string]string{"X-Portal-Event": "ticket.created"}, payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, payload, body)
+	assert.Equal(t, "ticket.created", received.Header.Get("X-Portal-Event"))
+	assert.NoError(t, Verify(body, received.Header.Get(SignatureHeader), "secret", time.Minute, time.Now()))
+}
+
+func TestSender_SendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:1/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		sender *Sender
+		code   int
+		err    error
+	}{
+		{name: "redirects are not followed", sender: NewSender(time.Second, true), code: http.StatusFound, err: ErrUnexpectedStatus},
+		{name: "private networks are denied", sender: NewSender(time.Second, false), code: 0, err: ErrPrivateAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := tt.sender.Send(context.Background(), server.URL, "secret", nil, []byte(`{}`))
+
+			assert.Equal(t, tt.code, code)
+			assert.True(t, errors.Is(err, tt.err), err)
+		})
+	}
+}
+
+func TestNewSender_IgnoresProxy(t *testing.T) {
+	transport := NewSender(time.Second, false).client.Transport.(*http.Transport)
+
+	assert.Nil(t, transport.Proxy)
+}
+
+func TestDenyPrivateAddresses(t *testing.T) {
+	tests := []struct {
+		address string
+		denied  bool
+	}{
+		{address: "93.184.216.34:443", denied: false},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443", denied: false},
+		{address: "127.0.0.1:80", denied: true},
+		{address: "10.1.2.3:80", denied: true},
+		{address: "169.254.169.254:80", denied: true},
+		{address: "0.0.0.1:80", denied: true},
+		{address: "100.64.0.1:80", denied: true},
+		{address: "100.127.255.254:80", denied: true},
+		{address: "198.18.0.1:80", denied: true},
+		{address: "198.19.255.1:80", denied: true},
+		{address: "[64:ff9b::a00:1]:80", denied: true},
+		{address: "[::ffff:10.0.0.1]:80", denied: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := denyPrivateAddresses("tcp", tt.address, nil)
+
+			assert.Equal(t, tt.denied, errors.Is(err, ErrPrivateAddress), err)
+		})
+	}
+}
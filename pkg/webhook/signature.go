@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultTolerance = 5 * time.Minute
+
+var ErrNoSecret = errors.New("webhook secret is not configured")
+var ErrNoSignature = errors.New("webhook has no valid signature")
+var ErrInvalidSignature = errors.New("webhook signature does not match payload")
+var ErrTooOld = errors.New("webhook timestamp is outside of tolerance")
+
+// Sign returns value of signature header in format "t=timestamp,v1=signature", where signature is
+// hex encoded HMAC-SHA256 of "timestamp.payload". The same format is used by Stripe.
+func Sign(payload []byte, secret string, timestamp time.Time) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(computeSignature(payload, t, secret))
+}
+
+// Verify checks signature header created by Sign. Header can contain several v1 signatures, when secret is rotated.
+func Verify(payload []byte, header string, secret string, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return ErrNoSecret
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	timestamp, signatures := parseHeader(header)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrNoSignature
+	}
+	expected := computeSignature(payload, timestamp, secret)
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal(expected, signature) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+	diff := now.Sub(time.Unix(unix, 0))
+	if diff > tolerance || diff < -tolerance {
+		return ErrTooOld
+	}
+	return nil
+}
+
+// DeliveryId identifies signed payload. Header can be changed without breaking signature, so id is built from
+// timestamp and payload, which are signed.
+func DeliveryId(payload []byte, header string) string {
+	timestamp, _ := parseHeader(header)
+	hash := sha256.New()
+	hash.Write([]byte(timestamp))
+	hash.Write([]byte("."))
+	hash.Write(payload)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func parseHeader(header string) (string, [][]byte) {
+	var timestamp string
+	signatures := make([][]byte, 0, 1)
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	return timestamp, signatures
+}
+
+func computeSignature(payload []byte, timestamp string, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
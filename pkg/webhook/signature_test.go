@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"module":"HelpDesk","id":"17x1"}`)
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		err    error
+	}{
+		{name: "valid signature", header: Sign(payload, "secret", now), secret: "secret"},
+		{name: "one of rotated signatures is valid", header: Sign(payload, "old", now) + "," + strings.Split(Sign(payload, "secret", now), ",")[1], secret: "secret"},
+		{name: "wrong secret", header: Sign(payload, "other", now), secret: "secret", err: ErrInvalidSignature},
+		{name: "expired timestamp", header: Sign(payload, "secret", now.Add(-time.Hour)), secret: "secret", err: ErrTooOld},
+		{name: "timestamp from future", header: Sign(payload, "secret", now.Add(time.Hour)), secret: "secret", err: ErrTooOld},
+		{name: "empty header", header: "", secret: "secret", err: ErrNoSignature},
+		{name: "no signature", header: "t=1700000000", secret: "secret", err: ErrNoSignature},
+		{name: "secret is not configured", header: Sign(payload, "", now), secret: "", err: ErrNoSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(payload, tt.header, tt.secret, time.Minute, now)
+
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestVerify_ChangedPayload(t *testing.T) {
+	now := time.Now()
+	header := Sign([]byte(`{"module":"HelpDesk","id":"17x1"}`), "secret", now)
+
+	err := Verify([]byte(`{"module":"HelpDesk","id":"17x2"}`), header, "secret", time.Minute, now)
+
+	assert.Equal(t, ErrInvalidSignature, err)
+}